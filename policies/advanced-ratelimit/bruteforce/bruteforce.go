@@ -0,0 +1,419 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+// Package bruteforce locks out clients after repeated authentication failures, counting failed
+// attempts with the fixed-window limiter. It is shared by the authentication policies so that they
+// parse the same configuration, resolve the same client address and enforce lockouts the same way.
+//
+// A successful authentication does not reset the counters; they expire with their window. Otherwise
+// a client holding one valid credential could clear the counters of the address it guesses others
+// from.
+package bruteforce
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	_ "github.com/wso2/gateway-controllers/policies/advanced-ratelimit/algorithms/fixedwindow"
+	"github.com/wso2/gateway-controllers/policies/advanced-ratelimit/clientip"
+	"github.com/wso2/gateway-controllers/policies/advanced-ratelimit/limiter"
+)
+
+const (
+	// TrackIP tracks failed attempts by client address; policies add their own attributes
+	TrackIP = "ip"
+
+	// Failed attempt storage backends
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+
+	// cleanupInterval is how often expired in-memory counters are removed
+	cleanupInterval = time.Minute
+)
+
+// DefaultThresholds lock a client out for the rest of the window once it reaches the failure count.
+// Longer windows with higher counts make the lockout progressively longer for clients that keep
+// failing after each lockout ends.
+var DefaultThresholds = []limiter.LimitConfig{
+	{Limit: 5, Duration: time.Minute},
+	{Limit: 20, Duration: 15 * time.Minute},
+	{Limit: 50, Duration: time.Hour},
+}
+
+// Options describes the attributes a policy tracks and its storage defaults
+type Options struct {
+	Attributes       []string // Attributes supported in addition to ip
+	DefaultTrackBy   []string // Attributes tracked when trackBy is not set
+	RedisKeyPrefix   string   // Default prefix of the Redis counter keys
+	HashedAttributes []string // Attributes whose values are logged by their hash only
+}
+
+// Config holds the brute-force protection settings of a route
+type Config struct {
+	TrackBy    []string              // Attributes failed attempts are counted by
+	Thresholds []limiter.LimitConfig // Failures allowed per window before lockout
+	Backend    string                // memory or redis
+	Redis      RedisConfig           // Redis settings (redis backend only)
+	ClientIP   *clientip.Resolver    // Resolves the client address tracked by ip
+
+	hashedAttributes []string
+}
+
+// RedisConfig holds the Redis connection settings for failed attempt counters
+type RedisConfig struct {
+	Host              string
+	Port              int
+	Username          string
+	Password          string
+	DB                int
+	KeyPrefix         string
+	FailOpen          bool // Skip brute-force checks when Redis is unavailable
+	ConnectionTimeout time.Duration
+}
+
+// TrackedKey is one attribute of a request that failed attempts are counted against
+type TrackedKey struct {
+	Attribute string
+	Value     string
+	Key       string
+}
+
+// Guard counts failed attempts with one fixed-window limiter per threshold
+type Guard struct {
+	limiters []limiter.Limiter
+}
+
+// Guards holds the guards of a policy, so that routes with the same thresholds and backend share
+// counters. The zero value is ready to use.
+type Guards struct {
+	mutex  sync.Mutex
+	guards map[string]*Guard
+}
+
+// ParseConfig parses the `bruteForceProtection` parameter, the `bruteForce` system parameter and the
+// `trustedProxies` system parameter. It returns nil when protection is disabled.
+func ParseConfig(params map[string]interface{}, options Options) (*Config, error) {
+	raw, _ := params["bruteForceProtection"].(map[string]interface{})
+	if enabled, _ := raw["enabled"].(bool); !enabled {
+		return nil, nil
+	}
+
+	config := &Config{
+		TrackBy:          options.DefaultTrackBy,
+		Thresholds:       DefaultThresholds,
+		hashedAttributes: options.HashedAttributes,
+	}
+	if trackBy, ok := raw["trackBy"].([]interface{}); ok && len(trackBy) > 0 {
+		config.TrackBy = nil
+		for _, item := range trackBy {
+			attribute, _ := item.(string)
+			if attribute != TrackIP && !slices.Contains(options.Attributes, attribute) {
+				return nil, fmt.Errorf("unsupported bruteForceProtection.trackBy value %q", attribute)
+			}
+			config.TrackBy = append(config.TrackBy, attribute)
+		}
+	}
+	if thresholds, ok := raw["thresholds"].([]interface{}); ok && len(thresholds) > 0 {
+		config.Thresholds = nil
+		for _, item := range thresholds {
+			entry, _ := item.(map[string]interface{})
+			failures := getIntParam(entry, "failures", 0)
+			window, err := time.ParseDuration(getStringParam(entry, "window", ""))
+			if failures <= 0 || err != nil || window <= 0 {
+				return nil, fmt.Errorf("bruteForceProtection.thresholds entries need positive failures and window")
+			}
+			config.Thresholds = append(config.Thresholds, limiter.LimitConfig{Limit: int64(failures), Duration: window})
+		}
+	}
+
+	resolver, err := clientip.ParseTrustedProxies(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trustedProxies: %w", err)
+	}
+	config.ClientIP = resolver
+
+	system, _ := params["bruteForce"].(map[string]interface{})
+	redisRaw, _ := system["redis"].(map[string]interface{})
+	config.Backend = getStringParam(system, "backend", BackendMemory)
+	config.Redis = RedisConfig{
+		Host:              getStringParam(redisRaw, "host", "localhost"),
+		Port:              getIntParam(redisRaw, "port", 6379),
+		Username:          getStringParam(redisRaw, "username", ""),
+		Password:          getStringParam(redisRaw, "password", ""),
+		DB:                getIntParam(redisRaw, "db", 0),
+		KeyPrefix:         getStringParam(redisRaw, "keyPrefix", options.RedisKeyPrefix),
+		FailOpen:          getStringParam(redisRaw, "failureMode", "open") == "open",
+		ConnectionTimeout: getDurationParam(redisRaw, "connectionTimeout", 2*time.Second),
+	}
+	return config, nil
+}
+
+// guardKey identifies the guard a configuration resolves to
+func (c *Config) guardKey() string {
+	var b strings.Builder
+	b.WriteString(c.Backend)
+	if c.Backend == BackendRedis {
+		fmt.Fprintf(&b, "|%s:%d|%d|%s|%s", c.Redis.Host, c.Redis.Port, c.Redis.DB, c.Redis.Username, c.Redis.KeyPrefix)
+	}
+	for _, t := range c.Thresholds {
+		fmt.Fprintf(&b, "|%d/%s", t.Limit, t.Duration)
+	}
+	return b.String()
+}
+
+// Get returns the guard for the configuration, creating its limiters on first use. created reports
+// whether the guard was created by this call.
+func (g *Guards) Get(config *Config) (guard *Guard, created bool, err error) {
+	key := config.guardKey()
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if guard, ok := g.guards[key]; ok {
+		return guard, false, nil
+	}
+
+	var redisClient *redis.Client
+	if config.Backend == BackendRedis {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:         fmt.Sprintf("%s:%d", config.Redis.Host, config.Redis.Port),
+			Username:     config.Redis.Username,
+			Password:     config.Redis.Password,
+			DB:           config.Redis.DB,
+			DialTimeout:  config.Redis.ConnectionTimeout,
+			ReadTimeout:  config.Redis.ConnectionTimeout,
+			WriteTimeout: config.Redis.ConnectionTimeout,
+		})
+	}
+
+	guard = &Guard{}
+	for i, threshold := range config.Thresholds {
+		lim, err := limiter.CreateLimiter(limiter.Config{
+			Algorithm:       "fixed-window",
+			Limits:          []limiter.LimitConfig{threshold},
+			Backend:         config.Backend,
+			RedisClient:     redisClient,
+			KeyPrefix:       fmt.Sprintf("%st%d:", config.Redis.KeyPrefix, i),
+			CleanupInterval: cleanupInterval,
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create failed attempt limiter: %w", err)
+		}
+		guard.limiters = append(guard.limiters, lim)
+	}
+	if g.guards == nil {
+		g.guards = make(map[string]*Guard)
+	}
+	g.guards[key] = guard
+	return guard, true, nil
+}
+
+// Check returns the response for a request whose tracked keys are locked out, or nil when the
+// request may proceed. A failed lockout check skips the check or rejects the request with 503
+// depending on the Redis failure mode. logPrefix names the calling policy in log messages.
+func (g *Guard) Check(config *Config, keys []TrackedKey, logPrefix string) policy.RequestAction {
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), config.Redis.ConnectionTimeout)
+	defer cancel()
+
+	retryAfter, err := g.lockedFor(timeoutCtx, keys)
+	if err != nil {
+		slog.Warn(logPrefix+": Failed to check brute-force lockout",
+			"error", err,
+			"failOpen", config.Redis.FailOpen,
+		)
+		if config.Redis.FailOpen {
+			return nil
+		}
+		return UnavailableResponse()
+	}
+	if retryAfter <= 0 {
+		return nil
+	}
+
+	slog.Debug(logPrefix+": Rejecting request from locked out client",
+		"retryAfter", retryAfter,
+	)
+	return LockedOutResponse(retryAfter)
+}
+
+// RecordFailure counts a failed attempt against the keys and logs the lockouts it starts.
+// logPrefix names the calling policy in log messages.
+func (g *Guard) RecordFailure(ctx *policy.RequestContext, config *Config, keys []TrackedKey, logPrefix string) {
+	timeoutCtx, cancel := context.WithTimeout(context.Background(), config.Redis.ConnectionTimeout)
+	defer cancel()
+
+	lockouts, err := g.countFailure(timeoutCtx, keys)
+	if err != nil {
+		slog.Warn(logPrefix+": Failed to record failed authentication attempt",
+			"error", err,
+		)
+		return
+	}
+	for k, lockout := range lockouts {
+		value := k.Value
+		if slices.Contains(config.hashedAttributes, k.Attribute) {
+			value = k.Key[strings.LastIndex(k.Key, ":")+1:]
+		}
+		slog.Warn(logPrefix+": Locking out after repeated authentication failures",
+			"trackedBy", k.Attribute,
+			"value", value,
+			"lockout", lockout.Round(time.Second),
+			"apiName", ctx.APIName,
+			"apiVersion", ctx.APIVersion,
+		)
+	}
+}
+
+// lockedFor returns the longest remaining lockout of the keys, or zero when none is locked out
+func (g *Guard) lockedFor(ctx context.Context, keys []TrackedKey) (time.Duration, error) {
+	var longest time.Duration
+	for _, k := range keys {
+		for _, lim := range g.limiters {
+			available, err := lim.GetAvailable(ctx, k.Key)
+			if err != nil {
+				return 0, err
+			}
+			if available > 0 {
+				continue
+			}
+			// A denied check does not consume, so it only reports when the window resets
+			result, err := lim.AllowN(ctx, k.Key, 1)
+			if err != nil {
+				return 0, err
+			}
+			if !result.Allowed && result.RetryAfter > longest {
+				longest = result.RetryAfter
+			}
+		}
+	}
+	return longest, nil
+}
+
+// countFailure counts a failed attempt against each key and returns the lockout that started for
+// each key that reached a threshold
+func (g *Guard) countFailure(ctx context.Context, keys []TrackedKey) (map[TrackedKey]time.Duration, error) {
+	lockouts := make(map[TrackedKey]time.Duration)
+	for _, k := range keys {
+		for _, lim := range g.limiters {
+			result, err := lim.AllowN(ctx, k.Key, 1)
+			if err != nil {
+				return nil, err
+			}
+			if result.Allowed && result.Remaining == 0 {
+				if lockout := time.Until(result.Reset); lockout > lockouts[k] {
+					lockouts[k] = lockout
+				}
+			}
+		}
+	}
+	return lockouts, nil
+}
+
+// Keys returns the keys failed attempts of this request are counted against. values holds the value
+// of each policy-specific attribute; ip is resolved from the request. Keys are scoped to the API and
+// hold a hash of the tracked value.
+func Keys(ctx *policy.RequestContext, config *Config, values map[string]string) []TrackedKey {
+	scope := ctx.APIId
+	if scope == "" {
+		scope = ctx.APIName
+	}
+
+	var keys []TrackedKey
+	for _, attribute := range config.TrackBy {
+		value := values[attribute]
+		if attribute == TrackIP {
+			value = config.ClientIP.ClientIP(ctx.Headers)
+		}
+		if value == "" {
+			continue
+		}
+		sum := sha256.Sum256([]byte(value))
+		keys = append(keys, TrackedKey{
+			Attribute: attribute,
+			Value:     value,
+			Key:       scope + ":" + attribute + ":" + hex.EncodeToString(sum[:16]),
+		})
+	}
+	return keys
+}
+
+// LockedOutResponse returns the 429 response for a client locked out for retryAfter
+func LockedOutResponse(retryAfter time.Duration) policy.ImmediateResponse {
+	body, _ := json.Marshal(map[string]string{
+		"error":   "Too Many Requests",
+		"message": "Too many failed authentication attempts",
+	})
+	return policy.ImmediateResponse{
+		StatusCode: 429,
+		Headers: map[string]string{
+			"retry-after":  strconv.Itoa(max(1, int(math.Ceil(retryAfter.Seconds())))),
+			"content-type": "application/json",
+		},
+		Body: body,
+	}
+}
+
+// UnavailableResponse returns the 503 response for a lockout check that failed closed
+func UnavailableResponse() policy.ImmediateResponse {
+	body, _ := json.Marshal(map[string]string{
+		"error":   "Service Unavailable",
+		"message": "Authentication is temporarily unavailable",
+	})
+	return policy.ImmediateResponse{
+		StatusCode: 503,
+		Headers: map[string]string{
+			"content-type": "application/json",
+		},
+		Body: body,
+	}
+}
+
+// Helper functions for type assertions
+func getStringParam(params map[string]interface{}, key, defaultValue string) string {
+	if v, ok := params[key].(string); ok && v != "" {
+		return v
+	}
+	return defaultValue
+}
+
+func getIntParam(params map[string]interface{}, key string, defaultValue int) int {
+	switch v := params[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return defaultValue
+}
+
+func getDurationParam(params map[string]interface{}, key string, defaultValue time.Duration) time.Duration {
+	if d, err := time.ParseDuration(getStringParam(params, key, "")); err == nil {
+		return d
+	}
+	return defaultValue
+}
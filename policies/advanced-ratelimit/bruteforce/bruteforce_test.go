@@ -0,0 +1,158 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package bruteforce
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+var testOptions = Options{
+	Attributes:     []string{"username"},
+	DefaultTrackBy: []string{"username", TrackIP},
+	RedisKeyPrefix: "test:bruteforce:",
+}
+
+func newRequestContext(headers map[string][]string) *policy.RequestContext {
+	return &policy.RequestContext{
+		SharedContext: &policy.SharedContext{APIId: "api-1"},
+		Headers:       policy.NewHeaders(headers),
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig(map[string]interface{}{}, testOptions)
+	if err != nil || config != nil {
+		t.Fatalf("expected protection to be disabled by default, got %v %v", config, err)
+	}
+
+	config, err = ParseConfig(map[string]interface{}{
+		"bruteForceProtection": map[string]interface{}{"enabled": true},
+	}, testOptions)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(config.TrackBy) != 2 || len(config.Thresholds) != len(DefaultThresholds) ||
+		config.Backend != BackendMemory || config.Redis.KeyPrefix != "test:bruteforce:" {
+		t.Fatalf("unexpected defaults: %+v", config)
+	}
+
+	for name, protection := range map[string]map[string]interface{}{
+		"unsupported attribute": {"enabled": true, "trackBy": []interface{}{"keyPrefix"}},
+		"invalid threshold":     {"enabled": true, "thresholds": []interface{}{map[string]interface{}{"failures": 0, "window": "1m"}}},
+	} {
+		if _, err := ParseConfig(map[string]interface{}{"bruteForceProtection": protection}, testOptions); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	_, err = ParseConfig(map[string]interface{}{
+		"bruteForceProtection": map[string]interface{}{"enabled": true},
+		"trustedProxies":       []interface{}{"not-a-cidr"},
+	}, testOptions)
+	if err == nil {
+		t.Fatal("expected invalid trustedProxies to be rejected")
+	}
+}
+
+func TestKeys_ClientIP(t *testing.T) {
+	params := map[string]interface{}{
+		"bruteForceProtection": map[string]interface{}{"enabled": true, "trackBy": []interface{}{TrackIP}},
+	}
+	config, _ := ParseConfig(params, testOptions)
+
+	// Without trusted proxies, only the hop appended by the gateway identifies the client
+	first := Keys(newRequestContext(map[string][]string{"x-forwarded-for": {"192.0.2.1, 198.51.100.9"}}), config, nil)
+	rotated := Keys(newRequestContext(map[string][]string{"x-forwarded-for": {"192.0.2.2, 198.51.100.9"}}), config, nil)
+	if len(first) != 1 || first[0].Value != "198.51.100.9" || first[0].Key != rotated[0].Key {
+		t.Fatalf("expected client-set X-Forwarded-For entries to be ignored, got %v and %v", first, rotated)
+	}
+
+	params["trustedProxies"] = []interface{}{"10.0.0.0/8"}
+	config, _ = ParseConfig(params, testOptions)
+	keys := Keys(newRequestContext(map[string][]string{"x-forwarded-for": {"192.0.2.1, 203.0.113.7, 10.0.0.1"}}), config, nil)
+	if len(keys) != 1 || keys[0].Value != "203.0.113.7" {
+		t.Fatalf("expected the address in front of the trusted proxy, got %v", keys)
+	}
+}
+
+func TestGuard_Lockout(t *testing.T) {
+	config, _ := ParseConfig(map[string]interface{}{
+		"bruteForceProtection": map[string]interface{}{
+			"enabled":    true,
+			"thresholds": []interface{}{map[string]interface{}{"failures": 2, "window": "1m"}},
+		},
+	}, testOptions)
+
+	var guards Guards
+	guard, created, err := guards.Get(config)
+	if err != nil || !created {
+		t.Fatalf("expected a new guard, got %v %v", created, err)
+	}
+	if same, created, _ := guards.Get(config); same != guard || created {
+		t.Fatal("expected the guard to be reused for the same configuration")
+	}
+
+	ctx := context.Background()
+	keys := Keys(newRequestContext(map[string][]string{"x-forwarded-for": {"198.51.100.9"}}), config, map[string]string{"username": "admin"})
+	if len(keys) != 2 {
+		t.Fatalf("expected username and ip keys, got %v", keys)
+	}
+	if lockouts, _ := guard.countFailure(ctx, keys); len(lockouts) != 0 {
+		t.Fatalf("expected no lockout after one failure, got %v", lockouts)
+	}
+	if lockouts, _ := guard.countFailure(ctx, keys); len(lockouts) != 2 {
+		t.Fatalf("expected both keys to be locked out, got %v", lockouts)
+	}
+	if retryAfter, err := guard.lockedFor(ctx, keys); err != nil || retryAfter <= 0 || retryAfter > time.Minute {
+		t.Fatalf("expected a lockout of up to a minute, got %v %v", retryAfter, err)
+	}
+
+	response := LockedOutResponse(1500 * time.Millisecond)
+	if response.StatusCode != 429 || response.Headers["retry-after"] != "2" {
+		t.Fatalf("unexpected lockout response %v", response)
+	}
+}
+
+func TestGuard_CheckAndRecordFailure(t *testing.T) {
+	config, _ := ParseConfig(map[string]interface{}{
+		"bruteForceProtection": map[string]interface{}{
+			"enabled":    true,
+			"thresholds": []interface{}{map[string]interface{}{"failures": 1, "window": "1m"}},
+		},
+	}, testOptions)
+	var guards Guards
+	guard, _, err := guards.Get(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := newRequestContext(map[string][]string{"x-forwarded-for": {"198.51.100.9"}})
+	keys := Keys(ctx, config, map[string]string{"username": "admin"})
+	if action := guard.Check(config, keys, "Test Policy"); action != nil {
+		t.Fatalf("expected no lockout before any failure, got %v", action)
+	}
+	guard.RecordFailure(ctx, config, keys, "Test Policy")
+	response, ok := guard.Check(config, keys, "Test Policy").(policy.ImmediateResponse)
+	if !ok || response.StatusCode != 429 {
+		t.Fatalf("expected a 429 lockout response, got %v", response)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+// Package clientip resolves the client address of a request from X-Forwarded-For and X-Real-IP,
+// so that every policy that keys on the client IP sees the same address.
+package clientip
+
+import (
+	"net/netip"
+	"strings"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+// Resolver resolves client addresses behind a set of trusted proxies
+type Resolver struct {
+	trusted *PrefixTree
+}
+
+// NewResolver returns a resolver that skips the given proxies (CIDR ranges or addresses) in
+// X-Forwarded-For. Without trusted proxies, the last X-Forwarded-For entry is used.
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	prefixes, err := ParsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{trusted: NewPrefixTree(prefixes)}, nil
+}
+
+// ParseTrustedProxies reads the trustedProxies list from policy parameters and returns its resolver
+func ParseTrustedProxies(params map[string]interface{}) (*Resolver, error) {
+	var proxies []string
+	if raw, ok := params["trustedProxies"].([]interface{}); ok {
+		for _, item := range raw {
+			if s, ok := item.(string); ok && s != "" {
+				proxies = append(proxies, s)
+			}
+		}
+	}
+	return NewResolver(proxies)
+}
+
+// ClientIP returns the client address of the request, or "" when none can be determined.
+//
+// X-Forwarded-For is read from right to left and the first address that is not a trusted proxy is the
+// client; entries to its left were set by the client and cannot be trusted. Without trusted proxies
+// this is the last entry, the peer address appended by the gateway or the proxy in front of it. When
+// every entry is a trusted proxy, the leftmost one is used. When a hop is malformed, the chain cannot
+// be followed past it and the peer address is used instead. X-Real-IP is used when the request has
+// no usable X-Forwarded-For header.
+func (r *Resolver) ClientIP(headers *policy.Headers) string {
+	var entries []string
+	for _, value := range headers.Get("x-forwarded-for") {
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(entries[i])
+		if !ok {
+			// A malformed hop cannot be attributed to a trusted proxy, so fall back to the peer
+			if peer, ok := parseAddr(entries[len(entries)-1]); ok {
+				return peer.String()
+			}
+			break
+		}
+		if i == 0 || r == nil || !r.trusted.Contains(addr) {
+			return addr.String()
+		}
+	}
+
+	if xri := headers.Get("x-real-ip"); len(xri) > 0 {
+		return normalizeAddr(strings.TrimSpace(xri[0]))
+	}
+	return ""
+}
+
+// ClientAddr returns the client address of the request as a netip.Addr
+func (r *Resolver) ClientAddr(headers *policy.Headers) (netip.Addr, bool) {
+	return parseAddr(r.ClientIP(headers))
+}
+
+// parseAddr parses an address that may carry a port ("1.2.3.4:80", "[::1]:80") or brackets
+func parseAddr(value string) (netip.Addr, bool) {
+	if addr, err := netip.ParseAddr(value); err == nil {
+		return addr.Unmap().WithZone(""), true
+	}
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap().WithZone(""), true
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")); err == nil {
+		return addr.Unmap().WithZone(""), true
+	}
+	return netip.Addr{}, false
+}
+
+// normalizeAddr returns the canonical form of an address, or the value itself when it is not one
+func normalizeAddr(value string) string {
+	if addr, ok := parseAddr(value); ok {
+		return addr.String()
+	}
+	return value
+}
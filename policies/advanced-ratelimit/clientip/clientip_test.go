@@ -0,0 +1,173 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package clientip
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+func TestResolver_ClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted []string
+		headers map[string][]string
+		want    string
+	}{
+		{
+			name:    "no trusted proxies uses the last entry",
+			headers: map[string][]string{"x-forwarded-for": {"203.0.113.7, 10.0.0.1"}},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "no trusted proxies ignores a spoofed entry",
+			headers: map[string][]string{"x-forwarded-for": {"192.0.2.1", "198.51.100.9"}},
+			want:    "198.51.100.9",
+		},
+		{
+			name:    "spoofed entry left of the client is ignored",
+			trusted: []string{"10.0.0.0/8"},
+			headers: map[string][]string{"x-forwarded-for": {"1.1.1.1, 203.0.113.7, 10.0.0.2, 10.0.0.1"}},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "untrusted last hop is the client",
+			trusted: []string{"10.0.0.0/8"},
+			headers: map[string][]string{"x-forwarded-for": {"1.1.1.1, 198.51.100.9"}},
+			want:    "198.51.100.9",
+		},
+		{
+			name:    "all hops trusted uses the leftmost",
+			trusted: []string{"10.0.0.0/8"},
+			headers: map[string][]string{"x-forwarded-for": {"10.1.1.1, 10.0.0.1"}},
+			want:    "10.1.1.1",
+		},
+		{
+			name:    "multiple header lines and ports",
+			trusted: []string{"2001:db8::/32"},
+			headers: map[string][]string{"x-forwarded-for": {"192.0.2.10:4711", "[2001:db8::1]:443"}},
+			want:    "192.0.2.10",
+		},
+		{
+			name:    "malformed hop behind trusted proxy",
+			trusted: []string{"10.0.0.0/8"},
+			headers: map[string][]string{"x-forwarded-for": {"203.0.113.7, garbage, 10.0.0.1"}},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "malformed peer falls back to x-real-ip",
+			headers: map[string][]string{"x-forwarded-for": {"203.0.113.7, garbage"}, "x-real-ip": {"192.0.2.1"}},
+			want:    "192.0.2.1",
+		},
+		{
+			name:    "malformed peer without x-real-ip",
+			headers: map[string][]string{"x-forwarded-for": {"garbage"}},
+			want:    "",
+		},
+		{
+			name:    "x-real-ip fallback",
+			headers: map[string][]string{"x-real-ip": {" ::ffff:192.0.2.1 "}},
+			want:    "192.0.2.1",
+		},
+		{
+			name: "no headers",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, err := NewResolver(tt.trusted)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := resolver.ClientIP(policy.NewHeaders(tt.headers)); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewResolver_InvalidProxy(t *testing.T) {
+	if _, err := NewResolver([]string{"10.0.0.0/33"}); err == nil {
+		t.Fatal("expected error for invalid CIDR")
+	}
+}
+
+func TestPrefixTree_Lookup(t *testing.T) {
+	prefixes, err := ParsePrefixes([]string{
+		"10.0.0.0/8",
+		"10.1.0.0/16",
+		"192.0.2.1",
+		"2001:db8::/32",
+		"::ffff:198.51.100.0/120",
+		"0.0.0.0/0",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tree := NewPrefixTree(prefixes)
+
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{addr: "10.1.2.3", want: "10.1.0.0/16"},
+		{addr: "10.2.3.4", want: "10.0.0.0/8"},
+		{addr: "192.0.2.1", want: "192.0.2.1/32"},
+		{addr: "192.0.2.2", want: "0.0.0.0/0"},
+		{addr: "198.51.100.20", want: "198.51.100.0/24"},
+		{addr: "::ffff:10.1.0.1", want: "10.1.0.0/16"},
+		{addr: "2001:db8:1::1", want: "2001:db8::/32"},
+		{addr: "2001:db9::1", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			prefix, ok := tree.Lookup(netip.MustParseAddr(tt.addr))
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("expected no match, got %s", prefix)
+				}
+				return
+			}
+			if !ok || prefix.String() != tt.want {
+				t.Fatalf("expected %s, got %s (ok=%v)", tt.want, prefix, ok)
+			}
+		})
+	}
+}
+
+func TestPrefixTree_ManyPrefixes(t *testing.T) {
+	tree := &PrefixTree{}
+	for i := 0; i < 4096; i++ {
+		tree.Insert(netip.MustParsePrefix(fmt.Sprintf("100.%d.%d.0/24", i/256, i%256)))
+	}
+	tree.Insert(netip.MustParsePrefix("100.0.0.0/24")) // Duplicate
+	if tree.Len() != 4096 {
+		t.Fatalf("expected 4096 prefixes, got %d", tree.Len())
+	}
+	if !tree.Contains(netip.MustParseAddr("100.15.255.9")) {
+		t.Fatal("expected 100.15.255.9 to match")
+	}
+	if tree.Contains(netip.MustParseAddr("100.16.0.1")) {
+		t.Fatal("expected 100.16.0.1 not to match")
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package clientip
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// PrefixTree is a binary radix tree of IPv4 and IPv6 prefixes. A lookup walks at most one node per
+// address bit, so its cost does not depend on the number of prefixes. A PrefixTree must not be
+// modified while it is used for lookups.
+type PrefixTree struct {
+	v4   *prefixNode
+	v6   *prefixNode
+	size int
+}
+
+type prefixNode struct {
+	children [2]*prefixNode
+	prefix   netip.Prefix // Valid when a prefix ends at this node
+}
+
+// NewPrefixTree returns a tree holding the given prefixes
+func NewPrefixTree(prefixes []netip.Prefix) *PrefixTree {
+	t := &PrefixTree{}
+	for _, prefix := range prefixes {
+		t.Insert(prefix)
+	}
+	return t
+}
+
+// Insert adds a prefix to the tree. IPv4-mapped IPv6 prefixes are stored as IPv4.
+func (t *PrefixTree) Insert(prefix netip.Prefix) {
+	prefix = normalizePrefix(prefix)
+	root := &t.v6
+	if prefix.Addr().Is4() {
+		root = &t.v4
+	}
+	if *root == nil {
+		*root = &prefixNode{}
+	}
+
+	node := *root
+	addr := prefix.Addr().AsSlice()
+	for i := 0; i < prefix.Bits(); i++ {
+		bit := addrBit(addr, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &prefixNode{}
+		}
+		node = node.children[bit]
+	}
+	if !node.prefix.IsValid() {
+		t.size++
+	}
+	node.prefix = prefix
+}
+
+// Lookup returns the longest prefix containing addr
+func (t *PrefixTree) Lookup(addr netip.Addr) (netip.Prefix, bool) {
+	if t == nil || !addr.IsValid() {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap().WithZone("")
+	node := t.v6
+	if addr.Is4() {
+		node = t.v4
+	}
+
+	var match netip.Prefix
+	bytes := addr.AsSlice()
+	for i := 0; node != nil; i++ {
+		if node.prefix.IsValid() {
+			match = node.prefix
+		}
+		if i == len(bytes)*8 {
+			break
+		}
+		node = node.children[addrBit(bytes, i)]
+	}
+	return match, match.IsValid()
+}
+
+// Contains reports whether a prefix of the tree contains addr
+func (t *PrefixTree) Contains(addr netip.Addr) bool {
+	_, ok := t.Lookup(addr)
+	return ok
+}
+
+// Len returns the number of distinct prefixes in the tree
+func (t *PrefixTree) Len() int {
+	if t == nil {
+		return 0
+	}
+	return t.size
+}
+
+// ParsePrefix parses a CIDR range or a single address, which is treated as a full-length prefix
+func ParsePrefix(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q: %w", value, err)
+		}
+		return normalizePrefix(prefix), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid IP address %q: %w", value, err)
+	}
+	addr = addr.Unmap().WithZone("")
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ParsePrefixes parses a list of CIDR ranges and addresses
+func ParsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		prefix, err := ParsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// normalizePrefix masks the host bits and converts IPv4-mapped IPv6 prefixes to IPv4
+func normalizePrefix(prefix netip.Prefix) netip.Prefix {
+	addr := prefix.Addr().WithZone("")
+	bits := prefix.Bits()
+	if addr.Is4In6() && bits >= 96 {
+		addr = addr.Unmap()
+		bits -= 96
+	}
+	return netip.PrefixFrom(addr, bits).Masked()
+}
+
+func addrBit(addr []byte, i int) int {
+	return int(addr[i/8]>>(7-uint(i%8))) & 1
+}
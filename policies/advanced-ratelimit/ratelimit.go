@@ -33,6 +33,7 @@ import (
 	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
 	_ "github.com/wso2/gateway-controllers/policies/advanced-ratelimit/algorithms/fixedwindow" // Register Fixed Window algorithm
 	_ "github.com/wso2/gateway-controllers/policies/advanced-ratelimit/algorithms/gcra"        // Register GCRA algorithm
+	"github.com/wso2/gateway-controllers/policies/advanced-ratelimit/clientip"
 	"github.com/wso2/gateway-controllers/policies/advanced-ratelimit/limiter"
 )
 
@@ -109,6 +110,7 @@ type RateLimitPolicy struct {
 	includeXRL     bool
 	includeIETF    bool
 	includeRetry   bool
+	clientIP       *clientip.Resolver // Resolves the client address for the ip key component
 }
 
 // GetPolicy creates and initializes a rate limit policy instance
@@ -157,6 +159,12 @@ func GetPolicy(
 	includeIETF := getBoolParam(params, "headers.includeIETF", true)
 	includeRetry := getBoolParam(params, "headers.includeRetryAfter", true)
 
+	// Proxies skipped when resolving the client IP from X-Forwarded-For
+	clientIP, err := clientip.ParseTrustedProxies(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trustedProxies: %w", err)
+	}
+
 	// Parse global keyExtraction (used as default for quotas missing keyExtraction)
 	globalKeyExtraction, err := parseKeyExtraction(params["keyExtraction"])
 	if err != nil {
@@ -404,6 +412,7 @@ func GetPolicy(
 		includeXRL:     includeXRL,
 		includeIETF:    includeIETF,
 		includeRetry:   includeRetry,
+		clientIP:       clientIP,
 	}, nil
 }
 
@@ -874,23 +883,10 @@ func (p *RateLimitPolicy) extractKeyComponent(ctx *policy.RequestContext, comp K
 	}
 }
 
-// extractIPAddress extracts client IP from headers
+// extractIPAddress extracts the client IP from X-Forwarded-For or X-Real-IP, skipping trusted proxies
 func (p *RateLimitPolicy) extractIPAddress(ctx *policy.RequestContext) string {
-	// Try X-Forwarded-For first (most common)
-	if xff := ctx.Headers.Get("x-forwarded-for"); len(xff) > 0 && xff[0] != "" {
-		// Take the first IP (client)
-		ips := strings.Split(xff[0], ",")
-		if len(ips) > 0 {
-			ip := strings.TrimSpace(ips[0])
-			if ip != "" {
-				return ip
-			}
-		}
-	}
-
-	// Try X-Real-IP
-	if xri := ctx.Headers.Get("x-real-ip"); len(xri) > 0 && xri[0] != "" {
-		return xri[0]
+	if ip := p.clientIP.ClientIP(ctx.Headers); ip != "" {
+		return ip
 	}
 
 	slog.Warn("Could not extract IP address for rate limit key, using 'unknown'")
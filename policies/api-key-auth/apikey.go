@@ -21,22 +21,46 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
-	"net/http"
-	"net/url"
-	"strings"
+	"sync"
 
 	store "github.com/wso2/api-platform/common/apikey"
 	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/advanced-ratelimit/bruteforce"
 )
 
 const (
 	// Metadata keys for context storage
 	MetadataKeyAuthSuccess = "auth.success"
 	MetadataKeyAuthMethod  = "auth.method"
+
+	// Metadata keys for the attributes of local API keys
+	MetadataKeyKeyID         = "auth.keyId"
+	MetadataKeyApplicationID = "auth.applicationId"
+	MetadataKeyOwner         = "auth.owner"
+	MetadataKeyTier          = "auth.tier"
+	MetadataKeyAttributes    = "auth.attributes"
+
+	// AuthContext keys for the attributes of local API keys (used for analytics and rate limiting)
+	AuthContextKeyUserID        = "x-wso2-user-id"
+	AuthContextKeyApplicationID = "x-wso2-application-id"
+	AuthContextKeyTier          = "x-wso2-tier"
+
+	// AuthContextAttributePrefix namespaces key attributes in AuthContext so they cannot replace platform keys
+	AuthContextAttributePrefix = "api-key-auth."
 )
 
-// APIKeyPolicy implements API Key Authentication
+// APIKeyPolicy implements API Key Authentication. Its brute-force guards and local key store are
+// shared by the policies of all routes.
 type APIKeyPolicy struct {
+	bruteForceGuards bruteforce.Guards
+	localStoreOnce   sync.Once
+	localStore       *localKeyStore
+}
+
+// routePolicy is the API Key Authentication policy of a route, with its keys parameter parsed once
+type routePolicy struct {
+	*APIKeyPolicy
+	keys localKeys
 }
 
 var ins = &APIKeyPolicy{}
@@ -45,7 +69,15 @@ func GetPolicy(
 	metadata policy.PolicyMetadata,
 	params map[string]interface{},
 ) (policy.Policy, error) {
-	return ins, nil
+	return &routePolicy{
+		APIKeyPolicy: ins,
+		keys:         parseLocalKeys(params["keys"], "policy"),
+	}, nil
+}
+
+// OnRequest performs API Key Authentication with the keys parsed when the policy was created
+func (p *routePolicy) OnRequest(ctx *policy.RequestContext, params map[string]interface{}) policy.RequestAction {
+	return p.authenticate(ctx, params, p.keys)
 }
 
 // Mode returns the processing mode for this policy
@@ -58,8 +90,14 @@ func (p *APIKeyPolicy) Mode() policy.ProcessingMode {
 	}
 }
 
-// OnRequest performs API Key Authentication
+// OnRequest performs API Key Authentication, parsing the keys parameter on each call. The policies
+// returned by GetPolicy parse it once.
 func (p *APIKeyPolicy) OnRequest(ctx *policy.RequestContext, params map[string]interface{}) policy.RequestAction {
+	return p.authenticate(ctx, params, parseLocalKeys(params["keys"], "policy"))
+}
+
+// authenticate performs API Key Authentication, validating local API keys against keys and the key file
+func (p *APIKeyPolicy) authenticate(ctx *policy.RequestContext, params map[string]interface{}, keys localKeys) policy.RequestAction {
 	slog.Debug("API Key Auth Policy: OnRequest started",
 		"path", ctx.Path,
 		"method", ctx.Method,
@@ -69,64 +107,41 @@ func (p *APIKeyPolicy) OnRequest(ctx *policy.RequestContext, params map[string]i
 	)
 
 	// Get configuration parameters
-	keyName, ok := params["key"].(string)
-	if !ok || keyName == "" {
-		slog.Debug("API Key Auth Policy: Missing or invalid 'key' configuration",
-			"keyName", keyName,
-			"ok", ok,
+	locations, err := getKeyLocations(params)
+	if err != nil {
+		slog.Debug("API Key Auth Policy: Invalid key location configuration",
+			"error", err,
 		)
-		return p.handleAuthFailure(ctx, 401, "json", "Valid API key required",
-			"missing or invalid 'key' configuration")
+		return p.handleAuthFailure(ctx, 401, "json", "Valid API key required", err.Error())
 	}
-
-	location, ok := params["in"].(string)
-	if !ok || location == "" {
-		slog.Debug("API Key Auth Policy: Missing or invalid 'in' configuration",
-			"location", location,
-			"ok", ok,
-		)
-		return p.handleAuthFailure(ctx, 401, "json", "Valid API key required",
-			"missing or invalid 'in' configuration")
+	stripCredential := true
+	if v, ok := params["stripCredential"].(bool); ok {
+		stripCredential = v
 	}
 
 	slog.Debug("API Key Auth Policy: Configuration loaded",
-		"keyName", keyName,
-		"location", location,
+		"locations", len(locations),
+		"stripCredential", stripCredential,
 	)
 
-	// Extract API key based on location
-	var providedKey string
-
-	if location == "header" {
-		// Check header (case-insensitive)
-		if headerValues := ctx.Headers.Get(http.CanonicalHeaderKey(keyName)); len(headerValues) > 0 {
-			providedKey = headerValues[0]
-			slog.Debug("API Key Auth Policy: Found API key in header",
-				"headerName", keyName,
-				"keyLength", len(providedKey),
-			)
-		}
-	} else if location == "query" {
-		// Extract query parameters from the full path
-		providedKey = extractQueryParam(ctx.Path, keyName)
-		if providedKey != "" {
-			slog.Debug("API Key Auth Policy: Found API key in query parameter",
-				"paramName", keyName,
-				"keyLength", len(providedKey),
-			)
-		}
-	}
+	// Extract the API key from the first location that carries one
+	providedKey, keyLocation := extractAPIKey(ctx, locations)
 
 	// If no API key provided
 	if providedKey == "" {
 		slog.Debug("API Key Auth Policy: No API key found",
-			"location", location,
-			"keyName", keyName,
+			"locations", len(locations),
 		)
 		return p.handleAuthFailure(ctx, 401, "json", "Valid API key required",
 			"missing API key")
 	}
 
+	// Remove the key from the forwarded request so it does not reach upstream access logs
+	var strip *KeyLocation
+	if stripCredential {
+		strip = keyLocation
+	}
+
 	apiId := ctx.APIId
 	apiName := ctx.APIName
 	apiVersion := ctx.APIVersion
@@ -154,6 +169,61 @@ func (p *APIKeyPolicy) OnRequest(ctx *policy.RequestContext, params map[string]i
 		"keyLength", len(providedKey),
 	)
 
+	// Reject clients locked out after repeated failures before validating their key
+	bruteForce, err := bruteforce.ParseConfig(params, bruteForceOptions)
+	if err != nil {
+		slog.Debug("API Key Auth Policy: Invalid brute-force protection configuration",
+			"error", err,
+		)
+		return p.handleAuthFailure(ctx, 401, "json", "Valid API key required",
+			"invalid 'bruteForceProtection' configuration")
+	}
+	var guard *bruteforce.Guard
+	var attemptKeys []bruteforce.TrackedKey
+	if bruteForce != nil {
+		guard, err = p.getBruteForceGuard(bruteForce)
+		if err != nil {
+			slog.Debug("API Key Auth Policy: Failed to create brute-force guard",
+				"error", err,
+			)
+			return p.handleAuthFailure(ctx, 401, "json", "Valid API key required",
+				"invalid 'bruteForceProtection' configuration")
+		}
+		attemptKeys = bruteForceKeys(ctx, bruteForce, params, providedKey)
+		if action := guard.Check(bruteForce, attemptKeys, "API Key Auth Policy"); action != nil {
+			ctx.Metadata[MetadataKeyAuthSuccess] = false
+			ctx.Metadata[MetadataKeyAuthMethod] = "api-key"
+			return action
+		}
+	}
+
+	keyStore, _ := params["keyStore"].(string)
+	if keyStore == KeyStoreLocal {
+		// API key was provided - validate it against the keys declared in the policy and key file
+		localKey, reason, err := p.validateLocalAPIKey(ctx, params, keys, providedKey)
+		if err != nil {
+			slog.Debug("API Key Auth Policy: Local key store error",
+				"error", err,
+			)
+			return p.handleAuthFailure(ctx, 401, "json", "Valid API key required",
+				"error validating API key")
+		}
+		if localKey == nil {
+			slog.Debug("API Key Auth Policy: Local API key rejected",
+				"reason", reason,
+			)
+			if guard != nil {
+				guard.RecordFailure(ctx, bruteForce, attemptKeys, "API Key Auth Policy")
+			}
+			return p.handleAuthFailure(ctx, 401, "json", "Valid API key required", reason)
+		}
+
+		slog.Debug("API Key Auth Policy: Authentication successful",
+			"keyId", localKey.ID,
+		)
+		return p.handleAuthSuccess(ctx, localKey, strip)
+	}
+
 	// API key was provided - validate it using external validation
 	isValid, err := p.validateAPIKey(apiId, apiOperation, operationMethod, providedKey)
 	if err != nil {
@@ -165,17 +235,22 @@ func (p *APIKeyPolicy) OnRequest(ctx *policy.RequestContext, params map[string]i
 	}
 	if !isValid {
 		slog.Debug("API Key Auth Policy: Invalid API key")
+		if guard != nil {
+			guard.RecordFailure(ctx, bruteForce, attemptKeys, "API Key Auth Policy")
+		}
 		return p.handleAuthFailure(ctx, 401, "json", "Valid API key required",
 			"invalid API key")
 	}
 
 	// Authentication successful
 	slog.Debug("API Key Auth Policy: Authentication successful")
-	return p.handleAuthSuccess(ctx)
+	return p.handleAuthSuccess(ctx, nil, strip)
 }
 
-// handleAuthSuccess handles successful authentication
-func (p *APIKeyPolicy) handleAuthSuccess(ctx *policy.RequestContext) policy.RequestAction {
+// handleAuthSuccess handles successful authentication. For local keys the key's attributes are
+// exported to metadata and AuthContext; strip, when set, is the location removed from the forwarded request.
+func (p *APIKeyPolicy) handleAuthSuccess(ctx *policy.RequestContext, localKey *LocalAPIKey,
+	strip *KeyLocation) policy.RequestAction {
 	slog.Debug("API Key Auth Policy: handleAuthSuccess called",
 		"apiId", ctx.APIId,
 		"apiName", ctx.APIName,
@@ -188,13 +263,41 @@ func (p *APIKeyPolicy) handleAuthSuccess(ctx *policy.RequestContext) policy.Requ
 	ctx.Metadata[MetadataKeyAuthSuccess] = true
 	ctx.Metadata[MetadataKeyAuthMethod] = "api-key"
 
+	if localKey != nil {
+		ctx.Metadata[MetadataKeyKeyID] = localKey.ID
+		if ctx.SharedContext.AuthContext == nil {
+			ctx.SharedContext.AuthContext = make(map[string]string)
+		}
+		if localKey.ApplicationID != "" {
+			ctx.Metadata[MetadataKeyApplicationID] = localKey.ApplicationID
+			ctx.SharedContext.AuthContext[AuthContextKeyApplicationID] = localKey.ApplicationID
+		}
+		if localKey.Owner != "" {
+			ctx.Metadata[MetadataKeyOwner] = localKey.Owner
+			ctx.SharedContext.AuthContext[AuthContextKeyUserID] = localKey.Owner
+		}
+		if localKey.Tier != "" {
+			ctx.Metadata[MetadataKeyTier] = localKey.Tier
+			ctx.SharedContext.AuthContext[AuthContextKeyTier] = localKey.Tier
+		}
+		if len(localKey.Attributes) > 0 {
+			ctx.Metadata[MetadataKeyAttributes] = localKey.Attributes
+			for name, value := range localKey.Attributes {
+				ctx.SharedContext.AuthContext[AuthContextAttributePrefix+name] = value
+			}
+		}
+	}
+
 	slog.Debug("API Key Auth Policy: Authentication metadata set",
 		"authSuccess", true,
 		"authMethod", "api-key",
 	)
 
-	// Continue to upstream with no modifications
-	return policy.UpstreamRequestModifications{}
+	modifications := policy.UpstreamRequestModifications{}
+	if strip != nil {
+		stripKeyLocation(ctx, strip, &modifications)
+	}
+	return modifications
 }
 
 // OnResponse is not used by this policy (authentication is request-only)
@@ -262,32 +365,3 @@ func (p *APIKeyPolicy) validateAPIKey(apiId, apiOperation, operationMethod, apiK
 	}
 	return isValid, nil
 }
-
-// extractQueryParam extracts the first value of the given query parameter from the request path
-func extractQueryParam(path, param string) string {
-	// Parse the URL-encoded path
-	decodedPath, err := url.PathUnescape(path)
-	if err != nil {
-		return ""
-	}
-
-	// Split the path into components
-	parts := strings.Split(decodedPath, "?")
-	if len(parts) != 2 {
-		return ""
-	}
-
-	// Parse the query string
-	queryString := parts[1]
-	values, err := url.ParseQuery(queryString)
-	if err != nil {
-		return ""
-	}
-
-	// Get the first value of the specified parameter
-	if value, ok := values[param]; ok && len(value) > 0 {
-		return value[0]
-	}
-
-	return ""
-}
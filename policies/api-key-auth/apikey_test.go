@@ -2,14 +2,20 @@ package apikey
 
 import (
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	apikeycommon "github.com/wso2/api-platform/common/apikey"
 	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"golang.org/x/crypto/argon2"
 )
 
 func TestAPIKeyPolicy_Mode(t *testing.T) {
@@ -27,7 +33,7 @@ func TestAPIKeyPolicy_Mode(t *testing.T) {
 	}
 }
 
-func TestGetPolicy_ReturnsSingleton(t *testing.T) {
+func TestGetPolicy_SharesState(t *testing.T) {
 	p1, err := GetPolicy(policy.PolicyMetadata{}, map[string]interface{}{})
 	if err != nil {
 		t.Fatalf("GetPolicy failed: %v", err)
@@ -36,8 +42,8 @@ func TestGetPolicy_ReturnsSingleton(t *testing.T) {
 	if err != nil {
 		t.Fatalf("GetPolicy failed: %v", err)
 	}
-	if p1 != p2 {
-		t.Fatalf("expected singleton policy instance")
+	if p1.(*routePolicy).APIKeyPolicy != p2.(*routePolicy).APIKeyPolicy {
+		t.Fatalf("expected the policies to share the brute-force guards and local key store")
 	}
 }
 
@@ -217,6 +223,82 @@ func TestAPIKeyPolicy_HandleAuthFailure_PlainFormat(t *testing.T) {
 	}
 }
 
+func TestAPIKeyPolicy_OnRequest_KeyLocations(t *testing.T) {
+	locations := []interface{}{
+		map[string]interface{}{"in": "header", "name": "x-api-key"},
+		map[string]interface{}{"in": "authorization"},
+		map[string]interface{}{"in": "cookie", "name": "api_key", "prefix": "key_"},
+		map[string]interface{}{"in": "query", "name": "api_key"},
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		headers     map[string][]string
+		strip       interface{}
+		wantRemove  []string
+		wantQuery   []string
+		wantCookies string
+	}{
+		{
+			name:       "authorization scheme",
+			path:       "/orders",
+			headers:    map[string][]string{"authorization": {"ApiKey loc-secret"}},
+			wantRemove: []string{"authorization"},
+		},
+		{
+			name:        "cookie with prefix keeps other cookies",
+			path:        "/orders",
+			headers:     map[string][]string{"cookie": {"session=s1; api_key=key_loc-secret"}},
+			wantCookies: "session=s1",
+		},
+		{
+			name:      "query",
+			path:      "/orders?api_key=loc-secret&page=2",
+			wantQuery: []string{"api_key"},
+		},
+		{
+			name:    "stripping disabled",
+			path:    "/orders?api_key=loc-secret",
+			strip:   false,
+			headers: map[string][]string{},
+		},
+		{
+			name:       "earlier location wins",
+			path:       "/orders?api_key=wrong",
+			headers:    map[string][]string{"x-api-key": {"loc-secret"}},
+			wantRemove: []string{"x-api-key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetAPIKeyStore(t)
+			seedExternalAPIKey(t, "api-loc", "loc-secret", `["GET /orders"]`)
+
+			params := map[string]interface{}{"locations": locations}
+			if tt.strip != nil {
+				params["stripCredential"] = tt.strip
+			}
+			p := &APIKeyPolicy{}
+			ctx := newRequestContext(t, "GET", tt.path, tt.headers, "api-loc", "OrdersAPI", "v1", "/orders")
+			action, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications)
+			if !ok {
+				t.Fatalf("expected UpstreamRequestModifications, got %T", action)
+			}
+			if strings.Join(action.RemoveHeaders, ",") != strings.Join(tt.wantRemove, ",") {
+				t.Fatalf("unexpected removed headers: %v", action.RemoveHeaders)
+			}
+			if strings.Join(action.RemoveQueryParameters, ",") != strings.Join(tt.wantQuery, ",") {
+				t.Fatalf("unexpected removed query parameters: %v", action.RemoveQueryParameters)
+			}
+			if action.SetHeaders["cookie"] != tt.wantCookies {
+				t.Fatalf("unexpected cookie header: %q", action.SetHeaders["cookie"])
+			}
+		})
+	}
+}
+
 func TestExtractQueryParam(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -231,10 +313,16 @@ func TestExtractQueryParam(t *testing.T) {
 			expected: "abc123",
 		},
 		{
-			name:     "encoded path",
+			name:     "encoded question mark in path is not a query",
 			path:     "/orders%3Ftoken%3Dabc123",
 			param:    "token",
-			expected: "abc123",
+			expected: "",
+		},
+		{
+			name:     "encoded value",
+			path:     "/files/a%3Fb?token=abc%2B123%3F",
+			param:    "token",
+			expected: "abc+123?",
 		},
 		{
 			name:     "multiple values takes first",
@@ -312,6 +400,51 @@ func assertUnauthorizedJSON(t *testing.T, action policy.RequestAction) {
 	}
 }
 
+func TestAPIKeyPolicy_OnRequest_BruteForceLockout(t *testing.T) {
+	resetAPIKeyStore(t)
+	seedExternalAPIKey(t, "api-bf", "valid-secret-key", `["GET /orders"]`)
+
+	p := &APIKeyPolicy{}
+	params := map[string]interface{}{
+		"key": "x-api-key",
+		"in":  "header",
+		"bruteForceProtection": map[string]interface{}{
+			"enabled": true,
+			"trackBy": []interface{}{"ip"},
+			"thresholds": []interface{}{
+				map[string]interface{}{"failures": 2, "window": "1h"},
+			},
+		},
+	}
+	send := func(key, ip string) policy.RequestAction {
+		ctx := newRequestContext(t, "GET", "/orders", map[string][]string{
+			"x-api-key":       {key},
+			"x-forwarded-for": {ip},
+		}, "api-bf", "OrdersAPI", "v1", "/orders")
+		return p.OnRequest(ctx, params)
+	}
+
+	assertUnauthorizedJSON(t, send("guess-1", "192.0.2.20"))
+	assertUnauthorizedJSON(t, send("guess-2", "192.0.2.20"))
+
+	resp, ok := send("valid-secret-key", "192.0.2.20").(policy.ImmediateResponse)
+	if !ok || resp.StatusCode != 429 {
+		t.Fatalf("expected 429 for locked out address, got %#v", resp)
+	}
+	if resp.Headers["retry-after"] == "" {
+		t.Fatalf("expected retry-after header")
+	}
+
+	// Prepending another address to X-Forwarded-For does not escape the lockout
+	if resp, ok := send("valid-secret-key", "203.0.113.1, 192.0.2.20").(policy.ImmediateResponse); !ok || resp.StatusCode != 429 {
+		t.Fatalf("expected 429 for spoofed X-Forwarded-For, got %#v", resp)
+	}
+
+	if _, ok := send("valid-secret-key", "198.51.100.20").(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("expected other address to be authenticated")
+	}
+}
+
 func resetAPIKeyStore(t *testing.T) {
 	t.Helper()
 	if err := apikeycommon.GetAPIkeyStoreInstance().ClearAll(); err != nil {
@@ -347,3 +480,129 @@ func sanitizeTestName(v string) string {
 	v = strings.ReplaceAll(v, " ", "-")
 	return strings.ToLower(v)
 }
+
+func TestAPIKeyPolicy_OnRequest_LocalKeyStore(t *testing.T) {
+	params := map[string]interface{}{
+		"key":      "x-api-key",
+		"in":       "header",
+		"keyStore": "local",
+		"keys": []interface{}{
+			map[string]interface{}{
+				"id":            "orders-client",
+				"keyHash":       sha256KeyHash("orders-client.local-secret", "salt-1"),
+				"applicationId": "app-42",
+				"owner":         "alice",
+				"tier":          "gold",
+				"operations":    []interface{}{"GET /orders"},
+				"attributes":    map[string]interface{}{"team": "billing"},
+			},
+			map[string]interface{}{
+				"id":        "expired-client",
+				"keyHash":   argon2KeyHash("expired-client.expired-secret", "salt-2"),
+				"expiresAt": time.Now().Add(-time.Hour).Format(time.RFC3339),
+			},
+			map[string]interface{}{
+				"id":      "broken-client",
+				"keyHash": "$argon2id$v=19$m=64,t=1,p=0$c2FsdA$" + base64.RawStdEncoding.EncodeToString(make([]byte, 32)),
+			},
+		},
+	}
+	instance, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("GetPolicy failed: %v", err)
+	}
+	p := instance.(*routePolicy)
+	send := func(key, method, opPath string) (*policy.RequestContext, policy.RequestAction) {
+		ctx := newRequestContext(t, method, opPath, map[string][]string{
+			"x-api-key": {key},
+		}, "api-local", "OrdersAPI", "v1", opPath)
+		return ctx, p.OnRequest(ctx, params)
+	}
+
+	ctx, action := send("orders-client.local-secret", "GET", "/orders")
+	if _, ok := action.(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("expected UpstreamRequestModifications, got %T", action)
+	}
+	if ctx.Metadata[MetadataKeyApplicationID] != "app-42" || ctx.Metadata[MetadataKeyTier] != "gold" ||
+		ctx.Metadata[MetadataKeyKeyID] != "orders-client" {
+		t.Fatalf("unexpected metadata: %#v", ctx.Metadata)
+	}
+	if ctx.AuthContext[AuthContextKeyUserID] != "alice" || ctx.AuthContext[AuthContextKeyApplicationID] != "app-42" ||
+		ctx.AuthContext[AuthContextAttributePrefix+"team"] != "billing" {
+		t.Fatalf("unexpected AuthContext: %#v", ctx.AuthContext)
+	}
+
+	_, action = send("orders-client.local-secret", "DELETE", "/orders")
+	assertUnauthorizedJSON(t, action)
+
+	_, action = send("expired-client.expired-secret", "GET", "/orders")
+	assertUnauthorizedJSON(t, action)
+
+	// Unknown IDs, keys without an ID, another key's secret and unusable hashes are all rejected
+	for _, key := range []string{"unknown.local-secret", "local-secret", "expired-client.local-secret", "broken-client.secret"} {
+		_, action = send(key, "GET", "/orders")
+		assertUnauthorizedJSON(t, action)
+	}
+
+	// The keys parameter is parsed once, when the policy is created
+	if len(p.keys) != 2 || p.keys["broken-client"] != nil {
+		t.Fatalf("expected the key with invalid argon2id parameters to be skipped, got %v", p.keys)
+	}
+}
+
+func TestAPIKeyPolicy_OnRequest_LocalKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	writeKeyFile := func(plainKey string) {
+		t.Helper()
+		content := fmt.Sprintf(`{"keys":[{"id":"file-key","keyHash":%q,"tier":"silver"}]}`, sha256KeyHash("file-key."+plainKey, "file-salt"))
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write key file: %v", err)
+		}
+	}
+	writeKeyFile("first-secret")
+
+	p := &APIKeyPolicy{}
+	params := map[string]interface{}{
+		"key":      "x-api-key",
+		"in":       "header",
+		"keyStore": "local",
+		"keyFile":  path,
+	}
+	send := func(key string) (*policy.RequestContext, policy.RequestAction) {
+		ctx := newRequestContext(t, "GET", "/orders", map[string][]string{
+			"x-api-key": {key},
+		}, "api-file", "OrdersAPI", "v1", "/orders")
+		return ctx, p.OnRequest(ctx, params)
+	}
+
+	ctx, action := send("file-key.first-secret")
+	if _, ok := action.(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("expected UpstreamRequestModifications, got %T", action)
+	}
+	if ctx.Metadata[MetadataKeyTier] != "silver" {
+		t.Fatalf("unexpected metadata: %#v", ctx.Metadata)
+	}
+
+	// Rotate the key and force the next request to check the file again
+	writeKeyFile("second-secret")
+	p.getLocalKeyStore().files[path].lastCheck = time.Time{}
+	p.getLocalKeyStore().files[path].size = 0
+
+	_, action = send("file-key.first-secret")
+	assertUnauthorizedJSON(t, action)
+	_, action = send("file-key.second-secret")
+	if _, ok := action.(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("expected rotated key to be accepted, got %T", action)
+	}
+}
+
+func sha256KeyHash(plainKey, salt string) string {
+	sum := sha256.Sum256(append([]byte(salt), plainKey...))
+	return "$sha256$" + base64.RawStdEncoding.EncodeToString([]byte(salt)) + "$" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+func argon2KeyHash(plainKey, salt string) string {
+	hash := argon2.IDKey([]byte(plainKey), []byte(salt), 1, 64, 1, 32)
+	return fmt.Sprintf("$argon2id$v=19$m=64,t=1,p=1$%s$%s",
+		base64.RawStdEncoding.EncodeToString([]byte(salt)), base64.RawStdEncoding.EncodeToString(hash))
+}
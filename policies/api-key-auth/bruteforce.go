@@ -0,0 +1,71 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package apikey
+
+import (
+	"log/slog"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/advanced-ratelimit/bruteforce"
+)
+
+const (
+	// Attributes failed attempts can be tracked by
+	BruteForceTrackIP        = bruteforce.TrackIP
+	BruteForceTrackKeyPrefix = "keyPrefix"
+
+	// defaultKeyPrefixLength is the number of leading key characters tracked by keyPrefix
+	defaultKeyPrefixLength = 8
+)
+
+// bruteForceOptions are the attributes and storage defaults of api-key-auth brute-force protection
+var bruteForceOptions = bruteforce.Options{
+	Attributes:     []string{BruteForceTrackKeyPrefix},
+	DefaultTrackBy: []string{BruteForceTrackIP, BruteForceTrackKeyPrefix},
+	RedisKeyPrefix: "apikeyauth:bruteforce:",
+	// Key prefixes are logged by their hash only
+	HashedAttributes: []string{BruteForceTrackKeyPrefix},
+}
+
+// getBruteForceGuard returns the guard for the configuration, creating its limiters on first use
+func (p *APIKeyPolicy) getBruteForceGuard(config *bruteforce.Config) (*bruteforce.Guard, error) {
+	guard, created, err := p.bruteForceGuards.Get(config)
+	if created {
+		slog.Debug("API Key Auth Policy: Created brute-force guard",
+			"backend", config.Backend,
+			"thresholds", len(config.Thresholds),
+		)
+	}
+	return guard, err
+}
+
+// bruteForceKeys returns the keys failed attempts of this request are counted against. keyPrefix
+// tracks the first `keyPrefixLength` characters of the presented key.
+func bruteForceKeys(ctx *policy.RequestContext, config *bruteforce.Config, params map[string]interface{}, apiKey string) []bruteforce.TrackedKey {
+	prefixLen := defaultKeyPrefixLength
+	if raw, ok := params["bruteForceProtection"].(map[string]interface{}); ok {
+		switch v := raw["keyPrefixLength"].(type) {
+		case int:
+			prefixLen = v
+		case float64:
+			prefixLen = int(v)
+		}
+	}
+	prefix := apiKey[:min(len(apiKey), max(prefixLen, 1))]
+	return bruteforce.Keys(ctx, config, map[string]string{BruteForceTrackKeyPrefix: prefix})
+}
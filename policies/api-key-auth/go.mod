@@ -5,9 +5,18 @@ go 1.25.1
 require (
 	github.com/wso2/api-platform/common v0.0.0-20260209055520-167eafeb88ca
 	github.com/wso2/api-platform/sdk v0.3.10
+	github.com/wso2/gateway-controllers/policies/advanced-ratelimit v0.5.0
+	github.com/wso2/gateway-controllers/policies/basic-auth v0.10.0
+	golang.org/x/crypto v0.47.0
 )
 
 require (
-	golang.org/x/crypto v0.46.0 // indirect
-	golang.org/x/sys v0.39.0 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/redis/go-redis/v9 v9.17.2 // indirect
+	golang.org/x/sys v0.40.0 // indirect
 )
+
+replace github.com/wso2/gateway-controllers/policies/advanced-ratelimit => ../advanced-ratelimit
+
+replace github.com/wso2/gateway-controllers/policies/basic-auth => ../basic-auth
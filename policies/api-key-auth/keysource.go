@@ -0,0 +1,216 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package apikey
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+const (
+	// Key locations for the in field of locations
+	KeyLocationHeader        = "header"
+	KeyLocationQuery         = "query"
+	KeyLocationCookie        = "cookie"
+	KeyLocationAuthorization = "authorization" // Authorization: <scheme> <key>
+
+	// Default scheme of the authorization location
+	DefaultAuthorizationScheme = "ApiKey"
+)
+
+// KeyLocation is a place in the request that may carry the API key
+type KeyLocation struct {
+	In     string // header, query, cookie or authorization
+	Name   string // Header, query parameter or cookie name; the scheme for authorization
+	Prefix string // Prefix stripped (case-insensitively) from the value before validation
+}
+
+// getKeyLocations parses the ordered locations parameter. When it is not configured, the key is
+// read from the single location given by key, in and value-prefix.
+func getKeyLocations(params map[string]interface{}) ([]KeyLocation, error) {
+	raw, ok := params["locations"].([]interface{})
+	if !ok || len(raw) == 0 {
+		keyName, _ := params["key"].(string)
+		if keyName == "" {
+			return nil, fmt.Errorf("missing or invalid 'key' configuration")
+		}
+		location, _ := params["in"].(string)
+		if location != KeyLocationHeader && location != KeyLocationQuery {
+			return nil, fmt.Errorf("missing or invalid 'in' configuration")
+		}
+		prefix, _ := params["value-prefix"].(string)
+		return []KeyLocation{{In: location, Name: keyName, Prefix: prefix}}, nil
+	}
+
+	locations := make([]KeyLocation, 0, len(raw))
+	for i, item := range raw {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("locations[%d] must be an object", i)
+		}
+		location := KeyLocation{}
+		location.In, _ = entry["in"].(string)
+		location.Name, _ = entry["name"].(string)
+		location.Prefix, _ = entry["prefix"].(string)
+		switch location.In {
+		case KeyLocationHeader, KeyLocationQuery, KeyLocationCookie:
+			if location.Name == "" {
+				return nil, fmt.Errorf("locations[%d] requires a name", i)
+			}
+		case KeyLocationAuthorization:
+			if location.Name == "" {
+				location.Name = DefaultAuthorizationScheme
+			}
+		default:
+			return nil, fmt.Errorf("locations[%d] has unsupported in %q", i, location.In)
+		}
+		locations = append(locations, location)
+	}
+	return locations, nil
+}
+
+// extractAPIKey tries each location in order and returns the first non-empty key with its location
+func extractAPIKey(ctx *policy.RequestContext, locations []KeyLocation) (string, *KeyLocation) {
+	for i := range locations {
+		location := &locations[i]
+		value, present := lookupKeyLocation(ctx, location)
+		if !present {
+			continue
+		}
+		if location.Prefix != "" {
+			if len(value) < len(location.Prefix) || !strings.EqualFold(value[:len(location.Prefix)], location.Prefix) {
+				slog.Debug("API Key Auth Policy: API key does not have the configured prefix",
+					"in", location.In,
+					"name", location.Name,
+				)
+				continue
+			}
+			value = value[len(location.Prefix):]
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		slog.Debug("API Key Auth Policy: Found API key",
+			"in", location.In,
+			"name", location.Name,
+			"keyLength", len(value),
+		)
+		return value, location
+	}
+	return "", nil
+}
+
+// lookupKeyLocation returns the raw value of the location and whether it is present in the request
+func lookupKeyLocation(ctx *policy.RequestContext, location *KeyLocation) (string, bool) {
+	switch location.In {
+	case KeyLocationHeader:
+		values := ctx.Headers.Get(location.Name)
+		if len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
+	case KeyLocationQuery:
+		value := extractQueryParam(ctx.Path, location.Name)
+		return value, value != ""
+	case KeyLocationCookie:
+		for _, cookie := range requestCookies(ctx) {
+			if cookie.Name == location.Name {
+				return cookie.Value, true
+			}
+		}
+		return "", false
+	case KeyLocationAuthorization:
+		for _, value := range ctx.Headers.Get("authorization") {
+			scheme, credentials, found := strings.Cut(strings.TrimSpace(value), " ")
+			if found && strings.EqualFold(scheme, location.Name) {
+				return credentials, true
+			}
+		}
+		return "", false
+	default:
+		return "", false
+	}
+}
+
+// requestCookies parses all cookies sent with the request
+func requestCookies(ctx *policy.RequestContext) []*http.Cookie {
+	var cookies []*http.Cookie
+	for _, line := range ctx.Headers.Get("cookie") {
+		parsed, err := http.ParseCookie(line)
+		if err != nil {
+			slog.Debug("API Key Auth Policy: Failed to parse cookie header", "error", err)
+			continue
+		}
+		cookies = append(cookies, parsed...)
+	}
+	return cookies
+}
+
+// extractQueryParam extracts the first value of the given query parameter from the raw query string
+// of the request path. Only the query string is decoded, so encoded '?' characters in the path are
+// not mistaken for the start of the query.
+func extractQueryParam(path, param string) string {
+	_, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return ""
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		slog.Debug("API Key Auth Policy: Failed to parse query string", "error", err)
+	}
+	// ParseQuery keeps the parameters it could decode when another one is malformed
+	return values.Get(param)
+}
+
+// stripKeyLocation removes the location that carried the key from the request forwarded upstream
+func stripKeyLocation(ctx *policy.RequestContext, location *KeyLocation, modifications *policy.UpstreamRequestModifications) {
+	switch location.In {
+	case KeyLocationHeader:
+		modifications.RemoveHeaders = append(modifications.RemoveHeaders, strings.ToLower(location.Name))
+	case KeyLocationAuthorization:
+		modifications.RemoveHeaders = append(modifications.RemoveHeaders, "authorization")
+	case KeyLocationQuery:
+		modifications.RemoveQueryParameters = append(modifications.RemoveQueryParameters, location.Name)
+	case KeyLocationCookie:
+		// Rewrite the cookie header with every other cookie so session state still reaches upstream
+		var remaining []string
+		for _, cookie := range requestCookies(ctx) {
+			if cookie.Name != location.Name {
+				remaining = append(remaining, cookie.Name+"="+cookie.Value)
+			}
+		}
+		if len(remaining) == 0 {
+			modifications.RemoveHeaders = append(modifications.RemoveHeaders, "cookie")
+			return
+		}
+		if modifications.SetHeaders == nil {
+			modifications.SetHeaders = make(map[string]string)
+		}
+		modifications.SetHeaders["cookie"] = strings.Join(remaining, "; ")
+	}
+	slog.Debug("API Key Auth Policy: Stripped API key from forwarded request",
+		"in", location.In,
+		"name", location.Name,
+	)
+}
@@ -0,0 +1,342 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package apikey
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/basic-auth/secrethash"
+)
+
+const (
+	// Key stores for the keyStore parameter
+	KeyStorePlatform = "platform" // Keys managed by the platform and pushed to the gateway
+	KeyStoreLocal    = "local"    // Keys declared in the policy or a key file
+
+	// keyFileCheckInterval is the minimum time between checks of a key file for changes
+	keyFileCheckInterval = 5 * time.Second
+
+	// localKeySeparator separates the key ID from the secret in a local API key: <id>.<secret>
+	localKeySeparator = "."
+)
+
+// LocalAPIKey is an API key declared in the policy configuration or a key file
+type LocalAPIKey struct {
+	ID            string            // Identifier that prefixes the key (<id>.<secret>); used in logs and metadata
+	KeyHash       string            // $sha256$<salt>$<hash> or $argon2id$ PHC string of the whole key
+	ApplicationID string            // Application the key was issued to
+	Owner         string            // Owner of the key, exported as the analytics user ID
+	Tier          string            // Subscription tier, for rate limiting
+	Operations    []string          // "METHOD /path" entries; "*" matches any method or path. Empty allows all.
+	ExpiresAt     time.Time         // Zero when the key does not expire
+	Attributes    map[string]string // Additional attributes exported to metadata and AuthContext
+}
+
+// localKeys indexes local API keys by ID
+type localKeys map[string]*LocalAPIKey
+
+// localKeyStore holds the key files and verification cache shared across requests
+type localKeyStore struct {
+	fileMutex sync.Mutex
+	files     map[string]*keyFile
+	verified  *secrethash.VerifiedCache
+}
+
+// keyFile holds the keys loaded from a JSON key file
+type keyFile struct {
+	modTime   time.Time
+	size      int64
+	lastCheck time.Time
+	keys      localKeys
+}
+
+// getLocalKeyStore returns the policy's local key store, creating it on first use
+func (p *APIKeyPolicy) getLocalKeyStore() *localKeyStore {
+	p.localStoreOnce.Do(func() {
+		p.localStore = &localKeyStore{
+			files:    make(map[string]*keyFile),
+			verified: secrethash.NewVerifiedCache(),
+		}
+	})
+	return p.localStore
+}
+
+// validateLocalAPIKey looks the provided key (<id>.<secret>) up by its ID in keys, the parsed keys
+// parameter, and then the key file, and verifies it against that entry's hash only. A non-empty reason
+// is returned when the key is unknown, expired or not allowed for the operation.
+func (p *APIKeyPolicy) validateLocalAPIKey(ctx *policy.RequestContext, params map[string]interface{},
+	keys localKeys, providedKey string) (*LocalAPIKey, string, error) {
+	store := p.getLocalKeyStore()
+	var fileKeys localKeys
+	if path, _ := params["keyFile"].(string); path != "" {
+		var err error
+		fileKeys, err = store.getKeyFile(path)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	if len(keys) == 0 && len(fileKeys) == 0 {
+		return nil, "", fmt.Errorf("no local API keys configured")
+	}
+
+	id, _, found := strings.Cut(providedKey, localKeySeparator)
+	if !found {
+		return nil, "invalid API key", nil
+	}
+	match := keys[id]
+	if match == nil {
+		match = fileKeys[id]
+	}
+	if match == nil {
+		return nil, "invalid API key", nil
+	}
+	if !store.verified.Contains(providedKey, match.KeyHash) {
+		ok, err := verifyKeyHash(providedKey, match.KeyHash)
+		if err != nil {
+			slog.Debug("API Key Auth Policy: Failed to verify local key hash",
+				"keyId", match.ID,
+				"error", err,
+			)
+		}
+		if !ok {
+			return nil, "invalid API key", nil
+		}
+		store.verified.Add(providedKey, match.KeyHash)
+	}
+
+	if !match.ExpiresAt.IsZero() && time.Now().After(match.ExpiresAt) {
+		slog.Debug("API Key Auth Policy: Local API key expired",
+			"keyId", match.ID,
+			"expiresAt", match.ExpiresAt,
+		)
+		return nil, "expired API key", nil
+	}
+	if !operationAllowed(match.Operations, ctx.Method, ctx.OperationPath) {
+		slog.Debug("API Key Auth Policy: Local API key not allowed for operation",
+			"keyId", match.ID,
+			"method", ctx.Method,
+			"operation", ctx.OperationPath,
+		)
+		return nil, "API key not allowed for operation", nil
+	}
+	return match, "", nil
+}
+
+// operationAllowed reports whether one of the "METHOD /path" entries matches the operation
+func operationAllowed(operations []string, method, operationPath string) bool {
+	if len(operations) == 0 {
+		return true
+	}
+	for _, operation := range operations {
+		if operation == "*" {
+			return true
+		}
+		allowedMethod, allowedPath, found := strings.Cut(strings.TrimSpace(operation), " ")
+		if !found {
+			continue
+		}
+		allowedPath = strings.TrimSpace(allowedPath)
+		if (allowedMethod == "*" || strings.EqualFold(allowedMethod, method)) &&
+			(allowedPath == "*" || allowedPath == operationPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyKeyHash checks key against a salted SHA-256 ($sha256$<salt>$<hash>, the hash of salt followed
+// by the key) or argon2id ($argon2id$v=19$m=<KiB>,t=<iterations>,p=<threads>$<salt>$<hash>) hash, with
+// salts and hashes in unpadded standard base64
+func verifyKeyHash(key, hash string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	switch {
+	case strings.HasPrefix(hash, "$sha256$"):
+		if len(parts) != 4 {
+			return false, fmt.Errorf("invalid sha256 key hash")
+		}
+		salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+		if err != nil {
+			return false, fmt.Errorf("invalid sha256 salt: %w", err)
+		}
+		expected, err := base64.RawStdEncoding.DecodeString(parts[3])
+		if err != nil || len(expected) != sha256.Size {
+			return false, fmt.Errorf("invalid sha256 hash value")
+		}
+		computed := sha256.Sum256(append(salt, key...))
+		return subtle.ConstantTimeCompare(computed[:], expected) == 1, nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return secrethash.VerifyArgon2id(key, hash)
+	default:
+		return false, fmt.Errorf("unsupported key hash format")
+	}
+}
+
+// validateKeyHash checks that hash has a supported format and, for argon2id, usable parameters
+func validateKeyHash(hash string) error {
+	switch {
+	case strings.HasPrefix(hash, "$sha256$"):
+		return nil
+	case strings.HasPrefix(hash, "$argon2id$"):
+		_, err := secrethash.ParseArgon2id(hash)
+		return err
+	default:
+		return fmt.Errorf("unsupported key hash format")
+	}
+}
+
+// parseLocalKeys converts the keys of the policy parameters or a key file into an index by ID,
+// skipping invalid entries
+func parseLocalKeys(raw interface{}, source string) localKeys {
+	entries, _ := raw.([]interface{})
+	keys := make(localKeys, len(entries))
+	for i, item := range entries {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key := &LocalAPIKey{Attributes: make(map[string]string)}
+		key.ID, _ = entry["id"].(string)
+		if key.ID == "" || strings.Contains(key.ID, localKeySeparator) {
+			slog.Warn("API Key Auth Policy: Skipping local API key without a valid id",
+				"source", source,
+				"index", i,
+			)
+			continue
+		}
+		if _, exists := keys[key.ID]; exists {
+			slog.Warn("API Key Auth Policy: Skipping local API key with duplicate id",
+				"source", source,
+				"keyId", key.ID,
+			)
+			continue
+		}
+		key.KeyHash, _ = entry["keyHash"].(string)
+		if err := validateKeyHash(key.KeyHash); err != nil {
+			slog.Warn("API Key Auth Policy: Skipping local API key with invalid hash",
+				"source", source,
+				"keyId", key.ID,
+				"error", err,
+			)
+			continue
+		}
+		key.ApplicationID, _ = entry["applicationId"].(string)
+		key.Owner, _ = entry["owner"].(string)
+		key.Tier, _ = entry["tier"].(string)
+		if operations, ok := entry["operations"].([]interface{}); ok {
+			for _, operation := range operations {
+				if s, ok := operation.(string); ok {
+					key.Operations = append(key.Operations, s)
+				}
+			}
+		}
+		if expiresAt, _ := entry["expiresAt"].(string); expiresAt != "" {
+			t, err := time.Parse(time.RFC3339, expiresAt)
+			if err != nil {
+				slog.Warn("API Key Auth Policy: Skipping local API key with invalid expiresAt",
+					"source", source,
+					"keyId", key.ID,
+					"error", err,
+				)
+				continue
+			}
+			key.ExpiresAt = t
+		}
+		if attributes, ok := entry["attributes"].(map[string]interface{}); ok {
+			for name, value := range attributes {
+				key.Attributes[name] = fmt.Sprintf("%v", value)
+			}
+		}
+		keys[key.ID] = key
+	}
+	return keys
+}
+
+// getKeyFile returns the keys of a JSON key file ({"keys": [...]}), reloading it when its modification
+// time or size changed. A file that fails to load keeps the previously loaded keys.
+func (s *localKeyStore) getKeyFile(path string) (localKeys, error) {
+	s.fileMutex.Lock()
+	defer s.fileMutex.Unlock()
+
+	file, ok := s.files[path]
+	if ok && time.Since(file.lastCheck) < keyFileCheckInterval {
+		return file.keys, nil
+	}
+	if !ok {
+		file = &keyFile{}
+		s.files[path] = file
+	}
+	file.lastCheck = time.Now()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if file.keys != nil {
+			slog.Warn("API Key Auth Policy: Failed to stat key file, keeping previous keys",
+				"path", path,
+				"error", err,
+			)
+			return file.keys, nil
+		}
+		return nil, fmt.Errorf("failed to stat key file: %w", err)
+	}
+	if file.keys != nil && info.ModTime().Equal(file.modTime) && info.Size() == file.size {
+		return file.keys, nil
+	}
+
+	keys, err := loadKeyFile(path)
+	if err != nil {
+		if file.keys != nil {
+			slog.Warn("API Key Auth Policy: Failed to reload key file, keeping previous keys",
+				"path", path,
+				"error", err,
+			)
+			return file.keys, nil
+		}
+		return nil, err
+	}
+	file.keys = keys
+	file.modTime = info.ModTime()
+	file.size = info.Size()
+
+	slog.Debug("API Key Auth Policy: Loaded key file",
+		"path", path,
+		"keys", len(keys),
+	)
+	return keys, nil
+}
+
+func loadKeyFile(path string) (localKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	var content map[string]interface{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("failed to parse key file: %w", err)
+	}
+	return parseLocalKeys(content["keys"], path), nil
+}
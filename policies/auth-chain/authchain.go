@@ -0,0 +1,283 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package authchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	apikey "github.com/wso2/gateway-controllers/policies/api-key-auth"
+	basicauth "github.com/wso2/gateway-controllers/policies/basic-auth"
+	jwtauth "github.com/wso2/gateway-controllers/policies/jwt-auth"
+)
+
+const (
+	// Metadata keys written by the delegate authentication policies
+	MetadataKeyAuthSuccess = "auth.success"
+	MetadataKeyAuthMethod  = "auth.method"
+
+	// WWWAuthenticateHeader is the challenge header set by the delegates and the combined failure response
+	WWWAuthenticateHeader = "www-authenticate"
+
+	// Authentication method types
+	MethodJWT    = "jwt-auth"
+	MethodAPIKey = "api-key-auth"
+	MethodBasic  = "basic-auth"
+)
+
+// delegate is an authentication policy the chain can run, with the key of its system parameters
+type delegate struct {
+	getPolicy    func(policy.PolicyMetadata, map[string]interface{}) (policy.Policy, error)
+	systemParams string
+	// systemParamNames are the system parameters of the delegate policy, which method params must not set
+	systemParamNames []string
+	authMethod       string // auth.method value recorded when the delegate succeeds
+}
+
+var delegates = map[string]delegate{
+	MethodJWT: {
+		getPolicy:    jwtauth.GetPolicy,
+		systemParams: "jwtAuth",
+		systemParamNames: []string{
+			"keyManagers", "jwksCacheTtl", "jwksFetchTimeout", "jwksFetchRetryCount", "jwksFetchRetryInterval",
+			"jwksRefreshAhead", "jwksMaxStale", "jwksUnknownKidRefetchInterval", "tokenCacheSize", "tokenCacheMaxTtl",
+			"discoveryRefreshInterval", "dpopAllowedAlgorithms", "dpopIatWindow", "dpopReplayCacheSize",
+			"clientCertHeader", "clientCertHeaderFormat", "revocation", "allowedAlgorithms", "leeway",
+			"authHeaderScheme", "headerName", "onFailureStatusCode", "errorMessageFormat", "realm", "errorMessage",
+			"validateIssuer",
+		},
+		authMethod: "jwt",
+	},
+	MethodAPIKey: {
+		getPolicy:        apikey.GetPolicy,
+		systemParams:     "apiKeyAuth",
+		systemParamNames: []string{"bruteForce", "trustedProxies"},
+		authMethod:       "api-key",
+	},
+	MethodBasic: {
+		getPolicy:        basicauth.GetPolicy,
+		systemParams:     "basicAuth",
+		systemParamNames: []string{"bruteForce", "trustedProxies"},
+		authMethod:       "basic",
+	},
+}
+
+// Method is one authentication method of the chain
+type Method struct {
+	Type   string                 // jwt-auth, api-key-auth or basic-auth
+	Params map[string]interface{} // User parameters of the delegate policy
+}
+
+// AuthChainPolicy accepts a request when any of the configured authentication methods accepts it
+type AuthChainPolicy struct{}
+
+var ins = &AuthChainPolicy{}
+
+func GetPolicy(
+	metadata policy.PolicyMetadata,
+	params map[string]interface{},
+) (policy.Policy, error) {
+	return ins, nil
+}
+
+// Mode returns the processing mode for this policy
+func (p *AuthChainPolicy) Mode() policy.ProcessingMode {
+	return policy.ProcessingMode{
+		RequestHeaderMode:  policy.HeaderModeProcess, // Process request headers for auth
+		RequestBodyMode:    policy.BodyModeSkip,      // Don't need request body
+		ResponseHeaderMode: policy.HeaderModeSkip,    // Don't process response headers
+		ResponseBodyMode:   policy.BodyModeSkip,      // Don't need response body
+	}
+}
+
+// OnRequest runs the configured methods in order and passes on the first success. When every method
+// fails, the first non-401 rejection (for example a 403 for missing scopes or a 429 lockout) is returned
+// as is; otherwise a single 401 carries one WWW-Authenticate challenge per scheme.
+func (p *AuthChainPolicy) OnRequest(ctx *policy.RequestContext, params map[string]interface{}) policy.RequestAction {
+	slog.Debug("Auth Chain Policy: OnRequest started",
+		"path", ctx.Path,
+		"method", ctx.Method,
+	)
+
+	realm := "Restricted"
+	if r, ok := params["realm"].(string); ok && r != "" {
+		realm = r
+	}
+
+	methods, err := parseMethods(params)
+	if err != nil {
+		slog.Debug("Auth Chain Policy: Invalid methods configuration",
+			"error", err,
+		)
+		return p.handleAuthFailure(ctx, realm, nil)
+	}
+
+	var challenges []string
+	var rejection *policy.ImmediateResponse
+	for i, method := range methods {
+		d := delegates[method.Type]
+		delegatePolicy, err := d.getPolicy(policy.PolicyMetadata{}, method.Params)
+		if err != nil {
+			slog.Debug("Auth Chain Policy: Failed to create delegate policy",
+				"type", method.Type,
+				"error", err,
+			)
+			continue
+		}
+
+		slog.Debug("Auth Chain Policy: Delegating authentication",
+			"type", method.Type,
+			"index", i,
+		)
+		action := delegatePolicy.OnRequest(ctx, delegateParams(params, d.systemParams, method.Params))
+		if ir, ok := action.(policy.ImmediateResponse); ok {
+			slog.Debug("Auth Chain Policy: Authentication method failed",
+				"type", method.Type,
+				"statusCode", ir.StatusCode,
+			)
+			if ir.StatusCode != 401 {
+				if rejection == nil {
+					rejection = &ir
+				}
+				continue
+			}
+			challenge := ir.Headers[WWWAuthenticateHeader]
+			if challenge == "" && method.Type == MethodAPIKey {
+				challenge = fmt.Sprintf("ApiKey realm=%q", strings.ReplaceAll(realm, `"`, ""))
+			}
+			if challenge != "" {
+				challenges = append(challenges, challenge)
+			}
+			continue
+		}
+
+		// Delegates that allow unauthenticated requests pass them on with auth.success=false
+		if success, _ := ctx.Metadata[MetadataKeyAuthSuccess].(bool); !success {
+			slog.Debug("Auth Chain Policy: Authentication method passed the request without authenticating it",
+				"type", method.Type,
+			)
+			continue
+		}
+
+		ctx.Metadata[MetadataKeyAuthMethod] = d.authMethod
+		slog.Debug("Auth Chain Policy: Authentication successful",
+			"type", method.Type,
+		)
+		return action
+	}
+
+	if rejection != nil {
+		slog.Debug("Auth Chain Policy: All methods failed, returning first non-401 rejection",
+			"statusCode", rejection.StatusCode,
+		)
+		return *rejection
+	}
+	return p.handleAuthFailure(ctx, realm, challenges)
+}
+
+// OnResponse is not used by this policy (authentication is request-only)
+func (p *AuthChainPolicy) OnResponse(ctx *policy.ResponseContext, params map[string]interface{}) policy.ResponseAction {
+	return nil
+}
+
+// handleAuthFailure returns the combined 401 response with one challenge per scheme
+func (p *AuthChainPolicy) handleAuthFailure(ctx *policy.RequestContext, realm string, challenges []string) policy.RequestAction {
+	ctx.Metadata[MetadataKeyAuthSuccess] = false
+	delete(ctx.Metadata, MetadataKeyAuthMethod)
+
+	seen := make(map[string]bool)
+	var unique []string
+	for _, challenge := range challenges {
+		scheme, _, _ := strings.Cut(challenge, " ")
+		scheme = strings.ToLower(scheme)
+		if seen[scheme] {
+			continue
+		}
+		seen[scheme] = true
+		unique = append(unique, challenge)
+	}
+	if len(unique) == 0 {
+		unique = append(unique, fmt.Sprintf("Basic realm=%q", strings.ReplaceAll(realm, `"`, "")))
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"error":   "Unauthorized",
+		"message": "Authentication failed for all configured methods",
+	})
+	slog.Debug("Auth Chain Policy: Returning combined authentication failure",
+		"challenges", len(unique),
+	)
+	return policy.ImmediateResponse{
+		StatusCode: 401,
+		Headers: map[string]string{
+			"content-type":        "application/json",
+			WWWAuthenticateHeader: strings.Join(unique, ", "),
+		},
+		Body: body,
+	}
+}
+
+// parseMethods reads the ordered list of authentication methods
+func parseMethods(params map[string]interface{}) ([]Method, error) {
+	raw, ok := params["methods"].([]interface{})
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("at least one authentication method must be configured")
+	}
+	methods := make([]Method, 0, len(raw))
+	for i, item := range raw {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("methods[%d] must be an object", i)
+		}
+		method := Method{Params: map[string]interface{}{}}
+		method.Type, _ = entry["type"].(string)
+		d, ok := delegates[method.Type]
+		if !ok {
+			return nil, fmt.Errorf("methods[%d] has unsupported type %q", i, method.Type)
+		}
+		if methodParams, ok := entry["params"].(map[string]interface{}); ok {
+			// System parameters such as key managers are set by the administrator, not per API
+			for _, name := range d.systemParamNames {
+				if _, ok := methodParams[name]; ok {
+					return nil, fmt.Errorf("methods[%d].params must not set the system parameter %q", i, name)
+				}
+			}
+			method.Params = methodParams
+		}
+		methods = append(methods, method)
+	}
+	return methods, nil
+}
+
+// delegateParams merges the user parameters of the method with the delegate's system parameters.
+// System parameters are applied last, so they take precedence.
+func delegateParams(params map[string]interface{}, systemKey string, methodParams map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for k, v := range methodParams {
+		merged[k] = v
+	}
+	if system, ok := params[systemKey].(map[string]interface{}); ok {
+		for k, v := range system {
+			merged[k] = v
+		}
+	}
+	return merged
+}
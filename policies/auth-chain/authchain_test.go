@@ -0,0 +1,175 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package authchain
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+func chainParams() map[string]interface{} {
+	salt := []byte("chain-salt")
+	sum := sha256.Sum256(append(salt, "chain-key.chain-api-key"...))
+	keyHash := "$sha256$" + base64.RawStdEncoding.EncodeToString(salt) + "$" + base64.RawStdEncoding.EncodeToString(sum[:])
+
+	return map[string]interface{}{
+		"realm": "Orders",
+		"methods": []interface{}{
+			map[string]interface{}{
+				"type":   "jwt-auth",
+				"params": map[string]interface{}{},
+			},
+			map[string]interface{}{
+				"type": "api-key-auth",
+				"params": map[string]interface{}{
+					"key":      "x-api-key",
+					"in":       "header",
+					"keyStore": "local",
+					"keys": []interface{}{
+						map[string]interface{}{"id": "chain-key", "keyHash": keyHash},
+					},
+				},
+			},
+			map[string]interface{}{
+				"type": "basic-auth",
+				"params": map[string]interface{}{
+					"username": "admin",
+					"password": "secret",
+					"realm":    "Orders",
+				},
+			},
+		},
+	}
+}
+
+func newRequestContext(headers map[string][]string) *policy.RequestContext {
+	return &policy.RequestContext{
+		SharedContext: &policy.SharedContext{
+			RequestID:     "req-1",
+			Metadata:      map[string]interface{}{},
+			AuthContext:   map[string]string{},
+			APIId:         "api-1",
+			APIName:       "OrdersAPI",
+			APIVersion:    "v1",
+			OperationPath: "/orders",
+		},
+		Headers: policy.NewHeaders(headers),
+		Method:  "GET",
+		Path:    "/orders",
+	}
+}
+
+func TestAuthChainPolicy_OnRequest_FirstSuccessWins(t *testing.T) {
+	tests := []struct {
+		name       string
+		headers    map[string][]string
+		wantMethod string
+	}{
+		{
+			name:       "api key",
+			headers:    map[string][]string{"x-api-key": {"chain-key.chain-api-key"}},
+			wantMethod: "api-key",
+		},
+		{
+			name: "basic credentials after failed bearer parsing",
+			headers: map[string][]string{
+				"authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte("admin:secret"))},
+			},
+			wantMethod: "basic",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newRequestContext(tt.headers)
+			action := ins.OnRequest(ctx, chainParams())
+			if _, ok := action.(policy.UpstreamRequestModifications); !ok {
+				t.Fatalf("expected UpstreamRequestModifications, got %#v", action)
+			}
+			if ctx.Metadata[MetadataKeyAuthSuccess] != true {
+				t.Fatalf("expected auth.success=true, got %v", ctx.Metadata[MetadataKeyAuthSuccess])
+			}
+			if ctx.Metadata[MetadataKeyAuthMethod] != tt.wantMethod {
+				t.Fatalf("expected auth.method=%s, got %v", tt.wantMethod, ctx.Metadata[MetadataKeyAuthMethod])
+			}
+		})
+	}
+}
+
+func TestAuthChainPolicy_OnRequest_CombinedChallenge(t *testing.T) {
+	ctx := newRequestContext(map[string][]string{"x-api-key": {"wrong-key"}})
+	action := ins.OnRequest(ctx, chainParams())
+
+	resp, ok := action.(policy.ImmediateResponse)
+	if !ok {
+		t.Fatalf("expected ImmediateResponse, got %T", action)
+	}
+	if resp.StatusCode != 401 {
+		t.Fatalf("expected status 401, got %d", resp.StatusCode)
+	}
+	challenge := resp.Headers[WWWAuthenticateHeader]
+	for _, scheme := range []string{"Bearer", `ApiKey realm="Orders"`, `Basic realm="Orders"`} {
+		if !strings.Contains(challenge, scheme) {
+			t.Fatalf("expected challenge %q in %q", scheme, challenge)
+		}
+	}
+	if ctx.Metadata[MetadataKeyAuthSuccess] != false {
+		t.Fatalf("expected auth.success=false, got %v", ctx.Metadata[MetadataKeyAuthSuccess])
+	}
+}
+
+func TestAuthChainPolicy_OnRequest_InvalidMethods(t *testing.T) {
+	ctx := newRequestContext(nil)
+	action := ins.OnRequest(ctx, map[string]interface{}{
+		"methods": []interface{}{map[string]interface{}{"type": "oauth"}},
+	})
+	resp, ok := action.(policy.ImmediateResponse)
+	if !ok || resp.StatusCode != 401 {
+		t.Fatalf("expected 401 ImmediateResponse, got %#v", action)
+	}
+}
+
+func TestAuthChainPolicy_OnRequest_MethodParamsCannotSetSystemParams(t *testing.T) {
+	params := chainParams()
+	params["methods"] = []interface{}{
+		map[string]interface{}{
+			"type": "jwt-auth",
+			"params": map[string]interface{}{
+				"keyManagers": []interface{}{
+					map[string]interface{}{"name": "forged", "jwks": map[string]interface{}{"local": map[string]interface{}{"inline": "attacker key"}}},
+				},
+			},
+		},
+	}
+	ctx := newRequestContext(map[string][]string{"authorization": {"Bearer forged.token.value"}})
+	resp, ok := ins.OnRequest(ctx, params).(policy.ImmediateResponse)
+	if !ok || resp.StatusCode != 401 {
+		t.Fatalf("expected method params setting keyManagers to be rejected, got %#v", resp)
+	}
+
+	merged := delegateParams(map[string]interface{}{
+		"basicAuth": map[string]interface{}{"trustedProxies": []interface{}{"10.0.0.0/8"}},
+	}, "basicAuth", map[string]interface{}{"trustedProxies": []interface{}{"0.0.0.0/0"}, "username": "admin"})
+	if proxies := merged["trustedProxies"].([]interface{}); len(proxies) != 1 || proxies[0] != "10.0.0.0/8" || merged["username"] != "admin" {
+		t.Fatalf("expected system parameters to take precedence, got %v", merged)
+	}
+}
@@ -0,0 +1,37 @@
+module github.com/wso2/gateway-controllers/policies/auth-chain
+
+go 1.25.1
+
+require (
+	github.com/wso2/api-platform/sdk v0.3.10
+	github.com/wso2/gateway-controllers/policies/api-key-auth v0.10.0
+	github.com/wso2/gateway-controllers/policies/basic-auth v0.10.0
+	github.com/wso2/gateway-controllers/policies/jwt-auth v0.10.0
+)
+
+require (
+	cel.dev/expr v0.24.0 // indirect
+	github.com/antlr4-go/antlr/v4 v4.13.0 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/golang-jwt/jwt/v5 v5.3.1 // indirect
+	github.com/google/cel-go v0.26.1 // indirect
+	github.com/redis/go-redis/v9 v9.17.2 // indirect
+	github.com/stoewer/go-strcase v1.3.0 // indirect
+	github.com/wso2/api-platform/common v0.0.0-20260209055520-167eafeb88ca // indirect
+	github.com/wso2/gateway-controllers/policies/advanced-ratelimit v0.5.0 // indirect
+	golang.org/x/crypto v0.47.0 // indirect
+	golang.org/x/exp v0.0.0-20250408133849-7e4ce0ab07d0 // indirect
+	golang.org/x/sys v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20251213004720-97cd9d5aeac2 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20251213004720-97cd9d5aeac2 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+)
+
+replace github.com/wso2/gateway-controllers/policies/jwt-auth => ../jwt-auth
+
+replace github.com/wso2/gateway-controllers/policies/basic-auth => ../basic-auth
+
+replace github.com/wso2/gateway-controllers/policies/api-key-auth => ../api-key-auth
+
+replace github.com/wso2/gateway-controllers/policies/advanced-ratelimit => ../advanced-ratelimit
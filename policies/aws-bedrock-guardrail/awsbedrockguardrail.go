@@ -27,14 +27,11 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
-	"github.com/aws/aws-sdk-go-v2/service/sts"
 	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
 	utils "github.com/wso2/api-platform/sdk/utils"
+	"github.com/wso2/gateway-controllers/policies/aws-bedrock-guardrail/awscreds"
 )
 
 const (
@@ -462,91 +459,15 @@ func (p *AWSBedrockGuardrailPolicy) validatePayload(payload []byte, params AWSBe
 // loadAWSConfig creates AWS configuration with custom credentials and role assumption
 func (p *AWSBedrockGuardrailPolicy) loadAWSConfig(ctx context.Context, region string) (aws.Config, error) {
 	// Use AWS credentials from policy instance (params)
-	accessKeyID := p.awsAccessKeyID
-	secretAccessKey := p.awsSecretAccessKey
-	sessionToken := p.awsSessionToken
-	roleARN := p.awsRoleARN
-	roleRegion := p.awsRoleRegion
-	roleExternalID := p.awsRoleExternalID
-
-	// Check if role-based authentication should be used
-	if roleARN != "" && roleRegion != "" {
-		return p.loadAWSConfigWithAssumeRole(ctx, accessKeyID, secretAccessKey, sessionToken, roleARN, roleRegion, roleExternalID, region)
-	} else if accessKeyID != "" && secretAccessKey != "" {
-		return p.loadAWSConfigWithStaticCredentials(ctx, accessKeyID, secretAccessKey, sessionToken, region)
-	} else {
-		// Use default credential chain
-		return config.LoadDefaultConfig(ctx, config.WithRegion(region))
-	}
-}
-
-// loadAWSConfigWithStaticCredentials creates AWS config with static credentials
-func (p *AWSBedrockGuardrailPolicy) loadAWSConfigWithStaticCredentials(ctx context.Context, accessKeyID, secretAccessKey, sessionToken, region string) (aws.Config, error) {
-	var credsProvider aws.CredentialsProvider
-	if sessionToken != "" {
-		credsProvider = credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, sessionToken)
-	} else {
-		credsProvider = credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, "")
-	}
-
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion(region),
-		config.WithCredentialsProvider(credsProvider),
-	)
-	if err != nil {
-		return aws.Config{}, fmt.Errorf("failed to load AWS config with static credentials: %w", err)
-	}
-
-	return cfg, nil
-}
-
-// loadAWSConfigWithAssumeRole creates AWS config with role assumption
-func (p *AWSBedrockGuardrailPolicy) loadAWSConfigWithAssumeRole(ctx context.Context, accessKeyID, secretAccessKey, sessionToken, roleARN, roleRegion, roleExternalID, region string) (aws.Config, error) {
-	// Create base config for role assumption
-	var baseCfg aws.Config
-	var err error
-
-	if accessKeyID != "" && secretAccessKey != "" {
-		var baseCredsProvider aws.CredentialsProvider
-		if sessionToken != "" {
-			baseCredsProvider = credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, sessionToken)
-		} else {
-			baseCredsProvider = credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, "")
-		}
-
-		baseCfg, err = config.LoadDefaultConfig(ctx,
-			config.WithRegion(roleRegion),
-			config.WithCredentialsProvider(baseCredsProvider),
-		)
-	} else {
-		baseCfg, err = config.LoadDefaultConfig(ctx, config.WithRegion(roleRegion))
-	}
-
-	if err != nil {
-		return aws.Config{}, fmt.Errorf("failed to load base AWS config for role assumption: %w", err)
-	}
-
-	// Create STS client for role assumption
-	stsClient := sts.NewFromConfig(baseCfg)
-
-	// Create assume role credentials provider
-	assumeRoleProvider := stscreds.NewAssumeRoleProvider(stsClient, roleARN, func(o *stscreds.AssumeRoleOptions) {
-		if roleExternalID != "" {
-			o.ExternalID = aws.String(roleExternalID)
-		}
-		o.RoleSessionName = "bedrock-guardrail-session"
-	})
-
-	// Load final config with assumed role credentials for the target region
-	cfg, err := config.LoadDefaultConfig(ctx,
-		config.WithRegion(region),
-		config.WithCredentialsProvider(assumeRoleProvider),
-	)
-	if err != nil {
-		return aws.Config{}, fmt.Errorf("failed to load AWS config with assume role: %w", err)
-	}
-
-	return cfg, nil
+	return awscreds.LoadAWSConfig(ctx, awscreds.Credentials{
+		AccessKeyID:     p.awsAccessKeyID,
+		SecretAccessKey: p.awsSecretAccessKey,
+		SessionToken:    p.awsSessionToken,
+		RoleARN:         p.awsRoleARN,
+		RoleRegion:      p.awsRoleRegion,
+		RoleExternalID:  p.awsRoleExternalID,
+		RoleSessionName: "bedrock-guardrail-session",
+	}, region)
 }
 
 // applyBedrockGuardrail calls AWS Bedrock Guardrail ApplyGuardrail API
@@ -0,0 +1,221 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+// Package awscreds loads AWS configuration from policy parameters, using static credentials,
+// role assumption or the default credential chain. It is shared by the policies that call AWS.
+package awscreds
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// DefaultExpiryWindow is how long before expiry the Loader refreshes temporary credentials
+const DefaultExpiryWindow = 5 * time.Minute
+
+// Credentials identifies how AWS credentials are obtained
+type Credentials struct {
+	AccessKeyID     string // Static access key ID; the default credential chain is used when empty
+	SecretAccessKey string // Static secret access key
+	SessionToken    string // Optional session token for temporary static credentials
+	RoleARN         string // Role to assume with the static or default credentials
+	RoleRegion      string // Region of the STS endpoint used for role assumption
+	RoleExternalID  string // Optional external ID for role assumption
+	RoleSessionName string // Session name for role assumption
+}
+
+// FromParams reads the awsAccessKeyID, awsSecretAccessKey, awsSessionToken, awsRoleARN,
+// awsRoleRegion and awsRoleExternalID policy parameters
+func FromParams(params map[string]interface{}) (Credentials, error) {
+	var creds Credentials
+	fields := []struct {
+		key    string
+		target *string
+	}{
+		{"awsAccessKeyID", &creds.AccessKeyID},
+		{"awsSecretAccessKey", &creds.SecretAccessKey},
+		{"awsSessionToken", &creds.SessionToken},
+		{"awsRoleARN", &creds.RoleARN},
+		{"awsRoleRegion", &creds.RoleRegion},
+		{"awsRoleExternalID", &creds.RoleExternalID},
+	}
+	for _, field := range fields {
+		raw, ok := params[field.key]
+		if !ok || raw == nil {
+			continue
+		}
+		value, ok := raw.(string)
+		if !ok {
+			return Credentials{}, fmt.Errorf("'%s' must be a string", field.key)
+		}
+		*field.target = value
+	}
+
+	if (creds.AccessKeyID == "") != (creds.SecretAccessKey == "") {
+		return Credentials{}, fmt.Errorf("'awsAccessKeyID' and 'awsSecretAccessKey' must be specified together")
+	}
+	if creds.RoleARN != "" && creds.RoleRegion == "" {
+		return Credentials{}, fmt.Errorf("'awsRoleRegion' is required when 'awsRoleARN' is specified")
+	}
+	return creds, nil
+}
+
+// LoadAWSConfig creates AWS configuration with custom credentials and role assumption.
+// The returned config caches its credentials and refreshes them when they expire.
+func LoadAWSConfig(ctx context.Context, creds Credentials, region string, optFns ...func(*config.LoadOptions) error) (aws.Config, error) {
+	// Check if role-based authentication should be used
+	if creds.RoleARN != "" && creds.RoleRegion != "" {
+		return LoadAWSConfigWithAssumeRole(ctx, creds, region, optFns...)
+	} else if creds.AccessKeyID != "" && creds.SecretAccessKey != "" {
+		return LoadAWSConfigWithStaticCredentials(ctx, creds, region, optFns...)
+	} else {
+		// Use default credential chain
+		return config.LoadDefaultConfig(ctx, append([]func(*config.LoadOptions) error{config.WithRegion(region)}, optFns...)...)
+	}
+}
+
+// LoadAWSConfigWithStaticCredentials creates AWS config with static credentials
+func LoadAWSConfigWithStaticCredentials(ctx context.Context, creds Credentials, region string, optFns ...func(*config.LoadOptions) error) (aws.Config, error) {
+	credsProvider := credentials.NewStaticCredentialsProvider(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken)
+
+	cfg, err := config.LoadDefaultConfig(ctx, append([]func(*config.LoadOptions) error{
+		config.WithRegion(region),
+		config.WithCredentialsProvider(credsProvider),
+	}, optFns...)...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config with static credentials: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// LoadAWSConfigWithAssumeRole creates AWS config with role assumption
+func LoadAWSConfigWithAssumeRole(ctx context.Context, creds Credentials, region string, optFns ...func(*config.LoadOptions) error) (aws.Config, error) {
+	// Create base config for role assumption
+	var baseCfg aws.Config
+	var err error
+
+	if creds.AccessKeyID != "" && creds.SecretAccessKey != "" {
+		baseCredsProvider := credentials.NewStaticCredentialsProvider(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken)
+		baseCfg, err = config.LoadDefaultConfig(ctx,
+			config.WithRegion(creds.RoleRegion),
+			config.WithCredentialsProvider(baseCredsProvider),
+		)
+	} else {
+		baseCfg, err = config.LoadDefaultConfig(ctx, config.WithRegion(creds.RoleRegion))
+	}
+
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load base AWS config for role assumption: %w", err)
+	}
+
+	// Create STS client for role assumption
+	stsClient := sts.NewFromConfig(baseCfg)
+
+	// Create assume role credentials provider
+	assumeRoleProvider := stscreds.NewAssumeRoleProvider(stsClient, creds.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		if creds.RoleExternalID != "" {
+			o.ExternalID = aws.String(creds.RoleExternalID)
+		}
+		if creds.RoleSessionName != "" {
+			o.RoleSessionName = creds.RoleSessionName
+		}
+	})
+
+	// Load final config with assumed role credentials for the target region
+	cfg, err := config.LoadDefaultConfig(ctx, append([]func(*config.LoadOptions) error{
+		config.WithRegion(region),
+		config.WithCredentialsProvider(assumeRoleProvider),
+	}, optFns...)...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config with assume role: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// Loader loads an AWS config once and reuses it, so role assumption and credential
+// resolution are not repeated for every request. Temporary credentials are refreshed
+// ExpiryWindow before they expire; a failed load is retried on the next call.
+type Loader struct {
+	creds        Credentials
+	region       string
+	expiryWindow time.Duration
+
+	mutex sync.Mutex
+	cfg   *aws.Config
+}
+
+// NewLoader creates a Loader for the credentials and region
+func NewLoader(creds Credentials, region string) *Loader {
+	return &Loader{
+		creds:        creds,
+		region:       region,
+		expiryWindow: DefaultExpiryWindow,
+	}
+}
+
+// Config returns the cached AWS config, loading it on first use
+func (l *Loader) Config(ctx context.Context) (aws.Config, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.cfg != nil {
+		return *l.cfg, nil
+	}
+
+	cfg, err := LoadAWSConfig(ctx, l.creds, l.region,
+		config.WithCredentialsCacheOptions(func(o *aws.CredentialsCacheOptions) {
+			o.ExpiryWindow = l.expiryWindow
+		}),
+	)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	l.cfg = &cfg
+
+	slog.Debug("AWS Credentials: Loaded AWS config",
+		"region", l.region,
+		"assumeRole", l.creds.RoleARN != "",
+		"staticCredentials", l.creds.AccessKeyID != "",
+	)
+	return cfg, nil
+}
+
+// Retrieve returns the current credentials, refreshing them when they are about to expire
+func (l *Loader) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	cfg, err := l.Config(ctx)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	if cfg.Credentials == nil {
+		return aws.Credentials{}, fmt.Errorf("no AWS credentials configured")
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+	return creds, nil
+}
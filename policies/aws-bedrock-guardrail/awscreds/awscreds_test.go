@@ -0,0 +1,71 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package awscreds
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFromParams(t *testing.T) {
+	creds, err := FromParams(map[string]interface{}{
+		"awsAccessKeyID":     "AKIDEXAMPLE",
+		"awsSecretAccessKey": "secret",
+		"awsRoleARN":         "arn:aws:iam::123456789012:role/gateway",
+		"awsRoleRegion":      "us-east-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.AccessKeyID != "AKIDEXAMPLE" || creds.RoleRegion != "us-east-1" {
+		t.Fatalf("unexpected credentials %+v", creds)
+	}
+
+	invalid := []map[string]interface{}{
+		{"awsAccessKeyID": 42},
+		{"awsAccessKeyID": "AKIDEXAMPLE"},
+		{"awsRoleARN": "arn:aws:iam::123456789012:role/gateway"},
+	}
+	for _, params := range invalid {
+		if _, err := FromParams(params); err == nil {
+			t.Fatalf("expected error for %v", params)
+		}
+	}
+}
+
+func TestLoader_StaticCredentials(t *testing.T) {
+	loader := NewLoader(Credentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+		SessionToken:    "session",
+	}, "eu-west-1")
+
+	creds, err := loader.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.AccessKeyID != "AKIDEXAMPLE" || creds.SecretAccessKey != "secret" || creds.SessionToken != "session" {
+		t.Fatalf("unexpected credentials %+v", creds)
+	}
+
+	first, _ := loader.Config(context.Background())
+	second, _ := loader.Config(context.Background())
+	if first.Credentials != second.Credentials || first.Region != "eu-west-1" {
+		t.Fatal("expected the loaded config to be reused")
+	}
+}
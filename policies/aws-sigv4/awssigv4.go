@@ -0,0 +1,350 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package awssigv4
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/aws-bedrock-guardrail/awscreds"
+)
+
+const (
+	// Headers set on the upstream request by the signer
+	HeaderAuthorization = "authorization"
+	HeaderAmzDate       = "x-amz-date"
+	HeaderSecurityToken = "x-amz-security-token"
+	HeaderContentSHA256 = "x-amz-content-sha256"
+
+	// unsignedPayload is the payload hash used when the body is not signed
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+
+	// RoleSessionName is the session name used when assuming awsRoleARN
+	RoleSessionName = "aws-sigv4-session"
+
+	// credentialsTimeout bounds credential resolution, including role assumption
+	credentialsTimeout = 10 * time.Second
+)
+
+// now returns the signing time; replaced in tests
+var now = time.Now
+
+// AWSSigV4Policy signs upstream requests with AWS Signature Version 4
+type AWSSigV4Policy struct {
+	service          string
+	region           string
+	host             string
+	upstreamBasePath string
+	signedHeaders    []string
+	unsignedPayload  bool
+	signer           *v4.Signer
+	credentials      *awscreds.Loader
+}
+
+// GetPolicy creates a signer for a route. AWS credentials are loaded on first use and reused
+// for the lifetime of the route; temporary credentials are refreshed before they expire.
+func GetPolicy(
+	metadata policy.PolicyMetadata,
+	params map[string]interface{},
+) (policy.Policy, error) {
+	slog.Debug("AWS SigV4 Policy: Creating policy",
+		"route", metadata.RouteName,
+		"apiName", metadata.APIName,
+	)
+
+	p := &AWSSigV4Policy{
+		service:          getStringParam(params, "service", ""),
+		region:           getStringParam(params, "region", ""),
+		host:             strings.ToLower(getStringParam(params, "host", "")),
+		upstreamBasePath: strings.TrimSuffix(getStringParam(params, "upstreamBasePath", ""), "/"),
+		signedHeaders:    lowerAll(getStringArrayParam(params, "signedHeaders")),
+		unsignedPayload:  getBoolParam(params, "unsignedPayload", false),
+		signer:           v4.NewSigner(),
+	}
+	if p.service == "" {
+		return nil, fmt.Errorf("'service' parameter is required")
+	}
+	if p.region == "" {
+		return nil, fmt.Errorf("'region' parameter is required")
+	}
+	if p.host == "" {
+		return nil, fmt.Errorf("'host' parameter is required")
+	}
+	if p.upstreamBasePath != "" && !strings.HasPrefix(p.upstreamBasePath, "/") {
+		return nil, fmt.Errorf("'upstreamBasePath' must start with '/'")
+	}
+
+	creds, err := awscreds.FromParams(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	creds.RoleSessionName = RoleSessionName
+	p.credentials = awscreds.NewLoader(creds, p.region)
+
+	slog.Debug("AWS SigV4 Policy: Policy created",
+		"route", metadata.RouteName,
+		"service", p.service,
+		"region", p.region,
+		"host", p.host,
+		"unsignedPayload", p.unsignedPayload,
+	)
+	return p, nil
+}
+
+// Mode returns the processing mode for this policy
+func (p *AWSSigV4Policy) Mode() policy.ProcessingMode {
+	bodyMode := policy.BodyModeBuffer // Buffer the request body to hash it
+	if p.unsignedPayload {
+		bodyMode = policy.BodyModeSkip
+	}
+	return policy.ProcessingMode{
+		RequestHeaderMode:  policy.HeaderModeProcess, // Process request headers to sign them
+		RequestBodyMode:    bodyMode,
+		ResponseHeaderMode: policy.HeaderModeSkip, // Don't process response headers
+		ResponseBodyMode:   policy.BodyModeSkip,   // Don't need response body
+	}
+}
+
+// OnRequest signs the request as the upstream will receive it and sets the signature headers
+func (p *AWSSigV4Policy) OnRequest(ctx *policy.RequestContext, params map[string]interface{}) policy.RequestAction {
+	slog.Debug("AWS SigV4 Policy: OnRequest started",
+		"path", ctx.Path,
+		"method", ctx.Method,
+	)
+
+	credCtx, cancel := context.WithTimeout(context.Background(), credentialsTimeout)
+	defer cancel()
+	creds, err := p.credentials.Retrieve(credCtx)
+	if err != nil {
+		slog.Error("AWS SigV4 Policy: Failed to retrieve AWS credentials",
+			"service", p.service,
+			"region", p.region,
+			"error", err,
+		)
+		return signingFailed()
+	}
+
+	req, stripped, err := p.buildRequest(ctx)
+	if err != nil {
+		slog.Debug("AWS SigV4 Policy: Invalid request path",
+			"path", ctx.Path,
+			"error", err,
+		)
+		return signingFailed()
+	}
+
+	payloadHash := unsignedPayload
+	if !p.unsignedPayload {
+		var body []byte
+		if ctx.Body != nil {
+			body = ctx.Body.Content
+		}
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	// S3 requires the payload hash header; other services accept it when it is signed
+	if p.service == "s3" || p.unsignedPayload {
+		req.Header.Set(HeaderContentSHA256, payloadHash)
+	}
+
+	err = p.signer.SignHTTP(credCtx, creds, req, payloadHash, p.service, p.region, now(), func(o *v4.SignerOptions) {
+		// S3 object keys are signed as sent; other services sign the path escaped twice
+		o.DisableURIPathEscaping = p.service == "s3"
+	})
+	if err != nil {
+		slog.Error("AWS SigV4 Policy: Failed to sign request",
+			"error", err,
+		)
+		return signingFailed()
+	}
+
+	modifications := policy.UpstreamRequestModifications{
+		SetHeaders: map[string]string{
+			HeaderAuthorization: req.Header.Get("Authorization"),
+			HeaderAmzDate:       req.Header.Get("X-Amz-Date"),
+		},
+	}
+	if hash := req.Header.Get("X-Amz-Content-Sha256"); hash != "" {
+		modifications.SetHeaders[HeaderContentSHA256] = hash
+	}
+	if creds.SessionToken != "" {
+		modifications.SetHeaders[HeaderSecurityToken] = creds.SessionToken
+	} else if len(ctx.Headers.Get(HeaderSecurityToken)) > 0 {
+		// A token sent by the client would not match the gateway's credentials
+		modifications.RemoveHeaders = append(modifications.RemoveHeaders, HeaderSecurityToken)
+	}
+	if len(stripped) > 0 {
+		slog.Debug("AWS SigV4 Policy: Removing x-amz-* headers that are not in signedHeaders",
+			"headers", stripped,
+		)
+		modifications.RemoveHeaders = append(modifications.RemoveHeaders, stripped...)
+	}
+
+	slog.Debug("AWS SigV4 Policy: Request signed",
+		"service", p.service,
+		"region", p.region,
+		"path", req.URL.EscapedPath(),
+	)
+	return modifications
+}
+
+// OnResponse is not used by this policy
+func (p *AWSSigV4Policy) OnResponse(ctx *policy.ResponseContext, params map[string]interface{}) policy.ResponseAction {
+	return nil
+}
+
+// buildRequest creates the request that is signed: the upstream host, the request path with the
+// API context replaced by upstreamBasePath, the query, and the headers that are not changed in transit.
+// It also returns the client's x-amz-* headers that are not signed, which must be removed.
+func (p *AWSSigV4Policy) buildRequest(ctx *policy.RequestContext) (*http.Request, []string, error) {
+	pathOnly, rawQuery, _ := strings.Cut(ctx.Path, "?")
+	target := p.upstreamBasePath + relativePath(ctx.APIContext, pathOnly)
+	if target == "" {
+		target = "/"
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	u.Scheme = "https"
+	u.Host = p.host
+	u.RawQuery = rawQuery
+
+	req := &http.Request{
+		Method: ctx.Method,
+		URL:    u,
+		Host:   p.host,
+		Header: http.Header{},
+	}
+
+	// Only sign headers that proxies leave untouched: content-type and the configured headers.
+	// x-amz-* headers change what the request does with the gateway's credentials (e.g. x-amz-acl
+	// or x-amz-copy-source), so a client's x-amz-* header is only signed if it is configured and
+	// is removed otherwise; AWS rejects unsigned x-amz-* headers anyway.
+	if values := ctx.Headers.Get("content-type"); len(values) > 0 {
+		req.Header["Content-Type"] = values
+	}
+	for _, name := range p.signedHeaders {
+		switch name {
+		case HeaderAmzDate, HeaderSecurityToken, HeaderContentSHA256:
+			// Set by the signer
+			continue
+		}
+		if values := ctx.Headers.Get(name); len(values) > 0 {
+			req.Header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	var stripped []string
+	ctx.Headers.Iterate(func(name string, values []string) {
+		name = strings.ToLower(name)
+		switch name {
+		case HeaderAmzDate, HeaderSecurityToken, HeaderContentSHA256:
+			return
+		}
+		if strings.HasPrefix(name, "x-amz-") && len(req.Header.Values(name)) == 0 {
+			stripped = append(stripped, name)
+		}
+	})
+	sort.Strings(stripped)
+	return req, stripped, nil
+}
+
+// relativePath strips the API context from the request path
+func relativePath(apiContext, pathOnly string) string {
+	base := strings.TrimSuffix(strings.TrimSpace(apiContext), "/")
+	if base == "" {
+		return pathOnly
+	}
+	if !strings.HasPrefix(base, "/") {
+		base = "/" + base
+	}
+	if pathOnly == base {
+		return ""
+	}
+	if strings.HasPrefix(pathOnly, base+"/") {
+		return strings.TrimPrefix(pathOnly, base)
+	}
+	return pathOnly
+}
+
+// signingFailed is returned when the request cannot be signed; unsigned requests are not forwarded
+func signingFailed() policy.RequestAction {
+	body, _ := json.Marshal(map[string]string{
+		"error":   "Service Unavailable",
+		"message": "Failed to sign upstream request",
+	})
+	return policy.ImmediateResponse{
+		StatusCode: 503,
+		Headers: map[string]string{
+			"content-type": "application/json",
+		},
+		Body: body,
+	}
+}
+
+// Helper functions for type assertions
+func getBoolParam(params map[string]interface{}, key string, defaultValue bool) bool {
+	if v, ok := params[key]; ok {
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func getStringParam(params map[string]interface{}, key, defaultValue string) string {
+	if v, ok := params[key]; ok {
+		if s, ok := v.(string); ok && s != "" {
+			return s
+		}
+	}
+	return defaultValue
+}
+
+// lowerAll lower-cases header names
+func lowerAll(names []string) []string {
+	for i, name := range names {
+		names[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+	return names
+}
+
+func getStringArrayParam(params map[string]interface{}, key string) []string {
+	raw, ok := params[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
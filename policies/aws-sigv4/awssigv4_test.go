@@ -0,0 +1,222 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package awssigv4
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+func init() {
+	// Fixed signing time so signatures are reproducible
+	now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+}
+
+func createParams() map[string]interface{} {
+	return map[string]interface{}{
+		"service":            "service",
+		"region":             "us-east-1",
+		"host":               "example.amazon.com",
+		"awsAccessKeyID":     "AKIDEXAMPLE",
+		"awsSecretAccessKey": "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+}
+
+func createMockRequestContext(method, path string, headers map[string][]string, body []byte) *policy.RequestContext {
+	ctx := &policy.RequestContext{
+		SharedContext: &policy.SharedContext{
+			RequestID: "test-request-id",
+			Metadata:  map[string]interface{}{},
+		},
+		Headers:   policy.NewHeaders(headers),
+		Path:      path,
+		Method:    method,
+		Authority: "gateway.example.com",
+	}
+	if body != nil {
+		ctx.Body = &policy.Body{Content: body, EndOfStream: true, Present: true}
+	}
+	return ctx
+}
+
+func sign(t *testing.T, params map[string]interface{}, ctx *policy.RequestContext) policy.UpstreamRequestModifications {
+	t.Helper()
+	p, err := GetPolicy(policy.PolicyMetadata{RouteName: "test"}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mods, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("expected UpstreamRequestModifications")
+	}
+	return mods
+}
+
+// TestAWSSigV4Policy_Signature checks signatures computed independently from the SigV4 specification
+// with the example credentials of the AWS documentation
+func TestAWSSigV4Policy_Signature(t *testing.T) {
+	tests := []struct {
+		name          string
+		ctx           *policy.RequestContext
+		signedHeaders string
+		signature     string
+	}{
+		{
+			name:          "get",
+			ctx:           createMockRequestContext("GET", "/", map[string][]string{"user-agent": {"curl"}}, nil),
+			signedHeaders: "host;x-amz-date",
+			signature:     "7ab4567ae243ee168f6bf18206b2b40b61ce08277323168138fa113ed23c538e",
+		},
+		{
+			name:          "query parameters are sorted",
+			ctx:           createMockRequestContext("GET", "/?Param2=value2&Param1=value1", nil, nil),
+			signedHeaders: "host;x-amz-date",
+			signature:     "ca0a842792a27475df455b2925aa79d50a98e27d1733a46b57c22810e6b1a7bc",
+		},
+		{
+			name: "post with body and content-type",
+			ctx: createMockRequestContext("POST", "/", map[string][]string{
+				"content-type": {"application/x-www-form-urlencoded"},
+			}, []byte("Param1=value1")),
+			signedHeaders: "content-type;host;x-amz-date",
+			signature:     "41d2a27fc225294a117632c2c31dc9fc09d68ce5127fbbd5b7092515046b48bb",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mods := sign(t, createParams(), tt.ctx)
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=" + tt.signedHeaders + ", Signature=" + tt.signature
+			if got := mods.SetHeaders[HeaderAuthorization]; got != want {
+				t.Fatalf("unexpected authorization header\n got: %s\nwant: %s", got, want)
+			}
+			if mods.SetHeaders[HeaderAmzDate] != "20150830T123600Z" {
+				t.Fatalf("unexpected x-amz-date %q", mods.SetHeaders[HeaderAmzDate])
+			}
+		})
+	}
+}
+
+func TestAWSSigV4Policy_UpstreamPath(t *testing.T) {
+	params := createParams()
+	params["upstreamBasePath"] = "/prod/"
+	ctx := createMockRequestContext("GET", "/orders/v1/items/a%20b?limit=5", nil, nil)
+	ctx.APIContext = "/orders/v1"
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req, _, err := p.(*AWSSigV4Policy).buildRequest(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := req.URL.EscapedPath(); got != "/prod/items/a%20b" {
+		t.Fatalf("expected upstream path /prod/items/a%%20b, got %s", got)
+	}
+	if req.URL.RawQuery != "limit=5" || req.Host != "example.amazon.com" {
+		t.Fatalf("unexpected request %s %s", req.Host, req.URL.RawQuery)
+	}
+}
+
+func TestAWSSigV4Policy_SessionTokenAndS3(t *testing.T) {
+	params := createParams()
+	params["service"] = "s3"
+	params["awsSessionToken"] = "session-token"
+	params["signedHeaders"] = []interface{}{"X-Amz-Acl"}
+	ctx := createMockRequestContext("PUT", "/bucket/key", map[string][]string{
+		"x-amz-acl":            {"private"},
+		"x-amz-security-token": {"client-token"},
+		"x-request-id":         {"abc"},
+	}, []byte("hello"))
+
+	mods := sign(t, params, ctx)
+	if mods.SetHeaders[HeaderSecurityToken] != "session-token" {
+		t.Fatalf("expected the gateway session token, got %q", mods.SetHeaders[HeaderSecurityToken])
+	}
+	// sha256("hello")
+	if mods.SetHeaders[HeaderContentSHA256] != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Fatalf("unexpected payload hash %q", mods.SetHeaders[HeaderContentSHA256])
+	}
+	authz := mods.SetHeaders[HeaderAuthorization]
+	if !strings.Contains(authz, "/us-east-1/s3/aws4_request") ||
+		!strings.Contains(authz, "SignedHeaders=host;x-amz-acl;x-amz-content-sha256;x-amz-date;x-amz-security-token,") {
+		t.Fatalf("unexpected authorization header %s", authz)
+	}
+}
+
+func TestAWSSigV4Policy_ClientAmzHeaders(t *testing.T) {
+	params := createParams()
+	params["service"] = "s3"
+	params["signedHeaders"] = []interface{}{"x-amz-meta-owner"}
+	ctx := createMockRequestContext("PUT", "/bucket/key", map[string][]string{
+		"x-amz-meta-owner":             {"alice"},
+		"x-amz-copy-source":            {"/other-bucket/secret"},
+		"x-amz-grant-full-control":     {"id=attacker"},
+		"x-amz-server-side-encryption": {"AES256"},
+	}, []byte("hello"))
+
+	mods := sign(t, params, ctx)
+	authz := mods.SetHeaders[HeaderAuthorization]
+	if !strings.Contains(authz, "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-meta-owner,") {
+		t.Fatalf("expected only the configured x-amz-* header to be signed, got %s", authz)
+	}
+	expected := []string{"x-amz-copy-source", "x-amz-grant-full-control", "x-amz-server-side-encryption"}
+	if strings.Join(mods.RemoveHeaders, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected the unsigned x-amz-* headers %v to be removed, got %v", expected, mods.RemoveHeaders)
+	}
+}
+
+func TestAWSSigV4Policy_UnsignedPayload(t *testing.T) {
+	params := createParams()
+	params["unsignedPayload"] = true
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Mode().RequestBodyMode != policy.BodyModeSkip {
+		t.Fatal("expected the body not to be buffered")
+	}
+	mods := p.OnRequest(createMockRequestContext("GET", "/", map[string][]string{
+		"x-amz-security-token": {"client-token"},
+	}, nil), params).(policy.UpstreamRequestModifications)
+	if mods.SetHeaders[HeaderContentSHA256] != "UNSIGNED-PAYLOAD" {
+		t.Fatalf("expected UNSIGNED-PAYLOAD, got %q", mods.SetHeaders[HeaderContentSHA256])
+	}
+	if len(mods.RemoveHeaders) != 1 || mods.RemoveHeaders[0] != HeaderSecurityToken {
+		t.Fatalf("expected the client's security token to be removed, got %v", mods.RemoveHeaders)
+	}
+}
+
+func TestGetPolicy_InvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"region": "us-east-1", "host": "example.amazon.com"},
+		{"service": "es", "host": "example.amazon.com"},
+		{"service": "es", "region": "us-east-1"},
+		{"service": "es", "region": "us-east-1", "host": "example.amazon.com", "upstreamBasePath": "prod"},
+		{"service": "es", "region": "us-east-1", "host": "example.amazon.com", "awsRoleARN": "arn:aws:iam::123456789012:role/gw"},
+	}
+	for _, params := range tests {
+		if _, err := GetPolicy(policy.PolicyMetadata{}, params); err == nil {
+			t.Fatalf("expected error for %v", params)
+		}
+	}
+}
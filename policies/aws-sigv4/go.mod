@@ -0,0 +1,28 @@
+module github.com/wso2/gateway-controllers/policies/aws-sigv4
+
+go 1.25.1
+
+require (
+	github.com/aws/aws-sdk-go-v2 v1.31.0
+	github.com/wso2/api-platform/sdk v0.3.10
+	github.com/wso2/gateway-controllers/policies/aws-bedrock-guardrail v0.2.2
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2/config v1.27.16 // indirect
+	github.com/aws/aws-sdk-go-v2/credentials v1.17.16 // indirect
+	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.16.14 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.14 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.14 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/ini v1.8.0 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/accept-encoding v1.11.2 // indirect
+	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.11.9 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sso v1.20.9 // indirect
+	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.24.3 // indirect
+	github.com/aws/aws-sdk-go-v2/service/sts v1.28.10 // indirect
+	github.com/aws/smithy-go v1.21.0 // indirect
+	golang.org/x/crypto v0.46.0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
+)
+
+replace github.com/wso2/gateway-controllers/policies/aws-bedrock-guardrail => ../aws-bedrock-guardrail
@@ -0,0 +1,192 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package backendauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeyBits is the smallest RSA signing key accepted
+const minRSAKeyBits = 2048
+
+// AssertionConfig configures the JWT the gateway signs for the jwt-bearer grant (RFC 7523)
+type AssertionConfig struct {
+	KeyFile   string        // Path to a PEM private key file
+	KeyPEM    string        // Inline PEM private key, used when KeyFile is empty
+	KeyID     string        // Optional kid header value
+	Algorithm string        // Signing algorithm; derived from the key type when empty
+	Issuer    string        // iss claim
+	Subject   string        // sub claim
+	Audience  string        // aud claim, usually the token endpoint
+	Lifetime  time.Duration // Time until the assertion expires
+}
+
+// assertionKey is a loaded private key and the algorithm it signs with
+type assertionKey struct {
+	privateKey crypto.Signer
+	method     jwt.SigningMethod
+	modTime    time.Time // Modification time of the key file, used to reload rotated keys
+}
+
+// signAssertion creates a signed JWT assertion for the jwt-bearer grant
+func (p *BackendAuthPolicy) signAssertion(config AssertionConfig) (string, error) {
+	key, err := p.getAssertionKey(config)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(key.method, jwt.MapClaims{
+		"iss": config.Issuer,
+		"sub": config.Subject,
+		"aud": config.Audience,
+		"iat": now.Unix(),
+		"exp": now.Add(config.Lifetime).Unix(),
+		"jti": newTokenID(),
+	})
+	if config.KeyID != "" {
+		token.Header["kid"] = config.KeyID
+	}
+	signed, err := token.SignedString(key.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign assertion: %w", err)
+	}
+	return signed, nil
+}
+
+// getAssertionKey returns the cached assertion key, reloading it when the key file changed
+func (p *BackendAuthPolicy) getAssertionKey(config AssertionConfig) (*assertionKey, error) {
+	cacheKey := config.KeyFile + "|" + config.KeyPEM + "|" + config.Algorithm
+	var modTime time.Time
+	if config.KeyFile != "" {
+		info, err := os.Stat(config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat assertion key file: %w", err)
+		}
+		modTime = info.ModTime()
+	}
+
+	p.keyMutex.Lock()
+	defer p.keyMutex.Unlock()
+	if key, ok := p.keys[cacheKey]; ok && key.modTime.Equal(modTime) {
+		return key, nil
+	}
+
+	data := []byte(config.KeyPEM)
+	if config.KeyFile != "" {
+		var err error
+		data, err = os.ReadFile(config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read assertion key file: %w", err)
+		}
+	}
+
+	key, err := parseAssertionKey(data, config.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	key.modTime = modTime
+	p.keys[cacheKey] = key
+
+	slog.Debug("Backend Auth Policy: Loaded assertion signing key",
+		"alg", key.method.Alg(),
+	)
+	return key, nil
+}
+
+// parseAssertionKey parses a PKCS#1, PKCS#8 or SEC 1 PEM private key and checks it against the algorithm
+func parseAssertionKey(data []byte, algorithm string) (*assertionKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("assertion key is not PEM encoded")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse assertion key: %w", err)
+	}
+
+	key := &assertionKey{}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA assertion key must be at least %d bits", minRSAKeyBits)
+		}
+		if algorithm == "" {
+			algorithm = "RS256"
+		}
+		switch algorithm {
+		case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		default:
+			return nil, fmt.Errorf("algorithm %s cannot be used with an RSA key", algorithm)
+		}
+		key.privateKey = k
+	case *ecdsa.PrivateKey:
+		var curveAlg string
+		switch k.Curve {
+		case elliptic.P256():
+			curveAlg = "ES256"
+		case elliptic.P384():
+			curveAlg = "ES384"
+		case elliptic.P521():
+			curveAlg = "ES512"
+		default:
+			return nil, fmt.Errorf("unsupported EC curve")
+		}
+		if algorithm == "" {
+			algorithm = curveAlg
+		}
+		if algorithm != curveAlg {
+			return nil, fmt.Errorf("algorithm %s cannot be used with a %s key", algorithm, k.Curve.Params().Name)
+		}
+		key.privateKey = k
+	default:
+		return nil, fmt.Errorf("assertion key must be an RSA or EC private key")
+	}
+	key.method = jwt.GetSigningMethod(algorithm)
+	return key, nil
+}
+
+// newTokenID returns a random jti
+func newTokenID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,368 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package backendauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// Grant types supported for obtaining the upstream token
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeJWTBearer         = "jwt-bearer"
+
+	// grantTypeJWTBearerURI is the grant_type value defined by RFC 7523
+	grantTypeJWTBearerURI = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+	// Client authentication methods supported for the token endpoint
+	ClientAuthMethodBasic = "client_secret_basic"
+	ClientAuthMethodPost  = "client_secret_post"
+
+	// Default header carrying the token to the upstream service
+	DefaultHeaderName = "Authorization"
+
+	// DefaultTokenLifetime is used when the token response has no expires_in
+	DefaultTokenLifetime = 5 * time.Minute
+
+	// maxCacheEntries bounds the token cache
+	maxCacheEntries = 1000
+)
+
+// BackendAuthPolicy obtains OAuth2 access tokens for upstream services and injects them into requests
+type BackendAuthPolicy struct {
+	cacheMutex sync.RWMutex
+	cacheStore map[string]*cachedToken
+	fetchGroup singleflight.Group // Collapses concurrent fetches of the same token
+
+	keyMutex sync.Mutex
+	keys     map[string]*assertionKey
+}
+
+// TokenEndpoint holds the token endpoint and grant configuration
+type TokenEndpoint struct {
+	URI              string          // Token endpoint URL
+	GrantType        string          // client_credentials or jwt-bearer
+	ClientID         string          // Client ID used to authenticate to the endpoint
+	ClientSecret     string          // Client secret used to authenticate to the endpoint
+	ClientAuthMethod string          // client_secret_basic or client_secret_post
+	Scopes           []string        // Requested scopes, sent space-delimited
+	Audience         string          // Optional audience request parameter
+	Assertion        AssertionConfig // Assertion settings for the jwt-bearer grant
+	Timeout          time.Duration   // Timeout for the token request
+	client           *http.Client    // Client for the token endpoint, with the custom CA or skip verification
+}
+
+// routePolicy is the Backend Auth policy of a route, with its token endpoint parsed once
+type routePolicy struct {
+	*BackendAuthPolicy
+	endpoint    *TokenEndpoint
+	endpointErr error
+}
+
+var ins = &BackendAuthPolicy{
+	cacheStore: make(map[string]*cachedToken),
+	keys:       make(map[string]*assertionKey),
+}
+
+func GetPolicy(
+	metadata policy.PolicyMetadata,
+	params map[string]interface{},
+) (policy.Policy, error) {
+	slog.Debug("Backend Auth Policy: GetPolicy called")
+
+	// The endpoint, its TLS config and its HTTP client are shared by every request of the route.
+	// An invalid configuration fails each request with 503, as a direct OnRequest call does.
+	endpoint, err := parseTokenEndpoint(params)
+	if err != nil {
+		slog.Error("Backend Auth Policy: Invalid token endpoint configuration",
+			"error", err,
+		)
+	}
+	return &routePolicy{
+		BackendAuthPolicy: ins,
+		endpoint:          endpoint,
+		endpointErr:       err,
+	}, nil
+}
+
+// OnRequest sets an upstream access token using the token endpoint parsed when the policy was created
+func (p *routePolicy) OnRequest(ctx *policy.RequestContext, params map[string]interface{}) policy.RequestAction {
+	return p.setToken(ctx, params, p.endpoint, p.endpointErr)
+}
+
+// Mode returns the processing mode for this policy
+func (p *BackendAuthPolicy) Mode() policy.ProcessingMode {
+	return policy.ProcessingMode{
+		RequestHeaderMode:  policy.HeaderModeProcess, // Process request headers to set the upstream token
+		RequestBodyMode:    policy.BodyModeSkip,      // Don't need request body
+		ResponseHeaderMode: policy.HeaderModeSkip,    // Don't process response headers
+		ResponseBodyMode:   policy.BodyModeSkip,      // Don't need response body
+	}
+}
+
+// OnRequest sets an upstream access token obtained from the configured token endpoint
+func (p *BackendAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]interface{}) policy.RequestAction {
+	endpoint, err := parseTokenEndpoint(params)
+	return p.setToken(ctx, params, endpoint, err)
+}
+
+// setToken sets an upstream access token obtained from endpoint, which failed to parse with endpointErr
+func (p *BackendAuthPolicy) setToken(ctx *policy.RequestContext, params map[string]interface{}, endpoint *TokenEndpoint, endpointErr error) policy.RequestAction {
+	slog.Debug("Backend Auth Policy: OnRequest started",
+		"path", ctx.Path,
+		"method", ctx.Method,
+	)
+
+	// Get system configuration
+	refreshBefore := getDurationParam(params, "refreshBefore", 30*time.Second)
+
+	// Get user configuration
+	headerName := strings.ToLower(getStringParam(params, "headerName", DefaultHeaderName))
+	headerScheme := getStringParam(params, "headerScheme", "Bearer")
+	replaceClientHeader := getBoolParam(params, "replaceClientHeader", false)
+
+	if !replaceClientHeader && len(ctx.Headers.Get(headerName)) > 0 {
+		slog.Debug("Backend Auth Policy: Client supplied the header, keeping it",
+			"headerName", headerName,
+		)
+		return policy.UpstreamRequestModifications{}
+	}
+
+	if endpointErr != nil {
+		slog.Error("Backend Auth Policy: Invalid token endpoint configuration",
+			"error", endpointErr,
+		)
+		return unavailable()
+	}
+
+	slog.Debug("Backend Auth Policy: Configuration loaded",
+		"tokenEndpoint", endpoint.URI,
+		"grantType", endpoint.GrantType,
+		"clientAuthMethod", endpoint.ClientAuthMethod,
+		"scopes", endpoint.Scopes,
+		"headerName", headerName,
+		"refreshBefore", refreshBefore,
+	)
+
+	token, err := p.getToken(endpoint, refreshBefore)
+	if err != nil {
+		slog.Error("Backend Auth Policy: Failed to obtain upstream access token",
+			"tokenEndpoint", endpoint.URI,
+			"error", err,
+		)
+		return unavailable()
+	}
+
+	value := token
+	if headerScheme != "" {
+		value = headerScheme + " " + token
+	}
+	slog.Debug("Backend Auth Policy: Upstream access token set",
+		"headerName", headerName,
+	)
+	return policy.UpstreamRequestModifications{
+		SetHeaders: map[string]string{
+			headerName: value,
+		},
+	}
+}
+
+// OnResponse is not used by this policy
+func (p *BackendAuthPolicy) OnResponse(ctx *policy.ResponseContext, params map[string]interface{}) policy.ResponseAction {
+	return nil
+}
+
+// parseTokenEndpoint builds the token endpoint configuration from the parameters
+func parseTokenEndpoint(params map[string]interface{}) (*TokenEndpoint, error) {
+	uri := getStringParam(params, "tokenEndpoint", "")
+	if uri == "" {
+		return nil, fmt.Errorf("token endpoint not configured")
+	}
+
+	endpoint := &TokenEndpoint{
+		URI:              uri,
+		GrantType:        getStringParam(params, "grantType", GrantTypeClientCredentials),
+		ClientID:         getStringParam(params, "clientId", ""),
+		ClientSecret:     getStringParam(params, "clientSecret", ""),
+		ClientAuthMethod: getStringParam(params, "clientAuthMethod", ClientAuthMethodBasic),
+		Scopes:           getStringArrayParam(params, "scopes", nil),
+		Audience:         getStringParam(params, "audience", ""),
+		Timeout:          getDurationParam(params, "timeout", 5*time.Second),
+	}
+
+	switch endpoint.ClientAuthMethod {
+	case ClientAuthMethodBasic, ClientAuthMethodPost:
+	default:
+		return nil, fmt.Errorf("unsupported client authentication method '%s'", endpoint.ClientAuthMethod)
+	}
+
+	switch endpoint.GrantType {
+	case GrantTypeClientCredentials:
+		if endpoint.ClientID == "" || endpoint.ClientSecret == "" {
+			return nil, fmt.Errorf("clientId and clientSecret are required for the client_credentials grant")
+		}
+	case GrantTypeJWTBearer:
+		endpoint.Assertion = AssertionConfig{
+			KeyFile:   getStringParam(params, "assertionKeyFile", ""),
+			KeyPEM:    getStringParam(params, "assertionKeyPem", ""),
+			KeyID:     getStringParam(params, "assertionKeyId", ""),
+			Algorithm: getStringParam(params, "assertionAlgorithm", ""),
+			Issuer:    getStringParam(params, "assertionIssuer", ""),
+			Subject:   getStringParam(params, "assertionSubject", ""),
+			Audience:  getStringParam(params, "assertionAudience", ""),
+			Lifetime:  getDurationParam(params, "assertionLifetime", 5*time.Minute),
+		}
+		// The assertion identifies the client to the token endpoint unless configured otherwise
+		if endpoint.Assertion.Issuer == "" {
+			endpoint.Assertion.Issuer = endpoint.ClientID
+		}
+		if endpoint.Assertion.Subject == "" {
+			endpoint.Assertion.Subject = endpoint.ClientID
+		}
+		if endpoint.Assertion.Audience == "" {
+			endpoint.Assertion.Audience = uri
+		}
+		if endpoint.Assertion.KeyFile == "" && endpoint.Assertion.KeyPEM == "" {
+			return nil, fmt.Errorf("either assertionKeyFile or assertionKeyPem is required for the jwt-bearer grant")
+		}
+		if endpoint.Assertion.Issuer == "" || endpoint.Assertion.Subject == "" {
+			return nil, fmt.Errorf("assertionIssuer and assertionSubject are required when clientId is not set")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported grant type '%s'", endpoint.GrantType)
+	}
+
+	var tlsConfig *tls.Config
+	if certPath := getStringParam(params, "certificatePath", ""); certPath != "" {
+		var err error
+		tlsConfig, err = loadTLSConfig(certPath)
+		if err != nil {
+			return nil, err
+		}
+	} else if getBoolParam(params, "skipTlsVerify", false) {
+		tlsConfig = &tls.Config{
+			InsecureSkipVerify: true,
+			MinVersion:         tls.VersionTLS12,
+		}
+	}
+	endpoint.client = &http.Client{Timeout: endpoint.Timeout}
+	if tlsConfig != nil {
+		endpoint.client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
+	return endpoint, nil
+}
+
+// unavailable is returned when no upstream token can be obtained; the request is not forwarded without one
+func unavailable() policy.RequestAction {
+	body, _ := json.Marshal(map[string]string{
+		"error":   "Service Unavailable",
+		"message": "Failed to obtain upstream access token",
+	})
+	return policy.ImmediateResponse{
+		StatusCode: 503,
+		Headers: map[string]string{
+			"content-type": "application/json",
+		},
+		Body: body,
+	}
+}
+
+// loadTLSConfig loads TLS configuration from a CA certificate file
+func loadTLSConfig(certPath string) (*tls.Config, error) {
+	certData, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate file: %w", err)
+	}
+
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(certData) {
+		return nil, fmt.Errorf("failed to parse PEM certificate from %s", certPath)
+	}
+
+	return &tls.Config{
+		RootCAs:    caCertPool,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+// Helper functions for type assertions
+func getBoolParam(params map[string]interface{}, key string, defaultValue bool) bool {
+	if v, ok := params[key]; ok {
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func getStringParam(params map[string]interface{}, key, defaultValue string) string {
+	if v, ok := params[key]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return defaultValue
+}
+
+func getDurationParam(params map[string]interface{}, key string, defaultValue time.Duration) time.Duration {
+	s := getStringParam(params, key, "")
+	if s == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		slog.Debug("Backend Auth Policy: Failed to parse duration, using default",
+			"param", key,
+			"value", s,
+			"error", err,
+			"default", defaultValue,
+		)
+		return defaultValue
+	}
+	return d
+}
+
+func getStringArrayParam(params map[string]interface{}, key string, defaultValue []string) []string {
+	if v, ok := params[key]; ok {
+		if arr, ok := v.([]interface{}); ok {
+			var result []string
+			for _, item := range arr {
+				if s, ok := item.(string); ok {
+					result = append(result, s)
+				}
+			}
+			if len(result) > 0 {
+				return result
+			}
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,344 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package backendauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+// createTokenServer returns a token endpoint that issues numbered tokens after checking the request
+func createTokenServer(t *testing.T, check func(r *http.Request) error, response map[string]interface{}) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if check != nil {
+			if err := check(r); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client", "error_description": err.Error()})
+				return
+			}
+		}
+		body := map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		}
+		for k, v := range response {
+			body[k] = v
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func createParams(tokenEndpoint string) map[string]interface{} {
+	return map[string]interface{}{
+		"tokenEndpoint": tokenEndpoint,
+		"clientId":      "gateway client",
+		"clientSecret":  "s3cret:value",
+		"scopes":        []interface{}{"orders.read", "orders.write"},
+	}
+}
+
+func createMockRequestContext(headers map[string][]string) *policy.RequestContext {
+	return &policy.RequestContext{
+		SharedContext: &policy.SharedContext{
+			RequestID: "test-request-id",
+			Metadata:  map[string]interface{}{},
+		},
+		Headers: policy.NewHeaders(headers),
+		Path:    "/orders",
+		Method:  "GET",
+	}
+}
+
+func runPolicy(t *testing.T, params map[string]interface{}, headers map[string][]string) policy.RequestAction {
+	t.Helper()
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return p.OnRequest(createMockRequestContext(headers), params)
+}
+
+func expectHeader(t *testing.T, action policy.RequestAction, name, want string) {
+	t.Helper()
+	mods, ok := action.(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("expected UpstreamRequestModifications, got %#v", action)
+	}
+	if got := mods.SetHeaders[name]; got != want {
+		t.Fatalf("expected %s header %q, got %q", name, want, got)
+	}
+}
+
+func TestBackendAuthPolicy_ClientCredentials(t *testing.T) {
+	server, calls := createTokenServer(t, func(r *http.Request) error {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "gateway+client" || pass != "s3cret%3Avalue" {
+			return fmt.Errorf("unexpected basic credentials %q %q", user, pass)
+		}
+		if r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("scope") != "orders.read orders.write" {
+			return fmt.Errorf("unexpected form %v", r.PostForm)
+		}
+		return nil
+	}, nil)
+	params := createParams(server.URL)
+
+	expectHeader(t, runPolicy(t, params, nil), "authorization", "Bearer token-1")
+	expectHeader(t, runPolicy(t, params, nil), "authorization", "Bearer token-1")
+	if *calls != 1 {
+		t.Fatalf("expected the token to be cached, got %d token requests", *calls)
+	}
+}
+
+func TestBackendAuthPolicy_ClientSecretPost(t *testing.T) {
+	server, _ := createTokenServer(t, func(r *http.Request) error {
+		if _, _, ok := r.BasicAuth(); ok {
+			return fmt.Errorf("unexpected basic credentials")
+		}
+		if r.PostForm.Get("client_id") != "gateway client" || r.PostForm.Get("client_secret") != "s3cret:value" {
+			return fmt.Errorf("unexpected form %v", r.PostForm)
+		}
+		if r.PostForm.Get("audience") != "https://orders.internal" {
+			return fmt.Errorf("missing audience")
+		}
+		return nil
+	}, nil)
+	params := createParams(server.URL)
+	params["clientAuthMethod"] = ClientAuthMethodPost
+	params["audience"] = "https://orders.internal"
+	params["headerName"] = "X-Upstream-Token"
+	params["headerScheme"] = ""
+
+	expectHeader(t, runPolicy(t, params, nil), "x-upstream-token", "token-1")
+}
+
+func TestBackendAuthPolicy_RefreshBeforeExpiry(t *testing.T) {
+	server, calls := createTokenServer(t, nil, map[string]interface{}{"expires_in": "120"})
+	params := createParams(server.URL)
+	params["refreshBefore"] = "30s"
+
+	expectHeader(t, runPolicy(t, params, nil), "authorization", "Bearer token-1")
+
+	endpoint, err := parseTokenEndpoint(params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ins.cacheMutex.Lock()
+	cached := ins.cacheStore[cacheKeyFor(endpoint)]
+	ins.cacheMutex.Unlock()
+	if remaining := time.Until(cached.RefreshAt); remaining > 90*time.Second || remaining < 85*time.Second {
+		t.Fatalf("expected refresh 30s before the 120s expiry, got %v remaining", remaining)
+	}
+
+	// Once the refresh time passes the next request fetches a new token
+	ins.cacheMutex.Lock()
+	cached.RefreshAt = time.Now().Add(-time.Second)
+	ins.cacheMutex.Unlock()
+	expectHeader(t, runPolicy(t, params, nil), "authorization", "Bearer token-2")
+	if *calls != 2 {
+		t.Fatalf("expected 2 token requests, got %d", *calls)
+	}
+}
+
+func TestBackendAuthPolicy_RefreshFailureKeepsToken(t *testing.T) {
+	var failing atomic.Bool
+	server, calls := createTokenServer(t, func(r *http.Request) error {
+		if failing.Load() {
+			return fmt.Errorf("endpoint unavailable")
+		}
+		return nil
+	}, map[string]interface{}{"expires_in": "120"})
+	params := createParams(server.URL)
+	params["scopes"] = []interface{}{"refresh.failure"}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectHeader(t, p.OnRequest(createMockRequestContext(nil), params), "authorization", "Bearer token-1")
+
+	route := p.(*routePolicy)
+	ins.cacheMutex.Lock()
+	cached := ins.cacheStore[cacheKeyFor(route.endpoint)]
+	cached.RefreshAt = time.Now().Add(-time.Second)
+	ins.cacheMutex.Unlock()
+
+	// The refresh fails, so the token is used until it expires
+	failing.Store(true)
+	expectHeader(t, p.OnRequest(createMockRequestContext(nil), params), "authorization", "Bearer token-1")
+
+	ins.cacheMutex.Lock()
+	cached.ExpiresAt = time.Now().Add(-time.Second)
+	ins.cacheMutex.Unlock()
+	if resp, ok := p.OnRequest(createMockRequestContext(nil), params).(policy.ImmediateResponse); !ok || resp.StatusCode != 503 {
+		t.Fatalf("expected 503 once the cached token expired, got %#v", resp)
+	}
+	if *calls != 3 {
+		t.Fatalf("expected 3 token requests, got %d", *calls)
+	}
+}
+
+func TestBackendAuthPolicy_SingleflightRefresh(t *testing.T) {
+	release := make(chan struct{})
+	server, calls := createTokenServer(t, func(r *http.Request) error {
+		<-release
+		return nil
+	}, nil)
+	params := createParams(server.URL)
+
+	const workers = 20
+	var wg sync.WaitGroup
+	results := make(chan policy.RequestAction, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- runPolicy(t, params, nil)
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for action := range results {
+		expectHeader(t, action, "authorization", "Bearer token-1")
+	}
+	if *calls != 1 {
+		t.Fatalf("expected concurrent requests to share one token request, got %d", *calls)
+	}
+}
+
+func TestBackendAuthPolicy_ClientHeader(t *testing.T) {
+	server, calls := createTokenServer(t, nil, nil)
+	params := createParams(server.URL)
+	clientHeaders := map[string][]string{"authorization": {"Bearer client-token"}}
+
+	action := runPolicy(t, params, clientHeaders)
+	if mods, ok := action.(policy.UpstreamRequestModifications); !ok || len(mods.SetHeaders) != 0 {
+		t.Fatalf("expected the client header to be kept, got %#v", action)
+	}
+	if *calls != 0 {
+		t.Fatalf("expected no token request, got %d", *calls)
+	}
+
+	params["replaceClientHeader"] = true
+	expectHeader(t, runPolicy(t, params, clientHeaders), "authorization", "Bearer token-1")
+}
+
+func TestBackendAuthPolicy_TokenEndpointFailure(t *testing.T) {
+	server, calls := createTokenServer(t, func(r *http.Request) error {
+		return fmt.Errorf("client disabled")
+	}, nil)
+	params := createParams(server.URL)
+
+	for i := 0; i < 2; i++ {
+		action := runPolicy(t, params, nil)
+		resp, ok := action.(policy.ImmediateResponse)
+		if !ok || resp.StatusCode != 503 {
+			t.Fatalf("expected 503 ImmediateResponse, got %#v", action)
+		}
+	}
+	if *calls != 2 {
+		t.Fatalf("expected failures not to be cached, got %d token requests", *calls)
+	}
+}
+
+func TestBackendAuthPolicy_InvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"clientId": "a", "clientSecret": "b"},
+		{"tokenEndpoint": "http://localhost", "clientId": "a"},
+		{"tokenEndpoint": "http://localhost", "clientId": "a", "clientSecret": "b", "clientAuthMethod": "private_key_jwt"},
+		{"tokenEndpoint": "http://localhost", "clientId": "a", "grantType": "password"},
+		{"tokenEndpoint": "http://localhost", "clientId": "a", "grantType": GrantTypeJWTBearer},
+	}
+	for _, params := range tests {
+		action := runPolicy(t, params, nil)
+		if resp, ok := action.(policy.ImmediateResponse); !ok || resp.StatusCode != 503 {
+			t.Fatalf("expected 503 for %v, got %#v", params, action)
+		}
+	}
+}
+
+func TestBackendAuthPolicy_JWTBearer(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	var tokenURL string
+	server, _ := createTokenServer(t, func(r *http.Request) error {
+		if r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			return fmt.Errorf("unexpected grant_type %q", r.PostForm.Get("grant_type"))
+		}
+		if _, _, ok := r.BasicAuth(); ok || r.PostForm.Get("client_id") != "orders-gateway" {
+			return fmt.Errorf("expected client_id without client authentication")
+		}
+		claims := jwt.MapClaims{}
+		token, err := jwt.ParseWithClaims(r.PostForm.Get("assertion"), claims, func(token *jwt.Token) (interface{}, error) {
+			return &privateKey.PublicKey, nil
+		}, jwt.WithValidMethods([]string{"ES256"}), jwt.WithAudience(tokenURL), jwt.WithIssuer("orders-gateway"))
+		if err != nil {
+			return err
+		}
+		if claims["sub"] != "service-account@example.com" || token.Header["kid"] != "gw-1" || claims["jti"] == "" {
+			return fmt.Errorf("unexpected assertion %v %v", token.Header, claims)
+		}
+		return nil
+	}, nil)
+	tokenURL = server.URL
+
+	params := map[string]interface{}{
+		"tokenEndpoint":    server.URL,
+		"grantType":        GrantTypeJWTBearer,
+		"clientId":         "orders-gateway",
+		"assertionKeyPem":  keyPEM,
+		"assertionKeyId":   "gw-1",
+		"assertionSubject": "service-account@example.com",
+	}
+	expectHeader(t, runPolicy(t, params, nil), "authorization", "Bearer token-1")
+}
@@ -0,0 +1,14 @@
+module github.com/wso2/gateway-controllers/policies/backend-auth
+
+go 1.25.1
+
+require (
+	github.com/golang-jwt/jwt/v5 v5.3.1
+	github.com/wso2/api-platform/sdk v0.3.10
+	golang.org/x/sync v0.19.0
+)
+
+require (
+	golang.org/x/crypto v0.46.0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
+)
@@ -0,0 +1,235 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package backendauth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTokenResponseSize bounds the token endpoint response body that is read
+const maxTokenResponseSize = 1 << 20
+
+// cachedToken is an access token with the time it must be refreshed at and the time it expires at
+type cachedToken struct {
+	AccessToken string
+	RefreshAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// tokenResponse is the successful (RFC 6749 section 5.1) or error (section 5.2) token endpoint response
+type tokenResponse struct {
+	AccessToken      string      `json:"access_token"`
+	TokenType        string      `json:"token_type"`
+	ExpiresIn        json.Number `json:"expires_in"`
+	Error            string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+// getToken returns the cached token for the endpoint or fetches a new one. Concurrent callers
+// that miss the cache share a single token request. When the refresh fails, the cached token is
+// used until it expires.
+func (p *BackendAuthPolicy) getToken(endpoint *TokenEndpoint, refreshBefore time.Duration) (string, error) {
+	cacheKey := cacheKeyFor(endpoint)
+	if token, fresh, _ := p.cachedToken(cacheKey); fresh {
+		return token, nil
+	}
+
+	result, err, shared := p.fetchGroup.Do(cacheKey, func() (interface{}, error) {
+		// Another caller may have refreshed the token while this one waited for the group
+		if token, fresh, _ := p.cachedToken(cacheKey); fresh {
+			return token, nil
+		}
+		return p.fetchAndCache(cacheKey, endpoint, refreshBefore)
+	})
+	if err != nil {
+		if token, _, ok := p.cachedToken(cacheKey); ok {
+			slog.Warn("Backend Auth Policy: Failed to refresh access token, using the cached token until it expires",
+				"tokenEndpoint", endpoint.URI,
+				"error", err,
+			)
+			return token, nil
+		}
+		return "", err
+	}
+	slog.Debug("Backend Auth Policy: Access token obtained from token endpoint",
+		"tokenEndpoint", endpoint.URI,
+		"shared", shared,
+	)
+	return result.(string), nil
+}
+
+// cachedToken returns the cached token of the key. fresh reports whether it is not due for
+// refresh yet; ok is false when no unexpired token is cached.
+func (p *BackendAuthPolicy) cachedToken(cacheKey string) (token string, fresh bool, ok bool) {
+	p.cacheMutex.RLock()
+	cached, found := p.cacheStore[cacheKey]
+	p.cacheMutex.RUnlock()
+	now := time.Now()
+	if !found || !now.Before(cached.ExpiresAt) {
+		return "", false, false
+	}
+	return cached.AccessToken, now.Before(cached.RefreshAt), true
+}
+
+// fetchAndCache requests a token and caches it until refreshBefore ahead of its expiry
+func (p *BackendAuthPolicy) fetchAndCache(cacheKey string, endpoint *TokenEndpoint, refreshBefore time.Duration) (string, error) {
+	now := time.Now()
+	token, lifetime, err := p.requestToken(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	// Short-lived tokens are refreshed halfway through their lifetime instead of on every request
+	if refreshBefore > lifetime/2 {
+		refreshBefore = lifetime / 2
+	}
+	refreshAt := now.Add(lifetime - refreshBefore)
+
+	p.cacheMutex.Lock()
+	if len(p.cacheStore) >= maxCacheEntries {
+		for key, entry := range p.cacheStore {
+			if !now.Before(entry.ExpiresAt) {
+				delete(p.cacheStore, key)
+			}
+		}
+	}
+	if len(p.cacheStore) < maxCacheEntries {
+		p.cacheStore[cacheKey] = &cachedToken{AccessToken: token, RefreshAt: refreshAt, ExpiresAt: now.Add(lifetime)}
+	}
+	p.cacheMutex.Unlock()
+
+	slog.Debug("Backend Auth Policy: Access token cached",
+		"tokenEndpoint", endpoint.URI,
+		"expiresIn", lifetime,
+		"refreshAt", refreshAt,
+	)
+	return token, nil
+}
+
+// requestToken performs the configured grant against the token endpoint and returns the access token and its lifetime
+func (p *BackendAuthPolicy) requestToken(endpoint *TokenEndpoint) (string, time.Duration, error) {
+	form := url.Values{}
+	switch endpoint.GrantType {
+	case GrantTypeJWTBearer:
+		assertion, err := p.signAssertion(endpoint.Assertion)
+		if err != nil {
+			return "", 0, err
+		}
+		form.Set("grant_type", grantTypeJWTBearerURI)
+		form.Set("assertion", assertion)
+	default:
+		form.Set("grant_type", GrantTypeClientCredentials)
+	}
+	if len(endpoint.Scopes) > 0 {
+		form.Set("scope", strings.Join(endpoint.Scopes, " "))
+	}
+	if endpoint.Audience != "" {
+		form.Set("audience", endpoint.Audience)
+	}
+
+	// Client authentication is optional for the jwt-bearer grant (RFC 7523 section 3.1)
+	useBasicAuth := false
+	if endpoint.ClientSecret != "" {
+		if endpoint.ClientAuthMethod == ClientAuthMethodPost {
+			form.Set("client_id", endpoint.ClientID)
+			form.Set("client_secret", endpoint.ClientSecret)
+		} else {
+			useBasicAuth = true
+		}
+	} else if endpoint.ClientID != "" {
+		form.Set("client_id", endpoint.ClientID)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint.URI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasicAuth {
+		// RFC 6749 section 2.3.1: credentials are form-encoded before being used in Basic auth
+		req.SetBasicAuth(url.QueryEscape(endpoint.ClientID), url.QueryEscape(endpoint.ClientSecret))
+	}
+
+	slog.Debug("Backend Auth Policy: Calling token endpoint",
+		"uri", endpoint.URI,
+		"grantType", endpoint.GrantType,
+	)
+	resp, err := endpoint.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to call token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTokenResponseSize))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var tokenResp tokenResponse
+	parseErr := json.Unmarshal(body, &tokenResp)
+	if resp.StatusCode != http.StatusOK {
+		if parseErr == nil && tokenResp.Error != "" {
+			return "", 0, fmt.Errorf("token endpoint returned status %d: %s %s", resp.StatusCode, tokenResp.Error, tokenResp.ErrorDescription)
+		}
+		return "", 0, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+	if parseErr != nil {
+		return "", 0, fmt.Errorf("failed to parse token response: %w", parseErr)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", 0, fmt.Errorf("token response has no access_token")
+	}
+
+	lifetime := DefaultTokenLifetime
+	if tokenResp.ExpiresIn != "" {
+		seconds, err := strconv.ParseFloat(tokenResp.ExpiresIn.String(), 64)
+		if err != nil || seconds <= 0 {
+			return "", 0, fmt.Errorf("invalid expires_in value %q in token response", tokenResp.ExpiresIn)
+		}
+		lifetime = time.Duration(seconds * float64(time.Second))
+	}
+	return tokenResp.AccessToken, lifetime, nil
+}
+
+// cacheKeyFor identifies a token by everything that is sent to the token endpoint. The
+// credentials are hashed so a rotated secret or key gets a new token and raw secrets are never used as keys.
+func cacheKeyFor(endpoint *TokenEndpoint) string {
+	a := endpoint.Assertion
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		endpoint.URI,
+		endpoint.GrantType,
+		endpoint.ClientID,
+		endpoint.ClientSecret,
+		endpoint.ClientAuthMethod,
+		strings.Join(endpoint.Scopes, " "),
+		endpoint.Audience,
+		a.KeyFile, a.KeyPEM, a.KeyID, a.Algorithm, a.Issuer, a.Subject, a.Audience,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
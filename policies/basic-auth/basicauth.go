@@ -22,9 +22,13 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"strings"
+	"sync"
 
 	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/advanced-ratelimit/bruteforce"
+	"github.com/wso2/gateway-controllers/policies/basic-auth/secrethash"
 )
 
 const (
@@ -32,12 +36,43 @@ const (
 	MetadataKeyAuthSuccess = "auth.success"
 	MetadataKeyAuthUser    = "auth.username"
 	MetadataKeyAuthMethod  = "auth.method"
+
+	// Metadata keys for user attributes
+	MetadataKeyAuthRoles      = "auth.roles"
+	MetadataKeyAuthTenant     = "auth.tenant"
+	MetadataKeyAuthAttributes = "auth.attributes"
+
+	// AuthContext key for user ID (used for analytics)
+	AuthContextKeyUserID = "x-wso2-user-id"
+
+	// AuthContextAttributePrefix namespaces user attributes in AuthContext so they cannot replace platform keys
+	AuthContextAttributePrefix = "basic-auth."
 )
 
 // BasicAuthPolicy implements HTTP Basic Authentication
-type BasicAuthPolicy struct{}
+type BasicAuthPolicy struct {
+	htpasswdMutex sync.Mutex
+	htpasswdFiles map[string]*htpasswdFile
 
-var ins = &BasicAuthPolicy{}
+	verified *secrethash.VerifiedCache
+
+	bruteForceGuards bruteforce.Guards
+}
+
+// User is a configured user with a password hash and optional attributes. A user without a password
+// hash only adds attributes to the user of the same name in the htpasswd file.
+type User struct {
+	Username     string
+	PasswordHash string            // bcrypt, argon2id or SHA-512-crypt hash
+	Roles        []string          // Exported as auth.roles
+	Tenant       string            // Exported as auth.tenant
+	Attributes   map[string]string // Additional attributes exported as auth.attributes
+}
+
+var ins = &BasicAuthPolicy{
+	htpasswdFiles: make(map[string]*htpasswdFile),
+	verified:      secrethash.NewVerifiedCache(),
+}
 
 func GetPolicy(
 	metadata policy.PolicyMetadata,
@@ -59,33 +94,22 @@ func (p *BasicAuthPolicy) Mode() policy.ProcessingMode {
 // OnRequest performs Basic Authentication
 func (p *BasicAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]interface{}) policy.RequestAction {
 	// Get configuration parameters with safe type assertions
-	expectedUsername, ok := params["username"].(string)
-	if !ok || expectedUsername == "" {
-		errBody, _ := json.Marshal(map[string]string{
-			"error":   "Internal Server Error",
-			"message": "Invalid policy configuration: username must be a non-empty string",
-		})
-		return policy.ImmediateResponse{
-			StatusCode: 500,
-			Headers: map[string]string{
-				"content-type": "application/json",
-			},
-			Body: errBody,
-		}
-	}
-
-	expectedPassword, ok := params["password"].(string)
-	if !ok || expectedPassword == "" {
-		errBody, _ := json.Marshal(map[string]string{
-			"error":   "Internal Server Error",
-			"message": "Invalid policy configuration: password must be a non-empty string",
-		})
-		return policy.ImmediateResponse{
-			StatusCode: 500,
-			Headers: map[string]string{
-				"content-type": "application/json",
-			},
-			Body: errBody,
+	_, hasUsername := params["username"]
+	_, hasPassword := params["password"]
+	expectedUsername, _ := params["username"].(string)
+	expectedPassword, _ := params["password"].(string)
+	users, err := parseUsers(params)
+	if err != nil {
+		return configurationError(err.Error())
+	}
+	htpasswdPath, _ := params["htpasswdFile"].(string)
+
+	if hasUsername || hasPassword || (len(users) == 0 && htpasswdPath == "") {
+		if expectedUsername == "" {
+			return configurationError("username must be a non-empty string")
+		}
+		if expectedPassword == "" {
+			return configurationError("password must be a non-empty string")
 		}
 	}
 
@@ -103,6 +127,15 @@ func (p *BasicAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[strin
 		}
 	}
 
+	attributeMappings := make(map[string]string)
+	if mappingsRaw, ok := params["attributeMappings"].(map[string]interface{}); ok {
+		for attribute, header := range mappingsRaw {
+			if headerStr, ok := header.(string); ok && headerStr != "" {
+				attributeMappings[attribute] = headerStr
+			}
+		}
+	}
+
 	// Extract and validate Authorization header
 	authHeaders := ctx.Headers.Get("authorization")
 	if len(authHeaders) == 0 {
@@ -133,27 +166,131 @@ func (p *BasicAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[strin
 	providedUsername := parts[0]
 	providedPassword := parts[1]
 
-	// Validate credentials using constant-time comparison to prevent timing attacks
-	usernameMatch := subtle.ConstantTimeCompare([]byte(providedUsername), []byte(expectedUsername)) == 1
-	passwordMatch := subtle.ConstantTimeCompare([]byte(providedPassword), []byte(expectedPassword)) == 1
-
-	if !usernameMatch || !passwordMatch {
+	// Reject clients locked out after repeated failures before checking their credentials
+	bruteForce, err := bruteforce.ParseConfig(params, bruteForceOptions)
+	if err != nil {
+		return configurationError(err.Error())
+	}
+	var guard *bruteforce.Guard
+	var attemptKeys []bruteforce.TrackedKey
+	if bruteForce != nil {
+		guard, err = p.getBruteForceGuard(bruteForce)
+		if err != nil {
+			return configurationError(err.Error())
+		}
+		attemptKeys = bruteForceKeys(ctx, bruteForce, providedUsername)
+		if action := guard.Check(bruteForce, attemptKeys, "Basic Auth Policy"); action != nil {
+			ctx.Metadata[MetadataKeyAuthSuccess] = false
+			ctx.Metadata[MetadataKeyAuthMethod] = "basic"
+			return action
+		}
+	}
+	invalidCredentials := func() policy.RequestAction {
+		if guard != nil {
+			guard.RecordFailure(ctx, bruteForce, attemptKeys, "Basic Auth Policy")
+		}
 		return p.handleAuthFailure(ctx, allowUnauthenticated, realm, "invalid credentials")
 	}
 
+	// Validate the single configured user using constant-time comparison to prevent timing attacks
+	if expectedUsername != "" {
+		usernameMatch := subtle.ConstantTimeCompare([]byte(providedUsername), []byte(expectedUsername)) == 1
+		passwordMatch := subtle.ConstantTimeCompare([]byte(providedPassword), []byte(expectedPassword)) == 1
+		if usernameMatch && passwordMatch {
+			return p.handleAuthSuccess(ctx, providedUsername, nil, attributeMappings)
+		}
+	}
+
+	// Validate against the hashed users and the htpasswd file
+	var htpasswdHashes map[string]string
+	if htpasswdPath != "" {
+		htpasswdHashes, err = p.getHtpasswdUsers(htpasswdPath)
+		if err != nil {
+			slog.Error("Basic Auth Policy: Failed to load htpasswd file",
+				"path", htpasswdPath,
+				"error", err,
+			)
+			return configurationError("htpasswd file could not be loaded")
+		}
+	}
+
+	user := users[providedUsername]
+	hash := htpasswdHashes[providedUsername]
+	if user != nil && user.PasswordHash != "" {
+		hash = user.PasswordHash
+	}
+	if hash == "" {
+		// Spend the same time as a real verification so usernames cannot be discovered by timing.
+		// The hash of a configured user has the algorithm and cost of real verifications; the
+		// result is ignored.
+		if dummy := dummyHash(users, htpasswdHashes); dummy != "" {
+			_, _ = verifyPasswordHash(providedPassword, dummy)
+		}
+		return invalidCredentials()
+	}
+
+	if !p.verified.Contains(providedUsername, providedPassword, hash) {
+		match, err := verifyPasswordHash(providedPassword, hash)
+		if err != nil {
+			slog.Warn("Basic Auth Policy: Failed to verify password hash",
+				"username", providedUsername,
+				"error", err,
+			)
+		}
+		if !match {
+			return invalidCredentials()
+		}
+		p.verified.Add(providedUsername, providedPassword, hash)
+	}
+
 	// Authentication successful
-	return p.handleAuthSuccess(ctx, providedUsername)
+	return p.handleAuthSuccess(ctx, providedUsername, user, attributeMappings)
 }
 
 // handleAuthSuccess handles successful authentication
-func (p *BasicAuthPolicy) handleAuthSuccess(ctx *policy.RequestContext, username string) policy.RequestAction {
+func (p *BasicAuthPolicy) handleAuthSuccess(ctx *policy.RequestContext, username string, user *User, attributeMappings map[string]string) policy.RequestAction {
 	// Set metadata indicating successful authentication
 	ctx.Metadata[MetadataKeyAuthSuccess] = true
 	ctx.Metadata[MetadataKeyAuthUser] = username
 	ctx.Metadata[MetadataKeyAuthMethod] = "basic"
+	ctx.SharedContext.AuthContext[AuthContextKeyUserID] = username
+
+	// Export the user's attributes to metadata and AuthContext
+	attributes := map[string]string{"username": username}
+	if user != nil {
+		if len(user.Roles) > 0 {
+			ctx.Metadata[MetadataKeyAuthRoles] = user.Roles
+			attributes["roles"] = strings.Join(user.Roles, ",")
+		}
+		if user.Tenant != "" {
+			ctx.Metadata[MetadataKeyAuthTenant] = user.Tenant
+			attributes["tenant"] = user.Tenant
+		}
+		for name, value := range user.Attributes {
+			if !reservedAttributes[name] {
+				attributes[name] = value
+			}
+		}
+	}
+	ctx.Metadata[MetadataKeyAuthAttributes] = attributes
+	for name, value := range attributes {
+		if name != "username" {
+			ctx.SharedContext.AuthContext[AuthContextAttributePrefix+name] = value
+		}
+	}
 
-	// Continue to upstream with no modifications
-	return policy.UpstreamRequestModifications{}
+	// Apply attribute mappings as headers
+	modifications := policy.UpstreamRequestModifications{}
+	for attribute, headerName := range attributeMappings {
+		if value, ok := attributes[attribute]; ok {
+			if modifications.SetHeaders == nil {
+				modifications.SetHeaders = make(map[string]string)
+			}
+			modifications.SetHeaders[headerName] = value
+		}
+	}
+
+	return modifications
 }
 
 // OnResponse is not used by this policy (authentication is request-only)
@@ -191,3 +328,87 @@ func (p *BasicAuthPolicy) handleAuthFailure(ctx *policy.RequestContext, allowUna
 		Body:       body,
 	}
 }
+
+// reservedAttributes are the attributes set from the authenticated user, which user attributes can
+// not override
+var reservedAttributes = map[string]bool{"username": true, "roles": true, "tenant": true}
+
+// parseUsers reads the users parameter into a map keyed by username, rejecting invalid password hashes
+func parseUsers(params map[string]interface{}) (map[string]*User, error) {
+	users := make(map[string]*User)
+	usersRaw, ok := params["users"].([]interface{})
+	if !ok {
+		return users, nil
+	}
+	for _, item := range usersRaw {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		username, _ := entry["username"].(string)
+		if username == "" {
+			continue
+		}
+		user := &User{Username: username, Attributes: make(map[string]string)}
+		user.PasswordHash, _ = entry["passwordHash"].(string)
+		if user.PasswordHash != "" {
+			if err := validatePasswordHash(user.PasswordHash); err != nil {
+				return nil, fmt.Errorf("user %q: %v", username, err)
+			}
+		}
+		user.Tenant, _ = entry["tenant"].(string)
+		if roles, ok := entry["roles"].([]interface{}); ok {
+			for _, role := range roles {
+				if roleStr, ok := role.(string); ok {
+					user.Roles = append(user.Roles, roleStr)
+				}
+			}
+		}
+		if attributes, ok := entry["attributes"].(map[string]interface{}); ok {
+			for name, value := range attributes {
+				if reservedAttributes[name] {
+					return nil, fmt.Errorf("user %q: attribute %q is reserved", username, name)
+				}
+				user.Attributes[name] = fmt.Sprintf("%v", value)
+			}
+		}
+		users[username] = user
+	}
+	return users, nil
+}
+
+// dummyHash returns the hash verified for unknown usernames: the hash of the configured user with
+// the lowest username, so that the same hash is used for every request. It returns "" if no
+// hashed credentials are configured.
+func dummyHash(users map[string]*User, htpasswdHashes map[string]string) string {
+	var username, hash string
+	for name, user := range users {
+		if user.PasswordHash != "" && (hash == "" || name < username) {
+			username, hash = name, user.PasswordHash
+		}
+	}
+	if hash != "" {
+		return hash
+	}
+	for name, h := range htpasswdHashes {
+		if hash == "" || name < username {
+			username, hash = name, h
+		}
+	}
+	return hash
+}
+
+// configurationError returns a 500 response for an invalid policy configuration
+func configurationError(message string) policy.RequestAction {
+	errBody, _ := json.Marshal(map[string]string{
+		"error":   "Internal Server Error",
+		"message": "Invalid policy configuration: " + message,
+	})
+	return policy.ImmediateResponse{
+		StatusCode: 500,
+		Headers: map[string]string{
+			"content-type": "application/json",
+		},
+		Body: errBody,
+	}
+}
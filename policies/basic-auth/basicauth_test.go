@@ -0,0 +1,358 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package basicauth
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestSha512Crypt tests SHA-512-crypt against the vectors from the specification
+func TestSha512Crypt(t *testing.T) {
+	tests := []struct {
+		password string
+		setting  string
+		want     string
+	}{
+		{
+			password: "Hello world!",
+			setting:  "$6$saltstring",
+			want:     "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+		},
+		{
+			password: "Hello world!",
+			setting:  "$6$rounds=10000$saltstringsaltstring",
+			want:     "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.",
+		},
+		{
+			password: "we have a short salt string but not a short password",
+			setting:  "$6$rounds=77777$short",
+			want:     "$6$rounds=77777$short$WuQyW2YR.hBNpjjRhpYD/ifIw05xdfeEyQoMxIXbkvr0gge1a1x3yRULJ5CCaUeOxFmtlcGZelFl5CxtgfiAc0",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := sha512Crypt(tt.password, tt.setting)
+		if err != nil {
+			t.Fatalf("sha512Crypt failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+// TestVerifyPasswordHash tests each supported hash format
+func TestVerifyPasswordHash(t *testing.T) {
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	salt := []byte("0123456789abcdef")
+	argonHash := "$argon2id$v=19$m=1024,t=1,p=1$" + base64.RawStdEncoding.EncodeToString(salt) + "$" +
+		base64.RawStdEncoding.EncodeToString(argon2.IDKey([]byte("secret"), salt, 1, 1024, 1, 32))
+	shaHash, _ := sha512Crypt("secret", "$6$somesalt")
+
+	for name, hash := range map[string]string{"bcrypt": string(bcryptHash), "argon2id": argonHash, "sha512-crypt": shaHash} {
+		t.Run(name, func(t *testing.T) {
+			if ok, err := verifyPasswordHash("secret", hash); !ok || err != nil {
+				t.Errorf("Expected correct password to match, got %v %v", ok, err)
+			}
+			if ok, _ := verifyPasswordHash("wrong", hash); ok {
+				t.Errorf("Expected wrong password not to match")
+			}
+		})
+	}
+
+	if _, err := verifyPasswordHash("secret", "{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ="); err == nil {
+		t.Errorf("Expected unsupported hash format to be rejected")
+	}
+}
+
+// TestValidatePasswordHash tests that argon2id parameters argon2 would panic on are rejected
+func TestValidatePasswordHash(t *testing.T) {
+	tail := "$MDEyMzQ1Njc4OWFiY2RlZg$" + base64.RawStdEncoding.EncodeToString(make([]byte, 32))
+	for params, valid := range map[string]bool{
+		"m=1024,t=1,p=1": true,
+		"m=1024,t=0,p=1": false,
+		"m=1024,t=1,p=0": false,
+		"m=0,t=1,p=1":    false,
+	} {
+		err := validatePasswordHash("$argon2id$v=19$" + params + tail)
+		if valid != (err == nil) {
+			t.Errorf("%s: expected valid=%v, got error %v", params, valid, err)
+		}
+		if _, err := verifyPasswordHash("secret", "$argon2id$v=19$"+params+tail); valid != (err == nil) {
+			t.Errorf("%s: expected verification error=%v, got %v", params, !valid, err)
+		}
+	}
+
+	params := map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{"username": "alice", "passwordHash": "$argon2id$v=19$m=1024,t=1,p=0" + tail},
+		},
+	}
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+	response, ok := p.OnRequest(createMockRequestContext("alice", "secret"), params).(policy.ImmediateResponse)
+	if !ok || response.StatusCode != 500 {
+		t.Errorf("Expected a configuration error for an invalid argon2id hash, got %v", response)
+	}
+}
+
+// TestBasicAuthPolicy_HashedUsers tests authentication against a user list with attributes
+func TestBasicAuthPolicy_HashedUsers(t *testing.T) {
+	aliceHash, _ := bcrypt.GenerateFromPassword([]byte("alice-pass"), bcrypt.MinCost)
+	params := map[string]interface{}{
+		"users": []interface{}{
+			map[string]interface{}{
+				"username":     "alice",
+				"passwordHash": string(aliceHash),
+				"roles":        []interface{}{"admin", "reader"},
+				"tenant":       "acme",
+				"attributes":   map[string]interface{}{"department": "finance"},
+			},
+		},
+		"attributeMappings": map[string]interface{}{
+			"tenant": "X-Tenant",
+			"roles":  "X-Roles",
+		},
+	}
+
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+
+	ctx := createMockRequestContext("alice", "alice-pass")
+	mods, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamRequestModifications")
+	}
+	if mods.SetHeaders["X-Tenant"] != "acme" || mods.SetHeaders["X-Roles"] != "admin,reader" {
+		t.Errorf("Unexpected attribute headers %v", mods.SetHeaders)
+	}
+	if roles, _ := ctx.Metadata[MetadataKeyAuthRoles].([]string); len(roles) != 2 {
+		t.Errorf("Unexpected roles metadata %v", ctx.Metadata[MetadataKeyAuthRoles])
+	}
+	if ctx.Metadata[MetadataKeyAuthTenant] != "acme" {
+		t.Errorf("Unexpected tenant metadata %v", ctx.Metadata[MetadataKeyAuthTenant])
+	}
+	if ctx.AuthContext[AuthContextKeyUserID] != "alice" || ctx.AuthContext[AuthContextAttributePrefix+"department"] != "finance" {
+		t.Errorf("Unexpected AuthContext %v", ctx.AuthContext)
+	}
+
+	// Cached verification must still reject a wrong password
+	for _, creds := range [][2]string{{"alice", "wrong"}, {"mallory", "alice-pass"}} {
+		if _, ok := p.OnRequest(createMockRequestContext(creds[0], creds[1]), params).(policy.ImmediateResponse); !ok {
+			t.Errorf("Expected %s:%s to be rejected", creds[0], creds[1])
+		}
+	}
+}
+
+// TestBasicAuthPolicy_ReservedAttributes tests that user attributes can not override the username, roles or tenant
+func TestBasicAuthPolicy_ReservedAttributes(t *testing.T) {
+	aliceHash, _ := bcrypt.GenerateFromPassword([]byte("alice-pass"), bcrypt.MinCost)
+	for _, name := range []string{"username", "roles", "tenant"} {
+		params := map[string]interface{}{
+			"users": []interface{}{
+				map[string]interface{}{
+					"username":     "alice",
+					"passwordHash": string(aliceHash),
+					"attributes":   map[string]interface{}{name: "admin"},
+				},
+			},
+		}
+		p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+		response, ok := p.OnRequest(createMockRequestContext("alice", "alice-pass"), params).(policy.ImmediateResponse)
+		if !ok || response.StatusCode != 500 {
+			t.Errorf("Expected a configuration error for attribute %q, got %v", name, response)
+		}
+	}
+}
+
+// TestDummyHash tests the hash verified for unknown usernames
+func TestDummyHash(t *testing.T) {
+	users := map[string]*User{
+		"carol": {Username: "carol", PasswordHash: "$6$carol"},
+		"bob":   {Username: "bob", PasswordHash: "$argon2id$bob"},
+		"alice": {Username: "alice"},
+	}
+	htpasswd := map[string]string{"aaron": "$2y$aaron", "zoe": "$2y$zoe"}
+
+	if hash := dummyHash(users, htpasswd); hash != "$argon2id$bob" {
+		t.Errorf("Expected the hash of the lowest configured user, got %q", hash)
+	}
+	if hash := dummyHash(map[string]*User{"alice": {Username: "alice"}}, htpasswd); hash != "$2y$aaron" {
+		t.Errorf("Expected the hash of the lowest htpasswd user, got %q", hash)
+	}
+	if hash := dummyHash(map[string]*User{"alice": {Username: "alice"}}, nil); hash != "" {
+		t.Errorf("Expected no hash without hashed credentials, got %q", hash)
+	}
+}
+
+// TestBasicAuthPolicy_Htpasswd tests authentication against an htpasswd file that is reloaded on change
+func TestBasicAuthPolicy_Htpasswd(t *testing.T) {
+	bobHash, _ := bcrypt.GenerateFromPassword([]byte("bob-pass"), bcrypt.MinCost)
+	carolHash, _ := sha512Crypt("carol-pass", "$6$carolsalt")
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(path, []byte("# users\nbob:"+string(bobHash)+"\ndave:$apr1$abc$def\n"), 0600); err != nil {
+		t.Fatalf("Failed to write htpasswd: %v", err)
+	}
+
+	params := map[string]interface{}{
+		"htpasswdFile": path,
+		"users": []interface{}{
+			map[string]interface{}{"username": "bob", "roles": []interface{}{"ops"}},
+		},
+	}
+
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+
+	ctx := createMockRequestContext("bob", "bob-pass")
+	if _, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected htpasswd user to be authenticated")
+	}
+	if roles, _ := ctx.Metadata[MetadataKeyAuthRoles].([]string); len(roles) != 1 || roles[0] != "ops" {
+		t.Errorf("Expected roles from users list, got %v", ctx.Metadata[MetadataKeyAuthRoles])
+	}
+	if _, ok := p.OnRequest(createMockRequestContext("carol", "carol-pass"), params).(policy.ImmediateResponse); !ok {
+		t.Fatalf("Expected unknown user to be rejected")
+	}
+
+	if err := os.WriteFile(path, []byte("carol:"+carolHash+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write htpasswd: %v", err)
+	}
+	ins.htpasswdFiles[path].lastCheck = time.Time{}
+
+	if _, ok := p.OnRequest(createMockRequestContext("carol", "carol-pass"), params).(policy.UpstreamRequestModifications); !ok {
+		t.Errorf("Expected user added to htpasswd file to be authenticated after reload")
+	}
+	if _, ok := p.OnRequest(createMockRequestContext("bob", "bob-pass"), params).(policy.ImmediateResponse); !ok {
+		t.Errorf("Expected user removed from htpasswd file to be rejected after reload")
+	}
+}
+
+// TestBasicAuthPolicy_SingleUser tests the plaintext username and password parameters
+func TestBasicAuthPolicy_SingleUser(t *testing.T) {
+	params := map[string]interface{}{
+		"username": "admin",
+		"password": "secret",
+	}
+
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+
+	ctx := createMockRequestContext("admin", "secret")
+	if _, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected valid credentials to be accepted")
+	}
+	if ctx.Metadata[MetadataKeyAuthUser] != "admin" || ctx.Metadata[MetadataKeyAuthSuccess] != true {
+		t.Errorf("Unexpected metadata %v", ctx.Metadata)
+	}
+
+	response, ok := p.OnRequest(createMockRequestContext("admin", "wrong"), params).(policy.ImmediateResponse)
+	if !ok || response.StatusCode != 401 {
+		t.Fatalf("Expected 401 for invalid credentials")
+	}
+
+	response, ok = p.OnRequest(createMockRequestContext("admin", "secret"), map[string]interface{}{}).(policy.ImmediateResponse)
+	if !ok || response.StatusCode != 500 {
+		t.Errorf("Expected 500 when no credentials are configured")
+	}
+}
+
+func createMockRequestContext(username, password string) *policy.RequestContext {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return &policy.RequestContext{
+		SharedContext: &policy.SharedContext{
+			RequestID:   "test-request-id",
+			Metadata:    make(map[string]interface{}),
+			AuthContext: make(map[string]string),
+		},
+		Headers: policy.NewHeaders(map[string][]string{
+			"authorization": {"Basic " + credentials},
+		}),
+		Path:   "/api/test",
+		Method: "GET",
+	}
+}
+
+// TestBasicAuthPolicy_BruteForceLockout tests lockout after repeated failures per username and IP
+func TestBasicAuthPolicy_BruteForceLockout(t *testing.T) {
+	params := map[string]interface{}{
+		"username": "admin",
+		"password": "secret",
+		"bruteForceProtection": map[string]interface{}{
+			"enabled": true,
+			"thresholds": []interface{}{
+				map[string]interface{}{"failures": 3, "window": "1h"},
+			},
+		},
+		"trustedProxies": []interface{}{"10.0.0.0/8"},
+	}
+
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+
+	send := func(username, password, ip string) policy.RequestAction {
+		ctx := createMockRequestContext(username, password)
+		ctx.APIName = "brute-force-test"
+		ctx.Headers = policy.NewHeaders(map[string][]string{
+			"authorization":   {"Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))},
+			"x-forwarded-for": {ip + ", 10.0.0.1"},
+		})
+		return p.OnRequest(ctx, params)
+	}
+
+	for i := 0; i < 3; i++ {
+		response, ok := send("admin", "wrong", "192.0.2.10").(policy.ImmediateResponse)
+		if !ok || response.StatusCode != 401 {
+			t.Fatalf("Expected 401 for failed attempt %d", i+1)
+		}
+	}
+
+	// The username is locked out, even with the correct password and from another address
+	response, ok := send("admin", "secret", "192.0.2.99").(policy.ImmediateResponse)
+	if !ok || response.StatusCode != 429 {
+		t.Fatalf("Expected 429 for locked out username")
+	}
+	if retryAfter := response.Headers["retry-after"]; retryAfter == "" || retryAfter == "0" {
+		t.Errorf("Expected Retry-After header, got %q", retryAfter)
+	}
+
+	// The address is locked out for other usernames, also when the client prepends another address
+	for _, ip := range []string{"192.0.2.10", "203.0.113.5, 192.0.2.10"} {
+		response, ok = send("other", "secret", ip).(policy.ImmediateResponse)
+		if !ok || response.StatusCode != 429 {
+			t.Errorf("Expected 429 for locked out address %q", ip)
+		}
+	}
+
+	// Other clients are unaffected
+	params["users"] = []interface{}{map[string]interface{}{"username": "other", "passwordHash": mustSha512Crypt(t, "other-pass")}}
+	if _, ok := send("other", "other-pass", "198.51.100.7").(policy.UpstreamRequestModifications); !ok {
+		t.Errorf("Expected unrelated client to be authenticated")
+	}
+}
+
+func mustSha512Crypt(t *testing.T, password string) string {
+	hash, err := sha512Crypt(password, "$6$testsalt")
+	if err != nil {
+		t.Fatalf("sha512Crypt failed: %v", err)
+	}
+	return hash
+}
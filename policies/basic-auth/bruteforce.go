@@ -0,0 +1,55 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package basicauth
+
+import (
+	"log/slog"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/advanced-ratelimit/bruteforce"
+)
+
+const (
+	// Attributes failed attempts can be tracked by
+	BruteForceTrackUsername = "username"
+	BruteForceTrackIP       = bruteforce.TrackIP
+)
+
+// bruteForceOptions are the attributes and storage defaults of basic-auth brute-force protection
+var bruteForceOptions = bruteforce.Options{
+	Attributes:     []string{BruteForceTrackUsername},
+	DefaultTrackBy: []string{BruteForceTrackUsername, BruteForceTrackIP},
+	RedisKeyPrefix: "basicauth:bruteforce:",
+}
+
+// getBruteForceGuard returns the guard for the configuration, creating its limiters on first use
+func (p *BasicAuthPolicy) getBruteForceGuard(config *bruteforce.Config) (*bruteforce.Guard, error) {
+	guard, created, err := p.bruteForceGuards.Get(config)
+	if created {
+		slog.Debug("Basic Auth Policy: Created brute-force guard",
+			"backend", config.Backend,
+			"thresholds", len(config.Thresholds),
+		)
+	}
+	return guard, err
+}
+
+// bruteForceKeys returns the keys failed attempts of this request are counted against
+func bruteForceKeys(ctx *policy.RequestContext, config *bruteforce.Config, username string) []bruteforce.TrackedKey {
+	return bruteforce.Keys(ctx, config, map[string]string{BruteForceTrackUsername: username})
+}
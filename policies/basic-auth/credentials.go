@@ -0,0 +1,290 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package basicauth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha512"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wso2/gateway-controllers/policies/basic-auth/secrethash"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// htpasswdCheckInterval is the minimum time between checks of an htpasswd file for changes
+const htpasswdCheckInterval = 5 * time.Second
+
+// verifyPasswordHash checks password against a bcrypt ($2a$, $2b$, $2y$), argon2id ($argon2id$) or
+// SHA-512-crypt ($6$) hash
+func verifyPasswordHash(password, hash string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		return err == nil, err
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return secrethash.VerifyArgon2id(password, hash)
+	case strings.HasPrefix(hash, "$6$"):
+		computed, err := sha512Crypt(password, hash)
+		if err != nil {
+			return false, err
+		}
+		return subtle.ConstantTimeCompare([]byte(computed), []byte(hash)) == 1, nil
+	default:
+		return false, fmt.Errorf("unsupported password hash format")
+	}
+}
+
+// sha512Crypt computes the SHA-512-crypt hash of password using the salt and rounds of setting,
+// following Ulrich Drepper's "Unix crypt using SHA-256 and SHA-512" specification
+func sha512Crypt(password, setting string) (string, error) {
+	rest := strings.TrimPrefix(setting, "$6$")
+	rounds, customRounds := 5000, false
+	if strings.HasPrefix(rest, "rounds=") {
+		value, remainder, found := strings.Cut(strings.TrimPrefix(rest, "rounds="), "$")
+		if !found {
+			return "", fmt.Errorf("invalid SHA-512-crypt rounds")
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid SHA-512-crypt rounds: %w", err)
+		}
+		rounds = min(max(n, 1000), 999999999)
+		customRounds = true
+		rest = remainder
+	}
+	salt, _, _ := strings.Cut(rest, "$")
+	if len(salt) > 16 {
+		salt = salt[:16]
+	}
+
+	p, s := []byte(password), []byte(salt)
+
+	b := sha512.New()
+	b.Write(p)
+	b.Write(s)
+	b.Write(p)
+	digestB := b.Sum(nil)
+
+	a := sha512.New()
+	a.Write(p)
+	a.Write(s)
+	i := len(p)
+	for ; i > sha512.Size; i -= sha512.Size {
+		a.Write(digestB)
+	}
+	a.Write(digestB[:i])
+	for i = len(p); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			a.Write(digestB)
+		} else {
+			a.Write(p)
+		}
+	}
+	digestA := a.Sum(nil)
+
+	dp := sha512.New()
+	for range len(p) {
+		dp.Write(p)
+	}
+	pSeq := repeatToLength(dp.Sum(nil), len(p))
+
+	ds := sha512.New()
+	for range 16 + int(digestA[0]) {
+		ds.Write(s)
+	}
+	sSeq := repeatToLength(ds.Sum(nil), len(s))
+
+	for r := range rounds {
+		c := sha512.New()
+		if r&1 != 0 {
+			c.Write(pSeq)
+		} else {
+			c.Write(digestA)
+		}
+		if r%3 != 0 {
+			c.Write(sSeq)
+		}
+		if r%7 != 0 {
+			c.Write(pSeq)
+		}
+		if r&1 != 0 {
+			c.Write(digestA)
+		} else {
+			c.Write(pSeq)
+		}
+		digestA = c.Sum(nil)
+	}
+
+	var out strings.Builder
+	out.WriteString("$6$")
+	if customRounds {
+		out.WriteString("rounds=" + strconv.Itoa(rounds) + "$")
+	}
+	out.WriteString(salt)
+	out.WriteByte('$')
+	for _, t := range sha512CryptOrder {
+		encodeCrypt64(&out, uint32(digestA[t[0]])<<16|uint32(digestA[t[1]])<<8|uint32(digestA[t[2]]), 4)
+	}
+	encodeCrypt64(&out, uint32(digestA[63]), 2)
+	return out.String(), nil
+}
+
+// sha512CryptOrder is the byte permutation used when encoding the final SHA-512-crypt digest
+var sha512CryptOrder = [][3]int{
+	{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4}, {47, 5, 26}, {6, 27, 48},
+	{28, 49, 7}, {50, 8, 29}, {9, 30, 51}, {31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13},
+	{56, 14, 35}, {15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19}, {62, 20, 41},
+}
+
+const crypt64Alphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+func encodeCrypt64(out *strings.Builder, v uint32, n int) {
+	for range n {
+		out.WriteByte(crypt64Alphabet[v&0x3f])
+		v >>= 6
+	}
+}
+
+func repeatToLength(digest []byte, n int) []byte {
+	return bytes.Repeat(digest, n/len(digest)+1)[:n]
+}
+
+// htpasswdFile holds the users loaded from an Apache htpasswd file
+type htpasswdFile struct {
+	path      string
+	modTime   time.Time
+	size      int64
+	lastCheck time.Time
+	hashes    map[string]string
+}
+
+// getHtpasswdUsers returns the username to hash map of the file, reloading it when its modification
+// time or size changed. A file that fails to load keeps the previously loaded users.
+func (p *BasicAuthPolicy) getHtpasswdUsers(path string) (map[string]string, error) {
+	p.htpasswdMutex.Lock()
+	defer p.htpasswdMutex.Unlock()
+
+	file, ok := p.htpasswdFiles[path]
+	if ok && time.Since(file.lastCheck) < htpasswdCheckInterval {
+		return file.hashes, nil
+	}
+	if !ok {
+		file = &htpasswdFile{path: path}
+		p.htpasswdFiles[path] = file
+	}
+	file.lastCheck = time.Now()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if file.hashes != nil {
+			slog.Warn("Basic Auth Policy: Failed to stat htpasswd file, keeping previous users",
+				"path", path,
+				"error", err,
+			)
+			return file.hashes, nil
+		}
+		return nil, fmt.Errorf("failed to stat htpasswd file: %w", err)
+	}
+	if file.hashes != nil && info.ModTime().Equal(file.modTime) && info.Size() == file.size {
+		return file.hashes, nil
+	}
+
+	hashes, err := loadHtpasswd(path)
+	if err != nil {
+		if file.hashes != nil {
+			slog.Warn("Basic Auth Policy: Failed to reload htpasswd file, keeping previous users",
+				"path", path,
+				"error", err,
+			)
+			return file.hashes, nil
+		}
+		return nil, err
+	}
+	file.hashes = hashes
+	file.modTime = info.ModTime()
+	file.size = info.Size()
+
+	slog.Debug("Basic Auth Policy: Loaded htpasswd file",
+		"path", path,
+		"users", len(hashes),
+	)
+	return hashes, nil
+}
+
+// loadHtpasswd parses `username:hash` lines, skipping blank lines, comments and unsupported hashes
+func loadHtpasswd(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read htpasswd file: %w", err)
+	}
+
+	hashes := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		username, hash, found := strings.Cut(line, ":")
+		if !found || username == "" || hash == "" {
+			slog.Warn("Basic Auth Policy: Skipping malformed htpasswd line",
+				"path", path,
+				"line", lineNumber,
+			)
+			continue
+		}
+		if err := validatePasswordHash(hash); err != nil {
+			slog.Warn("Basic Auth Policy: Skipping htpasswd user with invalid hash",
+				"path", path,
+				"line", lineNumber,
+				"username", username,
+				"error", err,
+			)
+			continue
+		}
+		hashes[username] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read htpasswd file: %w", err)
+	}
+	return hashes, nil
+}
+
+// validatePasswordHash checks that hash has a supported format and, for argon2id, usable parameters
+func validatePasswordHash(hash string) error {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		_, err := secrethash.ParseArgon2id(hash)
+		return err
+	}
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$", "$6$"} {
+		if strings.HasPrefix(hash, prefix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported password hash format")
+}
@@ -1,5 +1,18 @@
 module github.com/wso2/gateway-controllers/policies/basic-auth
 
-go 1.23.0
+go 1.25.1
 
-require github.com/wso2/api-platform/sdk v0.3.0
+require (
+	github.com/wso2/api-platform/sdk v0.3.9
+	github.com/wso2/gateway-controllers/policies/advanced-ratelimit v0.5.0
+	golang.org/x/crypto v0.47.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/redis/go-redis/v9 v9.17.2 // indirect
+	golang.org/x/sys v0.40.0 // indirect
+)
+
+replace github.com/wso2/gateway-controllers/policies/advanced-ratelimit => ../advanced-ratelimit
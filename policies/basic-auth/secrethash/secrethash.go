@@ -0,0 +1,153 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+// Package secrethash verifies passwords and API keys against argon2id hashes and caches successful
+// hash verifications, for the authentication policies that store hashed secrets.
+package secrethash
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// verifiedCacheTTL and verifiedCacheSize bound the cache of successful hash verifications
+	verifiedCacheTTL  = 5 * time.Minute
+	verifiedCacheSize = 10000
+)
+
+// Argon2id is a parsed PHC-format hash: $argon2id$v=19$m=<KiB>,t=<iterations>,p=<threads>$<salt>$<hash>,
+// with the salt and hash in unpadded standard base64
+type Argon2id struct {
+	Memory     uint32
+	Iterations uint32
+	Threads    uint8
+	Salt       []byte
+	Hash       []byte
+}
+
+// ParseArgon2id parses and validates an argon2id hash, rejecting parameters that argon2 cannot use
+func ParseArgon2id(hash string) (*Argon2id, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" || parts[2] != "v=19" {
+		return nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	parsed := &Argon2id{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &parsed.Memory, &parsed.Iterations, &parsed.Threads); err != nil {
+		return nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	if parsed.Iterations < 1 || parsed.Threads < 1 || parsed.Memory < 8*uint32(parsed.Threads) {
+		return nil, fmt.Errorf("invalid argon2id parameters: t and p must be at least 1 and m at least 8*p")
+	}
+	var err error
+	parsed.Salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	parsed.Hash, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(parsed.Hash) == 0 {
+		return nil, fmt.Errorf("invalid argon2id hash value")
+	}
+	return parsed, nil
+}
+
+// Verify reports whether secret matches the hash, comparing in constant time
+func (h *Argon2id) Verify(secret string) bool {
+	computed := argon2.IDKey([]byte(secret), h.Salt, h.Iterations, h.Memory, h.Threads, uint32(len(h.Hash)))
+	return subtle.ConstantTimeCompare(computed, h.Hash) == 1
+}
+
+// VerifyArgon2id checks secret against a PHC-format argon2id hash
+func VerifyArgon2id(secret, hash string) (bool, error) {
+	parsed, err := ParseArgon2id(hash)
+	if err != nil {
+		return false, err
+	}
+	return parsed.Verify(secret), nil
+}
+
+// VerifiedCache remembers successful hash verifications so that repeated requests do not pay the
+// cost of bcrypt or argon2id each time. Entries are keyed by an HMAC with a per-process key, so the
+// cache never holds anything that can be attacked faster than the original hash.
+type VerifiedCache struct {
+	mutex   sync.Mutex
+	key     []byte
+	entries map[string]time.Time
+}
+
+// NewVerifiedCache returns an empty cache with a random HMAC key
+func NewVerifiedCache() *VerifiedCache {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return &VerifiedCache{key: key, entries: make(map[string]time.Time)}
+}
+
+// entryKey returns the HMAC of the parts of a verification, typically the identity, the secret and
+// the hash it was verified against. Each part is length-prefixed so that parts can not run into
+// each other.
+func (c *VerifiedCache) entryKey(parts []string) string {
+	mac := hmac.New(sha256.New, c.key)
+	for _, part := range parts {
+		_ = binary.Write(mac, binary.BigEndian, uint64(len(part)))
+		mac.Write([]byte(part))
+	}
+	return string(mac.Sum(nil))
+}
+
+// Contains reports whether a verification of the parts succeeded within the last 5 minutes
+func (c *VerifiedCache) Contains(parts ...string) bool {
+	key := c.entryKey(parts)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	expiresAt, ok := c.entries[key]
+	if ok && time.Now().After(expiresAt) {
+		delete(c.entries, key)
+		return false
+	}
+	return ok
+}
+
+// Add records a successful verification of the parts. When the cache is full, expired entries are
+// removed, and all entries if none expired.
+func (c *VerifiedCache) Add(parts ...string) {
+	key := c.entryKey(parts)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.entries) >= verifiedCacheSize {
+		now := time.Now()
+		for k, expiresAt := range c.entries {
+			if now.After(expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= verifiedCacheSize {
+			c.entries = make(map[string]time.Time)
+		}
+	}
+	c.entries[key] = time.Now().Add(verifiedCacheTTL)
+}
@@ -0,0 +1,72 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package secrethash
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestVerifyArgon2id(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	hash := "$argon2id$v=19$m=1024,t=1,p=1$" + base64.RawStdEncoding.EncodeToString(salt) + "$" +
+		base64.RawStdEncoding.EncodeToString(argon2.IDKey([]byte("secret"), salt, 1, 1024, 1, 32))
+
+	if ok, err := VerifyArgon2id("secret", hash); !ok || err != nil {
+		t.Errorf("Expected the secret to match, got %v %v", ok, err)
+	}
+	if ok, err := VerifyArgon2id("wrong", hash); ok || err != nil {
+		t.Errorf("Expected a wrong secret not to match, got %v %v", ok, err)
+	}
+}
+
+func TestParseArgon2id(t *testing.T) {
+	tail := "$c2FsdHNhbHQ$aGFzaGhhc2g"
+	for params, valid := range map[string]bool{
+		"m=1024,t=1,p=1": true,
+		"m=1024,t=0,p=1": false,
+		"m=1024,t=1,p=0": false,
+		"m=7,t=1,p=1":    false,
+		"m=x,t=1,p=1":    false,
+	} {
+		if _, err := ParseArgon2id("$argon2id$v=19$" + params + tail); valid != (err == nil) {
+			t.Errorf("%s: expected valid=%v, got error %v", params, valid, err)
+		}
+	}
+	for _, hash := range []string{"$argon2i$v=19$m=1024,t=1,p=1" + tail, "$argon2id$v=16$m=1024,t=1,p=1" + tail, "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$"} {
+		if _, err := ParseArgon2id(hash); err == nil {
+			t.Errorf("Expected %q to be rejected", hash)
+		}
+	}
+}
+
+func TestVerifiedCache(t *testing.T) {
+	cache := NewVerifiedCache()
+	cache.Add("alice", "secret", "$hash")
+
+	if !cache.Contains("alice", "secret", "$hash") {
+		t.Errorf("Expected the verification to be cached")
+	}
+	for _, parts := range [][]string{{"alice", "wrong", "$hash"}, {"alice", "secret", "$other"}, {"alice\x00secret", "$hash"}} {
+		if cache.Contains(parts...) {
+			t.Errorf("Expected %q not to be cached", parts)
+		}
+	}
+}
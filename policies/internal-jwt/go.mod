@@ -0,0 +1,13 @@
+module github.com/wso2/gateway-controllers/policies/internal-jwt
+
+go 1.25.1
+
+require (
+	github.com/golang-jwt/jwt/v5 v5.3.1
+	github.com/wso2/api-platform/sdk v0.3.9
+)
+
+require (
+	golang.org/x/crypto v0.46.0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
+)
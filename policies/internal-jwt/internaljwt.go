@@ -0,0 +1,343 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package internaljwt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+const (
+	// Metadata keys written by the authentication policies
+	MetadataKeyAuthSuccess = "auth.success"
+	MetadataKeyAuthMethod  = "auth.method"
+	MetadataKeySubject     = "auth.subject"
+	MetadataKeyAuthUser    = "auth.username"
+
+	// AuthContext key for user ID (set by the authentication policies for analytics)
+	AuthContextKeyUserID = "x-wso2-user-id"
+
+	// Prefixes of the claim source expressions
+	SourceMetadata    = "metadata."
+	SourceAuthContext = "authContext."
+	SourceAPI         = "api."
+	SourceRequest     = "request."
+
+	// Default header carrying the minted token to the upstream service
+	DefaultHeaderName = "X-JWT-Assertion"
+)
+
+// reservedClaims are set by the policy and cannot be overridden by claim mappings
+var reservedClaims = map[string]bool{"iss": true, "iat": true, "nbf": true, "exp": true, "jti": true}
+
+// InternalJwtPolicy mints gateway-signed JWTs that identify the authenticated caller to upstream services
+type InternalJwtPolicy struct {
+	keyMutex sync.Mutex
+	keys     map[string]*signingKey
+	keyRings map[string]*keyRing // Recently used keys by API, issuer and JWKS path
+}
+
+var ins = &InternalJwtPolicy{
+	keys:     make(map[string]*signingKey),
+	keyRings: make(map[string]*keyRing),
+}
+
+func GetPolicy(
+	metadata policy.PolicyMetadata,
+	params map[string]interface{},
+) (policy.Policy, error) {
+	slog.Debug("Internal JWT Policy: GetPolicy called")
+	return ins, nil
+}
+
+// Mode returns the processing mode for this policy
+func (p *InternalJwtPolicy) Mode() policy.ProcessingMode {
+	return policy.ProcessingMode{
+		RequestHeaderMode:  policy.HeaderModeProcess, // Process request headers to set the token
+		RequestBodyMode:    policy.BodyModeSkip,      // Don't need request body
+		ResponseHeaderMode: policy.HeaderModeSkip,    // Don't process response headers
+		ResponseBodyMode:   policy.BodyModeSkip,      // Don't need response body
+	}
+}
+
+// OnRequest mints the internal JWT for authenticated requests, or serves the JWKS at the configured path
+func (p *InternalJwtPolicy) OnRequest(ctx *policy.RequestContext, params map[string]interface{}) policy.RequestAction {
+	slog.Debug("Internal JWT Policy: OnRequest started",
+		"path", ctx.Path,
+		"method", ctx.Method,
+	)
+
+	// Get system configuration
+	keyConfig := KeyConfig{
+		File:      getStringParam(params, "signingKeyFile", ""),
+		PEM:       getStringParam(params, "signingKeyPem", ""),
+		KeyID:     getStringParam(params, "keyId", ""),
+		Algorithm: getStringParam(params, "algorithm", ""),
+	}
+	issuer := getStringParam(params, "issuer", "wso2-api-gateway")
+	tokenTTL := getDurationParam(params, "tokenTtl", 5*time.Minute)
+	jwksPath := getStringParam(params, "jwksPath", "/.well-known/gateway-jwks.json")
+
+	// Get user configuration
+	headerName := strings.ToLower(getStringParam(params, "headerName", DefaultHeaderName))
+	audience := getStringParam(params, "audience", "")
+	claimSources := getStringMapParam(params, "claims", map[string]string{})
+	requireAuthenticated := getBoolParam(params, "requireAuthenticated", true)
+
+	key, err := p.getSigningKey(keyConfig)
+	if err != nil {
+		slog.Error("Internal JWT Policy: Failed to load signing key",
+			"error", err,
+		)
+		return internalError("Failed to issue internal token")
+	}
+
+	// Tokens signed with a previous key of the API stay verifiable until they expire and the JWKS
+	// cached by verifiers is refreshed
+	ring := strings.Join([]string{ctx.APIId, ctx.APIName, ctx.APIVersion, issuer, jwksPath}, "|")
+	retention := tokenTTL + jwksMaxAge
+	p.useKey(ring, key, retention)
+
+	if jwksPath != "" && strings.EqualFold(ctx.Method, "GET") {
+		if path, _, _ := strings.Cut(ctx.Path, "?"); path == jwksPath {
+			return serveJWKS(p.publishedKeys(ring, key, retention))
+		}
+	}
+
+	// Remove any caller-supplied token so upstream services only see gateway-issued ones
+	if requireAuthenticated {
+		if authenticated, _ := ctx.Metadata[MetadataKeyAuthSuccess].(bool); !authenticated {
+			slog.Debug("Internal JWT Policy: Request is not authenticated, skipping token")
+			return policy.UpstreamRequestModifications{
+				RemoveHeaders: []string{headerName},
+			}
+		}
+	}
+
+	claims := buildClaims(ctx, claimSources)
+	now := time.Now()
+	claims["iss"] = issuer
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(tokenTTL).Unix()
+	claims["jti"] = newTokenID()
+	if audience != "" {
+		claims["aud"] = audience
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.keyID
+	signed, err := token.SignedString(key.privateKey)
+	if err != nil {
+		slog.Error("Internal JWT Policy: Failed to sign token",
+			"error", err,
+		)
+		return internalError("Failed to issue internal token")
+	}
+
+	slog.Debug("Internal JWT Policy: Issued internal token",
+		"header", headerName,
+		"sub", claims["sub"],
+		"kid", key.keyID,
+	)
+	return policy.UpstreamRequestModifications{
+		SetHeaders: map[string]string{
+			headerName: signed,
+		},
+	}
+}
+
+// OnResponse is not used by this policy
+func (p *InternalJwtPolicy) OnResponse(ctx *policy.ResponseContext, params map[string]interface{}) policy.ResponseAction {
+	return nil
+}
+
+// buildClaims resolves the configured claim sources. The subject defaults to auth.subject, then
+// auth.username, then the analytics user ID. Claims whose source has no value are omitted.
+func buildClaims(ctx *policy.RequestContext, claimSources map[string]string) jwt.MapClaims {
+	claims := jwt.MapClaims{}
+	for _, source := range []string{SourceMetadata + MetadataKeySubject, SourceMetadata + MetadataKeyAuthUser, SourceAuthContext + AuthContextKeyUserID} {
+		if v, ok := resolveClaimSource(ctx, source); ok {
+			claims["sub"] = v
+			break
+		}
+	}
+	if v, ok := resolveClaimSource(ctx, SourceMetadata+MetadataKeyAuthMethod); ok {
+		claims["auth_method"] = v
+	}
+
+	for name, source := range claimSources {
+		if reservedClaims[name] {
+			slog.Debug("Internal JWT Policy: Ignoring mapping for reserved claim",
+				"claim", name,
+			)
+			continue
+		}
+		if v, ok := resolveClaimSource(ctx, source); ok {
+			claims[name] = v
+		} else {
+			delete(claims, name)
+		}
+	}
+	return claims
+}
+
+// resolveClaimSource returns the value referenced by a source expression: metadata.<key>,
+// authContext.<key>, api.<name|version|context|id|kind|operationPath> or request.<method|path>.
+// Any other value is used as a literal.
+func resolveClaimSource(ctx *policy.RequestContext, source string) (interface{}, bool) {
+	switch {
+	case strings.HasPrefix(source, SourceMetadata):
+		v, ok := ctx.Metadata[strings.TrimPrefix(source, SourceMetadata)]
+		if !ok || v == nil || v == "" {
+			return nil, false
+		}
+		return v, true
+	case strings.HasPrefix(source, SourceAuthContext):
+		v := ctx.AuthContext[strings.TrimPrefix(source, SourceAuthContext)]
+		return v, v != ""
+	case strings.HasPrefix(source, SourceAPI):
+		var v string
+		switch strings.TrimPrefix(source, SourceAPI) {
+		case "name":
+			v = ctx.APIName
+		case "version":
+			v = ctx.APIVersion
+		case "context":
+			v = ctx.APIContext
+		case "id":
+			v = ctx.APIId
+		case "kind":
+			v = ctx.APIKind
+		case "operationPath":
+			v = ctx.OperationPath
+		}
+		return v, v != ""
+	case strings.HasPrefix(source, SourceRequest):
+		var v string
+		switch strings.TrimPrefix(source, SourceRequest) {
+		case "method":
+			v = ctx.Method
+		case "path":
+			v, _, _ = strings.Cut(ctx.Path, "?")
+		}
+		return v, v != ""
+	default:
+		return source, source != ""
+	}
+}
+
+// serveJWKS returns the public signing keys as a JWK Set
+func serveJWKS(jwks []interface{}) policy.RequestAction {
+	body, _ := json.Marshal(map[string]interface{}{
+		"keys": jwks,
+	})
+	return policy.ImmediateResponse{
+		StatusCode: 200,
+		Headers: map[string]string{
+			"content-type":  "application/json",
+			"cache-control": fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())),
+		},
+		Body: body,
+	}
+}
+
+func internalError(message string) policy.RequestAction {
+	body, _ := json.Marshal(map[string]string{
+		"error":   "Internal Server Error",
+		"message": message,
+	})
+	return policy.ImmediateResponse{
+		StatusCode: 500,
+		Headers: map[string]string{
+			"content-type": "application/json",
+		},
+		Body: body,
+	}
+}
+
+// newTokenID returns a random jti
+func newTokenID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Helper functions for type assertions
+func getBoolParam(params map[string]interface{}, key string, defaultValue bool) bool {
+	if v, ok := params[key]; ok {
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func getStringParam(params map[string]interface{}, key, defaultValue string) string {
+	if v, ok := params[key]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return defaultValue
+}
+
+func getDurationParam(params map[string]interface{}, key string, defaultValue time.Duration) time.Duration {
+	s := getStringParam(params, key, "")
+	if s == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		slog.Debug("Internal JWT Policy: Failed to parse duration, using default",
+			"param", key,
+			"value", s,
+			"error", err,
+			"default", defaultValue,
+		)
+		return defaultValue
+	}
+	return d
+}
+
+func getStringMapParam(params map[string]interface{}, key string, defaultValue map[string]string) map[string]string {
+	if v, ok := params[key]; ok {
+		if m, ok := v.(map[string]interface{}); ok {
+			result := make(map[string]string)
+			for k, val := range m {
+				if s, ok := val.(string); ok {
+					result[k] = s
+				} else {
+					result[k] = fmt.Sprintf("%v", val)
+				}
+			}
+			if len(result) > 0 {
+				return result
+			}
+		}
+	}
+	return defaultValue
+}
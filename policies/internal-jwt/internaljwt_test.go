@@ -0,0 +1,310 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package internaljwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+// TestInternalJwtPolicy_IssueToken tests minting a token from auth metadata and API context
+func TestInternalJwtPolicy_IssueToken(t *testing.T) {
+	params := map[string]interface{}{
+		"signingKeyPem": createRSAKeyPEM(t),
+		"issuer":        "https://gateway.example.com",
+		"audience":      "orders-service",
+		"claims": map[string]interface{}{
+			"api":    "api.name",
+			"method": "metadata.auth.method",
+			"tenant": "authContext.tenant",
+			"email":  "metadata.auth.claims.email",
+			"exp":    "api.version",
+			"env":    "production",
+		},
+	}
+
+	ctx := createMockRequestContext(map[string][]string{"x-jwt-assertion": {"spoofed"}})
+	ctx.Metadata[MetadataKeyAuthSuccess] = true
+	ctx.Metadata[MetadataKeyAuthMethod] = "api-key"
+	ctx.Metadata[MetadataKeyAuthUser] = "alice"
+	ctx.AuthContext["tenant"] = "acme"
+
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+	mods, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamRequestModifications")
+	}
+
+	claims := verifyToken(t, p, params, mods.SetHeaders["x-jwt-assertion"])
+	expected := map[string]interface{}{
+		"iss":         "https://gateway.example.com",
+		"aud":         "orders-service",
+		"sub":         "alice",
+		"auth_method": "api-key",
+		"api":         "PetStore",
+		"method":      "api-key",
+		"tenant":      "acme",
+		"env":         "production",
+	}
+	for name, want := range expected {
+		if claims[name] != want {
+			t.Errorf("Expected claim %s=%v, got %v", name, want, claims[name])
+		}
+	}
+	if _, ok := claims["email"]; ok {
+		t.Errorf("Expected claim with missing source to be omitted")
+	}
+	if claims["exp"].(float64)-claims["iat"].(float64) != 300 {
+		t.Errorf("Expected reserved exp claim to use the token TTL, got %v", claims["exp"])
+	}
+}
+
+// TestInternalJwtPolicy_Unauthenticated tests that unauthenticated requests get no token
+func TestInternalJwtPolicy_Unauthenticated(t *testing.T) {
+	params := map[string]interface{}{
+		"signingKeyPem": createRSAKeyPEM(t),
+		"headerName":    "X-Internal-Token",
+	}
+
+	ctx := createMockRequestContext(map[string][]string{"x-internal-token": {"spoofed"}})
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+	mods, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamRequestModifications")
+	}
+	if len(mods.SetHeaders) != 0 || len(mods.RemoveHeaders) != 1 || mods.RemoveHeaders[0] != "x-internal-token" {
+		t.Errorf("Expected caller-supplied token header to be removed, got %+v", mods)
+	}
+
+	params["requireAuthenticated"] = false
+	mods = p.OnRequest(ctx, params).(policy.UpstreamRequestModifications)
+	if mods.SetHeaders["x-internal-token"] == "" {
+		t.Errorf("Expected token to be issued when authentication is not required")
+	}
+}
+
+// TestInternalJwtPolicy_ECKeyFile tests signing with an EC key loaded from a file
+func TestInternalJwtPolicy_ECKeyFile(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	keyFile := filepath.Join(t.TempDir(), "gateway.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+
+	params := map[string]interface{}{
+		"signingKeyFile":       keyFile,
+		"keyId":                "gateway-2026",
+		"requireAuthenticated": false,
+	}
+
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+	mods, ok := p.OnRequest(createMockRequestContext(nil), params).(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamRequestModifications")
+	}
+	token := mods.SetHeaders["x-jwt-assertion"]
+	verifyToken(t, p, params, token)
+
+	parsed, _, _ := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if parsed.Header["alg"] != "ES384" || parsed.Header["kid"] != "gateway-2026" {
+		t.Errorf("Unexpected token header %v", parsed.Header)
+	}
+}
+
+// TestInternalJwtPolicy_KeyRotation tests that previous keys stay in the JWKS until their tokens expire
+// and the cached JWKS is refreshed
+func TestInternalJwtPolicy_KeyRotation(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "gateway.pem")
+	writeKey := func(modTime time.Time) {
+		if err := os.WriteFile(keyFile, []byte(createRSAKeyPEM(t)), 0600); err != nil {
+			t.Fatalf("Failed to write key: %v", err)
+		}
+		if err := os.Chtimes(keyFile, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set key modification time: %v", err)
+		}
+	}
+	params := map[string]interface{}{
+		"signingKeyFile":       keyFile,
+		"issuer":               "https://rotation.example.com",
+		"tokenTtl":             "1m",
+		"requireAuthenticated": false,
+	}
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+	issue := func() string {
+		mods, ok := p.OnRequest(createMockRequestContext(nil), params).(policy.UpstreamRequestModifications)
+		if !ok {
+			t.Fatalf("Expected UpstreamRequestModifications")
+		}
+		return mods.SetHeaders["x-jwt-assertion"]
+	}
+	publishedKIDs := func() []string {
+		ctx := createMockRequestContext(nil)
+		ctx.Path = "/.well-known/gateway-jwks.json"
+		response := p.OnRequest(ctx, params).(policy.ImmediateResponse)
+		var jwks struct {
+			Keys []map[string]string `json:"keys"`
+		}
+		_ = json.Unmarshal(response.Body, &jwks)
+		var kids []string
+		for _, k := range jwks.Keys {
+			kids = append(kids, k["kid"])
+		}
+		return kids
+	}
+	kidOf := func(token string) string {
+		parsed, _, _ := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		return parsed.Header["kid"].(string)
+	}
+
+	writeKey(time.Now().Add(-time.Hour))
+	oldToken := issue()
+	oldKID := kidOf(oldToken)
+
+	writeKey(time.Now())
+	newToken := issue()
+	newKID := kidOf(newToken)
+	if newKID == oldKID {
+		t.Fatalf("Expected the rotated key to be used")
+	}
+
+	if kids := publishedKIDs(); len(kids) != 2 || kids[0] != newKID || kids[1] != oldKID {
+		t.Fatalf("Expected the current key followed by the previous key, got %v", kids)
+	}
+	verifyToken(t, p, params, oldToken)
+	verifyToken(t, p, params, newToken)
+
+	// The previous key is dropped once tokenTtl and the JWKS max-age have passed since its last use
+	ring := strings.Join([]string{"", "PetStore", "v1.0", "https://rotation.example.com", "/.well-known/gateway-jwks.json"}, "|")
+	ins.keyMutex.Lock()
+	ins.keyRings[ring].keys[oldKID].lastUsed = time.Now().Add(-(time.Minute + jwksMaxAge + time.Second))
+	ins.keyMutex.Unlock()
+	if kids := publishedKIDs(); len(kids) != 1 || kids[0] != newKID {
+		t.Errorf("Expected only the current key after the retention, got %v", kids)
+	}
+}
+
+// TestParseSigningKey tests key validation
+func TestParseSigningKey(t *testing.T) {
+	if _, err := parseSigningKey([]byte(createRSAKeyPEM(t)), "", "ES256"); err == nil {
+		t.Errorf("Expected ES256 to be rejected for an RSA key")
+	}
+	if _, err := parseSigningKey([]byte("not a key"), "", ""); err == nil {
+		t.Errorf("Expected non-PEM key to be rejected")
+	}
+
+	small, _ := rsa.GenerateKey(rand.Reader, 1024)
+	smallPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(small)})
+	if _, err := parseSigningKey(smallPEM, "", ""); err == nil {
+		t.Errorf("Expected 1024-bit RSA key to be rejected")
+	}
+
+	key, err := parseSigningKey([]byte(createRSAKeyPEM(t)), "", "PS256")
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	if key.method.Alg() != "PS256" || key.keyID == "" {
+		t.Errorf("Unexpected key %v %q", key.method.Alg(), key.keyID)
+	}
+}
+
+// verifyToken fetches the JWKS from the policy and verifies the token against it
+func verifyToken(t *testing.T, p policy.Policy, params map[string]interface{}, token string) jwt.MapClaims {
+	t.Helper()
+	ctx := createMockRequestContext(nil)
+	ctx.Path = "/.well-known/gateway-jwks.json"
+	response, ok := p.OnRequest(ctx, params).(policy.ImmediateResponse)
+	if !ok || response.StatusCode != 200 {
+		t.Fatalf("Expected JWKS response")
+	}
+
+	var jwks struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	if err := json.Unmarshal(response.Body, &jwks); err != nil || len(jwks.Keys) == 0 {
+		t.Fatalf("Invalid JWKS %s: %v", response.Body, err)
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(tok *jwt.Token) (interface{}, error) {
+		var jwk map[string]string
+		for _, k := range jwks.Keys {
+			if tok.Header["kid"] == k["kid"] {
+				jwk = k
+			}
+		}
+		if jwk == nil {
+			return nil, fmt.Errorf("token kid %v is not in the JWKS", tok.Header["kid"])
+		}
+		decode := func(s string) *big.Int {
+			b, _ := base64.RawURLEncoding.DecodeString(s)
+			return new(big.Int).SetBytes(b)
+		}
+		if jwk["kty"] == "RSA" {
+			return &rsa.PublicKey{N: decode(jwk["n"]), E: int(decode(jwk["e"]).Int64())}, nil
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P384(), X: decode(jwk["x"]), Y: decode(jwk["y"])}, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to verify token: %v", err)
+	}
+	return claims
+}
+
+func createRSAKeyPEM(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+func createMockRequestContext(headers map[string][]string) *policy.RequestContext {
+	return &policy.RequestContext{
+		SharedContext: &policy.SharedContext{
+			RequestID:   "test-request-id",
+			Metadata:    make(map[string]interface{}),
+			AuthContext: make(map[string]string),
+			APIName:     "PetStore",
+			APIVersion:  "v1.0",
+			APIContext:  "/petstore",
+		},
+		Headers: policy.NewHeaders(headers),
+		Path:    "/petstore/v1.0/pets",
+		Method:  "GET",
+	}
+}
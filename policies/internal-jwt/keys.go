@@ -0,0 +1,262 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package internaljwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// minRSAKeyBits is the smallest RSA signing key accepted
+	minRSAKeyBits = 2048
+
+	// jwksMaxAge is how long verifiers may cache the JWKS
+	jwksMaxAge = 300 * time.Second
+)
+
+// KeyConfig identifies the gateway signing key
+type KeyConfig struct {
+	File      string // Path to a PEM private key file
+	PEM       string // Inline PEM private key, used when File is empty
+	KeyID     string // kid header value; defaults to the RFC 7638 thumbprint
+	Algorithm string // Signing algorithm; derived from the key type when empty
+}
+
+// signingKey is a loaded private key with its published JWK
+type signingKey struct {
+	privateKey crypto.Signer
+	method     jwt.SigningMethod
+	keyID      string
+	jwk        map[string]interface{}
+	modTime    time.Time // Modification time of the key file, used to reload rotated keys
+}
+
+// getSigningKey returns the cached signing key, reloading it when the key file changed
+func (p *InternalJwtPolicy) getSigningKey(config KeyConfig) (*signingKey, error) {
+	if config.File == "" && config.PEM == "" {
+		return nil, fmt.Errorf("either signingKeyFile or signingKeyPem must be configured")
+	}
+
+	cacheKey := config.File + "|" + config.PEM + "|" + config.KeyID + "|" + config.Algorithm
+	var modTime time.Time
+	if config.File != "" {
+		info, err := os.Stat(config.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat signing key file: %w", err)
+		}
+		modTime = info.ModTime()
+	}
+
+	p.keyMutex.Lock()
+	defer p.keyMutex.Unlock()
+	if key, ok := p.keys[cacheKey]; ok && key.modTime.Equal(modTime) {
+		return key, nil
+	}
+
+	data := []byte(config.PEM)
+	if config.File != "" {
+		var err error
+		data, err = os.ReadFile(config.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key file: %w", err)
+		}
+	}
+
+	key, err := parseSigningKey(data, config.KeyID, config.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	key.modTime = modTime
+	p.keys[cacheKey] = key
+
+	slog.Debug("Internal JWT Policy: Loaded signing key",
+		"kid", key.keyID,
+		"alg", key.method.Alg(),
+	)
+	return key, nil
+}
+
+// keyRing holds the keys that recently signed tokens of an API, by key ID
+type keyRing struct {
+	keys map[string]*ringKey
+}
+
+// ringKey is a published JWK with the time its key was last used
+type ringKey struct {
+	jwk      map[string]interface{}
+	lastUsed time.Time
+}
+
+// useKey records that key is the current signing key of the ring and drops the keys that have not
+// been used for longer than retention
+func (p *InternalJwtPolicy) useKey(ring string, key *signingKey, retention time.Duration) {
+	now := time.Now()
+
+	p.keyMutex.Lock()
+	defer p.keyMutex.Unlock()
+	r, ok := p.keyRings[ring]
+	if !ok {
+		r = &keyRing{keys: make(map[string]*ringKey)}
+		p.keyRings[ring] = r
+	}
+	if _, ok := r.keys[key.keyID]; !ok && len(r.keys) > 0 {
+		slog.Debug("Internal JWT Policy: Signing key rotated, publishing previous keys until their tokens expire",
+			"kid", key.keyID,
+			"retention", retention,
+		)
+	}
+	r.keys[key.keyID] = &ringKey{jwk: key.jwk, lastUsed: now}
+	for kid, k := range r.keys {
+		if now.Sub(k.lastUsed) > retention {
+			delete(r.keys, kid)
+		}
+	}
+}
+
+// publishedKeys returns the JWKs of the ring: the current key first, followed by the previous keys
+// used within retention, ordered by key ID
+func (p *InternalJwtPolicy) publishedKeys(ring string, current *signingKey, retention time.Duration) []interface{} {
+	now := time.Now()
+	jwks := []interface{}{current.jwk}
+
+	p.keyMutex.Lock()
+	defer p.keyMutex.Unlock()
+	r, ok := p.keyRings[ring]
+	if !ok {
+		return jwks
+	}
+	kids := make([]string, 0, len(r.keys))
+	for kid, k := range r.keys {
+		if kid != current.keyID && now.Sub(k.lastUsed) <= retention {
+			kids = append(kids, kid)
+		}
+	}
+	sort.Strings(kids)
+	for _, kid := range kids {
+		jwks = append(jwks, r.keys[kid].jwk)
+	}
+	return jwks
+}
+
+// parseSigningKey parses a PKCS#1, PKCS#8 or SEC 1 PEM private key and derives its algorithm and JWK
+func parseSigningKey(data []byte, keyID, algorithm string) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key is not PEM encoded")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key: %w", err)
+	}
+
+	key := &signingKey{}
+	var thumbprintMembers map[string]string
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA signing key must be at least %d bits", minRSAKeyBits)
+		}
+		if algorithm == "" {
+			algorithm = "RS256"
+		}
+		switch algorithm {
+		case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		default:
+			return nil, fmt.Errorf("algorithm %s cannot be used with an RSA key", algorithm)
+		}
+		key.privateKey = k
+		thumbprintMembers = map[string]string{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+	case *ecdsa.PrivateKey:
+		var crv, curveAlg string
+		switch k.Curve {
+		case elliptic.P256():
+			crv, curveAlg = "P-256", "ES256"
+		case elliptic.P384():
+			crv, curveAlg = "P-384", "ES384"
+		case elliptic.P521():
+			crv, curveAlg = "P-521", "ES512"
+		default:
+			return nil, fmt.Errorf("unsupported EC curve")
+		}
+		if algorithm == "" {
+			algorithm = curveAlg
+		}
+		if algorithm != curveAlg {
+			return nil, fmt.Errorf("algorithm %s cannot be used with a %s key", algorithm, crv)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		key.privateKey = k
+		thumbprintMembers = map[string]string{
+			"kty": "EC",
+			"crv": crv,
+			"x":   base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, size))),
+			"y":   base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, size))),
+		}
+	default:
+		return nil, fmt.Errorf("signing key must be an RSA or EC private key")
+	}
+	key.method = jwt.GetSigningMethod(algorithm)
+
+	if keyID == "" {
+		// RFC 7638: members in lexicographic order, which json.Marshal guarantees for maps
+		canonical, _ := json.Marshal(thumbprintMembers)
+		sum := sha256.Sum256(canonical)
+		keyID = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	key.keyID = keyID
+
+	key.jwk = map[string]interface{}{
+		"kid": keyID,
+		"alg": algorithm,
+		"use": "sig",
+	}
+	for k, v := range thumbprintMembers {
+		key.jwk[k] = v
+	}
+	return key, nil
+}
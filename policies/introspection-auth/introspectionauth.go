@@ -0,0 +1,727 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package introspectionauth
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+const (
+	// Metadata keys for context storage (shared with jwt-auth so downstream policies work unchanged)
+	MetadataKeyAuthSuccess  = "auth.success"
+	MetadataKeyAuthMethod   = "auth.method"
+	MetadataKeyTokenClaims  = "auth.claims"
+	MetadataKeyIssuer       = "auth.issuer"
+	MetadataKeySubject      = "auth.subject"
+	MetadataValidatedClaims = "auth.validatedClaims"
+
+	// AuthContext key for user ID (used for analytics)
+	AuthContextKeyUserID = "x-wso2-user-id"
+
+	// Default claim to extract user ID from
+	DefaultUserIdClaim = "sub"
+
+	// AuthMethod is the value stored under auth.method by this policy
+	AuthMethod = "introspection"
+
+	// Client authentication methods supported for the introspection endpoint
+	ClientAuthMethodBasic = "client_secret_basic"
+	ClientAuthMethodPost  = "client_secret_post"
+
+	// maxCacheEntries bounds the introspection result cache
+	maxCacheEntries = 10000
+)
+
+// IntrospectionAuthPolicy implements OAuth2 token introspection (RFC 7662)
+type IntrospectionAuthPolicy struct {
+	cacheMutex sync.RWMutex
+	cacheStore map[string]*CachedIntrospection
+}
+
+// CachedIntrospection stores a cached introspection result
+type CachedIntrospection struct {
+	Active    bool          // Value of the "active" member in the introspection response
+	Claims    jwt.MapClaims // Full introspection response
+	ExpiresAt time.Time     // Time after which the entry must be refreshed
+}
+
+// IntrospectionEndpoint holds the authorization server's introspection endpoint configuration
+type IntrospectionEndpoint struct {
+	URI              string        // Introspection endpoint URL
+	ClientID         string        // Client ID used to authenticate to the endpoint
+	ClientSecret     string        // Client secret used to authenticate to the endpoint
+	ClientAuthMethod string        // client_secret_basic or client_secret_post
+	TokenTypeHint    string        // Optional token_type_hint sent with the request
+	Timeout          time.Duration // Timeout for the introspection request
+	client           *http.Client  // Client for the endpoint, with the custom CA or skip verification
+}
+
+// routePolicy is the Introspection Auth policy of a route, with its introspection endpoint parsed once
+type routePolicy struct {
+	*IntrospectionAuthPolicy
+	endpoint    *IntrospectionEndpoint
+	endpointErr error
+}
+
+var ins = &IntrospectionAuthPolicy{
+	cacheStore: make(map[string]*CachedIntrospection),
+}
+
+func GetPolicy(
+	metadata policy.PolicyMetadata,
+	params map[string]interface{},
+) (policy.Policy, error) {
+	slog.Debug("Introspection Auth Policy: GetPolicy called")
+
+	// The endpoint, its TLS config and its HTTP client are shared by every request of the route.
+	// An invalid configuration fails each request, as a direct OnRequest call does.
+	endpoint, err := parseIntrospectionEndpoint(params)
+	if err != nil {
+		slog.Debug("Introspection Auth Policy: Invalid introspection endpoint configuration",
+			"error", err,
+		)
+	}
+	return &routePolicy{
+		IntrospectionAuthPolicy: ins,
+		endpoint:                endpoint,
+		endpointErr:             err,
+	}, nil
+}
+
+// OnRequest validates the access token using the introspection endpoint parsed when the policy was created
+func (p *routePolicy) OnRequest(ctx *policy.RequestContext, params map[string]interface{}) policy.RequestAction {
+	return p.authenticate(ctx, params, p.endpoint, p.endpointErr)
+}
+
+// Mode returns the processing mode for this policy
+func (p *IntrospectionAuthPolicy) Mode() policy.ProcessingMode {
+	return policy.ProcessingMode{
+		RequestHeaderMode:  policy.HeaderModeProcess, // Process request headers for token
+		RequestBodyMode:    policy.BodyModeSkip,      // Don't need request body
+		ResponseHeaderMode: policy.HeaderModeSkip,    // Don't process response headers
+		ResponseBodyMode:   policy.BodyModeSkip,      // Don't need response body
+	}
+}
+
+// OnRequest validates the access token against the introspection endpoint
+func (p *IntrospectionAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]interface{}) policy.RequestAction {
+	endpoint, err := parseIntrospectionEndpoint(params)
+	return p.authenticate(ctx, params, endpoint, err)
+}
+
+// authenticate validates the access token against endpoint, which failed to parse with endpointErr
+func (p *IntrospectionAuthPolicy) authenticate(ctx *policy.RequestContext, params map[string]interface{}, endpoint *IntrospectionEndpoint, endpointErr error) policy.RequestAction {
+	slog.Debug("Introspection Auth Policy: OnRequest started",
+		"path", ctx.Path,
+		"method", ctx.Method,
+	)
+
+	// Get system configuration
+	headerName := getStringParam(params, "headerName", "Authorization")
+	authHeaderScheme := getStringParam(params, "authHeaderScheme", "Bearer")
+	onFailureStatusCode := getIntParam(params, "onFailureStatusCode", 401)
+	errorMessageFormat := getStringParam(params, "errorMessageFormat", "json")
+	errorMessage := getStringParam(params, "errorMessage", "Authentication failed")
+	cacheTTL := getDurationParam(params, "cacheTtl", 5*time.Minute)
+	negativeCacheTTL := getDurationParam(params, "negativeCacheTtl", 30*time.Second)
+
+	if endpointErr != nil {
+		slog.Debug("Introspection Auth Policy: Invalid introspection endpoint configuration",
+			"error", endpointErr,
+		)
+		return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, endpointErr.Error())
+	}
+
+	// Get user configuration
+	userIssuers := getStringArrayParam(params, "issuers", []string{})
+	userAudiences := getStringArrayParam(params, "audiences", []string{})
+	userRequiredScopes := getStringArrayParam(params, "requiredScopes", []string{})
+	userRequiredClaims := getStringMapParam(params, "requiredClaims", map[string]string{})
+	userClaimMappings := getStringMapParam(params, "claimMappings", map[string]string{})
+	userAuthHeaderPrefix := getStringParam(params, "authHeaderPrefix", "")
+	userIdClaim := getStringParam(params, "userIdClaim", DefaultUserIdClaim)
+
+	slog.Debug("Introspection Auth Policy: Configuration loaded",
+		"introspectionEndpoint", endpoint.URI,
+		"clientAuthMethod", endpoint.ClientAuthMethod,
+		"headerName", headerName,
+		"authHeaderScheme", authHeaderScheme,
+		"timeout", endpoint.Timeout,
+		"cacheTtl", cacheTTL,
+		"negativeCacheTtl", negativeCacheTTL,
+		"issuers", userIssuers,
+		"audiences", userAudiences,
+		"requiredScopes", userRequiredScopes,
+	)
+
+	// Use user override if provided
+	if userAuthHeaderPrefix != "" {
+		authHeaderScheme = userAuthHeaderPrefix
+	}
+
+	// Extract token from header
+	authHeaders := ctx.Headers.Get(strings.ToLower(headerName))
+	if len(authHeaders) == 0 {
+		slog.Debug("Introspection Auth Policy: Missing authorization header",
+			"headerName", headerName,
+		)
+		return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, "missing authorization header")
+	}
+
+	token := extractToken(authHeaders[0], authHeaderScheme)
+	if token == "" {
+		slog.Debug("Introspection Auth Policy: Failed to extract token from authorization header",
+			"authHeaderScheme", authHeaderScheme,
+		)
+		return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, "invalid authorization header format")
+	}
+
+	result, err := p.introspectWithCache(endpoint, token, cacheTTL, negativeCacheTTL)
+	if err != nil {
+		slog.Debug("Introspection Auth Policy: Token introspection failed",
+			"error", err,
+		)
+		return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, fmt.Sprintf("token introspection failed: %v", err))
+	}
+
+	if !result.Active {
+		slog.Debug("Introspection Auth Policy: Token is not active")
+		return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, "token is not active")
+	}
+
+	claims := result.Claims
+
+	// An active response may still carry an exp that has passed while the result was cached
+	if exp, ok := claims["exp"].(float64); ok && time.Now().After(time.Unix(int64(exp), 0)) {
+		slog.Debug("Introspection Auth Policy: Token has expired",
+			"exp", int64(exp),
+		)
+		return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, "token expired")
+	}
+
+	// Validate issuer if specified
+	if len(userIssuers) > 0 {
+		tokenIssuer := getString(claims["iss"])
+		if !containsString(userIssuers, tokenIssuer) {
+			slog.Debug("Introspection Auth Policy: Token issuer not accepted",
+				"tokenIssuer", tokenIssuer,
+				"issuers", userIssuers,
+			)
+			return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, fmt.Sprintf("token issuer '%s' is not accepted", tokenIssuer))
+		}
+	}
+
+	// Validate audience if specified
+	if len(userAudiences) > 0 {
+		aud := parseAudience(claims["aud"])
+		found := false
+		for _, userAud := range userAudiences {
+			if containsString(aud, userAud) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			slog.Debug("Introspection Auth Policy: No valid audience found in token",
+				"tokenAudiences", aud,
+			)
+			return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, "no valid audience found in token")
+		}
+	}
+
+	// Validate required scopes if specified
+	if len(userRequiredScopes) > 0 {
+		scopes := parseScopes(claims["scope"], claims["scp"])
+		for _, requiredScope := range userRequiredScopes {
+			if !containsString(scopes, requiredScope) {
+				slog.Debug("Introspection Auth Policy: Required scope not found",
+					"missingScope", requiredScope,
+					"tokenScopes", scopes,
+				)
+				return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, fmt.Sprintf("required scope '%s' not found", requiredScope))
+			}
+		}
+	}
+
+	// Validate required claims if specified
+	for claimName, expectedValue := range userRequiredClaims {
+		if claimValueToString(claims[claimName]) != expectedValue {
+			slog.Debug("Introspection Auth Policy: Required claim validation failed",
+				"claimName", claimName,
+			)
+			return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, fmt.Sprintf("claim '%s' validation failed", claimName))
+		}
+	}
+
+	// Store validated claims in metadata for authz policies to use
+	ctx.Metadata[MetadataValidatedClaims] = claims
+
+	slog.Debug("Introspection Auth Policy: All validations passed, authentication successful")
+
+	return p.handleAuthSuccess(ctx, claims, userClaimMappings, userIdClaim)
+}
+
+// introspectWithCache returns the cached introspection result for the token or calls the endpoint.
+// Active results are cached until the earlier of the token's exp and cacheTTL; inactive results
+// are cached for negativeCacheTTL. A non-positive TTL disables caching for that result type.
+func (p *IntrospectionAuthPolicy) introspectWithCache(endpoint *IntrospectionEndpoint, token string,
+	cacheTTL, negativeCacheTTL time.Duration) (*CachedIntrospection, error) {
+
+	cacheKey := cacheKeyFor(endpoint.URI, token)
+	now := time.Now()
+
+	p.cacheMutex.RLock()
+	cached, ok := p.cacheStore[cacheKey]
+	p.cacheMutex.RUnlock()
+	if ok && now.Before(cached.ExpiresAt) {
+		slog.Debug("Introspection Auth Policy: Introspection cache hit",
+			"active", cached.Active,
+			"cacheExpiry", cached.ExpiresAt,
+		)
+		return cached, nil
+	}
+
+	result, err := p.introspect(endpoint, token)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := negativeCacheTTL
+	if result.Active {
+		ttl = cacheTTL
+	}
+	if ttl <= 0 {
+		return result, nil
+	}
+	result.ExpiresAt = now.Add(ttl)
+	if result.Active {
+		if exp, ok := result.Claims["exp"].(float64); ok {
+			if expTime := time.Unix(int64(exp), 0); expTime.Before(result.ExpiresAt) {
+				result.ExpiresAt = expTime
+			}
+		}
+	}
+
+	p.cacheMutex.Lock()
+	if len(p.cacheStore) >= maxCacheEntries {
+		for key, entry := range p.cacheStore {
+			if !now.Before(entry.ExpiresAt) {
+				delete(p.cacheStore, key)
+			}
+		}
+	}
+	if len(p.cacheStore) < maxCacheEntries {
+		p.cacheStore[cacheKey] = result
+	}
+	p.cacheMutex.Unlock()
+
+	slog.Debug("Introspection Auth Policy: Introspection result cached",
+		"active", result.Active,
+		"cacheExpiry", result.ExpiresAt,
+	)
+	return result, nil
+}
+
+// introspect posts the token to the introspection endpoint and parses the response
+func (p *IntrospectionAuthPolicy) introspect(endpoint *IntrospectionEndpoint, token string) (*CachedIntrospection, error) {
+	form := url.Values{}
+	form.Set("token", token)
+	if endpoint.TokenTypeHint != "" {
+		form.Set("token_type_hint", endpoint.TokenTypeHint)
+	}
+	if endpoint.ClientAuthMethod == ClientAuthMethodPost {
+		form.Set("client_id", endpoint.ClientID)
+		form.Set("client_secret", endpoint.ClientSecret)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint.URI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if endpoint.ClientAuthMethod != ClientAuthMethodPost {
+		// RFC 6749 section 2.3.1: credentials are form-encoded before being used in Basic auth
+		req.SetBasicAuth(url.QueryEscape(endpoint.ClientID), url.QueryEscape(endpoint.ClientSecret))
+	}
+
+	slog.Debug("Introspection Auth Policy: Calling introspection endpoint",
+		"uri", endpoint.URI,
+	)
+	resp, err := endpoint.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call introspection endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection endpoint returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read introspection response: %w", err)
+	}
+
+	var claims jwt.MapClaims
+	if err := json.Unmarshal(body, &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse introspection response: %w", err)
+	}
+
+	active, _ := claims["active"].(bool)
+	slog.Debug("Introspection Auth Policy: Introspection response received",
+		"uri", endpoint.URI,
+		"active", active,
+	)
+	return &CachedIntrospection{
+		Active: active,
+		Claims: claims,
+	}, nil
+}
+
+// parseIntrospectionEndpoint builds the endpoint configuration from system parameters
+func parseIntrospectionEndpoint(params map[string]interface{}) (*IntrospectionEndpoint, error) {
+	uri := getStringParam(params, "introspectionEndpoint", "")
+	if uri == "" {
+		return nil, fmt.Errorf("introspection endpoint not configured")
+	}
+
+	endpoint := &IntrospectionEndpoint{
+		URI:              uri,
+		ClientID:         getStringParam(params, "clientId", ""),
+		ClientSecret:     getStringParam(params, "clientSecret", ""),
+		ClientAuthMethod: getStringParam(params, "clientAuthMethod", ClientAuthMethodBasic),
+		TokenTypeHint:    getStringParam(params, "tokenTypeHint", "access_token"),
+		Timeout:          getDurationParam(params, "timeout", 5*time.Second),
+	}
+
+	switch endpoint.ClientAuthMethod {
+	case ClientAuthMethodBasic, ClientAuthMethodPost:
+	default:
+		return nil, fmt.Errorf("unsupported client authentication method '%s'", endpoint.ClientAuthMethod)
+	}
+
+	var tlsConfig *tls.Config
+	if certPath := getStringParam(params, "certificatePath", ""); certPath != "" {
+		var err error
+		tlsConfig, err = loadTLSConfig(certPath)
+		if err != nil {
+			return nil, err
+		}
+	} else if getBoolParam(params, "skipTlsVerify", false) {
+		tlsConfig = &tls.Config{
+			InsecureSkipVerify: true,
+			MinVersion:         tls.VersionTLS12,
+		}
+	}
+	endpoint.client = &http.Client{Timeout: endpoint.Timeout}
+	if tlsConfig != nil {
+		endpoint.client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
+	return endpoint, nil
+}
+
+// cacheKeyFor derives the cache key from the endpoint and a hash of the token so raw tokens are never stored
+func cacheKeyFor(endpointURI, token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return endpointURI + "#" + hex.EncodeToString(sum[:])
+}
+
+// handleAuthSuccess handles successful authentication
+func (p *IntrospectionAuthPolicy) handleAuthSuccess(ctx *policy.RequestContext, claims jwt.MapClaims, claimMappings map[string]string, userIdClaim string) policy.RequestAction {
+	// Set metadata indicating successful authentication
+	ctx.Metadata[MetadataKeyAuthSuccess] = true
+	ctx.Metadata[MetadataKeyAuthMethod] = AuthMethod
+	ctx.Metadata[MetadataKeyTokenClaims] = claims
+
+	// Set standard metadata
+	if iss, ok := claims["iss"].(string); ok {
+		ctx.Metadata[MetadataKeyIssuer] = iss
+	}
+	if sub, ok := claims["sub"].(string); ok {
+		ctx.Metadata[MetadataKeySubject] = sub
+	}
+
+	// Extract user ID from the specified claim and set it in AuthContext for analytics
+	if userIdClaim != "" {
+		if claimValue, exists := claims[userIdClaim]; exists {
+			if userId := claimValueToString(claimValue); userId != "" {
+				ctx.SharedContext.AuthContext[AuthContextKeyUserID] = userId
+			}
+		}
+	}
+
+	// Apply claim mappings as headers
+	modifications := policy.UpstreamRequestModifications{
+		SetHeaders: make(map[string]string),
+	}
+	for claimName, headerName := range claimMappings {
+		if claimValue, ok := claims[claimName]; ok {
+			modifications.SetHeaders[headerName] = claimValueToString(claimValue)
+		}
+	}
+
+	slog.Debug("Introspection Auth Policy: Authentication successful, returning modifications",
+		"headersToSet", len(modifications.SetHeaders),
+	)
+
+	return modifications
+}
+
+// OnResponse is not used by this policy (authentication is request-only)
+func (p *IntrospectionAuthPolicy) OnResponse(ctx *policy.ResponseContext, params map[string]interface{}) policy.ResponseAction {
+	return nil // No response processing needed
+}
+
+// handleAuthFailure handles authentication failure
+func (p *IntrospectionAuthPolicy) handleAuthFailure(ctx *policy.RequestContext, statusCode int, errorFormat, errorMessage, reason string) policy.RequestAction {
+	slog.Debug("Introspection Auth Policy: handleAuthFailure called",
+		"statusCode", statusCode,
+		"reason", reason,
+	)
+
+	// Set metadata indicating failed authentication
+	ctx.Metadata[MetadataKeyAuthSuccess] = false
+	ctx.Metadata[MetadataKeyAuthMethod] = AuthMethod
+
+	headers := map[string]string{
+		"content-type": "application/json",
+	}
+
+	var body string
+	switch errorFormat {
+	case "plain":
+		body = errorMessage
+		headers["content-type"] = "text/plain"
+	default: // json
+		errResponse := map[string]interface{}{
+			"error":   "Unauthorized",
+			"message": errorMessage,
+		}
+		bodyBytes, _ := json.Marshal(errResponse)
+		body = string(bodyBytes)
+	}
+
+	return policy.ImmediateResponse{
+		StatusCode: statusCode,
+		Headers:    headers,
+		Body:       []byte(body),
+	}
+}
+
+// extractToken extracts the access token from authorization header
+func extractToken(authHeader, scheme string) string {
+	authHeader = strings.TrimSpace(authHeader)
+	if scheme != "" {
+		prefix := scheme + " "
+		if strings.HasPrefix(authHeader, prefix) {
+			return strings.TrimPrefix(authHeader, prefix)
+		}
+		// If scheme is specified but not found, return empty
+		return ""
+	}
+	// If no scheme specified, accept raw token or try to strip known schemes
+	if strings.Contains(authHeader, " ") {
+		parts := strings.SplitN(authHeader, " ", 2)
+		return parts[1]
+	}
+	return authHeader
+}
+
+// parseAudience parses audience claim which can be string or array
+func parseAudience(audClaim interface{}) []string {
+	if audStr, ok := audClaim.(string); ok {
+		return []string{audStr}
+	}
+	if audArr, ok := audClaim.([]interface{}); ok {
+		var result []string
+		for _, a := range audArr {
+			if aStr, ok := a.(string); ok {
+				result = append(result, aStr)
+			}
+		}
+		return result
+	}
+	return []string{}
+}
+
+// parseScopes parses scope claim (space-delimited string or array)
+func parseScopes(scopeClaim, scpClaim interface{}) []string {
+	var scopes []string
+	if scopeStr, ok := scopeClaim.(string); ok {
+		scopes = append(scopes, strings.Fields(scopeStr)...)
+	}
+	if scpArr, ok := scpClaim.([]interface{}); ok {
+		for _, s := range scpArr {
+			if sStr, ok := s.(string); ok {
+				scopes = append(scopes, sStr)
+			}
+		}
+	}
+	return scopes
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+// loadTLSConfig loads TLS configuration from a CA certificate file
+func loadTLSConfig(certPath string) (*tls.Config, error) {
+	certData, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate file: %w", err)
+	}
+
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(certData) {
+		return nil, fmt.Errorf("failed to parse PEM certificate from %s", certPath)
+	}
+
+	return &tls.Config{
+		RootCAs:    caCertPool,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+// Helper functions for type assertions
+func getString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return ""
+}
+
+func getBoolParam(params map[string]interface{}, key string, defaultValue bool) bool {
+	if v, ok := params[key]; ok {
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func getStringParam(params map[string]interface{}, key, defaultValue string) string {
+	if v, ok := params[key]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return defaultValue
+}
+
+func getIntParam(params map[string]interface{}, key string, defaultValue int) int {
+	if v, ok := params[key]; ok {
+		if i, ok := v.(int); ok {
+			return i
+		}
+		if f, ok := v.(float64); ok {
+			return int(f)
+		}
+	}
+	return defaultValue
+}
+
+func getDurationParam(params map[string]interface{}, key string, defaultValue time.Duration) time.Duration {
+	s := getStringParam(params, key, "")
+	if s == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		slog.Debug("Introspection Auth Policy: Failed to parse duration, using default",
+			"param", key,
+			"value", s,
+			"error", err,
+			"default", defaultValue,
+		)
+		return defaultValue
+	}
+	return d
+}
+
+func getStringArrayParam(params map[string]interface{}, key string, defaultValue []string) []string {
+	if v, ok := params[key]; ok {
+		if arr, ok := v.([]interface{}); ok {
+			var result []string
+			for _, item := range arr {
+				if s, ok := item.(string); ok {
+					result = append(result, s)
+				}
+			}
+			if len(result) > 0 {
+				return result
+			}
+		}
+	}
+	return defaultValue
+}
+
+func getStringMapParam(params map[string]interface{}, key string, defaultValue map[string]string) map[string]string {
+	if v, ok := params[key]; ok {
+		if m, ok := v.(map[string]interface{}); ok {
+			result := make(map[string]string)
+			for k, val := range m {
+				if s, ok := val.(string); ok {
+					result[k] = s
+				}
+			}
+			if len(result) > 0 {
+				return result
+			}
+		}
+	}
+	return defaultValue
+}
+
+func claimValueToString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return fmt.Sprintf("%v", int64(val))
+	case bool:
+		return fmt.Sprintf("%v", val)
+	default:
+		bytes, _ := json.Marshal(val)
+		return string(bytes)
+	}
+}
@@ -0,0 +1,303 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package introspectionauth
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+// TestIntrospectionAuthPolicy_ActiveToken tests successful authentication with an active token
+func TestIntrospectionAuthPolicy_ActiveToken(t *testing.T) {
+	server, _ := createIntrospectionServer(t, map[string]map[string]interface{}{
+		"opaque-token": {
+			"active": true,
+			"sub":    "user123",
+			"iss":    "https://issuer.example.com",
+			"aud":    "api-audience",
+			"scope":  "read write",
+			"email":  "user@example.com",
+			"exp":    time.Now().Add(time.Hour).Unix(),
+		},
+	})
+	defer server.Close()
+
+	params := createParams(server.URL)
+	params["audiences"] = []interface{}{"api-audience"}
+	params["requiredScopes"] = []interface{}{"read"}
+	params["issuers"] = []interface{}{"https://issuer.example.com"}
+	params["claimMappings"] = map[string]interface{}{
+		"email": "X-User-Email",
+	}
+
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {"Bearer opaque-token"},
+	})
+
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+	action := p.OnRequest(ctx, params)
+
+	modifications, ok := action.(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamRequestModifications, got %T", action)
+	}
+	if modifications.SetHeaders["X-User-Email"] != "user@example.com" {
+		t.Errorf("Expected X-User-Email header to be set, got %v", modifications.SetHeaders)
+	}
+	if ctx.Metadata[MetadataKeyAuthSuccess] != true {
+		t.Errorf("Expected auth.success to be true, got %v", ctx.Metadata[MetadataKeyAuthSuccess])
+	}
+	if ctx.Metadata[MetadataKeyAuthMethod] != AuthMethod {
+		t.Errorf("Expected auth.method to be '%s', got %v", AuthMethod, ctx.Metadata[MetadataKeyAuthMethod])
+	}
+	if ctx.Metadata[MetadataKeySubject] != "user123" {
+		t.Errorf("Expected auth.subject to be 'user123', got %v", ctx.Metadata[MetadataKeySubject])
+	}
+	if _, ok := ctx.Metadata[MetadataValidatedClaims].(jwt.MapClaims); !ok {
+		t.Errorf("Expected auth.validatedClaims to be jwt.MapClaims, got %T", ctx.Metadata[MetadataValidatedClaims])
+	}
+	if ctx.AuthContext[AuthContextKeyUserID] != "user123" {
+		t.Errorf("Expected user ID 'user123' in AuthContext, got %q", ctx.AuthContext[AuthContextKeyUserID])
+	}
+}
+
+// TestIntrospectionAuthPolicy_InactiveToken tests rejection of inactive tokens
+func TestIntrospectionAuthPolicy_InactiveToken(t *testing.T) {
+	server, _ := createIntrospectionServer(t, map[string]map[string]interface{}{})
+	defer server.Close()
+
+	params := createParams(server.URL)
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {"Bearer revoked-token"},
+	})
+
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+	action := p.OnRequest(ctx, params)
+
+	assertUnauthorized(t, ctx, action)
+}
+
+// TestIntrospectionAuthPolicy_MissingScope tests rejection when a required scope is missing
+func TestIntrospectionAuthPolicy_MissingScope(t *testing.T) {
+	server, _ := createIntrospectionServer(t, map[string]map[string]interface{}{
+		"opaque-token": {"active": true, "sub": "user123", "scope": "read"},
+	})
+	defer server.Close()
+
+	params := createParams(server.URL)
+	params["requiredScopes"] = []interface{}{"write"}
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {"Bearer opaque-token"},
+	})
+
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+	action := p.OnRequest(ctx, params)
+
+	assertUnauthorized(t, ctx, action)
+}
+
+// TestIntrospectionAuthPolicy_InvalidAudience tests rejection when no audience matches
+func TestIntrospectionAuthPolicy_InvalidAudience(t *testing.T) {
+	server, _ := createIntrospectionServer(t, map[string]map[string]interface{}{
+		"opaque-token": {"active": true, "sub": "user123", "aud": []interface{}{"other-api"}},
+	})
+	defer server.Close()
+
+	params := createParams(server.URL)
+	params["audiences"] = []interface{}{"api-audience"}
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {"Bearer opaque-token"},
+	})
+
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+	action := p.OnRequest(ctx, params)
+
+	assertUnauthorized(t, ctx, action)
+}
+
+// TestIntrospectionAuthPolicy_CachesResults tests that positive and negative results are cached
+func TestIntrospectionAuthPolicy_CachesResults(t *testing.T) {
+	server, calls := createIntrospectionServer(t, map[string]map[string]interface{}{
+		"opaque-token": {"active": true, "sub": "user123"},
+	})
+	defer server.Close()
+
+	params := createParams(server.URL)
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+
+	for i := 0; i < 3; i++ {
+		ctx := createMockRequestContext(map[string][]string{
+			"authorization": {"Bearer opaque-token"},
+		})
+		if _, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications); !ok {
+			t.Fatalf("Expected request %d to succeed", i)
+		}
+		ctx = createMockRequestContext(map[string][]string{
+			"authorization": {"Bearer unknown-token"},
+		})
+		if _, ok := p.OnRequest(ctx, params).(policy.ImmediateResponse); !ok {
+			t.Fatalf("Expected request %d with unknown token to fail", i)
+		}
+	}
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected 2 introspection calls, got %d", got)
+	}
+}
+
+// TestIntrospectionAuthPolicy_ClientSecretPost tests sending client credentials in the form body
+func TestIntrospectionAuthPolicy_ClientSecretPost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Failed to parse form: %v", err)
+		}
+		active := r.PostForm.Get("client_id") == "gateway" && r.PostForm.Get("client_secret") == "secret"
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"active": active, "sub": "user123"})
+	}))
+	defer server.Close()
+
+	params := createParams(server.URL)
+	params["clientAuthMethod"] = ClientAuthMethodPost
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {"Bearer post-token"},
+	})
+
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+	if _, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected authentication with client_secret_post to succeed")
+	}
+}
+
+// TestIntrospectionAuthPolicy_MissingToken tests rejection when no token is sent
+func TestIntrospectionAuthPolicy_MissingToken(t *testing.T) {
+	params := createParams("http://127.0.0.1:0/introspect")
+	ctx := createMockRequestContext(map[string][]string{})
+
+	p, _ := GetPolicy(policy.PolicyMetadata{}, params)
+	action := p.OnRequest(ctx, params)
+
+	assertUnauthorized(t, ctx, action)
+}
+
+// TestIntrospectionAuthPolicy_LoadsCertificateOnce tests that the CA certificate and HTTP client
+// of a route are created once, not for every introspection request
+func TestIntrospectionAuthPolicy_LoadsCertificateOnce(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"active": true, "sub": "user123"})
+	}))
+	defer server.Close()
+
+	certPath := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(certPath, certPEM, 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	params := createParams(server.URL)
+	params["certificatePath"] = certPath
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	// Requests keep using the loaded certificate after the file is removed
+	if err := os.Remove(certPath); err != nil {
+		t.Fatalf("Failed to remove certificate: %v", err)
+	}
+	for _, token := range []string{"first-token", "second-token"} {
+		ctx := createMockRequestContext(map[string][]string{"authorization": {"Bearer " + token}})
+		if _, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications); !ok {
+			t.Fatalf("Expected %s to be accepted", token)
+		}
+	}
+}
+
+// createIntrospectionServer creates an introspection endpoint that requires client_secret_basic
+// credentials and returns the configured response for known tokens and inactive for others
+func createIntrospectionServer(t *testing.T, responses map[string]map[string]interface{}) (*httptest.Server, *atomic.Int32) {
+	calls := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != "gateway" || clientSecret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("Failed to parse form: %v", err)
+		}
+		response, ok := responses[r.PostForm.Get("token")]
+		if !ok {
+			response = map[string]interface{}{"active": false}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			t.Logf("Failed to encode introspection response: %v", err)
+		}
+	}))
+	return server, calls
+}
+
+func createParams(endpoint string) map[string]interface{} {
+	return map[string]interface{}{
+		"introspectionEndpoint": endpoint,
+		"clientId":              "gateway",
+		"clientSecret":          "secret",
+		"timeout":               "5s",
+		"cacheTtl":              "5m",
+		"negativeCacheTtl":      "30s",
+	}
+}
+
+func createMockRequestContext(headers map[string][]string) *policy.RequestContext {
+	return &policy.RequestContext{
+		SharedContext: &policy.SharedContext{
+			RequestID:   "test-request-id",
+			Metadata:    make(map[string]interface{}),
+			AuthContext: make(map[string]string),
+		},
+		Headers: policy.NewHeaders(headers),
+		Path:    "/api/test",
+		Method:  "GET",
+	}
+}
+
+func assertUnauthorized(t *testing.T, ctx *policy.RequestContext, action policy.RequestAction) {
+	t.Helper()
+	response, ok := action.(policy.ImmediateResponse)
+	if !ok {
+		t.Fatalf("Expected ImmediateResponse, got %T", action)
+	}
+	if response.StatusCode != 401 {
+		t.Errorf("Expected status code 401, got %d", response.StatusCode)
+	}
+	if ctx.Metadata[MetadataKeyAuthSuccess] != false {
+		t.Errorf("Expected auth.success to be false, got %v", ctx.Metadata[MetadataKeyAuthSuccess])
+	}
+}
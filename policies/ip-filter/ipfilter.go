@@ -0,0 +1,200 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package ipfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/advanced-ratelimit/clientip"
+)
+
+const (
+	// Evaluation orders for the order parameter
+	OrderDenyFirst  = "deny-first"  // A deny match rejects the request even when the address is allowed
+	OrderAllowFirst = "allow-first" // An allow match accepts the request even when the address is denied
+
+	// Actions for the defaultAction parameter
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+
+	// MetadataKeyClientIP is the resolved client address
+	MetadataKeyClientIP = "ipfilter.clientIp"
+)
+
+// IPFilterPolicy accepts or rejects requests by client address
+type IPFilterPolicy struct {
+	allow         *ipList
+	deny          *ipList
+	order         string
+	defaultAction string
+	clientIP      *clientip.Resolver
+}
+
+// GetPolicy creates an IP filter for a route. The lists are parsed and their prefix trees built
+// once here; list files are reloaded when they change.
+func GetPolicy(
+	metadata policy.PolicyMetadata,
+	params map[string]interface{},
+) (policy.Policy, error) {
+	slog.Debug("IP Filter Policy: Creating policy",
+		"route", metadata.RouteName,
+		"apiName", metadata.APIName,
+	)
+
+	allow, err := newIPList(getStringArrayParam(params, "allow"), getStringParam(params, "allowFile", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid allow list: %w", err)
+	}
+	deny, err := newIPList(getStringArrayParam(params, "deny"), getStringParam(params, "denyFile", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid deny list: %w", err)
+	}
+
+	order := getStringParam(params, "order", OrderDenyFirst)
+	if order != OrderDenyFirst && order != OrderAllowFirst {
+		return nil, fmt.Errorf("unsupported order %q", order)
+	}
+
+	// Configuring an allow list means "only these addresses" unless a default is given
+	defaultAction := ActionAllow
+	if allow.configured() {
+		defaultAction = ActionDeny
+	}
+	defaultAction = getStringParam(params, "defaultAction", defaultAction)
+	if defaultAction != ActionAllow && defaultAction != ActionDeny {
+		return nil, fmt.Errorf("unsupported defaultAction %q", defaultAction)
+	}
+
+	resolver, err := clientip.ParseTrustedProxies(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trustedProxies: %w", err)
+	}
+
+	slog.Debug("IP Filter Policy: Policy created",
+		"route", metadata.RouteName,
+		"allowRanges", allow.len(),
+		"denyRanges", deny.len(),
+		"order", order,
+		"defaultAction", defaultAction,
+	)
+	return &IPFilterPolicy{
+		allow:         allow,
+		deny:          deny,
+		order:         order,
+		defaultAction: defaultAction,
+		clientIP:      resolver,
+	}, nil
+}
+
+// Mode returns the processing mode for this policy
+func (p *IPFilterPolicy) Mode() policy.ProcessingMode {
+	return policy.ProcessingMode{
+		RequestHeaderMode:  policy.HeaderModeProcess, // Process request headers for the client address
+		RequestBodyMode:    policy.BodyModeSkip,      // Don't need request body
+		ResponseHeaderMode: policy.HeaderModeSkip,    // Don't process response headers
+		ResponseBodyMode:   policy.BodyModeSkip,      // Don't need response body
+	}
+}
+
+// OnRequest resolves the client address and checks it against the allow and deny lists
+func (p *IPFilterPolicy) OnRequest(ctx *policy.RequestContext, params map[string]interface{}) policy.RequestAction {
+	addr, ok := p.clientIP.ClientAddr(ctx.Headers)
+	if !ok {
+		slog.Debug("IP Filter Policy: Could not resolve client address, applying default action",
+			"defaultAction", p.defaultAction,
+			"path", ctx.Path,
+		)
+		if p.defaultAction == ActionDeny {
+			return p.forbidden(ctx, "")
+		}
+		return policy.UpstreamRequestModifications{}
+	}
+	ctx.Metadata[MetadataKeyClientIP] = addr.String()
+
+	first, second := p.deny, p.allow
+	firstAction, secondAction := ActionDeny, ActionAllow
+	if p.order == OrderAllowFirst {
+		first, second = p.allow, p.deny
+		firstAction, secondAction = ActionAllow, ActionDeny
+	}
+
+	action := p.defaultAction
+	if prefix, ok := first.lookup(addr); ok {
+		action = firstAction
+		slog.Debug("IP Filter Policy: Client address matched", "clientIp", addr, "list", firstAction, "range", prefix)
+	} else if prefix, ok := second.lookup(addr); ok {
+		action = secondAction
+		slog.Debug("IP Filter Policy: Client address matched", "clientIp", addr, "list", secondAction, "range", prefix)
+	}
+
+	if action == ActionDeny {
+		return p.forbidden(ctx, addr.String())
+	}
+	return policy.UpstreamRequestModifications{}
+}
+
+// OnResponse is not used by this policy
+func (p *IPFilterPolicy) OnResponse(ctx *policy.ResponseContext, params map[string]interface{}) policy.ResponseAction {
+	return nil
+}
+
+func (p *IPFilterPolicy) forbidden(ctx *policy.RequestContext, clientIP string) policy.RequestAction {
+	slog.Debug("IP Filter Policy: Request rejected",
+		"clientIp", clientIP,
+		"apiName", ctx.APIName,
+		"apiVersion", ctx.APIVersion,
+		"method", ctx.Method,
+		"path", ctx.Path,
+	)
+	body, _ := json.Marshal(map[string]string{
+		"error":   "Forbidden",
+		"message": "Access denied for the client address",
+	})
+	return policy.ImmediateResponse{
+		StatusCode: 403,
+		Headers: map[string]string{
+			"content-type": "application/json",
+		},
+		Body: body,
+	}
+}
+
+// Helper functions for type assertions
+func getStringParam(params map[string]interface{}, key, defaultValue string) string {
+	if v, ok := params[key].(string); ok && v != "" {
+		return v
+	}
+	return defaultValue
+}
+
+func getStringArrayParam(params map[string]interface{}, key string) []string {
+	raw, ok := params[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
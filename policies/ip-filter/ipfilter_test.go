@@ -0,0 +1,199 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package ipfilter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+func newRequestContext(xff string) *policy.RequestContext {
+	headers := map[string][]string{}
+	if xff != "" {
+		headers["x-forwarded-for"] = []string{xff}
+	}
+	return &policy.RequestContext{
+		SharedContext: &policy.SharedContext{
+			RequestID: "req-1",
+			Metadata:  map[string]interface{}{},
+		},
+		Headers: policy.NewHeaders(headers),
+		Method:  "GET",
+		Path:    "/orders",
+	}
+}
+
+func newPolicy(t *testing.T, params map[string]interface{}) policy.Policy {
+	t.Helper()
+	p, err := GetPolicy(policy.PolicyMetadata{RouteName: "orders"}, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return p
+}
+
+func isAllowed(t *testing.T, p policy.Policy, xff string) bool {
+	t.Helper()
+	switch action := p.OnRequest(newRequestContext(xff), nil).(type) {
+	case policy.UpstreamRequestModifications:
+		return true
+	case policy.ImmediateResponse:
+		if action.StatusCode != 403 {
+			t.Fatalf("expected status 403, got %d", action.StatusCode)
+		}
+		return false
+	default:
+		t.Fatalf("unexpected action %#v", action)
+		return false
+	}
+}
+
+func TestIPFilterPolicy_Order(t *testing.T) {
+	lists := map[string]interface{}{
+		"allow": []interface{}{"10.0.0.0/8", "2001:db8::/32"},
+		"deny":  []interface{}{"10.0.5.0/24"},
+	}
+	withOrder := func(order string) map[string]interface{} {
+		params := map[string]interface{}{"order": order}
+		for k, v := range lists {
+			params[k] = v
+		}
+		return params
+	}
+
+	tests := []struct {
+		name      string
+		order     string
+		clientIP  string
+		wantAllow bool
+	}{
+		{name: "deny-first allowed range", order: OrderDenyFirst, clientIP: "10.1.2.3", wantAllow: true},
+		{name: "deny-first denied inside allowed range", order: OrderDenyFirst, clientIP: "10.0.5.9", wantAllow: false},
+		{name: "deny-first outside allow list", order: OrderDenyFirst, clientIP: "192.0.2.1", wantAllow: false},
+		{name: "deny-first ipv6", order: OrderDenyFirst, clientIP: "2001:db8::42", wantAllow: true},
+		{name: "allow-first allow wins", order: OrderAllowFirst, clientIP: "10.0.5.9", wantAllow: true},
+		{name: "allow-first outside lists", order: OrderAllowFirst, clientIP: "192.0.2.1", wantAllow: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPolicy(t, withOrder(tt.order))
+			if got := isAllowed(t, p, tt.clientIP); got != tt.wantAllow {
+				t.Fatalf("expected allowed=%v, got %v", tt.wantAllow, got)
+			}
+		})
+	}
+}
+
+func TestIPFilterPolicy_DenyListOnly(t *testing.T) {
+	p := newPolicy(t, map[string]interface{}{"deny": []interface{}{"198.51.100.7"}})
+	if isAllowed(t, p, "198.51.100.7") {
+		t.Fatal("expected denied address to be rejected")
+	}
+	if !isAllowed(t, p, "198.51.100.8") {
+		t.Fatal("expected other addresses to be allowed by default")
+	}
+	if !isAllowed(t, p, "") {
+		t.Fatal("expected unresolved address to follow the allow default")
+	}
+}
+
+func TestIPFilterPolicy_TrustedProxies(t *testing.T) {
+	p := newPolicy(t, map[string]interface{}{
+		"allow":          []interface{}{"203.0.113.0/24"},
+		"trustedProxies": []interface{}{"10.0.0.0/8"},
+	})
+	if !isAllowed(t, p, "203.0.113.5, 10.0.0.1") {
+		t.Fatal("expected client behind trusted proxy to be allowed")
+	}
+	// A client cannot claim an allowed address by prepending it to X-Forwarded-For
+	if isAllowed(t, p, "203.0.113.5, 192.0.2.66, 10.0.0.1") {
+		t.Fatal("expected spoofed X-Forwarded-For entry to be ignored")
+	}
+	if isAllowed(t, p, "") {
+		t.Fatal("expected unresolved address to be rejected when an allow list is configured")
+	}
+}
+
+func TestIPFilterPolicy_NoTrustedProxies(t *testing.T) {
+	p := newPolicy(t, map[string]interface{}{"allow": []interface{}{"203.0.113.0/24"}})
+	if !isAllowed(t, p, "203.0.113.5") {
+		t.Fatal("expected allowed peer address to be allowed")
+	}
+	// Without trusted proxies only the last hop, appended by the gateway, identifies the client
+	if isAllowed(t, p, "203.0.113.5, 192.0.2.66") {
+		t.Fatal("expected allowed address sent by the client to be ignored")
+	}
+}
+
+func TestIPFilterPolicy_ListFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deny.txt")
+	if err := os.WriteFile(path, []byte("# blocked ranges\n192.0.2.0/24\n\n198.51.100.1 # single address\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p := newPolicy(t, map[string]interface{}{"denyFile": path})
+	if isAllowed(t, p, "192.0.2.77") || isAllowed(t, p, "198.51.100.1") {
+		t.Fatal("expected addresses from the list file to be rejected")
+	}
+
+	if err := os.WriteFile(path, []byte("203.0.113.0/24\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	deny := p.(*IPFilterPolicy).deny
+	deny.mutex.Lock()
+	deny.lastCheck = time.Time{}
+	deny.modTime = time.Time{}
+	deny.mutex.Unlock()
+
+	if !isAllowed(t, p, "192.0.2.77") {
+		t.Fatal("expected removed range to be allowed after reload")
+	}
+	if isAllowed(t, p, "203.0.113.9") {
+		t.Fatal("expected added range to be rejected after reload")
+	}
+
+	// An invalid file keeps the previous ranges
+	if err := os.WriteFile(path, []byte("not-an-ip\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	deny.mutex.Lock()
+	deny.lastCheck = time.Time{}
+	deny.modTime = time.Time{}
+	deny.mutex.Unlock()
+	if isAllowed(t, p, "203.0.113.9") {
+		t.Fatal("expected previous ranges to be kept when the file is invalid")
+	}
+}
+
+func TestGetPolicy_InvalidConfig(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"allow": []interface{}{"10.0.0.0/40"}},
+		{"denyFile": "/nonexistent/deny.txt"},
+		{"order": "random"},
+		{"defaultAction": "maybe"},
+		{"trustedProxies": []interface{}{"proxy.local"}},
+	}
+	for _, params := range tests {
+		if _, err := GetPolicy(policy.PolicyMetadata{}, params); err == nil {
+			t.Fatalf("expected error for %v", params)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package ipfilter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wso2/gateway-controllers/policies/advanced-ratelimit/clientip"
+)
+
+// listFileCheckInterval is the minimum time between checks of a list file for changes
+const listFileCheckInterval = 5 * time.Second
+
+// ipList is an allow or deny list made of inline ranges and an optional list file
+type ipList struct {
+	inline []netip.Prefix
+	path   string
+	tree   atomic.Pointer[clientip.PrefixTree]
+
+	mutex     sync.Mutex
+	modTime   time.Time
+	size      int64
+	lastCheck time.Time
+}
+
+func newIPList(entries []string, path string) (*ipList, error) {
+	inline, err := clientip.ParsePrefixes(entries)
+	if err != nil {
+		return nil, err
+	}
+	l := &ipList{inline: inline, path: path}
+	if path == "" {
+		l.tree.Store(clientip.NewPrefixTree(inline))
+		return l, nil
+	}
+	if err := l.reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// configured reports whether the list has any ranges or a list file
+func (l *ipList) configured() bool {
+	return len(l.inline) > 0 || l.path != ""
+}
+
+func (l *ipList) len() int {
+	return l.tree.Load().Len()
+}
+
+// lookup returns the longest range of the list containing addr
+func (l *ipList) lookup(addr netip.Addr) (netip.Prefix, bool) {
+	if l.path != "" {
+		l.refresh()
+	}
+	return l.tree.Load().Lookup(addr)
+}
+
+// refresh reloads the list file when it changed. A file that fails to load keeps the previous ranges.
+func (l *ipList) refresh() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if time.Since(l.lastCheck) < listFileCheckInterval {
+		return
+	}
+	l.lastCheck = time.Now()
+
+	info, err := os.Stat(l.path)
+	if err != nil {
+		slog.Warn("IP Filter Policy: Failed to stat list file, keeping previous ranges",
+			"path", l.path,
+			"error", err,
+		)
+		return
+	}
+	if info.ModTime().Equal(l.modTime) && info.Size() == l.size {
+		return
+	}
+	if err := l.load(info); err != nil {
+		slog.Warn("IP Filter Policy: Failed to reload list file, keeping previous ranges",
+			"path", l.path,
+			"error", err,
+		)
+	}
+}
+
+// reload loads the list file unconditionally
+func (l *ipList) reload() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.lastCheck = time.Now()
+
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat list file: %w", err)
+	}
+	return l.load(info)
+}
+
+func (l *ipList) load(info os.FileInfo) error {
+	fileRanges, err := readListFile(l.path)
+	if err != nil {
+		return err
+	}
+	prefixes := make([]netip.Prefix, 0, len(l.inline)+len(fileRanges))
+	prefixes = append(prefixes, l.inline...)
+	prefixes = append(prefixes, fileRanges...)
+
+	l.tree.Store(clientip.NewPrefixTree(prefixes))
+	l.modTime = info.ModTime()
+	l.size = info.Size()
+
+	slog.Debug("IP Filter Policy: Loaded list file",
+		"path", l.path,
+		"ranges", len(fileRanges),
+	)
+	return nil
+}
+
+// readListFile reads one CIDR range or address per line. Blank lines and text after '#' are ignored.
+func readListFile(path string) ([]netip.Prefix, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read list file: %w", err)
+	}
+	var prefixes []netip.Prefix
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		prefix, err := clientip.ParsePrefix(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read list file: %w", err)
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,206 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/cel-go/cel"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+const (
+	// Rule composition modes for authorizationRulesMatch
+	RulesMatchAll = "all"
+	RulesMatchAny = "any"
+)
+
+// AuthorizationRule is a named CEL expression that must evaluate to true to grant access
+type AuthorizationRule struct {
+	Name       string
+	Expression string
+}
+
+// CELEvaluator compiles and evaluates authorization rule expressions
+type CELEvaluator struct {
+	mu sync.RWMutex
+
+	// Compiled CEL programs cache
+	// Key: expression string, Value: compiled cel.Program
+	programCache map[string]cel.Program
+
+	// CEL environment for authorization rules (returns bool)
+	authzEnv *cel.Env
+}
+
+// globalCELEvaluator is a singleton CEL evaluator instance
+var (
+	globalCELEvaluator *CELEvaluator
+	celEvaluatorOnce   sync.Once
+	celInitErr         error
+)
+
+// GetCELEvaluator returns the singleton CEL evaluator instance
+func GetCELEvaluator() (*CELEvaluator, error) {
+	celEvaluatorOnce.Do(func() {
+		env, err := createAuthorizationEnv()
+		if err != nil {
+			celInitErr = fmt.Errorf("failed to create authorization CEL environment: %w", err)
+			return
+		}
+		globalCELEvaluator = &CELEvaluator{
+			programCache: make(map[string]cel.Program),
+			authzEnv:     env,
+		}
+	})
+	if celInitErr != nil {
+		return nil, celInitErr
+	}
+	return globalCELEvaluator, nil
+}
+
+// createAuthorizationEnv creates a CEL environment for authorization rules.
+// Claims are exposed as a dynamic map so nested claims can be accessed with field selection,
+// e.g. claims.realm_access.roles.
+func createAuthorizationEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("claims", cel.MapType(cel.StringType, cel.DynType)),
+		// Request context variables
+		cel.Variable("request.Headers", cel.MapType(cel.StringType, cel.ListType(cel.StringType))),
+		cel.Variable("request.Path", cel.StringType),
+		cel.Variable("request.Method", cel.StringType),
+	)
+}
+
+// EvaluateAuthorizationRules evaluates the rules against the validated claims and request.
+// With RulesMatchAny at least one rule must pass, otherwise all rules must pass. A rule that
+// fails to compile or evaluate, or does not return a bool, counts as not passing.
+// It returns whether access is granted and the name of the first failing rule when denied.
+func (e *CELEvaluator) EvaluateAuthorizationRules(rules []AuthorizationRule, match string, claims jwt.MapClaims, ctx *policy.RequestContext) (bool, string) {
+	evalCtx := buildAuthorizationEvalContext(claims, ctx)
+
+	var firstFailed string
+	for _, rule := range rules {
+		passed, err := e.evaluateRule(rule.Expression, evalCtx)
+		if err != nil {
+			slog.Debug("JWT Auth Policy: Authorization rule evaluation failed",
+				"rule", rule.Name,
+				"expression", rule.Expression,
+				"error", err,
+			)
+		}
+		slog.Debug("JWT Auth Policy: Authorization rule evaluated",
+			"rule", rule.Name,
+			"passed", passed,
+		)
+
+		if passed && match == RulesMatchAny {
+			return true, ""
+		}
+		if !passed {
+			if match != RulesMatchAny {
+				return false, rule.Name
+			}
+			if firstFailed == "" {
+				firstFailed = rule.Name
+			}
+		}
+	}
+
+	if match == RulesMatchAny && len(rules) > 0 {
+		return false, firstFailed
+	}
+	return true, ""
+}
+
+// evaluateRule evaluates a single expression, which must return a bool
+func (e *CELEvaluator) evaluateRule(expression string, evalCtx map[string]interface{}) (bool, error) {
+	program, err := e.getOrCompileProgram(expression)
+	if err != nil {
+		return false, err
+	}
+
+	result, _, err := program.Eval(evalCtx)
+	if err != nil {
+		return false, fmt.Errorf("CEL evaluation failed: %w", err)
+	}
+
+	passed, ok := result.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("CEL expression must return bool, got %T", result.Value())
+	}
+	return passed, nil
+}
+
+// buildAuthorizationEvalContext builds the CEL evaluation context for authorization rules
+func buildAuthorizationEvalContext(claims jwt.MapClaims, ctx *policy.RequestContext) map[string]interface{} {
+	// Convert headers to map[string][]string for CEL
+	headers := make(map[string][]string)
+	if ctx.Headers != nil {
+		ctx.Headers.Iterate(func(key string, values []string) {
+			headers[key] = values
+		})
+	}
+
+	return map[string]interface{}{
+		"claims":          map[string]interface{}(claims),
+		"request.Headers": headers,
+		"request.Path":    ctx.Path,
+		"request.Method":  ctx.Method,
+	}
+}
+
+// getOrCompileProgram gets a cached program or compiles a new one
+func (e *CELEvaluator) getOrCompileProgram(expression string) (cel.Program, error) {
+	// Check cache first (read lock)
+	e.mu.RLock()
+	if program, ok := e.programCache[expression]; ok {
+		e.mu.RUnlock()
+		return program, nil
+	}
+	e.mu.RUnlock()
+
+	// Compile (write lock)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Double-check after acquiring write lock
+	if program, ok := e.programCache[expression]; ok {
+		return program, nil
+	}
+
+	ast, issues := e.authzEnv.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("CEL compilation failed: %w", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("CEL expression must return bool, got %s", ast.OutputType())
+	}
+
+	program, err := e.authzEnv.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("CEL program creation failed: %w", err)
+	}
+
+	e.programCache[expression] = program
+	return program, nil
+}
@@ -0,0 +1,163 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+// TestCELEvaluator_AuthorizationRules tests rule evaluation over claims and request attributes
+func TestCELEvaluator_AuthorizationRules(t *testing.T) {
+	evaluator, err := GetCELEvaluator()
+	if err != nil {
+		t.Fatalf("Failed to create CEL evaluator: %v", err)
+	}
+
+	claims := jwt.MapClaims{
+		"sub":            "user123",
+		"groups":         []interface{}{"users", "admins"},
+		"tenant":         "acme",
+		"email_verified": true,
+		"realm_access": map[string]interface{}{
+			"roles": []interface{}{"ops"},
+		},
+	}
+	ctx := createMockRequestContext(map[string][]string{
+		"x-tenant": {"acme"},
+	})
+
+	tests := []struct {
+		name       string
+		expression string
+		want       bool
+	}{
+		{"group membership", `"admins" in claims.groups`, true},
+		{"tenant matches header", `claims.tenant == request.Headers["x-tenant"][0]`, true},
+		{"email verified", `claims.email_verified == true`, true},
+		{"nested claim", `claims.realm_access.roles.exists(r, r == "ops")`, true},
+		{"method and path", `request.Method == "GET" && request.Path.startsWith("/api")`, true},
+		{"missing claim fails closed", `claims.department == "sales"`, false},
+		{"non-bool result fails", `claims.tenant`, false},
+		{"invalid expression fails", `claims.groups.(`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := []AuthorizationRule{{Name: tt.name, Expression: tt.expression}}
+			got, _ := evaluator.EvaluateAuthorizationRules(rules, RulesMatchAll, claims, ctx)
+			if got != tt.want {
+				t.Errorf("Expected %v for %q, got %v", tt.want, tt.expression, got)
+			}
+		})
+	}
+}
+
+// TestCELEvaluator_RulesMatch tests all/any composition of rules
+func TestCELEvaluator_RulesMatch(t *testing.T) {
+	evaluator, err := GetCELEvaluator()
+	if err != nil {
+		t.Fatalf("Failed to create CEL evaluator: %v", err)
+	}
+
+	claims := jwt.MapClaims{"groups": []interface{}{"users"}}
+	ctx := createMockRequestContext(map[string][]string{})
+	rules := []AuthorizationRule{
+		{Name: "admins", Expression: `"admins" in claims.groups`},
+		{Name: "users", Expression: `"users" in claims.groups`},
+	}
+
+	granted, failed := evaluator.EvaluateAuthorizationRules(rules, RulesMatchAll, claims, ctx)
+	if granted || failed != "admins" {
+		t.Errorf("Expected match=all to fail on rule 'admins', got granted=%v failed=%q", granted, failed)
+	}
+
+	granted, _ = evaluator.EvaluateAuthorizationRules(rules, RulesMatchAny, claims, ctx)
+	if !granted {
+		t.Errorf("Expected match=any to grant access")
+	}
+}
+
+// TestJWTAuthPolicy_AuthorizationRules_Forbidden tests that failing rules return 403 insufficient_scope
+func TestJWTAuthPolicy_AuthorizationRules_Forbidden(t *testing.T) {
+	privateKey, publicKey := generateTestKeys(t)
+	jwksServer := createJWKSServer(t, publicKey, "test-kid")
+	defer jwksServer.Close()
+
+	token := createTestToken(t, privateKey, map[string]interface{}{
+		"sub":    "user123",
+		"groups": []interface{}{"users"},
+	})
+
+	params := map[string]interface{}{
+		"allowedAlgorithms": []interface{}{"RS256"},
+		"validateIssuer":    false,
+		"keyManagers": []interface{}{
+			map[string]interface{}{
+				"name": "test-issuer",
+				"jwks": map[string]interface{}{
+					"remote": map[string]interface{}{"uri": jwksServer.URL + "/jwks.json"},
+				},
+			},
+		},
+		"authorizationRules": []interface{}{
+			map[string]interface{}{
+				"name":       "admins-only",
+				"expression": `"admins" in claims.groups`,
+			},
+		},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("GetPolicy failed: %v", err)
+	}
+
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", token)},
+	})
+	action := p.OnRequest(ctx, params)
+
+	response, ok := action.(policy.ImmediateResponse)
+	if !ok {
+		t.Fatalf("Expected ImmediateResponse, got %T", action)
+	}
+	if response.StatusCode != 403 {
+		t.Errorf("Expected status code 403, got %d", response.StatusCode)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(response.Body, &body); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	if body["error"] != "insufficient_scope" {
+		t.Errorf("Expected error 'insufficient_scope', got %v", body["error"])
+	}
+
+	// The same token passes once the rule is satisfied
+	params["authorizationRules"] = []interface{}{`"users" in claims.groups`}
+	ctx = createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", token)},
+	})
+	if _, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected request to be authorized")
+	}
+}
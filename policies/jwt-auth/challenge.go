@@ -0,0 +1,131 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	// WWWAuthenticateHeader is the response header carrying the RFC 6750 Bearer challenge
+	WWWAuthenticateHeader = "www-authenticate"
+
+	// RFC 6750 error codes
+	BearerErrorInvalidRequest    = "invalid_request"
+	BearerErrorInvalidToken      = "invalid_token"
+	BearerErrorInsufficientScope = "insufficient_scope"
+
+	// accessDeniedMessage is the error message of authorization failures other than missing scopes
+	accessDeniedMessage = "The access token does not grant access to this resource"
+
+	// Error response formats
+	ErrorFormatJSON        = "json"
+	ErrorFormatPlain       = "plain"
+	ErrorFormatProblemJSON = "problem+json"
+)
+
+// authFailure classifies why a request was rejected
+type authFailure struct {
+	code        string   // RFC 6750 error code; empty when the request carried no token
+	description string   // error_description returned to the client
+	reason      string   // Detailed reason for debug logs
+	scheme      string   // Challenge scheme; Bearer when empty
+	algs        []string // Accepted DPoP proof algorithms advertised with the DPoP scheme
+}
+
+// authChallenge holds the per-request settings used to build error responses
+type authChallenge struct {
+	statusCode   int
+	errorFormat  string
+	errorMessage string
+	realm        string
+	scopes       []string // Route scopes advertised in the challenge
+}
+
+// bearerChallenge builds the WWW-Authenticate header value for the failure.
+// As required by RFC 6750, no error is included when the request carried no token.
+func bearerChallenge(realm string, scopes []string, failure authFailure) string {
+	scheme := "Bearer"
+	if failure.scheme != "" {
+		scheme = failure.scheme
+	}
+
+	var attrs []string
+	if realm != "" {
+		attrs = append(attrs, "realm="+quoteChallengeParam(realm))
+	}
+	if len(scopes) > 0 {
+		attrs = append(attrs, "scope="+quoteChallengeParam(strings.Join(scopes, " ")))
+	}
+	if failure.code != "" {
+		attrs = append(attrs, "error="+quoteChallengeParam(failure.code))
+		if failure.description != "" {
+			attrs = append(attrs, "error_description="+quoteChallengeParam(failure.description))
+		}
+	}
+	if len(failure.algs) > 0 {
+		attrs = append(attrs, "algs="+quoteChallengeParam(strings.Join(failure.algs, " ")))
+	}
+	if len(attrs) == 0 {
+		return scheme
+	}
+	return scheme + " " + strings.Join(attrs, ", ")
+}
+
+// quoteChallengeParam returns v as a quoted-string, dropping characters RFC 6750 does not allow
+func quoteChallengeParam(v string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range v {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// errorResponseBody renders the error body in the configured format and returns it with its content type
+func errorResponseBody(format string, statusCode int, errorName, message string, failure authFailure) (string, string) {
+	switch format {
+	case ErrorFormatPlain:
+		return message, "text/plain"
+	case ErrorFormatProblemJSON:
+		problem := map[string]interface{}{
+			"type":   "about:blank",
+			"title":  http.StatusText(statusCode),
+			"status": statusCode,
+			"detail": message,
+		}
+		if failure.code != "" {
+			problem["error"] = failure.code
+			problem["error_description"] = failure.description
+		}
+		bodyBytes, _ := json.Marshal(problem)
+		return string(bodyBytes), "application/problem+json"
+	default: // json
+		bodyBytes, _ := json.Marshal(map[string]interface{}{
+			"error":   errorName,
+			"message": message,
+		})
+		return string(bodyBytes), "application/json"
+	}
+}
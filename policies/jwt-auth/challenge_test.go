@@ -0,0 +1,194 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+// TestBearerChallenge tests WWW-Authenticate header formatting
+func TestBearerChallenge(t *testing.T) {
+	tests := []struct {
+		name    string
+		realm   string
+		scopes  []string
+		failure authFailure
+		want    string
+	}{
+		{
+			name: "no token and no realm",
+			want: "Bearer",
+		},
+		{
+			name:  "no token omits error",
+			realm: "api",
+			failure: authFailure{
+				reason: tokenMissingReason,
+			},
+			want: `Bearer realm="api"`,
+		},
+		{
+			name:   "invalid token with scope",
+			realm:  "api",
+			scopes: []string{"read", "write"},
+			failure: authFailure{
+				code:        BearerErrorInvalidToken,
+				description: "The access token expired",
+			},
+			want: `Bearer realm="api", scope="read write", error="invalid_token", error_description="The access token expired"`,
+		},
+		{
+			name: "quotes are dropped from parameters",
+			failure: authFailure{
+				code:        BearerErrorInvalidRequest,
+				description: `bad "header"`,
+			},
+			want: `Bearer error="invalid_request", error_description="bad header"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bearerChallenge(tt.realm, tt.scopes, tt.failure); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestJWTAuthPolicy_WWWAuthenticate tests the challenge returned for classified failures
+func TestJWTAuthPolicy_WWWAuthenticate(t *testing.T) {
+	privateKey, publicKey := generateTestKeys(t)
+	jwksServer := createJWKSServer(t, publicKey, "test-kid")
+	defer jwksServer.Close()
+
+	params := map[string]interface{}{
+		"allowedAlgorithms": []interface{}{"RS256"},
+		"validateIssuer":    false,
+		"realm":             "orders",
+		"keyManagers": []interface{}{
+			map[string]interface{}{
+				"name": "test-issuer",
+				"jwks": map[string]interface{}{
+					"remote": map[string]interface{}{"uri": jwksServer.URL + "/jwks.json"},
+				},
+			},
+		},
+		"requiredScopes": []interface{}{"orders:read"},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("GetPolicy failed: %v", err)
+	}
+
+	expired := createTestTokenWithExpiry(t, privateKey, map[string]interface{}{"sub": "user123"}, time.Now().Add(-time.Hour))
+	noScope := createTestToken(t, privateKey, map[string]interface{}{"sub": "user123", "scope": "orders:write"})
+
+	tests := []struct {
+		name    string
+		headers map[string][]string
+		status  int
+		want    string
+	}{
+		{
+			name:    "missing token",
+			headers: map[string][]string{},
+			status:  401,
+			want:    `Bearer realm="orders", scope="orders:read"`,
+		},
+		{
+			name:    "wrong scheme",
+			headers: map[string][]string{"authorization": {"Basic dXNlcjpwYXNz"}},
+			status:  401,
+			want:    `Bearer realm="orders", scope="orders:read", error="invalid_request", error_description="The access token is malformed"`,
+		},
+		{
+			name:    "expired token",
+			headers: map[string][]string{"authorization": {"Bearer " + expired}},
+			status:  401,
+			want:    `Bearer realm="orders", scope="orders:read", error="invalid_token", error_description="The access token expired"`,
+		},
+		{
+			name:    "missing scope",
+			headers: map[string][]string{"authorization": {"Bearer " + noScope}},
+			status:  403,
+			want:    `Bearer realm="orders", scope="orders:read", error="insufficient_scope", error_description="The access token does not have the required scope"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createMockRequestContext(tt.headers)
+			response, ok := p.OnRequest(ctx, params).(policy.ImmediateResponse)
+			if !ok {
+				t.Fatalf("Expected ImmediateResponse")
+			}
+			if response.StatusCode != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, response.StatusCode)
+			}
+			if got := response.Headers[WWWAuthenticateHeader]; got != tt.want {
+				t.Errorf("Expected WWW-Authenticate %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestJWTAuthPolicy_ProblemJSON tests the application/problem+json error format
+func TestJWTAuthPolicy_ProblemJSON(t *testing.T) {
+	params := map[string]interface{}{
+		"errorMessageFormat": "problem+json",
+		"keyManagers": []interface{}{
+			map[string]interface{}{
+				"name": "test-issuer",
+				"jwks": map[string]interface{}{
+					"remote": map[string]interface{}{"uri": "http://127.0.0.1:1/jwks.json"},
+				},
+			},
+		},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("GetPolicy failed: %v", err)
+	}
+
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", "not-a-jwt")},
+	})
+	response, ok := p.OnRequest(ctx, params).(policy.ImmediateResponse)
+	if !ok {
+		t.Fatalf("Expected ImmediateResponse")
+	}
+	if response.Headers["content-type"] != "application/problem+json" {
+		t.Errorf("Expected problem+json content type, got %q", response.Headers["content-type"])
+	}
+
+	var problem map[string]interface{}
+	if err := json.Unmarshal(response.Body, &problem); err != nil {
+		t.Fatalf("Failed to parse response body: %v", err)
+	}
+	if problem["status"] != float64(401) || problem["title"] != "Unauthorized" || problem["error"] != BearerErrorInvalidToken {
+		t.Errorf("Unexpected problem document: %v", problem)
+	}
+}
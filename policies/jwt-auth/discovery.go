@@ -0,0 +1,235 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	oidcDiscoveryPath          = "/.well-known/openid-configuration"
+	oauthServerMetadataSegment = "/.well-known/oauth-authorization-server"
+)
+
+// OIDCDiscovery holds OpenID Connect discovery configuration for a key manager
+type OIDCDiscovery struct {
+	URL                     string      // OpenID provider configuration URL (.well-known/openid-configuration)
+	UseDiscoveredAlgorithms bool        // Restrict allowedAlgorithms to id_token_signing_alg_values_supported
+	tlsConfig               *tls.Config // TLS config used for discovery and the discovered JWKS endpoint
+}
+
+// CachedDiscovery stores the parts of an OpenID provider configuration used by the policy
+type CachedDiscovery struct {
+	Issuer            string
+	JWKSURI           string
+	SigningAlgorithms []string
+}
+
+// discoveryDocument represents the OpenID provider configuration response
+type discoveryDocument struct {
+	Issuer                           string   `json:"issuer"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// resolveDiscovery derives the key manager's issuer and remote JWKS from its discovery document.
+// A configured issuer must match the discovered one.
+func (p *JwtAuthPolicy) resolveDiscovery(km *KeyManager, refreshInterval time.Duration, fetchTimeout time.Duration, retryCount int, retryInterval time.Duration) error {
+	discovery, err := p.fetchDiscoveryWithRetry(km.Discovery, refreshInterval, fetchTimeout, retryCount, retryInterval)
+	if err != nil {
+		return err
+	}
+
+	if km.Issuer == "" {
+		km.Issuer = discovery.Issuer
+	} else if km.Issuer != discovery.Issuer {
+		return fmt.Errorf("configured issuer %q does not match discovered issuer %q", km.Issuer, discovery.Issuer)
+	}
+
+	km.JWKS = &JWKSConfig{
+		Remote: &RemoteJWKS{
+			URI:       discovery.JWKSURI,
+			tlsConfig: km.Discovery.tlsConfig,
+		},
+	}
+	if km.Discovery.UseDiscoveredAlgorithms {
+		km.SigningAlgorithms = discovery.SigningAlgorithms
+	}
+	return nil
+}
+
+// fetchDiscoveryWithRetry returns the cached discovery document, fetching it with retries when it
+// is not cached yet. A document older than refreshInterval keeps being served while it is
+// refreshed in the background; if the refresh fails, the previously discovered values are kept.
+func (p *JwtAuthPolicy) fetchDiscoveryWithRetry(discovery *OIDCDiscovery, refreshInterval time.Duration, fetchTimeout time.Duration, retryCount int, retryInterval time.Duration) (*CachedDiscovery, error) {
+	// Check cache first
+	p.cacheMutex.RLock()
+	cached, hasCached := p.discoveryStore[discovery.URL]
+	expiry := p.discoveryTTLs[discovery.URL]
+	p.cacheMutex.RUnlock()
+	if hasCached {
+		if time.Now().Before(expiry) {
+			slog.Debug("JWT Auth Policy: OIDC discovery cache hit",
+				"url", discovery.URL,
+				"cacheExpiry", expiry,
+			)
+		} else {
+			slog.Debug("JWT Auth Policy: OIDC discovery cache due for refresh, serving cached document",
+				"url", discovery.URL,
+				"cacheExpiry", expiry,
+			)
+			p.refreshDiscoveryInBackground(discovery, refreshInterval, fetchTimeout, retryCount, retryInterval)
+		}
+		return cached, nil
+	}
+
+	// Not in cache, fetch from server
+	return p.fetchAndCacheDiscovery(discovery, refreshInterval, fetchTimeout, retryCount, retryInterval)
+}
+
+// fetchAndCacheDiscovery fetches the discovery document with retries and caches the result
+func (p *JwtAuthPolicy) fetchAndCacheDiscovery(discovery *OIDCDiscovery, refreshInterval time.Duration, fetchTimeout time.Duration, retryCount int, retryInterval time.Duration) (*CachedDiscovery, error) {
+	var lastErr error
+	for attempt := 0; attempt <= retryCount; attempt++ {
+		slog.Debug("JWT Auth Policy: Fetching OIDC discovery document",
+			"url", discovery.URL,
+			"attempt", attempt+1,
+			"maxAttempts", retryCount+1,
+		)
+		doc, err := p.fetchDiscovery(discovery, fetchTimeout)
+		if err == nil {
+			p.cacheMutex.Lock()
+			p.discoveryStore[discovery.URL] = doc
+			p.discoveryTTLs[discovery.URL] = time.Now().Add(refreshInterval)
+			p.cacheMutex.Unlock()
+			slog.Debug("JWT Auth Policy: OIDC discovery document fetched and cached",
+				"url", discovery.URL,
+				"issuer", doc.Issuer,
+				"jwksUri", doc.JWKSURI,
+			)
+			return doc, nil
+		}
+
+		slog.Debug("JWT Auth Policy: OIDC discovery fetch attempt failed",
+			"url", discovery.URL,
+			"attempt", attempt+1,
+			"error", err,
+		)
+		lastErr = err
+		if attempt < retryCount {
+			time.Sleep(retryInterval)
+		}
+	}
+	return nil, lastErr
+}
+
+// refreshDiscoveryInBackground starts a background refresh for the discovery document unless one
+// is already running. On failure the cached document is left in place.
+func (p *JwtAuthPolicy) refreshDiscoveryInBackground(discovery *OIDCDiscovery, refreshInterval time.Duration, fetchTimeout time.Duration, retryCount int, retryInterval time.Duration) {
+	// Discovery and JWKS refreshes share the in-flight set, keyed apart by prefix
+	refreshKey := "discovery:" + discovery.URL
+	p.cacheMutex.Lock()
+	if p.refreshing[refreshKey] {
+		p.cacheMutex.Unlock()
+		return
+	}
+	p.refreshing[refreshKey] = true
+	p.cacheMutex.Unlock()
+
+	go func() {
+		defer func() {
+			p.cacheMutex.Lock()
+			delete(p.refreshing, refreshKey)
+			p.cacheMutex.Unlock()
+		}()
+		if _, err := p.fetchAndCacheDiscovery(discovery, refreshInterval, fetchTimeout, retryCount, retryInterval); err != nil {
+			slog.Debug("JWT Auth Policy: Background OIDC discovery refresh failed, keeping previously discovered values",
+				"url", discovery.URL,
+				"error", err,
+			)
+		}
+	}()
+}
+
+// fetchDiscovery fetches and parses the OpenID provider configuration
+func (p *JwtAuthPolicy) fetchDiscovery(discovery *OIDCDiscovery, fetchTimeout time.Duration) (*CachedDiscovery, error) {
+	client := newHTTPClient(discovery.tlsConfig, fetchTimeout)
+
+	resp, err := client.Get(discovery.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery endpoint returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var doc discoveryDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse discovery document: %w", err)
+	}
+	if doc.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document does not contain jwks_uri")
+	}
+	// OpenID Connect Discovery 1.0 section 4.3: the issuer must be the one the document was requested for
+	if expected, ok := issuerForDiscoveryURL(discovery.URL); ok && doc.Issuer != expected {
+		return nil, fmt.Errorf("discovery document issuer %q does not match the issuer %q of the discovery URL", doc.Issuer, expected)
+	}
+	if doc.Issuer == "" {
+		return nil, fmt.Errorf("discovery document does not contain issuer")
+	}
+
+	return &CachedDiscovery{
+		Issuer:            doc.Issuer,
+		JWKSURI:           doc.JWKSURI,
+		SigningAlgorithms: doc.IDTokenSigningAlgValuesSupported,
+	}, nil
+}
+
+// issuerForDiscoveryURL derives the issuer a discovery URL was built from. OpenID Connect
+// discovery appends the well-known path to the issuer, and OAuth 2.0 authorization server
+// metadata (RFC 8414 section 3) inserts it between the host and the issuer path. It returns
+// false for URLs that follow neither convention.
+func issuerForDiscoveryURL(discoveryURL string) (string, bool) {
+	if issuer, ok := strings.CutSuffix(discoveryURL, oidcDiscoveryPath); ok {
+		return issuer, true
+	}
+
+	parsed, err := url.Parse(discoveryURL)
+	if err != nil {
+		return "", false
+	}
+	if issuerPath, ok := strings.CutPrefix(parsed.Path, oauthServerMetadataSegment); ok && (issuerPath == "" || strings.HasPrefix(issuerPath, "/")) {
+		return parsed.Scheme + "://" + parsed.Host + issuerPath, true
+	}
+	return "", false
+}
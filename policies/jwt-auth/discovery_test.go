@@ -0,0 +1,272 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+// TestJWTAuthPolicy_OIDCDiscovery tests that issuer and JWKS URI are derived from the discovery document
+func TestJWTAuthPolicy_OIDCDiscovery(t *testing.T) {
+	privateKey, publicKey := generateTestKeys(t)
+
+	jwksServer := createJWKSServer(t, publicKey, "test-kid")
+	defer jwksServer.Close()
+
+	discoveryServer := createDiscoveryServer(t, "", jwksServer.URL+"/jwks.json", []string{"RS256"})
+	defer discoveryServer.Close()
+
+	token := createTestToken(t, privateKey, map[string]interface{}{
+		"sub": "user123",
+		"iss": discoveryServer.URL,
+	})
+
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", token)},
+	})
+
+	params := map[string]interface{}{
+		"keyManagers": []interface{}{
+			map[string]interface{}{
+				"name":                    "discovered-idp",
+				"discoveryUrl":            discoveryServer.URL + "/.well-known/openid-configuration",
+				"useDiscoveredAlgorithms": true,
+			},
+		},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	action := p.OnRequest(ctx, params)
+
+	if _, ok := action.(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected UpstreamRequestModifications, got %T", action)
+	}
+	if ctx.Metadata["auth.issuer"] != discoveryServer.URL {
+		t.Errorf("Expected auth.issuer to be the discovered issuer, got %v", ctx.Metadata["auth.issuer"])
+	}
+}
+
+// TestJWTAuthPolicy_OIDCDiscovery_AlgorithmNotSupported tests that discovered algorithms restrict allowedAlgorithms
+func TestJWTAuthPolicy_OIDCDiscovery_AlgorithmNotSupported(t *testing.T) {
+	privateKey, publicKey := generateTestKeys(t)
+
+	jwksServer := createJWKSServer(t, publicKey, "test-kid")
+	defer jwksServer.Close()
+
+	discoveryServer := createDiscoveryServer(t, "", jwksServer.URL+"/jwks.json", []string{"ES256"})
+	defer discoveryServer.Close()
+
+	token := createTestToken(t, privateKey, map[string]interface{}{
+		"sub": "user123",
+		"iss": discoveryServer.URL,
+	})
+
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", token)},
+	})
+
+	params := map[string]interface{}{
+		"keyManagers": []interface{}{
+			map[string]interface{}{
+				"name":                    "es-only-idp",
+				"discoveryUrl":            discoveryServer.URL + "/.well-known/openid-configuration",
+				"useDiscoveredAlgorithms": true,
+			},
+		},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	action := p.OnRequest(ctx, params)
+
+	response, ok := action.(policy.ImmediateResponse)
+	if !ok {
+		t.Fatalf("Expected ImmediateResponse, got %T", action)
+	}
+	if response.StatusCode != 401 {
+		t.Errorf("Expected status code 401, got %d", response.StatusCode)
+	}
+}
+
+// TestJWTAuthPolicy_OIDCDiscovery_IssuerMismatch tests that discovery documents are rejected when
+// their issuer differs from the discovery URL or from the configured issuer
+func TestJWTAuthPolicy_OIDCDiscovery_IssuerMismatch(t *testing.T) {
+	privateKey, publicKey := generateTestKeys(t)
+
+	jwksServer := createJWKSServer(t, publicKey, "test-kid")
+	defer jwksServer.Close()
+
+	forgedServer := createDiscoveryServer(t, "https://victim.example.com", jwksServer.URL+"/jwks.json", nil)
+	defer forgedServer.Close()
+	discoveryServer := createDiscoveryServer(t, "", jwksServer.URL+"/jwks.json", nil)
+	defer discoveryServer.Close()
+
+	tests := []struct {
+		name       string
+		issuer     string
+		keyManager map[string]interface{}
+	}{
+		{
+			name:   "document issuer differs from discovery URL",
+			issuer: "https://victim.example.com",
+			keyManager: map[string]interface{}{
+				"name":         "forged-idp",
+				"discoveryUrl": forgedServer.URL + "/.well-known/openid-configuration",
+			},
+		},
+		{
+			name:   "configured issuer differs from discovered issuer",
+			issuer: "https://configured.example.com",
+			keyManager: map[string]interface{}{
+				"name":         "configured-idp",
+				"issuer":       "https://configured.example.com",
+				"discoveryUrl": discoveryServer.URL + "/.well-known/openid-configuration",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := createTestToken(t, privateKey, map[string]interface{}{
+				"sub": "user123",
+				"iss": tt.issuer,
+			})
+			ctx := createMockRequestContext(map[string][]string{
+				"authorization": {fmt.Sprintf("Bearer %s", token)},
+			})
+			params := map[string]interface{}{
+				"keyManagers": []interface{}{tt.keyManager},
+			}
+
+			p, err := GetPolicy(policy.PolicyMetadata{}, params)
+			if err != nil {
+				t.Fatalf("Failed to create policy: %v", err)
+			}
+			response, ok := p.OnRequest(ctx, params).(policy.ImmediateResponse)
+			if !ok {
+				t.Fatalf("Expected ImmediateResponse, got %T", response)
+			}
+			if response.StatusCode != 401 {
+				t.Errorf("Expected status code 401, got %d", response.StatusCode)
+			}
+		})
+	}
+}
+
+// TestJWTAuthPolicy_OIDCDiscovery_BackgroundRefresh tests that an expired discovery document is
+// served while it is refreshed in the background
+func TestJWTAuthPolicy_OIDCDiscovery_BackgroundRefresh(t *testing.T) {
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			// Refreshes block until the test has checked that the cached document is served
+			<-release
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":   server.URL,
+			"jwks_uri": server.URL + "/jwks.json",
+		})
+	}))
+	defer server.Close()
+	defer close(release)
+
+	p := &JwtAuthPolicy{
+		discoveryStore: make(map[string]*CachedDiscovery),
+		discoveryTTLs:  make(map[string]time.Time),
+		refreshing:     make(map[string]bool),
+	}
+	discovery := &OIDCDiscovery{URL: server.URL + "/.well-known/openid-configuration"}
+
+	if _, err := p.fetchDiscoveryWithRetry(discovery, time.Nanosecond, time.Second, 0, 0); err != nil {
+		t.Fatalf("Failed to fetch discovery document: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if doc, err := p.fetchDiscoveryWithRetry(discovery, time.Nanosecond, time.Second, 0, 0); err != nil || doc.Issuer != server.URL {
+			t.Errorf("Expected the cached document, got %v, %v", doc, err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expired discovery document was refreshed on the request path")
+	}
+}
+
+func TestIssuerForDiscoveryURL(t *testing.T) {
+	tests := map[string]string{
+		"https://idp.example.com/.well-known/openid-configuration":                  "https://idp.example.com",
+		"https://idp.example.com/realms/main/.well-known/openid-configuration":      "https://idp.example.com/realms/main",
+		"https://idp.example.com/.well-known/oauth-authorization-server/tenant-one": "https://idp.example.com/tenant-one",
+		"https://idp.example.com/.well-known/oauth-authorization-server":            "https://idp.example.com",
+	}
+	for discoveryURL, expected := range tests {
+		if issuer, ok := issuerForDiscoveryURL(discoveryURL); !ok || issuer != expected {
+			t.Errorf("Expected issuer %q for %s, got %q", expected, discoveryURL, issuer)
+		}
+	}
+	if _, ok := issuerForDiscoveryURL("https://idp.example.com/discovery"); ok {
+		t.Error("Expected no issuer for a non-standard discovery URL")
+	}
+}
+
+// createDiscoveryServer serves a discovery document. An empty issuer is replaced by the server URL,
+// which is the issuer the discovery URL is built from.
+func createDiscoveryServer(t *testing.T, issuer, jwksURI string, algorithms []string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		documentIssuer := issuer
+		if documentIssuer == "" {
+			documentIssuer = server.URL
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                documentIssuer,
+			"jwks_uri":                              jwksURI,
+			"id_token_signing_alg_values_supported": algorithms,
+		}); err != nil {
+			t.Logf("Failed to encode discovery document: %v", err)
+		}
+	}))
+	return server
+}
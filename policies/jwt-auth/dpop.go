@@ -0,0 +1,393 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"container/list"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+const (
+	// DPoP modes for the dpop parameter
+	DPoPDisabled = "disabled"
+	DPoPOptional = "optional" // Proof required only for DPoP-bound tokens
+	DPoPRequired = "required" // Only DPoP-bound tokens with a valid proof are accepted
+
+	// DPoPHeader carries the DPoP proof JWT
+	DPoPHeader = "dpop"
+	// DPoPScheme is the authorization scheme for DPoP-bound access tokens
+	DPoPScheme = "DPoP"
+
+	dpopProofType = "dpop+jwt"
+
+	// BearerErrorInvalidDPoPProof is the RFC 9449 error code for rejected proofs
+	BearerErrorInvalidDPoPProof = "invalid_dpop_proof"
+
+	// minDPoPRSAKeyBits is the smallest RSA key accepted in a DPoP proof
+	minDPoPRSAKeyBits = 2048
+)
+
+// DPoPOptions holds the settings used to validate DPoP proofs
+type DPoPOptions struct {
+	Mode              string
+	AllowedAlgorithms []string
+	IatWindow         time.Duration // Maximum difference between the proof iat and the current time
+	ReplayCacheSize   int
+}
+
+// dpopProof holds the validated values of a DPoP proof
+type dpopProof struct {
+	jti        string
+	iat        time.Time
+	thumbprint string // RFC 7638 SHA-256 thumbprint of the proof key
+}
+
+// validateDPoP enforces the DPoP binding of an access token for the configured mode. dpopScheme
+// reports whether the token was sent with the DPoP authorization scheme.
+func (p *JwtAuthPolicy) validateDPoP(ctx *policy.RequestContext, token string, claims jwt.MapClaims, opts *DPoPOptions,
+	dpopScheme bool) *authFailure {
+	jkt := confirmationClaim(claims, "jkt")
+	if jkt == "" {
+		if opts.Mode == DPoPRequired {
+			return &authFailure{
+				code:        BearerErrorInvalidToken,
+				description: "The access token is not DPoP-bound",
+				reason:      "token has no cnf.jkt claim",
+				scheme:      DPoPScheme,
+				algs:        opts.AllowedAlgorithms,
+			}
+		}
+		slog.Debug("JWT Auth Policy: Token is not DPoP-bound, skipping proof validation")
+		return nil
+	}
+
+	// A DPoP-bound token sent as a bearer token would otherwise be usable without the proof key
+	// (RFC 9449 section 7.1)
+	if !dpopScheme {
+		return &authFailure{
+			code:        BearerErrorInvalidToken,
+			description: "The DPoP-bound access token must be sent with the DPoP scheme",
+			reason:      "DPoP-bound token sent without the DPoP authorization scheme",
+			scheme:      DPoPScheme,
+			algs:        opts.AllowedAlgorithms,
+		}
+	}
+
+	proofFailure := func(reason string) *authFailure {
+		return &authFailure{
+			code:        BearerErrorInvalidDPoPProof,
+			description: "The DPoP proof is missing or invalid",
+			reason:      reason,
+			scheme:      DPoPScheme,
+			algs:        opts.AllowedAlgorithms,
+		}
+	}
+
+	proofs := ctx.Headers.Get(DPoPHeader)
+	if len(proofs) != 1 {
+		return proofFailure(fmt.Sprintf("expected exactly one DPoP header, got %d", len(proofs)))
+	}
+
+	proof, err := parseDPoPProof(proofs[0], ctx, token, opts, time.Now())
+	if err != nil {
+		return proofFailure(fmt.Sprintf("DPoP proof validation failed: %v", err))
+	}
+	if proof.thumbprint != jkt {
+		return proofFailure("DPoP proof key does not match cnf.jkt")
+	}
+	seen, full := p.dpopReplayCache.seen(proof.thumbprint+"|"+proof.jti, proof.iat.Add(opts.IatWindow), opts.ReplayCacheSize)
+	if seen {
+		return proofFailure("DPoP proof jti has already been used")
+	}
+	if full {
+		// Evicting a live jti would allow its proof to be replayed, so refuse new proofs until some expire
+		slog.Warn("JWT Auth Policy: DPoP replay cache full, rejecting proof",
+			"dpopReplayCacheSize", opts.ReplayCacheSize,
+		)
+		return proofFailure("DPoP replay cache is full")
+	}
+
+	slog.Debug("JWT Auth Policy: DPoP proof validated")
+	return nil
+}
+
+// parseDPoPProof verifies a DPoP proof JWT against the request and access token (RFC 9449 section 4.3)
+func parseDPoPProof(proofString string, ctx *policy.RequestContext, accessToken string, opts *DPoPOptions, now time.Time) (*dpopProof, error) {
+	var thumbprint string
+	parsed, err := jwt.Parse(proofString, func(t *jwt.Token) (interface{}, error) {
+		if typ, _ := t.Header["typ"].(string); !strings.EqualFold(typ, dpopProofType) {
+			return nil, fmt.Errorf("typ must be %s", dpopProofType)
+		}
+		jwk, ok := t.Header["jwk"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("jwk header is missing")
+		}
+		key, tp, err := parseDPoPJWK(jwk)
+		if err != nil {
+			return nil, err
+		}
+		thumbprint = tp
+		return key, nil
+	}, jwt.WithValidMethods(opts.AllowedAlgorithms))
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("invalid claims")
+	}
+
+	jti := getString(claims["jti"])
+	if jti == "" {
+		return nil, fmt.Errorf("jti claim is missing")
+	}
+	if htm := getString(claims["htm"]); htm != ctx.Method {
+		return nil, fmt.Errorf("htm %q does not match request method %q", htm, ctx.Method)
+	}
+	if !htuMatches(getString(claims["htu"]), ctx) {
+		return nil, fmt.Errorf("htu %q does not match request URL", getString(claims["htu"]))
+	}
+
+	iatValue, ok := claims["iat"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("iat claim is missing")
+	}
+	iat := time.Unix(int64(iatValue), 0)
+	if math.Abs(float64(now.Sub(iat))) > float64(opts.IatWindow) {
+		return nil, fmt.Errorf("iat is outside the accepted window")
+	}
+
+	hash := sha256.Sum256([]byte(accessToken))
+	if getString(claims["ath"]) != base64.RawURLEncoding.EncodeToString(hash[:]) {
+		return nil, fmt.Errorf("ath does not match the access token")
+	}
+
+	return &dpopProof{jti: jti, iat: iat, thumbprint: thumbprint}, nil
+}
+
+// parseDPoPJWK parses the public JWK embedded in a DPoP proof and returns it with its RFC 7638 thumbprint
+func parseDPoPJWK(jwk map[string]interface{}) (crypto.PublicKey, string, error) {
+	if _, private := jwk["d"]; private {
+		return nil, "", fmt.Errorf("jwk must not contain a private key")
+	}
+
+	kty := getString(jwk["kty"])
+	var key crypto.PublicKey
+	var members map[string]string
+	switch kty {
+	case "RSA":
+		n, e := getString(jwk["n"]), getString(jwk["e"])
+		rsaKey, err := parseRSAPublicKey(n, e)
+		if err != nil {
+			return nil, "", err
+		}
+		if rsaKey.N.BitLen() < minDPoPRSAKeyBits {
+			return nil, "", fmt.Errorf("RSA key must be at least %d bits", minDPoPRSAKeyBits)
+		}
+		key = rsaKey
+		members = map[string]string{"e": e, "kty": kty, "n": n}
+	case "EC":
+		crv, x, y := getString(jwk["crv"]), getString(jwk["x"]), getString(jwk["y"])
+		ecKey, err := parseECPublicKey(crv, x, y)
+		if err != nil {
+			return nil, "", err
+		}
+		key = ecKey
+		members = map[string]string{"crv": crv, "kty": kty, "x": x, "y": y}
+	case "OKP":
+		crv, x := getString(jwk["crv"]), getString(jwk["x"])
+		if crv != "Ed25519" {
+			return nil, "", fmt.Errorf("unsupported OKP curve %q", crv)
+		}
+		xBytes, err := decodeBase64URL(x)
+		if err != nil || len(xBytes) != ed25519.PublicKeySize {
+			return nil, "", fmt.Errorf("invalid Ed25519 key")
+		}
+		key = ed25519.PublicKey(xBytes)
+		members = map[string]string{"crv": crv, "kty": kty, "x": x}
+	default:
+		return nil, "", fmt.Errorf("unsupported jwk kty %q", kty)
+	}
+
+	// Maps are marshaled with sorted keys and no whitespace, which is the RFC 7638 canonical form
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return key, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// parseECPublicKey builds an ECDSA public key from JWK coordinates, rejecting points not on the curve
+func parseECPublicKey(crv, x, y string) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	var ecdhCurve ecdh.Curve
+	switch crv {
+	case "P-256":
+		curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+	case "P-521":
+		curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+	default:
+		return nil, fmt.Errorf("unsupported EC curve %q", crv)
+	}
+
+	xBytes, err := decodeBase64URL(x)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode x coordinate: %w", err)
+	}
+	yBytes, err := decodeBase64URL(y)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode y coordinate: %w", err)
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	if len(xBytes) != size || len(yBytes) != size {
+		return nil, fmt.Errorf("invalid coordinate length for %s", crv)
+	}
+	point := append([]byte{4}, append(xBytes, yBytes...)...)
+	if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("point is not on curve %s", crv)
+	}
+
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(xBytes),
+		Y:     new(big.Int).SetBytes(yBytes),
+	}, nil
+}
+
+// htuMatches compares the proof htu with the request URL, ignoring query and fragment (RFC 9449 section 4.3)
+func htuMatches(htu string, ctx *policy.RequestContext) bool {
+	proofURL, err := url.Parse(htu)
+	if err != nil || proofURL.Host == "" {
+		return false
+	}
+	path, _, _ := strings.Cut(ctx.Path, "?")
+	return strings.EqualFold(proofURL.Scheme, ctx.Scheme) &&
+		normalizeHost(proofURL.Scheme, proofURL.Host) == normalizeHost(ctx.Scheme, ctx.Authority) &&
+		proofURL.EscapedPath() == path
+}
+
+// normalizeHost lowercases the host and drops the port when it is the default for the scheme
+func normalizeHost(scheme, host string) string {
+	host = strings.ToLower(host)
+	switch strings.ToLower(scheme) {
+	case "https":
+		return strings.TrimSuffix(host, ":443")
+	case "http":
+		return strings.TrimSuffix(host, ":80")
+	}
+	return host
+}
+
+// confirmationClaim returns a member of the cnf (confirmation) claim
+func confirmationClaim(claims jwt.MapClaims, member string) string {
+	cnf, ok := claims["cnf"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	return getString(cnf[member])
+}
+
+// replayCache remembers recently seen DPoP proof identifiers until their iat is no longer accepted. When
+// it is full of live identifiers, new proofs are refused rather than evicting one that could then be replayed.
+type replayCache struct {
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type replayEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+func newReplayCache() *replayCache {
+	return &replayCache{
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// seen records key until expiresAt and reports whether it was already recorded. full is true, and key
+// is not recorded, when capacity live keys are already recorded.
+func (c *replayCache) seen(key string, expiresAt time.Time, capacity int) (seen bool, full bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+
+	// Drop expired entries from the back; proofs with different iat values may expire out of
+	// order, and those are removed once they reach the back or when the cache is full
+	for c.ll.Len() > 0 {
+		oldest := c.ll.Back().Value.(*replayEntry)
+		if now.Before(oldest.expiresAt) {
+			break
+		}
+		c.ll.Remove(c.ll.Back())
+		delete(c.items, oldest.key)
+	}
+
+	if element, ok := c.items[key]; ok {
+		if now.Before(element.Value.(*replayEntry).expiresAt) {
+			return true, false
+		}
+		c.ll.Remove(element)
+		delete(c.items, key)
+	}
+	if capacity > 0 && c.ll.Len() >= capacity {
+		c.removeExpired(now)
+		if c.ll.Len() >= capacity {
+			return false, true
+		}
+	}
+	c.items[key] = c.ll.PushFront(&replayEntry{key: key, expiresAt: expiresAt})
+	return false, false
+}
+
+// removeExpired drops every expired entry, including those behind entries that expire later
+func (c *replayCache) removeExpired(now time.Time) {
+	for element := c.ll.Back(); element != nil; {
+		prev := element.Prev()
+		if entry := element.Value.(*replayEntry); !now.Before(entry.expiresAt) {
+			c.ll.Remove(element)
+			delete(c.items, entry.key)
+		}
+		element = prev
+	}
+}
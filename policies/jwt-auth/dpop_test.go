@@ -0,0 +1,226 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+// TestParseDPoPJWK_Thumbprint tests the RFC 7638 thumbprint using the example key from the RFC
+func TestParseDPoPJWK_Thumbprint(t *testing.T) {
+	jwk := map[string]interface{}{
+		"kty": "RSA",
+		"n": "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMst" +
+			"n64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n" +
+			"91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		"e":   "AQAB",
+		"alg": "RS256",
+		"kid": "2011-04-29",
+	}
+
+	_, thumbprint, err := parseDPoPJWK(jwk)
+	if err != nil {
+		t.Fatalf("Failed to parse JWK: %v", err)
+	}
+	if thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("Unexpected thumbprint %q", thumbprint)
+	}
+
+	jwk["d"] = "private"
+	if _, _, err := parseDPoPJWK(jwk); err == nil {
+		t.Errorf("Expected JWK with private key to be rejected")
+	}
+}
+
+// TestJWTAuthPolicy_DPoP tests proof validation for DPoP-bound tokens
+func TestJWTAuthPolicy_DPoP(t *testing.T) {
+	privateKey, publicKey := generateTestKeys(t)
+	jwksServer := createJWKSServer(t, publicKey, "test-kid")
+	defer jwksServer.Close()
+
+	proofKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate proof key: %v", err)
+	}
+	proofJWK := ecJWK(proofKey)
+	_, jkt, err := parseDPoPJWK(proofJWK)
+	if err != nil {
+		t.Fatalf("Failed to compute thumbprint: %v", err)
+	}
+
+	boundToken := createTestToken(t, privateKey, map[string]interface{}{
+		"sub": "user123",
+		"cnf": map[string]interface{}{"jkt": jkt},
+	})
+	bearerToken := createTestToken(t, privateKey, map[string]interface{}{"sub": "user123"})
+
+	params := map[string]interface{}{
+		"allowedAlgorithms": []interface{}{"RS256"},
+		"validateIssuer":    false,
+		"keyManagers": []interface{}{
+			map[string]interface{}{
+				"name": "test-issuer",
+				"jwks": map[string]interface{}{
+					"remote": map[string]interface{}{"uri": jwksServer.URL + "/jwks.json"},
+				},
+			},
+		},
+		"dpop": "optional",
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("GetPolicy failed: %v", err)
+	}
+
+	send := func(authorization string, proofs ...string) policy.RequestAction {
+		headers := map[string][]string{"authorization": {authorization}}
+		if len(proofs) > 0 {
+			headers["dpop"] = proofs
+		}
+		ctx := createMockRequestContext(headers)
+		ctx.Scheme = "https"
+		ctx.Authority = "api.example.com"
+		ctx.Path = "/api/test?page=2"
+		return p.OnRequest(ctx, params)
+	}
+
+	proof := func(claims map[string]interface{}) string {
+		base := map[string]interface{}{
+			"jti": "proof-" + time.Now().Format(time.RFC3339Nano),
+			"htm": "GET",
+			"htu": "https://api.example.com/api/test",
+			"iat": time.Now().Unix(),
+			"ath": accessTokenHash(boundToken),
+		}
+		for k, v := range claims {
+			base[k] = v
+		}
+		return createDPoPProof(t, proofKey, proofJWK, base)
+	}
+
+	validProof := proof(nil)
+	if _, ok := send("DPoP "+boundToken, validProof).(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected valid DPoP proof to be accepted")
+	}
+
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rejected := []struct {
+		name   string
+		proofs []string
+	}{
+		{"replayed proof", []string{validProof}},
+		{"missing proof", nil},
+		{"wrong method", []string{proof(map[string]interface{}{"htm": "POST"})}},
+		{"wrong url", []string{proof(map[string]interface{}{"htu": "https://evil.example.com/api/test"})}},
+		{"stale iat", []string{proof(map[string]interface{}{"iat": time.Now().Add(-10 * time.Minute).Unix()})}},
+		{"wrong access token hash", []string{proof(map[string]interface{}{"ath": accessTokenHash(bearerToken)})}},
+		{"key does not match cnf.jkt", []string{createDPoPProof(t, otherKey, ecJWK(otherKey), map[string]interface{}{
+			"jti": "other-key", "htm": "GET", "htu": "https://api.example.com/api/test",
+			"iat": time.Now().Unix(), "ath": accessTokenHash(boundToken),
+		})}},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			response, ok := send("DPoP "+boundToken, tt.proofs...).(policy.ImmediateResponse)
+			if !ok {
+				t.Fatalf("Expected request to be rejected")
+			}
+			if challenge := response.Headers[WWWAuthenticateHeader]; !strings.HasPrefix(challenge, `DPoP error="invalid_dpop_proof"`) {
+				t.Errorf("Unexpected WWW-Authenticate header %q", challenge)
+			}
+		})
+	}
+
+	// A DPoP-bound token must not be accepted as a bearer token, even with a valid proof
+	response, ok := send("Bearer "+boundToken, proof(nil)).(policy.ImmediateResponse)
+	if !ok {
+		t.Fatalf("Expected DPoP-bound token sent with the Bearer scheme to be rejected")
+	}
+	if challenge := response.Headers[WWWAuthenticateHeader]; !strings.HasPrefix(challenge, `DPoP error="invalid_token"`) {
+		t.Errorf("Unexpected WWW-Authenticate header %q", challenge)
+	}
+
+	// Unbound tokens are accepted in optional mode and rejected in required mode
+	if _, ok := send("Bearer " + bearerToken).(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected unbound token to be accepted in optional mode")
+	}
+	params["dpop"] = "required"
+	if _, ok := send("Bearer " + bearerToken).(policy.ImmediateResponse); !ok {
+		t.Fatalf("Expected unbound token to be rejected in required mode")
+	}
+}
+
+func ecJWK(key *ecdsa.PrivateKey) map[string]interface{} {
+	return map[string]interface{}{
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func accessTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func createDPoPProof(t *testing.T, key *ecdsa.PrivateKey, jwk map[string]interface{}, claims map[string]interface{}) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims(claims))
+	token.Header["typ"] = "dpop+jwt"
+	token.Header["jwk"] = jwk
+	proof, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign DPoP proof: %v", err)
+	}
+	return proof
+}
+
+func TestReplayCache_Capacity(t *testing.T) {
+	cache := newReplayCache()
+	expiresAt := time.Now().Add(time.Minute)
+	for _, jti := range []string{"a", "b"} {
+		if seen, full := cache.seen(jti, expiresAt, 2); seen || full {
+			t.Fatalf("jti %s reported as seen=%v full=%v", jti, seen, full)
+		}
+	}
+
+	// A full cache refuses new proofs instead of evicting a live jti
+	if seen, full := cache.seen("c", expiresAt, 2); seen || !full {
+		t.Fatalf("expected jti c to be refused by the full cache, got seen=%v full=%v", seen, full)
+	}
+	if seen, _ := cache.seen("a", expiresAt, 2); !seen {
+		t.Fatal("expected jti a to still be reported as seen")
+	}
+
+	// Expired entries free their slots, even behind live ones
+	cache.items["b"].Value.(*replayEntry).expiresAt = time.Now().Add(-time.Second)
+	if seen, full := cache.seen("c", expiresAt, 2); seen || full {
+		t.Fatalf("expected jti c to be recorded, got seen=%v full=%v", seen, full)
+	}
+}
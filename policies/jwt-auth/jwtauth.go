@@ -24,6 +24,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -56,10 +57,31 @@ const (
 
 // JwtAuthPolicy implements JWT Authentication with JWKS support
 type JwtAuthPolicy struct {
-	cacheMutex sync.RWMutex
-	cacheStore map[string]*CachedJWKS
-	cacheTTLs  map[string]time.Time
-	httpClient *http.Client
+	cacheMutex      sync.RWMutex
+	cacheStore      map[string]*CachedJWKS
+	cacheTTLs       map[string]time.Time
+	discoveryStore  map[string]*CachedDiscovery
+	discoveryTTLs   map[string]time.Time
+	refreshing      map[string]bool      // JWKS URIs with a background refresh in flight
+	kidRefetches    map[string]time.Time // Last unknown-kid triggered refetch per JWKS URI
+	jwksGenerations map[string]uint64    // Incremented whenever the key set cached for a JWKS URI changes
+	tokenCache      *tokenCache          // Validated-token cache (disabled when tokenCacheSize is 0)
+	httpClient      *http.Client
+
+	revocationMutex  sync.Mutex
+	revocationStores map[string]RevocationStore // Revocation denylists by backend configuration
+	dpopReplayCache  *replayCache               // Recently used DPoP proof jtis
+}
+
+// JWKSFetchOptions holds JWKS caching, refresh and fetch settings
+type JWKSFetchOptions struct {
+	CacheTTL                  time.Duration // Time a fetched JWKS is considered fresh
+	RefreshAhead              time.Duration // Start a background refresh this long before the TTL runs out
+	MaxStale                  time.Duration // Serve an expired JWKS for at most this long while refreshing
+	UnknownKidRefetchInterval time.Duration // Minimum interval between refetches triggered by an unknown kid
+	FetchTimeout              time.Duration // HTTP timeout for a single fetch
+	RetryCount                int           // Retries for a blocking fetch
+	RetryInterval             time.Duration // Interval between retries
 }
 
 // CachedJWKS stores cached JWKS data
@@ -67,11 +89,13 @@ type CachedJWKS struct {
 	Keys map[string]*rsa.PublicKey
 }
 
-// KeyManager represents a key manager with either remote JWKS or local certificate
+// KeyManager represents a key manager with either remote JWKS, local certificate or OIDC discovery
 type KeyManager struct {
-	Name   string      // Unique name for this key manager
-	Issuer string      // Optional issuer value
-	JWKS   *JWKSConfig // JWKS configuration (remote and/or local)
+	Name              string         // Unique name for this key manager
+	Issuer            string         // Optional issuer value
+	JWKS              *JWKSConfig    // JWKS configuration (remote and/or local)
+	Discovery         *OIDCDiscovery // Optional OIDC discovery configuration
+	SigningAlgorithms []string       // Discovered signing algorithms restricting allowedAlgorithms (optional)
 }
 
 // JWKSConfig holds both remote and local key configurations
@@ -111,11 +135,19 @@ type JWKSKey struct {
 }
 
 var ins = &JwtAuthPolicy{
-	cacheStore: make(map[string]*CachedJWKS),
-	cacheTTLs:  make(map[string]time.Time),
+	cacheStore:      make(map[string]*CachedJWKS),
+	cacheTTLs:       make(map[string]time.Time),
+	discoveryStore:  make(map[string]*CachedDiscovery),
+	discoveryTTLs:   make(map[string]time.Time),
+	refreshing:      make(map[string]bool),
+	kidRefetches:    make(map[string]time.Time),
+	jwksGenerations: make(map[string]uint64),
+	tokenCache:      newTokenCache(),
 	httpClient: &http.Client{
 		Timeout: 5 * time.Second,
 	},
+	revocationStores: make(map[string]RevocationStore),
+	dpopReplayCache:  newReplayCache(),
 }
 
 func GetPolicy(
@@ -149,6 +181,7 @@ func (p *JwtAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]
 	onFailureStatusCode := getIntParam(params, "onFailureStatusCode", 401)
 	errorMessageFormat := getStringParam(params, "errorMessageFormat", "json")
 	errorMessage := getStringParam(params, "errorMessage", "Authentication failed")
+	realm := getStringParam(params, "realm", "")
 	leewayStr := getStringParam(params, "leeway", "30s")
 	allowedAlgorithms := getStringArrayParam(params, "allowedAlgorithms", []string{"RS256", "ES256"})
 	jwksCacheTtlStr := getStringParam(params, "jwksCacheTtl", "5m")
@@ -156,6 +189,17 @@ func (p *JwtAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]
 	jwksFetchRetryCount := getIntParam(params, "jwksFetchRetryCount", 3)
 	jwksFetchRetryIntervalStr := getStringParam(params, "jwksFetchRetryInterval", "2s")
 	validateIssuer := getBoolParam(params, "validateIssuer", true)
+	discoveryRefreshIntervalStr := getStringParam(params, "discoveryRefreshInterval", "1h")
+	tokenCacheSize := getIntParam(params, "tokenCacheSize", 0)
+	tokenCacheMaxTtl := getDurationParam(params, "tokenCacheMaxTtl", 5*time.Minute)
+	revocationConfig := getRevocationConfig(params)
+	dpopOptions := &DPoPOptions{
+		AllowedAlgorithms: getStringArrayParam(params, "dpopAllowedAlgorithms", []string{"ES256", "RS256", "PS256", "EdDSA"}),
+		IatWindow:         getDurationParam(params, "dpopIatWindow", 60*time.Second),
+		ReplayCacheSize:   getIntParam(params, "dpopReplayCacheSize", 10000),
+	}
+	clientCertHeader := getStringParam(params, "clientCertHeader", "x-forwarded-client-cert")
+	clientCertHeaderFormat := getStringParam(params, "clientCertHeaderFormat", ClientCertFormatXFCC)
 
 	slog.Debug("JWT Auth Policy: Configuration loaded",
 		"headerName", headerName,
@@ -163,6 +207,7 @@ func (p *JwtAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]
 		"onFailureStatusCode", onFailureStatusCode,
 		"errorMessageFormat", errorMessageFormat,
 		"errorMessage", errorMessage,
+		"realm", realm,
 		"leeway", leewayStr,
 		"allowedAlgorithms", allowedAlgorithms,
 		"jwksCacheTtl", jwksCacheTtlStr,
@@ -170,6 +215,11 @@ func (p *JwtAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]
 		"jwksFetchRetryCount", jwksFetchRetryCount,
 		"jwksFetchRetryInterval", jwksFetchRetryIntervalStr,
 		"validateIssuer", validateIssuer,
+		"discoveryRefreshInterval", discoveryRefreshIntervalStr,
+		"tokenCacheSize", tokenCacheSize,
+		"tokenCacheMaxTtl", tokenCacheMaxTtl,
+		"revocationBackend", revocationConfig.Backend,
+		"revocationAdminPath", revocationConfig.AdminPath,
 	)
 
 	// Parse durations
@@ -213,18 +263,57 @@ func (p *JwtAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]
 		jwksFetchRetryInterval = 2 * time.Second
 	}
 
+	discoveryRefreshInterval, err := time.ParseDuration(discoveryRefreshIntervalStr)
+	if err != nil {
+		slog.Debug("JWT Auth Policy: Failed to parse discoveryRefreshInterval duration, using default",
+			"discoveryRefreshIntervalStr", discoveryRefreshIntervalStr,
+			"error", err,
+			"defaultInterval", "1h",
+		)
+		discoveryRefreshInterval = time.Hour
+	}
+
+	jwksOptions := &JWKSFetchOptions{
+		CacheTTL:                  jwksCacheTtl,
+		RefreshAhead:              getDurationParam(params, "jwksRefreshAhead", time.Minute),
+		MaxStale:                  getDurationParam(params, "jwksMaxStale", time.Hour),
+		UnknownKidRefetchInterval: getDurationParam(params, "jwksUnknownKidRefetchInterval", 30*time.Second),
+		FetchTimeout:              jwksFetchTimeout,
+		RetryCount:                jwksFetchRetryCount,
+		RetryInterval:             jwksFetchRetryInterval,
+	}
+	if jwksOptions.RefreshAhead >= jwksOptions.CacheTTL {
+		// A refresh window covering the whole TTL would refresh on every request
+		jwksOptions.RefreshAhead = jwksOptions.CacheTTL / 5
+	}
+
 	slog.Debug("JWT Auth Policy: Parsed duration values",
 		"leeway", leeway,
 		"jwksCacheTtl", jwksCacheTtl,
 		"jwksFetchTimeout", jwksFetchTimeout,
 		"jwksFetchRetryInterval", jwksFetchRetryInterval,
+		"jwksRefreshAhead", jwksOptions.RefreshAhead,
+		"jwksMaxStale", jwksOptions.MaxStale,
+		"jwksUnknownKidRefetchInterval", jwksOptions.UnknownKidRefetchInterval,
+		"discoveryRefreshInterval", discoveryRefreshInterval,
 	)
 
+	challenge := &authChallenge{
+		statusCode:   onFailureStatusCode,
+		errorFormat:  errorMessageFormat,
+		errorMessage: errorMessage,
+		realm:        realm,
+	}
+
 	// Get key managers configuration
 	keyManagersRaw, ok := params["keyManagers"]
 	if !ok {
 		slog.Debug("JWT Auth Policy: Key managers not configured in params")
-		return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, "key managers not configured")
+		return p.handleAuthFailure(ctx, challenge, authFailure{
+			code:        BearerErrorInvalidToken,
+			description: "The access token could not be validated",
+			reason:      "key managers not configured",
+		})
 	}
 
 	slog.Debug("JWT Auth Policy: Starting to parse key managers configuration")
@@ -370,13 +459,51 @@ func (p *JwtAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]
 						)
 					}
 				}
+
+				// Resolve issuer and JWKS endpoint through OIDC discovery when no explicit JWKS is configured
+				discoveryURL := getString(kmMap["discoveryUrl"])
+				if discoveryURL != "" && keyManager.JWKS == nil {
+					certPath := getString(kmMap["certificatePath"])
+					tlsConfig, err := buildTLSConfig(certPath, getBool(kmMap["skipTlsVerify"]))
+					if err != nil {
+						slog.Debug("JWT Auth Policy: Failed to load TLS config for OIDC discovery",
+							"keyManager", name,
+							"certificatePath", certPath,
+							"error", err,
+						)
+						continue // Skip this key manager if cert loading fails
+					}
+					keyManager.Discovery = &OIDCDiscovery{
+						URL:                     discoveryURL,
+						UseDiscoveredAlgorithms: getBool(kmMap["useDiscoveredAlgorithms"]),
+						tlsConfig:               tlsConfig,
+					}
+					if err := p.resolveDiscovery(keyManager, discoveryRefreshInterval, jwksFetchTimeout, jwksFetchRetryCount, jwksFetchRetryInterval); err != nil {
+						slog.Debug("JWT Auth Policy: OIDC discovery failed, skipping key manager",
+							"keyManager", name,
+							"discoveryUrl", discoveryURL,
+							"error", err,
+						)
+						continue
+					}
+					keyManagers[name] = keyManager
+					slog.Debug("JWT Auth Policy: Key manager added from OIDC discovery",
+						"keyManager", name,
+						"issuer", keyManager.Issuer,
+						"jwksUri", keyManager.JWKS.Remote.URI,
+					)
+				}
 			}
 		}
 	}
 
 	if len(keyManagers) == 0 {
 		slog.Debug("JWT Auth Policy: No key managers configured after parsing")
-		return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, "no key managers configured")
+		return p.handleAuthFailure(ctx, challenge, authFailure{
+			code:        BearerErrorInvalidToken,
+			description: "The access token could not be validated",
+			reason:      "no key managers configured",
+		})
 	}
 
 	slog.Debug("JWT Auth Policy: Key managers configured",
@@ -387,10 +514,18 @@ func (p *JwtAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]
 	userIssuers := getStringArrayParam(params, "issuers", []string{})
 	userAudiences := getStringArrayParam(params, "audiences", []string{})
 	userRequiredScopes := getStringArrayParam(params, "requiredScopes", []string{})
+	challenge.scopes = userRequiredScopes
 	userRequiredClaims := getStringMapParam(params, "requiredClaims", map[string]string{})
 	userClaimMappings := getStringMapParam(params, "claimMappings", map[string]string{})
 	userAuthHeaderPrefix := getStringParam(params, "authHeaderPrefix", "")
 	userIdClaim := getStringParam(params, "userIdClaim", DefaultUserIdClaim)
+	authorizationRules := getAuthorizationRulesParam(params, "authorizationRules")
+	authorizationRulesMatch := getStringParam(params, "authorizationRulesMatch", RulesMatchAll)
+	stripToken := getBoolParam(params, "stripToken", false)
+	revocationChecks := getStringArrayParam(params, "revocationChecks", []string{})
+	oneTimeUseJti := getBoolParam(params, "oneTimeUseJti", false)
+	dpopOptions.Mode = getStringParam(params, "dpop", DPoPDisabled)
+	certificateBinding := getStringParam(params, "certificateBinding", CertBindingDisabled)
 
 	slog.Debug("JWT Auth Policy: User configuration loaded",
 		"issuers", userIssuers,
@@ -400,6 +535,13 @@ func (p *JwtAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]
 		"claimMappingsCount", len(userClaimMappings),
 		"authHeaderPrefix", userAuthHeaderPrefix,
 		"userIdClaim", userIdClaim,
+		"authorizationRulesCount", len(authorizationRules),
+		"authorizationRulesMatch", authorizationRulesMatch,
+		"stripToken", stripToken,
+		"revocationChecks", revocationChecks,
+		"oneTimeUseJti", oneTimeUseJti,
+		"dpop", dpopOptions.Mode,
+		"certificateBinding", certificateBinding,
 	)
 
 	// Use user override if provided
@@ -411,56 +553,90 @@ func (p *JwtAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]
 		authHeaderScheme = userAuthHeaderPrefix
 	}
 
-	// Extract token from header
-	authHeaders := ctx.Headers.Get(strings.ToLower(headerName))
-	if len(authHeaders) == 0 {
-		slog.Debug("JWT Auth Policy: Missing authorization header",
-			"headerName", headerName,
-		)
-		return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, "missing authorization header")
+	// Extract token from the first configured source that carries one
+	tokenSources := getTokenSourcesParam(params, "tokenSources", headerName, authHeaderScheme)
+	if dpopOptions.Mode != DPoPDisabled {
+		for i := range tokenSources {
+			tokenSources[i].AllowDPoPScheme = true
+		}
 	}
-
-	authHeader := authHeaders[0]
-	slog.Debug("JWT Auth Policy: Authorization header found",
-		"headerName", headerName,
-		"headerValueLength", len(authHeader),
-	)
-
-	token := extractToken(authHeader, authHeaderScheme)
+	token, tokenSource, reason := extractTokenFromSources(ctx, tokenSources)
 	if token == "" {
-		slog.Debug("JWT Auth Policy: Failed to extract token from authorization header",
-			"authHeaderScheme", authHeaderScheme,
+		slog.Debug("JWT Auth Policy: No token found in configured sources",
+			"sourcesCount", len(tokenSources),
+			"reason", reason,
 		)
-		return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, "invalid authorization header format")
+		failure := authFailure{
+			code:        BearerErrorInvalidRequest,
+			description: "The access token is malformed",
+			reason:      reason,
+		}
+		// RFC 6750 omits the error code when the request carries no token at all
+		if reason == tokenMissingReason {
+			failure = authFailure{reason: reason}
+		}
+		return p.handleAuthFailure(ctx, challenge, failure)
 	}
 
 	slog.Debug("JWT Auth Policy: Token extracted successfully",
 		"tokenLength", len(token),
 	)
 
-	// Parse token to get header info
-	unverifiedToken, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
-	if err != nil {
-		slog.Debug("JWT Auth Policy: Failed to parse token",
-			"error", err,
+	// Reuse previously validated claims to skip signature verification for repeat tokens
+	var cacheKey string
+	var claims jwt.MapClaims
+	cacheHit := false
+	if tokenCacheSize > 0 {
+		p.tokenCache.size(tokenCacheSize)
+		cacheKey = tokenCacheKey(token, keyManagers, userIssuers, validateIssuer, allowedAlgorithms)
+		claims, cacheHit = p.lookupValidatedToken(cacheKey)
+		slog.Debug("JWT Auth Policy: Validated token cache lookup",
+			"hit", cacheHit,
 		)
-		return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, "invalid token format")
 	}
 
-	slog.Debug("JWT Auth Policy: Token parsed successfully",
-		"algorithm", unverifiedToken.Header["alg"],
-		"keyId", unverifiedToken.Header["kid"],
-		"type", unverifiedToken.Header["typ"],
-	)
+	if !cacheHit {
+		// Parse token to get header info
+		unverifiedToken, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		if err != nil {
+			slog.Debug("JWT Auth Policy: Failed to parse token",
+				"error", err,
+			)
+			return p.handleAuthFailure(ctx, challenge, authFailure{
+				code:        BearerErrorInvalidToken,
+				description: "The access token is malformed",
+				reason:      "invalid token format",
+			})
+		}
 
-	// Validate token and signature
-	claims, err := p.validateTokenWithSignature(token, unverifiedToken, keyManagers, userIssuers, validateIssuer,
-		allowedAlgorithms, leeway, jwksCacheTtl, jwksFetchTimeout, jwksFetchRetryCount, jwksFetchRetryInterval)
-	if err != nil {
-		slog.Debug("JWT Auth Policy: Token validation failed",
-			"error", err,
+		slog.Debug("JWT Auth Policy: Token parsed successfully",
+			"algorithm", unverifiedToken.Header["alg"],
+			"keyId", unverifiedToken.Header["kid"],
+			"type", unverifiedToken.Header["typ"],
 		)
-		return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, fmt.Sprintf("token validation failed: %v", err))
+
+		// Validate token and signature
+		generations := p.jwksGenerationSnapshot(keyManagers)
+		var verifiedBy *KeyManager
+		claims, verifiedBy, err = p.validateTokenWithSignature(token, unverifiedToken, keyManagers, userIssuers, validateIssuer,
+			allowedAlgorithms, leeway, jwksOptions)
+		if err != nil {
+			slog.Debug("JWT Auth Policy: Token validation failed",
+				"error", err,
+			)
+			description := "The access token is invalid"
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				description = "The access token expired"
+			}
+			return p.handleAuthFailure(ctx, challenge, authFailure{
+				code:        BearerErrorInvalidToken,
+				description: description,
+				reason:      fmt.Sprintf("token validation failed: %v", err),
+			})
+		}
+		if tokenCacheSize > 0 {
+			p.storeValidatedToken(cacheKey, claims, verifiedBy, generations, leeway, tokenCacheMaxTtl)
+		}
 	}
 	// Store validated claims in metadata for authz policies to use
 	ctx.Metadata[MetadataValidatedClaims] = claims
@@ -470,6 +646,32 @@ func (p *JwtAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]
 
 	slog.Debug("JWT Auth Policy: Token signature validated successfully")
 
+	// Reject tokens whose jti, sub or sid is on the revocation denylist
+	revocationIntake := isRevocationIntake(ctx, revocationConfig)
+	var revocationStore RevocationStore
+	if len(revocationChecks) > 0 || oneTimeUseJti || revocationIntake {
+		revocationStore = p.getRevocationStore(revocationConfig)
+	}
+	if len(revocationChecks) > 0 {
+		if failure := p.checkRevocation(revocationStore, revocationConfig, claims, revocationChecks); failure != nil {
+			return p.handleAuthFailure(ctx, challenge, *failure)
+		}
+	}
+
+	// Verify proof of possession for DPoP-bound tokens
+	if dpopOptions.Mode != DPoPDisabled {
+		if failure := p.validateDPoP(ctx, token, claims, dpopOptions, sentWithDPoPScheme(ctx, tokenSource)); failure != nil {
+			return p.handleAuthFailure(ctx, challenge, *failure)
+		}
+	}
+
+	// Verify certificate-bound tokens against the client certificate forwarded by the proxy
+	if certificateBinding != CertBindingDisabled {
+		if failure := p.validateCertificateBinding(ctx, claims, certificateBinding, clientCertHeader, clientCertHeaderFormat); failure != nil {
+			return p.handleAuthFailure(ctx, challenge, *failure)
+		}
+	}
+
 	// Note: Issuer validation is now done in validateTokenWithSignature when userIssuers is specified
 	// or when validateIssuer is true. No need for duplicate validation here.
 
@@ -496,7 +698,11 @@ func (p *JwtAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]
 			slog.Debug("JWT Auth Policy: No valid audience found in token",
 				"tokenAudiences", aud,
 			)
-			return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, "no valid audience found in token")
+			return p.handleAuthFailure(ctx, challenge, authFailure{
+				code:        BearerErrorInvalidToken,
+				description: "The access token is not intended for this resource",
+				reason:      "no valid audience found in token",
+			})
 		}
 		slog.Debug("JWT Auth Policy: Audience validation passed")
 	}
@@ -521,7 +727,8 @@ func (p *JwtAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]
 					"missingScope", requiredScope,
 					"tokenScopes", scopes,
 				)
-				return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, fmt.Sprintf("required scope '%s' not found", requiredScope))
+				return p.handleAuthorizationFailure(ctx, challenge, "The access token does not have the required scope",
+					fmt.Sprintf("required scope '%s' not found", requiredScope))
 			}
 		}
 		slog.Debug("JWT Auth Policy: Scope validation passed")
@@ -546,20 +753,63 @@ func (p *JwtAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]
 				"expectedValue", expectedValue,
 				"actualValue", claimValue,
 			)
-			return p.handleAuthFailure(ctx, onFailureStatusCode, errorMessageFormat, errorMessage, fmt.Sprintf("claim '%s' validation failed", claimName))
+			return p.handleAuthFailure(ctx, challenge, authFailure{
+				code:        BearerErrorInvalidToken,
+				description: "The access token does not carry the required claims",
+				reason:      fmt.Sprintf("claim '%s' validation failed", claimName),
+			})
 		}
 	}
 
+	// Evaluate authorization rules if specified
+	if len(authorizationRules) > 0 {
+		evaluator, err := GetCELEvaluator()
+		if err != nil {
+			slog.Debug("JWT Auth Policy: Failed to initialize CEL evaluator",
+				"error", err,
+			)
+			return p.handleAuthorizationFailure(ctx, challenge, accessDeniedMessage, "authorization rules could not be evaluated")
+		}
+		granted, failedRule := evaluator.EvaluateAuthorizationRules(authorizationRules, authorizationRulesMatch, claims, ctx)
+		if !granted {
+			slog.Debug("JWT Auth Policy: Authorization rules denied access",
+				"failedRule", failedRule,
+				"match", authorizationRulesMatch,
+			)
+			return p.handleAuthorizationFailure(ctx, challenge, accessDeniedMessage, fmt.Sprintf("authorization rule '%s' not satisfied", failedRule))
+		}
+		slog.Debug("JWT Auth Policy: Authorization rules passed")
+	}
+
+	// Record the jti last so a request rejected by an earlier check does not consume the token
+	if oneTimeUseJti {
+		if failure := p.markTokenUsed(revocationStore, revocationConfig, claims, leeway); failure != nil {
+			return p.handleAuthFailure(ctx, challenge, *failure)
+		}
+	}
+
+	if revocationIntake {
+		return p.handleRevocationIntake(ctx, revocationStore, revocationConfig, claims, challenge)
+	}
+
 	slog.Debug("JWT Auth Policy: All validations passed, authentication successful")
 
 	// Authentication successful - apply claim mappings and set metadata
-	return p.handleAuthSuccess(ctx, claims, userClaimMappings, userIdClaim)
+	action := p.handleAuthSuccess(ctx, claims, userClaimMappings, userIdClaim)
+	if stripToken {
+		if modifications, ok := action.(policy.UpstreamRequestModifications); ok {
+			stripTokenSource(ctx, tokenSource, &modifications)
+			return modifications
+		}
+	}
+	return action
 }
 
-// validateTokenWithSignature validates JWT signature using JWKS
+// validateTokenWithSignature validates JWT signature using JWKS and returns the claims along with
+// the key manager that verified the signature
 func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifiedToken *jwt.Token,
 	keyManagers map[string]*KeyManager, userIssuers []string, validateIssuer bool, allowedAlgorithms []string,
-	leeway time.Duration, cacheTTL time.Duration, fetchTimeout time.Duration, retryCount int, retryInterval time.Duration) (jwt.MapClaims, error) {
+	leeway time.Duration, jwksOptions *JWKSFetchOptions) (jwt.MapClaims, *KeyManager, error) {
 
 	slog.Debug("JWT Auth Policy: Starting token signature validation",
 		"keyManagersCount", len(keyManagers),
@@ -571,14 +821,14 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 	unverifiedClaims, ok := unverifiedToken.Claims.(jwt.MapClaims)
 	if !ok {
 		slog.Debug("JWT Auth Policy: Invalid token claims format")
-		return nil, fmt.Errorf("invalid token claims format")
+		return nil, nil, fmt.Errorf("invalid token claims format")
 	}
 
 	// Check allowed algorithms
 	alg, ok := unverifiedToken.Header["alg"].(string)
 	if !ok {
 		slog.Debug("JWT Auth Policy: Missing algorithm in token header")
-		return nil, fmt.Errorf("missing algorithm in token")
+		return nil, nil, fmt.Errorf("missing algorithm in token")
 	}
 
 	slog.Debug("JWT Auth Policy: Checking algorithm",
@@ -597,7 +847,7 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 		slog.Debug("JWT Auth Policy: Algorithm not in allowed list",
 			"tokenAlgorithm", alg,
 		)
-		return nil, fmt.Errorf("algorithm '%s' not in allowed list", alg)
+		return nil, nil, fmt.Errorf("algorithm '%s' not in allowed list", alg)
 	}
 
 	slog.Debug("JWT Auth Policy: Algorithm check passed",
@@ -618,7 +868,7 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 				"expTime", expTime,
 				"now", now,
 			)
-			return nil, fmt.Errorf("token expired")
+			return nil, nil, jwt.ErrTokenExpired
 		}
 		slog.Debug("JWT Auth Policy: Token expiration check passed")
 	} else {
@@ -637,7 +887,7 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 				"nbfTime", nbfTime,
 				"now", now,
 			)
-			return nil, fmt.Errorf("token not yet valid")
+			return nil, nil, jwt.ErrTokenNotValidYet
 		}
 		slog.Debug("JWT Auth Policy: Token not-before check passed")
 	} else {
@@ -721,7 +971,7 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 				"tokenIssuer", tokenIssuer,
 				"userIssuers", userIssuers,
 			)
-			return nil, fmt.Errorf("token issuer '%s' does not match any configured issuer or key manager", tokenIssuer)
+			return nil, nil, fmt.Errorf("token issuer '%s' does not match any configured issuer or key manager", tokenIssuer)
 		}
 	} else if tokenIssuer != "" {
 		// No user issuers specified, but token has issuer claim
@@ -753,7 +1003,7 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 					slog.Debug("JWT Auth Policy: No key manager found for token issuer (validateIssuer=true)",
 						"tokenIssuer", tokenIssuer,
 					)
-					return nil, fmt.Errorf("no key manager configured for token issuer '%s'", tokenIssuer)
+					return nil, nil, fmt.Errorf("no key manager configured for token issuer '%s'", tokenIssuer)
 				}
 				slog.Debug("JWT Auth Policy: Using key managers without issuer for token validation",
 					"tokenIssuer", tokenIssuer,
@@ -771,7 +1021,7 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 		// No issuer in token
 		if validateIssuer {
 			slog.Debug("JWT Auth Policy: Token has no issuer claim (validateIssuer=true)")
-			return nil, fmt.Errorf("token does not contain an issuer claim")
+			return nil, nil, fmt.Errorf("token does not contain an issuer claim")
 		}
 		// Lenient mode: try all key managers
 		slog.Debug("JWT Auth Policy: No issuer in token, using all key managers (validateIssuer=false)")
@@ -811,6 +1061,17 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 			continue
 		}
 
+		// Discovered signing algorithms further restrict allowedAlgorithms for this key manager
+		if len(km.SigningAlgorithms) > 0 && !containsString(km.SigningAlgorithms, alg) {
+			slog.Debug("JWT Auth Policy: Algorithm not supported by key manager",
+				"keyManager", km.Name,
+				"tokenAlgorithm", alg,
+				"supportedAlgorithms", km.SigningAlgorithms,
+			)
+			lastErr = fmt.Errorf("algorithm '%s' not supported by key manager '%s'", alg, km.Name)
+			continue
+		}
+
 		// Try local certificate validation first if available
 		if km.JWKS.Local != nil && km.JWKS.Local.PublicKey != nil {
 			slog.Debug("JWT Auth Policy: Attempting signature verification with local certificate",
@@ -826,7 +1087,7 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 					"keyManager", km.Name,
 				)
 				if claims, ok := verifiedToken.Claims.(jwt.MapClaims); ok {
-					return claims, nil
+					return claims, km, nil
 				}
 			}
 			slog.Debug("JWT Auth Policy: Signature verification failed with local certificate",
@@ -844,7 +1105,7 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 				"jwksUri", km.JWKS.Remote.URI,
 			)
 			// Get JWKS with retry logic
-			jwks, err := p.fetchJWKSWithRetry(km.JWKS.Remote, cacheTTL, fetchTimeout, retryCount, retryInterval)
+			jwks, err := p.fetchJWKSWithRetry(km.JWKS.Remote, jwksOptions)
 			if err != nil {
 				slog.Debug("JWT Auth Policy: Failed to fetch JWKS",
 					"keyManager", km.Name,
@@ -866,6 +1127,13 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 					"kid", kid,
 				)
 				publicKey, ok := jwks.Keys[kid]
+				if !ok {
+					// The key set may have been rotated since it was cached, refetch it (rate limited)
+					if refreshed, refetched := p.refetchJWKSForUnknownKid(km.JWKS.Remote, jwksOptions); refetched {
+						jwks = refreshed
+						publicKey, ok = jwks.Keys[kid]
+					}
+				}
 				if !ok {
 					slog.Debug("JWT Auth Policy: Key ID not found in JWKS",
 						"kid", kid,
@@ -898,7 +1166,7 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 					"keyManager", km.Name,
 				)
 				if claims, ok := verifiedToken.Claims.(jwt.MapClaims); ok {
-					return claims, nil
+					return claims, km, nil
 				}
 			} else {
 				// No kid, try all keys in JWKS
@@ -920,7 +1188,7 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 							"keyManager", km.Name,
 						)
 						if claims, ok := verifiedToken.Claims.(jwt.MapClaims); ok {
-							return claims, nil
+							return claims, km, nil
 						}
 					} else {
 						slog.Debug("JWT Auth Policy: Signature verification failed with key",
@@ -942,64 +1210,77 @@ func (p *JwtAuthPolicy) validateTokenWithSignature(tokenString string, unverifie
 		slog.Debug("JWT Auth Policy: All key managers failed to verify signature",
 			"lastError", lastErr,
 		)
-		return nil, lastErr
+		return nil, nil, lastErr
 	}
 	slog.Debug("JWT Auth Policy: Unable to verify token signature with any available key manager")
-	return nil, fmt.Errorf("unable to verify token signature with available key managers")
+	return nil, nil, fmt.Errorf("unable to verify token signature with available key managers")
 }
 
-// fetchJWKSWithRetry fetches JWKS with caching and retry logic
-func (p *JwtAuthPolicy) fetchJWKSWithRetry(remote *RemoteJWKS, cacheTTL time.Duration, fetchTimeout time.Duration, retryCount int, retryInterval time.Duration) (*CachedJWKS, error) {
+// fetchJWKSWithRetry fetches JWKS with caching and retry logic.
+// A cached set is refreshed in the background once it enters the refresh-ahead window, and an
+// expired set keeps being served for up to MaxStale while the background refresh is retried.
+// Only a missing or too-stale set is fetched inline on the request path.
+func (p *JwtAuthPolicy) fetchJWKSWithRetry(remote *RemoteJWKS, opts *JWKSFetchOptions) (*CachedJWKS, error) {
 	slog.Debug("JWT Auth Policy: fetchJWKSWithRetry called",
 		"uri", remote.URI,
-		"cacheTTL", cacheTTL,
-		"fetchTimeout", fetchTimeout,
-		"retryCount", retryCount,
-		"retryInterval", retryInterval,
+		"cacheTTL", opts.CacheTTL,
+		"fetchTimeout", opts.FetchTimeout,
+		"retryCount", opts.RetryCount,
+		"retryInterval", opts.RetryInterval,
 	)
 
 	// Check cache first
+	now := time.Now()
 	p.cacheMutex.RLock()
-	if cached, ok := p.cacheStore[remote.URI]; ok {
-		if ttl, ok := p.cacheTTLs[remote.URI]; ok && time.Now().Before(ttl) {
-			p.cacheMutex.RUnlock()
+	cached, hasCached := p.cacheStore[remote.URI]
+	expiry := p.cacheTTLs[remote.URI]
+	p.cacheMutex.RUnlock()
+
+	if hasCached {
+		switch {
+		case now.Before(expiry.Add(-opts.RefreshAhead)):
 			slog.Debug("JWT Auth Policy: JWKS cache hit",
 				"uri", remote.URI,
-				"cacheExpiry", ttl,
+				"cacheExpiry", expiry,
 				"keysCount", len(cached.Keys),
 			)
 			return cached, nil
+		case now.Before(expiry.Add(opts.MaxStale)):
+			slog.Debug("JWT Auth Policy: JWKS cache due for refresh, serving cached keys",
+				"uri", remote.URI,
+				"cacheExpiry", expiry,
+				"stale", !now.Before(expiry),
+			)
+			p.refreshJWKSInBackground(remote, opts)
+			return cached, nil
 		}
-		slog.Debug("JWT Auth Policy: JWKS cache expired",
+		slog.Debug("JWT Auth Policy: JWKS cache expired beyond max stale",
 			"uri", remote.URI,
+			"cacheExpiry", expiry,
+			"maxStale", opts.MaxStale,
 		)
 	} else {
 		slog.Debug("JWT Auth Policy: JWKS not in cache",
 			"uri", remote.URI,
 		)
 	}
-	p.cacheMutex.RUnlock()
 
-	// Not in cache or expired, fetch from server
+	// Not in cache or too stale, fetch from server
+	return p.fetchAndCacheJWKS(remote, opts)
+}
+
+// fetchAndCacheJWKS fetches JWKS from the server with retries and caches the result
+func (p *JwtAuthPolicy) fetchAndCacheJWKS(remote *RemoteJWKS, opts *JWKSFetchOptions) (*CachedJWKS, error) {
 	var lastErr error
-	for attempt := 0; attempt <= retryCount; attempt++ {
+	for attempt := 0; attempt <= opts.RetryCount; attempt++ {
 		slog.Debug("JWT Auth Policy: Fetching JWKS from server",
 			"uri", remote.URI,
 			"attempt", attempt+1,
-			"maxAttempts", retryCount+1,
+			"maxAttempts", opts.RetryCount+1,
 		)
-		jwks, err := p.fetchJWKS(remote, fetchTimeout)
+		jwks, err := p.fetchJWKS(remote, opts.FetchTimeout)
 		if err == nil {
-			// Cache the result
-			p.cacheMutex.Lock()
-			p.cacheStore[remote.URI] = jwks
-			p.cacheTTLs[remote.URI] = time.Now().Add(cacheTTL)
-			p.cacheMutex.Unlock()
-			slog.Debug("JWT Auth Policy: JWKS fetched and cached successfully",
-				"uri", remote.URI,
-				"keysCount", len(jwks.Keys),
-				"cacheExpiry", time.Now().Add(cacheTTL),
-			)
+			p.storeJWKS(remote.URI, jwks, opts.CacheTTL)
 			return jwks, nil
 		}
 
@@ -1009,11 +1290,11 @@ func (p *JwtAuthPolicy) fetchJWKSWithRetry(remote *RemoteJWKS, cacheTTL time.Dur
 			"error", err,
 		)
 		lastErr = err
-		if attempt < retryCount {
+		if attempt < opts.RetryCount {
 			slog.Debug("JWT Auth Policy: Waiting before retry",
-				"retryInterval", retryInterval,
+				"retryInterval", opts.RetryInterval,
 			)
-			time.Sleep(retryInterval)
+			time.Sleep(opts.RetryInterval)
 		}
 	}
 
@@ -1024,6 +1305,81 @@ func (p *JwtAuthPolicy) fetchJWKSWithRetry(remote *RemoteJWKS, cacheTTL time.Dur
 	return nil, lastErr
 }
 
+// storeJWKS caches a fetched JWKS for the given TTL and bumps its generation if the keys changed
+func (p *JwtAuthPolicy) storeJWKS(uri string, jwks *CachedJWKS, cacheTTL time.Duration) {
+	expiry := time.Now().Add(cacheTTL)
+	p.cacheMutex.Lock()
+	if previous, ok := p.cacheStore[uri]; ok && !sameKeys(previous, jwks) {
+		// Invalidates validated-token cache entries verified with the previous key set
+		p.jwksGenerations[uri]++
+	}
+	p.cacheStore[uri] = jwks
+	p.cacheTTLs[uri] = expiry
+	p.cacheMutex.Unlock()
+	slog.Debug("JWT Auth Policy: JWKS fetched and cached successfully",
+		"uri", uri,
+		"keysCount", len(jwks.Keys),
+		"cacheExpiry", expiry,
+	)
+}
+
+// refreshJWKSInBackground starts a background refresh for the JWKS unless one is already running.
+// On failure the cached set is left in place so it can be served until MaxStale.
+func (p *JwtAuthPolicy) refreshJWKSInBackground(remote *RemoteJWKS, opts *JWKSFetchOptions) {
+	p.cacheMutex.Lock()
+	if p.refreshing[remote.URI] {
+		p.cacheMutex.Unlock()
+		return
+	}
+	p.refreshing[remote.URI] = true
+	p.cacheMutex.Unlock()
+
+	go func() {
+		defer func() {
+			p.cacheMutex.Lock()
+			delete(p.refreshing, remote.URI)
+			p.cacheMutex.Unlock()
+		}()
+		if _, err := p.fetchAndCacheJWKS(remote, opts); err != nil {
+			slog.Debug("JWT Auth Policy: Background JWKS refresh failed, keeping cached keys",
+				"uri", remote.URI,
+				"error", err,
+			)
+		}
+	}()
+}
+
+// refetchJWKSForUnknownKid fetches the JWKS immediately when a token references a kid that is not
+// in the cached set, so key rotations are picked up before the TTL runs out. Refetches are limited
+// to one per UnknownKidRefetchInterval per JWKS URI. It returns false if no refetch happened.
+func (p *JwtAuthPolicy) refetchJWKSForUnknownKid(remote *RemoteJWKS, opts *JWKSFetchOptions) (*CachedJWKS, bool) {
+	p.cacheMutex.Lock()
+	if last, ok := p.kidRefetches[remote.URI]; ok && time.Since(last) < opts.UnknownKidRefetchInterval {
+		p.cacheMutex.Unlock()
+		slog.Debug("JWT Auth Policy: Skipping unknown kid JWKS refetch, rate limited",
+			"uri", remote.URI,
+			"lastRefetch", last,
+		)
+		return nil, false
+	}
+	p.kidRefetches[remote.URI] = time.Now()
+	p.cacheMutex.Unlock()
+
+	slog.Debug("JWT Auth Policy: Refetching JWKS for unknown kid",
+		"uri", remote.URI,
+	)
+	jwks, err := p.fetchJWKS(remote, opts.FetchTimeout)
+	if err != nil {
+		slog.Debug("JWT Auth Policy: Unknown kid JWKS refetch failed",
+			"uri", remote.URI,
+			"error", err,
+		)
+		return nil, false
+	}
+	p.storeJWKS(remote.URI, jwks, opts.CacheTTL)
+	return jwks, true
+}
+
 // fetchJWKS fetches JWKS from the given remote configuration
 func (p *JwtAuthPolicy) fetchJWKS(remote *RemoteJWKS, fetchTimeout time.Duration) (*CachedJWKS, error) {
 	slog.Debug("JWT Auth Policy: fetchJWKS called",
@@ -1033,28 +1389,7 @@ func (p *JwtAuthPolicy) fetchJWKS(remote *RemoteJWKS, fetchTimeout time.Duration
 	)
 
 	// Create a new HTTP client per request to avoid race conditions on shared state
-	var client *http.Client
-	if remote.tlsConfig != nil {
-		// Create a new client with custom TLS config
-		slog.Debug("JWT Auth Policy: Creating HTTP client with custom TLS config",
-			"uri", remote.URI,
-		)
-		customTransport := &http.Transport{
-			TLSClientConfig: remote.tlsConfig,
-		}
-		client = &http.Client{
-			Transport: customTransport,
-			Timeout:   fetchTimeout,
-		}
-	} else {
-		// Create a new client with default transport
-		slog.Debug("JWT Auth Policy: Creating HTTP client with default transport",
-			"uri", remote.URI,
-		)
-		client = &http.Client{
-			Timeout: fetchTimeout,
-		}
-	}
+	client := newHTTPClient(remote.tlsConfig, fetchTimeout)
 
 	slog.Debug("JWT Auth Policy: Sending HTTP GET request to JWKS endpoint",
 		"uri", remote.URI,
@@ -1351,50 +1686,65 @@ func (p *JwtAuthPolicy) OnResponse(ctx *policy.ResponseContext, params map[strin
 	return nil // No response processing needed
 }
 
-// handleAuthFailure handles authentication failure
-func (p *JwtAuthPolicy) handleAuthFailure(ctx *policy.RequestContext, statusCode int, errorFormat, errorMessage, reason string) policy.RequestAction {
+// handleAuthFailure handles authentication failure. The response carries an RFC 6750
+// WWW-Authenticate challenge describing the failure.
+func (p *JwtAuthPolicy) handleAuthFailure(ctx *policy.RequestContext, challenge *authChallenge, failure authFailure) policy.RequestAction {
 	slog.Debug("JWT Auth Policy: handleAuthFailure called",
-		"statusCode", statusCode,
-		"errorFormat", errorFormat,
-		"errorMessage", errorMessage,
-		"reason", reason,
+		"statusCode", challenge.statusCode,
+		"errorFormat", challenge.errorFormat,
+		"errorMessage", challenge.errorMessage,
+		"error", failure.code,
+		"reason", failure.reason,
 	)
 
 	// Set metadata indicating failed authentication
 	ctx.Metadata[MetadataKeyAuthSuccess] = false
 	ctx.Metadata[MetadataKeyAuthMethod] = "jwt"
 
+	body, contentType := errorResponseBody(challenge.errorFormat, challenge.statusCode, "Unauthorized", challenge.errorMessage, failure)
 	headers := map[string]string{
-		"content-type": "application/json",
-	}
-
-	var body string
-	switch errorFormat {
-	case "plain":
-		body = errorMessage
-		headers["content-type"] = "text/plain"
-	default: // json
-		errResponse := map[string]interface{}{
-			"error":   "Unauthorized",
-			"message": errorMessage,
-		}
-		bodyBytes, _ := json.Marshal(errResponse)
-		body = string(bodyBytes)
+		"content-type":        contentType,
+		WWWAuthenticateHeader: bearerChallenge(challenge.realm, challenge.scopes, failure),
 	}
 
 	slog.Debug("JWT Auth Policy: Returning immediate response",
-		"statusCode", statusCode,
+		"statusCode", challenge.statusCode,
 		"contentType", headers["content-type"],
 		"bodyLength", len(body),
 	)
 
 	return policy.ImmediateResponse{
-		StatusCode: statusCode,
+		StatusCode: challenge.statusCode,
 		Headers:    headers,
 		Body:       []byte(body),
 	}
 }
 
+// handleAuthorizationFailure handles a validated token that lacks a required scope or is not authorized
+// by the authorization rules. It returns 403 with an insufficient_scope error (RFC 6750 section 3.1) so
+// clients can tell it apart from authentication failures.
+func (p *JwtAuthPolicy) handleAuthorizationFailure(ctx *policy.RequestContext, challenge *authChallenge, message string, reason string) policy.RequestAction {
+	slog.Debug("JWT Auth Policy: handleAuthorizationFailure called",
+		"errorFormat", challenge.errorFormat,
+		"reason", reason,
+	)
+
+	ctx.Metadata[MetadataKeyAuthSuccess] = false
+	ctx.Metadata[MetadataKeyAuthMethod] = "jwt"
+
+	failure := authFailure{code: BearerErrorInsufficientScope, description: message, reason: reason}
+	body, contentType := errorResponseBody(challenge.errorFormat, 403, BearerErrorInsufficientScope, message, failure)
+
+	return policy.ImmediateResponse{
+		StatusCode: 403,
+		Headers: map[string]string{
+			"content-type":        contentType,
+			WWWAuthenticateHeader: bearerChallenge(challenge.realm, challenge.scopes, failure),
+		},
+		Body: []byte(body),
+	}
+}
+
 // Helper functions for type assertions
 func getString(v interface{}) string {
 	if s, ok := v.(string); ok {
@@ -1419,6 +1769,24 @@ func getBoolParam(params map[string]interface{}, key string, defaultValue bool)
 	return defaultValue
 }
 
+func getDurationParam(params map[string]interface{}, key string, defaultValue time.Duration) time.Duration {
+	if v, ok := params[key]; ok {
+		if s, ok := v.(string); ok {
+			d, err := time.ParseDuration(s)
+			if err == nil {
+				return d
+			}
+			slog.Debug("JWT Auth Policy: Failed to parse duration, using default",
+				"param", key,
+				"value", s,
+				"error", err,
+				"default", defaultValue,
+			)
+		}
+	}
+	return defaultValue
+}
+
 func getStringParam(params map[string]interface{}, key, defaultValue string) string {
 	if v, ok := params[key]; ok {
 		if s, ok := v.(string); ok {
@@ -1474,6 +1842,34 @@ func getStringMapParam(params map[string]interface{}, key string, defaultValue m
 	return defaultValue
 }
 
+// getAuthorizationRulesParam parses authorization rules given as objects with name and expression
+// or as plain expression strings
+func getAuthorizationRulesParam(params map[string]interface{}, key string) []AuthorizationRule {
+	arr, ok := params[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	var rules []AuthorizationRule
+	for i, item := range arr {
+		var rule AuthorizationRule
+		switch v := item.(type) {
+		case string:
+			rule.Expression = v
+		case map[string]interface{}:
+			rule.Name = getString(v["name"])
+			rule.Expression = getString(v["expression"])
+		}
+		if rule.Expression == "" {
+			continue
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
 func claimValueToString(v interface{}) string {
 	switch val := v.(type) {
 	case string:
@@ -1497,6 +1893,46 @@ func getKeyIds(keys map[string]*rsa.PublicKey) []string {
 	return ids
 }
 
+// newHTTPClient creates an HTTP client with the given TLS config (optional) and timeout
+func newHTTPClient(tlsConfig *tls.Config, timeout time.Duration) *http.Client {
+	if tlsConfig != nil {
+		return &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConfig,
+			},
+			Timeout: timeout,
+		}
+	}
+	return &http.Client{
+		Timeout: timeout,
+	}
+}
+
+// buildTLSConfig returns the TLS config for a custom CA certificate or for skipping verification.
+// It returns nil when neither option is set so the default transport is used.
+func buildTLSConfig(certPath string, skipTlsVerify bool) (*tls.Config, error) {
+	if certPath != "" {
+		return loadTLSConfig(certPath)
+	}
+	if skipTlsVerify {
+		return &tls.Config{
+			InsecureSkipVerify: true,
+			MinVersion:         tls.VersionTLS12,
+		}, nil
+	}
+	return nil, nil
+}
+
+// containsString reports whether target is present in values
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
 // loadTLSConfig loads TLS configuration from a certificate file for validating self-signed certificates
 // When a custom CA certificate is provided, hostname verification is skipped to allow
 // self-signed certificates with any hostname to be used (useful for development/testing)
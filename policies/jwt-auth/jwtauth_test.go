@@ -34,6 +34,8 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -1799,3 +1801,159 @@ func TestJWTAuthPolicy_UserIdClaim_WithClaimMappings(t *testing.T) {
 		t.Errorf("Expected X-User-Role='admin', got '%v'", modifications.SetHeaders["X-User-Role"])
 	}
 }
+
+// TestJWTAuthPolicy_StaleJWKSServedWhenRefreshFails tests that an expired JWKS keeps being served within jwksMaxStale
+func TestJWTAuthPolicy_StaleJWKSServedWhenRefreshFails(t *testing.T) {
+	privateKey, publicKey := generateTestKeys(t)
+	jwksServer, keySet := createRotatingJWKSServer(t)
+	defer jwksServer.Close()
+	keySet.set(publicKey, "test-kid")
+
+	params := map[string]interface{}{
+		"allowedAlgorithms":   []interface{}{"RS256"},
+		"jwksCacheTtl":        "200ms",
+		"jwksMaxStale":        "1h",
+		"jwksFetchRetryCount": 0,
+		"validateIssuer":      false,
+		"keyManagers": []interface{}{
+			map[string]interface{}{
+				"name": "test-issuer",
+				"jwks": map[string]interface{}{
+					"remote": map[string]interface{}{"uri": jwksServer.URL + "/jwks.json"},
+				},
+			},
+		},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("GetPolicy failed: %v", err)
+	}
+
+	token := createTestToken(t, privateKey, map[string]interface{}{"sub": "user123"})
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", token)},
+	})
+	if _, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected first request to succeed")
+	}
+
+	// Let the cached JWKS expire and make the endpoint fail
+	time.Sleep(300 * time.Millisecond)
+	keySet.fail.Store(true)
+
+	ctx = createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", token)},
+	})
+	if _, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected request to succeed with stale JWKS")
+	}
+}
+
+// TestJWTAuthPolicy_UnknownKidTriggersRefetch tests that a rotated key is picked up before the JWKS TTL runs out
+func TestJWTAuthPolicy_UnknownKidTriggersRefetch(t *testing.T) {
+	oldPrivateKey, oldPublicKey := generateTestKeys(t)
+	newPrivateKey, newPublicKey := generateTestKeys(t)
+	jwksServer, keySet := createRotatingJWKSServer(t)
+	defer jwksServer.Close()
+	keySet.set(oldPublicKey, "old-kid")
+
+	params := map[string]interface{}{
+		"allowedAlgorithms": []interface{}{"RS256"},
+		"jwksCacheTtl":      "5m",
+		"validateIssuer":    false,
+		"keyManagers": []interface{}{
+			map[string]interface{}{
+				"name": "test-issuer",
+				"jwks": map[string]interface{}{
+					"remote": map[string]interface{}{"uri": jwksServer.URL + "/jwks.json"},
+				},
+			},
+		},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("GetPolicy failed: %v", err)
+	}
+
+	oldToken := createTestTokenWithKid(t, oldPrivateKey, "old-kid", map[string]interface{}{"sub": "user123"})
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", oldToken)},
+	})
+	if _, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected request with old key to succeed")
+	}
+
+	// Rotate the signing key at the IdP
+	keySet.set(newPublicKey, "new-kid")
+
+	newToken := createTestTokenWithKid(t, newPrivateKey, "new-kid", map[string]interface{}{"sub": "user123"})
+	ctx = createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", newToken)},
+	})
+	if _, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected request with rotated key to succeed")
+	}
+}
+
+// rotatingKeySet is the key served by a rotating JWKS server
+type rotatingKeySet struct {
+	mu        sync.Mutex
+	publicKey *rsa.PublicKey
+	kid       string
+	fail      atomic.Bool
+}
+
+func (k *rotatingKeySet) set(publicKey *rsa.PublicKey, kid string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.publicKey = publicKey
+	k.kid = kid
+}
+
+// createRotatingJWKSServer creates a JWKS server whose key can be replaced or made to fail during a test
+func createRotatingJWKSServer(t *testing.T) (*httptest.Server, *rotatingKeySet) {
+	keySet := &rotatingKeySet{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if keySet.fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		keySet.mu.Lock()
+		publicKey, kid := keySet.publicKey, keySet.kid
+		keySet.mu.Unlock()
+
+		jwks := map[string]interface{}{
+			"keys": []map[string]interface{}{
+				{
+					"kty": "RSA",
+					"kid": kid,
+					"use": "sig",
+					"alg": "RS256",
+					"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+				},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(jwks); err != nil {
+			t.Logf("Failed to encode JWKS: %v", err)
+		}
+	}))
+	return server, keySet
+}
+
+func createTestTokenWithKid(t *testing.T, privateKey *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims(claims))
+	token.Header["kid"] = kid
+
+	tokenString, err := token.SignedString(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return tokenString
+}
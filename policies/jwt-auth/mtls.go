@@ -0,0 +1,249 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+const (
+	// Certificate binding modes for the certificateBinding parameter
+	CertBindingDisabled = "disabled"
+	CertBindingOptional = "optional" // Binding enforced only for tokens with cnf.x5t#S256
+	CertBindingRequired = "required" // Only certificate-bound tokens are accepted
+
+	// Client certificate header formats
+	ClientCertFormatXFCC = "xfcc" // Envoy x-forwarded-client-cert
+	ClientCertFormatPEM  = "pem"  // PEM, URL-encoded PEM or base64 DER
+
+	// Metadata keys for the forwarded client certificate
+	MetadataKeyClientCertSubject    = "auth.clientCert.subject"
+	MetadataKeyClientCertSANs       = "auth.clientCert.sans"
+	MetadataKeyClientCertThumbprint = "auth.clientCert.thumbprint"
+
+	x5tS256Member = "x5t#S256"
+)
+
+// ClientCertificate holds the details of the client certificate forwarded by the proxy
+type ClientCertificate struct {
+	Thumbprint string   // base64url SHA-256 of the DER certificate (RFC 8705 x5t#S256)
+	Subject    string   // Subject distinguished name
+	SANs       []string // DNS, URI, email and IP subject alternative names
+}
+
+// validateCertificateBinding enforces the RFC 8705 binding between the token and the forwarded client
+// certificate, and exposes the certificate details as metadata
+func (p *JwtAuthPolicy) validateCertificateBinding(ctx *policy.RequestContext, claims jwt.MapClaims, mode, headerName, format string) *authFailure {
+	boundThumbprint := confirmationClaim(claims, x5tS256Member)
+	if boundThumbprint == "" && mode == CertBindingRequired {
+		return &authFailure{
+			code:        BearerErrorInvalidToken,
+			description: "The access token is not certificate-bound",
+			reason:      "token has no cnf.x5t#S256 claim",
+		}
+	}
+
+	var cert *ClientCertificate
+	if values := ctx.Headers.Get(strings.ToLower(headerName)); len(values) > 0 {
+		var err error
+		cert, err = parseClientCertificateHeader(values[0], format)
+		if err != nil {
+			slog.Debug("JWT Auth Policy: Failed to parse client certificate header",
+				"header", headerName,
+				"error", err,
+			)
+		}
+	}
+
+	if cert != nil {
+		ctx.Metadata[MetadataKeyClientCertThumbprint] = cert.Thumbprint
+		ctx.Metadata[MetadataKeyClientCertSubject] = cert.Subject
+		ctx.Metadata[MetadataKeyClientCertSANs] = cert.SANs
+	}
+
+	if boundThumbprint == "" {
+		slog.Debug("JWT Auth Policy: Token is not certificate-bound, skipping binding check")
+		return nil
+	}
+	if cert == nil {
+		return &authFailure{
+			code:        BearerErrorInvalidToken,
+			description: "The access token is bound to a client certificate that was not presented",
+			reason:      "no client certificate forwarded for certificate-bound token",
+		}
+	}
+	if cert.Thumbprint != boundThumbprint {
+		return &authFailure{
+			code:        BearerErrorInvalidToken,
+			description: "The access token is not bound to the presented client certificate",
+			reason:      "client certificate thumbprint does not match cnf.x5t#S256",
+		}
+	}
+
+	slog.Debug("JWT Auth Policy: Certificate binding validated",
+		"subject", cert.Subject,
+	)
+	return nil
+}
+
+// parseClientCertificateHeader parses the client certificate forwarded in the given format
+func parseClientCertificateHeader(value, format string) (*ClientCertificate, error) {
+	if format == ClientCertFormatPEM {
+		der, err := decodeForwardedCertificate(value)
+		if err != nil {
+			return nil, err
+		}
+		return clientCertificateFromDER(der)
+	}
+	return parseXFCC(value)
+}
+
+// parseXFCC parses an Envoy x-forwarded-client-cert header. When several proxies appended elements, the
+// first element (added by the proxy that terminated the client connection) is used. The certificate is
+// taken from the Cert field, falling back to the Hash, Subject, URI and DNS fields when Cert is absent.
+func parseXFCC(value string) (*ClientCertificate, error) {
+	elements := splitXFCC(value, ',')
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("empty x-forwarded-client-cert header")
+	}
+
+	fields := make(map[string][]string)
+	for _, pair := range splitXFCC(elements[0], ';') {
+		key, val, found := strings.Cut(pair, "=")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+		if len(val) >= 2 && strings.HasPrefix(val, `"`) && strings.HasSuffix(val, `"`) {
+			val = strings.ReplaceAll(val[1:len(val)-1], `\"`, `"`)
+		}
+		fields[key] = append(fields[key], val)
+	}
+
+	if certs := fields["cert"]; len(certs) > 0 {
+		der, err := decodeForwardedCertificate(certs[0])
+		if err != nil {
+			return nil, err
+		}
+		return clientCertificateFromDER(der)
+	}
+
+	hashes := fields["hash"]
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("x-forwarded-client-cert has neither Cert nor Hash")
+	}
+	sum, err := hex.DecodeString(hashes[0])
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("invalid Hash in x-forwarded-client-cert")
+	}
+	cert := &ClientCertificate{Thumbprint: base64.RawURLEncoding.EncodeToString(sum)}
+	if subjects := fields["subject"]; len(subjects) > 0 {
+		cert.Subject = subjects[0]
+	}
+	cert.SANs = append(cert.SANs, fields["dns"]...)
+	cert.SANs = append(cert.SANs, fields["uri"]...)
+	return cert, nil
+}
+
+// splitXFCC splits s on sep, ignoring separators inside double-quoted values
+func splitXFCC(s string, sep byte) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && inQuotes:
+			i++
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			if part := strings.TrimSpace(s[start:i]); part != "" {
+				parts = append(parts, part)
+			}
+			start = i + 1
+		}
+	}
+	if part := strings.TrimSpace(s[start:]); part != "" {
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// decodeForwardedCertificate returns the DER bytes of a certificate forwarded as PEM, URL-encoded PEM
+// or base64-encoded DER
+func decodeForwardedCertificate(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "%") {
+		unescaped, err := url.PathUnescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to URL-decode certificate: %w", err)
+		}
+		value = unescaped
+	}
+
+	if block, _ := pem.Decode([]byte(value)); block != nil {
+		return block.Bytes, nil
+	}
+	// Some proxies replace the PEM line breaks with spaces
+	if strings.HasPrefix(value, "-----BEGIN CERTIFICATE-----") {
+		body := strings.TrimPrefix(value, "-----BEGIN CERTIFICATE-----")
+		body = strings.TrimSuffix(strings.TrimSpace(body), "-----END CERTIFICATE-----")
+		value = strings.Join(strings.Fields(body), "")
+	}
+	der, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("certificate is neither PEM nor base64 DER")
+	}
+	return der, nil
+}
+
+// clientCertificateFromDER parses the certificate and computes its x5t#S256 thumbprint
+func clientCertificateFromDER(der []byte) (*ClientCertificate, error) {
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	sum := sha256.Sum256(der)
+	cert := &ClientCertificate{
+		Thumbprint: base64.RawURLEncoding.EncodeToString(sum[:]),
+		Subject:    parsed.Subject.String(),
+	}
+	cert.SANs = append(cert.SANs, parsed.DNSNames...)
+	for _, uri := range parsed.URIs {
+		cert.SANs = append(cert.SANs, uri.String())
+	}
+	cert.SANs = append(cert.SANs, parsed.EmailAddresses...)
+	for _, ip := range parsed.IPAddresses {
+		cert.SANs = append(cert.SANs, ip.String())
+	}
+	return cert, nil
+}
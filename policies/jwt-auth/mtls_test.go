@@ -0,0 +1,181 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+// TestParseClientCertificateHeader tests XFCC and PEM client certificate parsing
+func TestParseClientCertificateHeader(t *testing.T) {
+	der := createTestClientCertificate(t)
+	sum := sha256.Sum256(der)
+	thumbprint := base64.RawURLEncoding.EncodeToString(sum[:])
+	pemCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	escaped := url.PathEscape(pemCert)
+
+	tests := []struct {
+		name   string
+		value  string
+		format string
+	}{
+		{"xfcc cert", `By=spiffe://gateway;Hash=` + hex.EncodeToString(sum[:]) + `;Cert="` + escaped + `";Subject="CN=client-app,O=Example"`, ClientCertFormatXFCC},
+		{"xfcc first element", `Cert="` + escaped + `",By=spiffe://mesh;Hash=00`, ClientCertFormatXFCC},
+		{"xfcc hash only", `Hash=` + hex.EncodeToString(sum[:]) + `;Subject="CN=client-app,O=Example";DNS=client.example.com`, ClientCertFormatXFCC},
+		{"raw pem", pemCert, ClientCertFormatPEM},
+		{"url-encoded pem", escaped, ClientCertFormatPEM},
+		{"pem with spaces", strings.ReplaceAll(strings.TrimSpace(pemCert), "\n", " "), ClientCertFormatPEM},
+		{"base64 der", base64.StdEncoding.EncodeToString(der), ClientCertFormatPEM},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := parseClientCertificateHeader(tt.value, tt.format)
+			if err != nil {
+				t.Fatalf("Failed to parse certificate: %v", err)
+			}
+			if cert.Thumbprint != thumbprint {
+				t.Errorf("Expected thumbprint %q, got %q", thumbprint, cert.Thumbprint)
+			}
+			if cert.Subject != "CN=client-app,O=Example" {
+				t.Errorf("Unexpected subject %q", cert.Subject)
+			}
+			if len(cert.SANs) == 0 || cert.SANs[0] != "client.example.com" {
+				t.Errorf("Unexpected SANs %v", cert.SANs)
+			}
+		})
+	}
+
+	if _, err := parseClientCertificateHeader("By=spiffe://gateway", ClientCertFormatXFCC); err == nil {
+		t.Errorf("Expected XFCC without Cert or Hash to be rejected")
+	}
+}
+
+// TestJWTAuthPolicy_CertificateBinding tests enforcement of cnf.x5t#S256
+func TestJWTAuthPolicy_CertificateBinding(t *testing.T) {
+	privateKey, publicKey := generateTestKeys(t)
+	jwksServer := createJWKSServer(t, publicKey, "test-kid")
+	defer jwksServer.Close()
+
+	der := createTestClientCertificate(t)
+	sum := sha256.Sum256(der)
+	xfcc := `Hash=` + hex.EncodeToString(sum[:]) + `;Cert="` + url.PathEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))) + `"`
+	otherSum := sha256.Sum256(createTestClientCertificate(t))
+
+	boundToken := createTestToken(t, privateKey, map[string]interface{}{
+		"sub": "user123",
+		"cnf": map[string]interface{}{"x5t#S256": base64.RawURLEncoding.EncodeToString(sum[:])},
+	})
+	unboundToken := createTestToken(t, privateKey, map[string]interface{}{"sub": "user123"})
+
+	params := map[string]interface{}{
+		"allowedAlgorithms": []interface{}{"RS256"},
+		"validateIssuer":    false,
+		"keyManagers": []interface{}{
+			map[string]interface{}{
+				"name": "test-issuer",
+				"jwks": map[string]interface{}{
+					"remote": map[string]interface{}{"uri": jwksServer.URL + "/jwks.json"},
+				},
+			},
+		},
+		"certificateBinding": "optional",
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("GetPolicy failed: %v", err)
+	}
+
+	send := func(token, clientCert string) (*policy.RequestContext, policy.RequestAction) {
+		headers := map[string][]string{"authorization": {"Bearer " + token}}
+		if clientCert != "" {
+			headers["x-forwarded-client-cert"] = []string{clientCert}
+		}
+		ctx := createMockRequestContext(headers)
+		return ctx, p.OnRequest(ctx, params)
+	}
+
+	ctx, action := send(boundToken, xfcc)
+	if _, ok := action.(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected bound token with matching certificate to be accepted")
+	}
+	if ctx.Metadata[MetadataKeyClientCertSubject] != "CN=client-app,O=Example" {
+		t.Errorf("Unexpected subject metadata %v", ctx.Metadata[MetadataKeyClientCertSubject])
+	}
+	if sans, _ := ctx.Metadata[MetadataKeyClientCertSANs].([]string); len(sans) != 2 {
+		t.Errorf("Unexpected SAN metadata %v", ctx.Metadata[MetadataKeyClientCertSANs])
+	}
+
+	if _, action := send(boundToken, "Hash="+hex.EncodeToString(otherSum[:])); !isImmediateResponse(action) {
+		t.Errorf("Expected bound token with another certificate to be rejected")
+	}
+	if _, action := send(boundToken, ""); !isImmediateResponse(action) {
+		t.Errorf("Expected bound token without a certificate to be rejected")
+	}
+	if _, action := send(unboundToken, ""); isImmediateResponse(action) {
+		t.Errorf("Expected unbound token to be accepted in optional mode")
+	}
+
+	params["certificateBinding"] = "required"
+	if _, action := send(unboundToken, xfcc); !isImmediateResponse(action) {
+		t.Errorf("Expected unbound token to be rejected in required mode")
+	}
+}
+
+func isImmediateResponse(action policy.RequestAction) bool {
+	_, ok := action.(policy.ImmediateResponse)
+	return ok
+}
+
+func createTestClientCertificate(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate certificate key: %v", err)
+	}
+	clientURI, _ := url.Parse("spiffe://example.com/client-app")
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "client-app", Organization: []string{"Example"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"client.example.com"},
+		URIs:         []*url.URL{clientURI},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return der
+}
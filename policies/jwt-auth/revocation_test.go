@@ -0,0 +1,163 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+// TestMemoryRevocationStore_FileReload tests that the denylist file is reloaded when it changes
+func TestMemoryRevocationStore_FileReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "revoked.txt")
+	if err := os.WriteFile(file, []byte("# revoked tokens\njti token-1\nsub user-1 2000-01-01T00:00:00Z\n"), 0600); err != nil {
+		t.Fatalf("Failed to write revocation file: %v", err)
+	}
+
+	store := newMemoryRevocationStore(file, 0)
+	ctx := context.Background()
+
+	if revoked, _ := store.IsRevoked(ctx, RevocationTypeJTI, "token-1"); !revoked {
+		t.Errorf("Expected jti 'token-1' to be revoked")
+	}
+	if revoked, _ := store.IsRevoked(ctx, RevocationTypeSub, "user-1"); revoked {
+		t.Errorf("Expected expired entry for sub 'user-1' to be ignored")
+	}
+
+	if err := os.WriteFile(file, []byte("sid session-9\n"), 0600); err != nil {
+		t.Fatalf("Failed to write revocation file: %v", err)
+	}
+	// Make sure the change is detected even on filesystems with coarse timestamps
+	if err := os.Chtimes(file, time.Now(), time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("Failed to update revocation file time: %v", err)
+	}
+
+	if revoked, _ := store.IsRevoked(ctx, RevocationTypeJTI, "token-1"); revoked {
+		t.Errorf("Expected jti 'token-1' to be dropped after reload")
+	}
+	if revoked, _ := store.IsRevoked(ctx, RevocationTypeSID, "session-9"); !revoked {
+		t.Errorf("Expected sid 'session-9' to be revoked after reload")
+	}
+
+	// A malformed file keeps the previous entries
+	if err := os.WriteFile(file, []byte("session-9\n"), 0600); err != nil {
+		t.Fatalf("Failed to write revocation file: %v", err)
+	}
+	if err := os.Chtimes(file, time.Now(), time.Now().Add(2*time.Second)); err != nil {
+		t.Fatalf("Failed to update revocation file time: %v", err)
+	}
+	if revoked, _ := store.IsRevoked(ctx, RevocationTypeSID, "session-9"); !revoked {
+		t.Errorf("Expected previous entries to be kept when the file is malformed")
+	}
+}
+
+// TestJWTAuthPolicy_Revocation tests admin intake, denylist checks and one-time jti use
+func TestJWTAuthPolicy_Revocation(t *testing.T) {
+	privateKey, publicKey := generateTestKeys(t)
+	jwksServer := createJWKSServer(t, publicKey, "test-kid")
+	defer jwksServer.Close()
+
+	params := map[string]interface{}{
+		"allowedAlgorithms": []interface{}{"RS256"},
+		"validateIssuer":    false,
+		"keyManagers": []interface{}{
+			map[string]interface{}{
+				"name": "test-issuer",
+				"jwks": map[string]interface{}{
+					"remote": map[string]interface{}{"uri": jwksServer.URL + "/jwks.json"},
+				},
+			},
+		},
+		"revocation": map[string]interface{}{
+			"adminPath": "/admin/revocations",
+		},
+		"revocationChecks": []interface{}{"jti", "sub"},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("GetPolicy failed: %v", err)
+	}
+
+	send := func(token, method, path string) policy.RequestAction {
+		ctx := createMockRequestContext(map[string][]string{
+			"authorization": {fmt.Sprintf("Bearer %s", token)},
+		})
+		ctx.Method = method
+		ctx.Path = path
+		return p.OnRequest(ctx, params)
+	}
+
+	userToken := createTestToken(t, privateKey, map[string]interface{}{
+		"sub": "revocation-user",
+		"jti": "revocation-jti-1",
+	})
+	if _, ok := send(userToken, "GET", "/api/test").(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected token to be accepted before revocation")
+	}
+
+	// An admin token without the admin scope cannot submit revocations
+	adminClaims := func(scope string) map[string]interface{} {
+		return map[string]interface{}{
+			"sub":   "admin",
+			"scope": scope,
+			"revocations": []interface{}{
+				map[string]interface{}{"type": "sub", "value": "revocation-user"},
+			},
+		}
+	}
+	response, ok := send(createTestToken(t, privateKey, adminClaims("read")), "POST", "/admin/revocations").(policy.ImmediateResponse)
+	if !ok || response.StatusCode != 403 {
+		t.Fatalf("Expected 403 for admin token without scope, got %#v", response)
+	}
+
+	response, ok = send(createTestToken(t, privateKey, adminClaims("jwt:revoke")), "POST", "/admin/revocations").(policy.ImmediateResponse)
+	if !ok || response.StatusCode != 200 {
+		t.Fatalf("Expected revocation to be accepted, got %#v", response)
+	}
+
+	response, ok = send(userToken, "GET", "/api/test").(policy.ImmediateResponse)
+	if !ok || response.StatusCode != 401 {
+		t.Fatalf("Expected revoked subject to be rejected, got %#v", response)
+	}
+
+	// One-time use rejects the replayed jti
+	params["revocationChecks"] = []interface{}{}
+	params["oneTimeUseJti"] = true
+	oneTimeToken := createTestToken(t, privateKey, map[string]interface{}{
+		"sub": "one-time-user",
+		"jti": "one-time-jti-1",
+	})
+	if _, ok := send(oneTimeToken, "GET", "/api/test").(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected first use of one-time token to succeed")
+	}
+	if _, ok := send(oneTimeToken, "GET", "/api/test").(policy.ImmediateResponse); !ok {
+		t.Fatalf("Expected replayed one-time token to be rejected")
+	}
+
+	noJtiToken := createTestToken(t, privateKey, map[string]interface{}{"sub": "one-time-user"})
+	if _, ok := send(noJtiToken, "GET", "/api/test").(policy.ImmediateResponse); !ok {
+		t.Fatalf("Expected token without jti to be rejected in one-time mode")
+	}
+}
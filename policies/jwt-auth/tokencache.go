@@ -0,0 +1,226 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// validatedToken is a token whose signature and time claims have already been verified
+type validatedToken struct {
+	key            string
+	claims         jwt.MapClaims
+	jwksURI        string    // Remote JWKS that verified the token (empty for local certificates)
+	jwksGeneration uint64    // Generation of that JWKS when the token was verified
+	expiresAt      time.Time // Entry is not used after this time
+}
+
+// tokenCache is a bounded LRU cache of validated tokens keyed by token hash
+type tokenCache struct {
+	mu       sync.Mutex
+	capacity int
+	sized    bool // capacity was set by the first caller with the cache enabled
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// size sets the maximum number of entries on first use. The policy instance is shared by routes
+// and by policies delegating to it, so later callers with a different size keep the first one
+// rather than resizing the cache back and forth.
+func (c *tokenCache) size(capacity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sized {
+		if capacity != c.capacity {
+			slog.Debug("JWT Auth Policy: Validated token cache already sized, ignoring different tokenCacheSize",
+				"capacity", c.capacity,
+				"requested", capacity,
+			)
+		}
+		return
+	}
+	c.capacity = capacity
+	c.sized = true
+}
+
+// get returns the entry for key if it has not expired and marks it as recently used
+func (c *tokenCache) get(key string, now time.Time) (*validatedToken, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*validatedToken)
+	if !now.Before(entry.expiresAt) {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry, true
+}
+
+// put adds or replaces an entry
+func (c *tokenCache) put(entry *validatedToken) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.capacity <= 0 {
+		return
+	}
+	if elem, ok := c.items[entry.key]; ok {
+		elem.Value = entry
+		c.ll.MoveToFront(elem)
+		return
+	}
+	c.items[entry.key] = c.ll.PushFront(entry)
+	c.evict()
+}
+
+// remove deletes the entry for key
+func (c *tokenCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+// evict drops least recently used entries until the cache fits its capacity. Callers hold mu.
+func (c *tokenCache) evict() {
+	for c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*validatedToken).key)
+	}
+}
+
+// tokenCacheKey hashes the raw token together with the settings that decide which key managers may
+// verify it, so a token validated for one route is not reused for a route with different issuers
+func tokenCacheKey(token string, keyManagers map[string]*KeyManager, userIssuers []string, validateIssuer bool, allowedAlgorithms []string) string {
+	names := make([]string, 0, len(keyManagers))
+	for name, km := range keyManagers {
+		names = append(names, name+"="+km.Issuer)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	h.Write([]byte(token))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(names, ",")))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(userIssuers, ",")))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatBool(validateIssuer)))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.Join(allowedAlgorithms, ",")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// lookupValidatedToken returns cached claims for the token if the entry is still valid and the
+// JWKS that verified it has not changed since
+func (p *JwtAuthPolicy) lookupValidatedToken(key string) (jwt.MapClaims, bool) {
+	entry, ok := p.tokenCache.get(key, time.Now())
+	if !ok {
+		return nil, false
+	}
+	if entry.jwksURI != "" && p.jwksGeneration(entry.jwksURI) != entry.jwksGeneration {
+		p.tokenCache.remove(key)
+		return nil, false
+	}
+	return entry.claims, true
+}
+
+// storeValidatedToken caches validated claims until the token's exp (minus leeway) or maxTTL, whichever is earlier.
+// generations is the JWKS generation snapshot taken before the token was verified.
+func (p *JwtAuthPolicy) storeValidatedToken(key string, claims jwt.MapClaims, km *KeyManager, generations map[string]uint64, leeway, maxTTL time.Duration) {
+	now := time.Now()
+	expiresAt := now.Add(maxTTL)
+	if exp, ok := claims["exp"].(float64); ok {
+		if expTime := time.Unix(int64(exp), 0).Add(-leeway); expTime.Before(expiresAt) {
+			expiresAt = expTime
+		}
+	}
+	if !now.Before(expiresAt) {
+		return
+	}
+
+	entry := &validatedToken{
+		key:       key,
+		claims:    claims,
+		expiresAt: expiresAt,
+	}
+	// Tokens verified with a local certificate do not depend on a JWKS that can change
+	if km != nil && km.JWKS != nil && km.JWKS.Remote != nil && (km.JWKS.Local == nil || km.JWKS.Local.PublicKey == nil) {
+		entry.jwksURI = km.JWKS.Remote.URI
+		entry.jwksGeneration = generations[entry.jwksURI]
+	}
+	p.tokenCache.put(entry)
+}
+
+// jwksGeneration returns the number of times the key set cached for uri has changed
+func (p *JwtAuthPolicy) jwksGeneration(uri string) uint64 {
+	p.cacheMutex.RLock()
+	defer p.cacheMutex.RUnlock()
+	return p.jwksGenerations[uri]
+}
+
+// jwksGenerationSnapshot returns the current JWKS generation of every remote key manager
+func (p *JwtAuthPolicy) jwksGenerationSnapshot(keyManagers map[string]*KeyManager) map[string]uint64 {
+	p.cacheMutex.RLock()
+	defer p.cacheMutex.RUnlock()
+	generations := make(map[string]uint64, len(keyManagers))
+	for _, km := range keyManagers {
+		if km.JWKS != nil && km.JWKS.Remote != nil {
+			generations[km.JWKS.Remote.URI] = p.jwksGenerations[km.JWKS.Remote.URI]
+		}
+	}
+	return generations
+}
+
+// sameKeys reports whether two key sets contain the same key IDs and keys
+func sameKeys(a, b *CachedJWKS) bool {
+	if len(a.Keys) != len(b.Keys) {
+		return false
+	}
+	for kid, keyA := range a.Keys {
+		keyB, ok := b.Keys[kid]
+		if !ok || !keyA.Equal(keyB) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+// TestTokenCache_EvictsLeastRecentlyUsed tests the LRU bound of the validated-token cache
+func TestTokenCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newTokenCache()
+	cache.size(2)
+	expiry := time.Now().Add(time.Minute)
+
+	cache.put(&validatedToken{key: "a", expiresAt: expiry})
+	cache.put(&validatedToken{key: "b", expiresAt: expiry})
+	if _, ok := cache.get("a", time.Now()); !ok {
+		t.Fatalf("Expected entry 'a' to be cached")
+	}
+	cache.put(&validatedToken{key: "c", expiresAt: expiry})
+
+	if _, ok := cache.get("b", time.Now()); ok {
+		t.Errorf("Expected least recently used entry 'b' to be evicted")
+	}
+	if _, ok := cache.get("a", time.Now()); !ok {
+		t.Errorf("Expected entry 'a' to remain cached")
+	}
+	if _, ok := cache.get("c", time.Now()); !ok {
+		t.Errorf("Expected entry 'c' to be cached")
+	}
+	if _, ok := cache.get("c", expiry); ok {
+		t.Errorf("Expected entry 'c' to expire")
+	}
+}
+
+// TestTokenCache_SizedOnce tests that callers with a different size do not resize the cache
+func TestTokenCache_SizedOnce(t *testing.T) {
+	cache := newTokenCache()
+	cache.size(2)
+	expiry := time.Now().Add(time.Minute)
+
+	cache.put(&validatedToken{key: "a", expiresAt: expiry})
+	cache.put(&validatedToken{key: "b", expiresAt: expiry})
+	cache.size(1)
+	cache.put(&validatedToken{key: "c", expiresAt: expiry})
+
+	if _, ok := cache.get("b", time.Now()); !ok {
+		t.Errorf("Expected entry 'b' to remain cached after a different size was requested")
+	}
+	if _, ok := cache.get("c", time.Now()); !ok {
+		t.Errorf("Expected entry 'c' to be cached")
+	}
+}
+
+// TestJWTAuthPolicy_TokenCache_RouteChecksStillApply tests that audience checks run on cached tokens
+func TestJWTAuthPolicy_TokenCache_RouteChecksStillApply(t *testing.T) {
+	privateKey, publicKey := generateTestKeys(t)
+	jwksServer := createJWKSServer(t, publicKey, "test-kid")
+	defer jwksServer.Close()
+
+	token := createTestToken(t, privateKey, map[string]interface{}{
+		"sub": "user123",
+		"aud": "orders-api",
+	})
+
+	params := map[string]interface{}{
+		"allowedAlgorithms": []interface{}{"RS256"},
+		"validateIssuer":    false,
+		"tokenCacheSize":    100,
+		"keyManagers": []interface{}{
+			map[string]interface{}{
+				"name": "test-issuer",
+				"jwks": map[string]interface{}{
+					"remote": map[string]interface{}{"uri": jwksServer.URL + "/jwks.json"},
+				},
+			},
+		},
+		"audiences": []interface{}{"orders-api"},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("GetPolicy failed: %v", err)
+	}
+
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", token)},
+	})
+	if _, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected first request to succeed")
+	}
+
+	params["audiences"] = []interface{}{"payments-api"}
+	ctx = createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", token)},
+	})
+	if _, ok := p.OnRequest(ctx, params).(policy.ImmediateResponse); !ok {
+		t.Fatalf("Expected cached token to fail audience validation")
+	}
+}
+
+// TestJWTAuthPolicy_TokenCache_InvalidatedOnJWKSChange tests that cached tokens are re-verified after key rotation
+func TestJWTAuthPolicy_TokenCache_InvalidatedOnJWKSChange(t *testing.T) {
+	oldPrivateKey, oldPublicKey := generateTestKeys(t)
+	newPrivateKey, newPublicKey := generateTestKeys(t)
+	jwksServer, keySet := createRotatingJWKSServer(t)
+	defer jwksServer.Close()
+	keySet.set(oldPublicKey, "old-kid")
+
+	params := map[string]interface{}{
+		"allowedAlgorithms": []interface{}{"RS256"},
+		"validateIssuer":    false,
+		"tokenCacheSize":    100,
+		"keyManagers": []interface{}{
+			map[string]interface{}{
+				"name": "test-issuer",
+				"jwks": map[string]interface{}{
+					"remote": map[string]interface{}{"uri": jwksServer.URL + "/jwks.json"},
+				},
+			},
+		},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("GetPolicy failed: %v", err)
+	}
+
+	oldToken := createTestTokenWithKid(t, oldPrivateKey, "old-kid", map[string]interface{}{"sub": "user123"})
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", oldToken)},
+	})
+	if _, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected request with old key to succeed")
+	}
+
+	// Rotate the key and let a token with the new kid refresh the cached JWKS
+	keySet.set(newPublicKey, "new-kid")
+	newToken := createTestTokenWithKid(t, newPrivateKey, "new-kid", map[string]interface{}{"sub": "user123"})
+	ctx = createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", newToken)},
+	})
+	if _, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("Expected request with new key to succeed")
+	}
+
+	// The old key is no longer published, so the cached old token must not be accepted
+	ctx = createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", oldToken)},
+	})
+	if _, ok := p.OnRequest(ctx, params).(policy.ImmediateResponse); !ok {
+		t.Fatalf("Expected cached token to be re-verified and rejected after key rotation")
+	}
+}
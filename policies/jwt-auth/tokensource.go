@@ -0,0 +1,229 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+const (
+	// Token source types for tokenSources
+	TokenSourceHeader = "header"
+	TokenSourceCookie = "cookie"
+	TokenSourceQuery  = "query"
+
+	// tokenMissingReason is the failure reason when no configured source carries a token
+	tokenMissingReason = "missing authorization header"
+)
+
+// TokenSource is a location in the request that may carry the access token
+type TokenSource struct {
+	Type   string // header, cookie or query
+	Name   string // Header, cookie or query parameter name
+	Scheme string // Authorization scheme prefix, used by header sources only
+
+	// AllowDPoPScheme also accepts the DPoP authorization scheme (header sources only)
+	AllowDPoPScheme bool
+}
+
+// extractTokenFromSources tries each source in order and returns the first token found along
+// with the source it came from. When no token is found, reason describes why.
+func extractTokenFromSources(ctx *policy.RequestContext, sources []TokenSource) (string, *TokenSource, string) {
+	reason := tokenMissingReason
+	for i := range sources {
+		source := &sources[i]
+		value, present := lookupTokenSource(ctx, source)
+		if !present {
+			slog.Debug("JWT Auth Policy: Token source not present",
+				"type", source.Type,
+				"name", source.Name,
+			)
+			continue
+		}
+
+		token := value
+		if source.Type == TokenSourceHeader {
+			token = extractToken(value, source.Scheme)
+			if token == "" && source.AllowDPoPScheme {
+				token = extractToken(value, DPoPScheme)
+			}
+		}
+		token = strings.TrimSpace(token)
+		if token == "" {
+			slog.Debug("JWT Auth Policy: Failed to extract token from source",
+				"type", source.Type,
+				"name", source.Name,
+				"scheme", source.Scheme,
+			)
+			reason = "invalid authorization header format"
+			continue
+		}
+
+		slog.Debug("JWT Auth Policy: Token found in source",
+			"type", source.Type,
+			"name", source.Name,
+		)
+		return token, source, ""
+	}
+	return "", nil, reason
+}
+
+// sentWithDPoPScheme reports whether the token was sent in a header with the DPoP authorization scheme
+func sentWithDPoPScheme(ctx *policy.RequestContext, source *TokenSource) bool {
+	if source == nil || source.Type != TokenSourceHeader || !source.AllowDPoPScheme {
+		return false
+	}
+	value, _ := lookupTokenSource(ctx, source)
+	return extractToken(value, DPoPScheme) != ""
+}
+
+// lookupTokenSource returns the raw value of the source and whether it is present in the request
+func lookupTokenSource(ctx *policy.RequestContext, source *TokenSource) (string, bool) {
+	switch source.Type {
+	case TokenSourceHeader:
+		values := ctx.Headers.Get(strings.ToLower(source.Name))
+		if len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
+	case TokenSourceCookie:
+		for _, cookie := range requestCookies(ctx) {
+			if cookie.Name == source.Name {
+				return cookie.Value, true
+			}
+		}
+		return "", false
+	case TokenSourceQuery:
+		return extractQueryParam(ctx.Path, source.Name)
+	default:
+		return "", false
+	}
+}
+
+// requestCookies parses all cookies sent with the request
+func requestCookies(ctx *policy.RequestContext) []*http.Cookie {
+	var cookies []*http.Cookie
+	for _, line := range ctx.Headers.Get("cookie") {
+		parsed, err := http.ParseCookie(line)
+		if err != nil {
+			slog.Debug("JWT Auth Policy: Failed to parse cookie header", "error", err)
+			continue
+		}
+		cookies = append(cookies, parsed...)
+	}
+	return cookies
+}
+
+// extractQueryParam extracts the value of the given parameter from the query string of path
+func extractQueryParam(path, param string) (string, bool) {
+	_, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return "", false
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		slog.Debug("JWT Auth Policy: Failed to parse query string", "error", err)
+		return "", false
+	}
+	if !values.Has(param) {
+		return "", false
+	}
+	return values.Get(param), true
+}
+
+// stripTokenSource removes the source that carried the token from the request forwarded upstream
+func stripTokenSource(ctx *policy.RequestContext, source *TokenSource, modifications *policy.UpstreamRequestModifications) {
+	switch source.Type {
+	case TokenSourceHeader:
+		modifications.RemoveHeaders = append(modifications.RemoveHeaders, strings.ToLower(source.Name))
+	case TokenSourceQuery:
+		modifications.RemoveQueryParameters = append(modifications.RemoveQueryParameters, source.Name)
+	case TokenSourceCookie:
+		// Rewrite the cookie header with every other cookie so session state still reaches upstream
+		var remaining []string
+		for _, cookie := range requestCookies(ctx) {
+			if cookie.Name != source.Name {
+				remaining = append(remaining, cookie.Name+"="+cookie.Value)
+			}
+		}
+		if len(remaining) == 0 {
+			modifications.RemoveHeaders = append(modifications.RemoveHeaders, "cookie")
+			return
+		}
+		if modifications.SetHeaders == nil {
+			modifications.SetHeaders = make(map[string]string)
+		}
+		modifications.SetHeaders["cookie"] = strings.Join(remaining, "; ")
+	}
+	slog.Debug("JWT Auth Policy: Stripped token from forwarded request",
+		"type", source.Type,
+		"name", source.Name,
+	)
+}
+
+// getTokenSourcesParam parses the ordered tokenSources parameter. When it is not configured, the
+// token is read from headerName using scheme. Header sources without a scheme also use scheme.
+func getTokenSourcesParam(params map[string]interface{}, key, headerName, scheme string) []TokenSource {
+	defaultSources := []TokenSource{{Type: TokenSourceHeader, Name: headerName, Scheme: scheme}}
+
+	raw, ok := params[key].([]interface{})
+	if !ok || len(raw) == 0 {
+		return defaultSources
+	}
+
+	sources := make([]TokenSource, 0, len(raw))
+	for _, item := range raw {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		source := TokenSource{
+			Type: strings.ToLower(getStringParam(entry, "type", TokenSourceHeader)),
+			Name: getStringParam(entry, "name", ""),
+		}
+		if source.Name == "" {
+			slog.Debug("JWT Auth Policy: Skipping token source without a name", "type", source.Type)
+			continue
+		}
+		switch source.Type {
+		case TokenSourceHeader:
+			source.Scheme = scheme
+			if s, exists := entry["scheme"].(string); exists {
+				source.Scheme = s
+			}
+		case TokenSourceCookie, TokenSourceQuery:
+		default:
+			slog.Debug("JWT Auth Policy: Skipping token source with unknown type",
+				"type", source.Type,
+				"name", source.Name,
+			)
+			continue
+		}
+		sources = append(sources, source)
+	}
+
+	if len(sources) == 0 {
+		return defaultSources
+	}
+	return sources
+}
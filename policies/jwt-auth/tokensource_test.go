@@ -0,0 +1,175 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package jwtauth
+
+import (
+	"fmt"
+	"testing"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+// TestExtractTokenFromSources tests ordered fallback across header, cookie and query sources
+func TestExtractTokenFromSources(t *testing.T) {
+	sources := []TokenSource{
+		{Type: TokenSourceHeader, Name: "Authorization", Scheme: "Bearer"},
+		{Type: TokenSourceCookie, Name: "access_token"},
+		{Type: TokenSourceQuery, Name: "access_token"},
+	}
+
+	tests := []struct {
+		name       string
+		headers    map[string][]string
+		path       string
+		wantToken  string
+		wantSource string
+		wantReason string
+	}{
+		{
+			name:       "header takes precedence",
+			headers:    map[string][]string{"authorization": {"Bearer header-token"}, "cookie": {"access_token=cookie-token"}},
+			path:       "/api/test?access_token=query-token",
+			wantToken:  "header-token",
+			wantSource: TokenSourceHeader,
+		},
+		{
+			name:       "falls back to cookie",
+			headers:    map[string][]string{"cookie": {"session=abc; access_token=cookie-token"}},
+			path:       "/api/test",
+			wantToken:  "cookie-token",
+			wantSource: TokenSourceCookie,
+		},
+		{
+			name:       "falls back past malformed header to query",
+			headers:    map[string][]string{"authorization": {"Basic dXNlcjpwYXNz"}},
+			path:       "/api/test?access_token=query-token",
+			wantToken:  "query-token",
+			wantSource: TokenSourceQuery,
+		},
+		{
+			name:       "no source present",
+			headers:    map[string][]string{},
+			path:       "/api/test",
+			wantReason: "missing authorization header",
+		},
+		{
+			name:       "only malformed source present",
+			headers:    map[string][]string{"authorization": {"Basic dXNlcjpwYXNz"}},
+			path:       "/api/test",
+			wantReason: "invalid authorization header format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createMockRequestContext(tt.headers)
+			ctx.Path = tt.path
+			token, source, reason := extractTokenFromSources(ctx, sources)
+			if token != tt.wantToken {
+				t.Errorf("Expected token %q, got %q", tt.wantToken, token)
+			}
+			if tt.wantSource != "" && (source == nil || source.Type != tt.wantSource) {
+				t.Errorf("Expected source %q, got %+v", tt.wantSource, source)
+			}
+			if reason != tt.wantReason {
+				t.Errorf("Expected reason %q, got %q", tt.wantReason, reason)
+			}
+		})
+	}
+}
+
+// TestJWTAuthPolicy_TokenSources_StripToken tests that the token is removed from the forwarded request
+func TestJWTAuthPolicy_TokenSources_StripToken(t *testing.T) {
+	privateKey, publicKey := generateTestKeys(t)
+	jwksServer := createJWKSServer(t, publicKey, "test-kid")
+	defer jwksServer.Close()
+
+	token := createTestToken(t, privateKey, map[string]interface{}{"sub": "user123"})
+
+	params := map[string]interface{}{
+		"allowedAlgorithms": []interface{}{"RS256"},
+		"validateIssuer":    false,
+		"keyManagers": []interface{}{
+			map[string]interface{}{
+				"name": "test-issuer",
+				"jwks": map[string]interface{}{
+					"remote": map[string]interface{}{"uri": jwksServer.URL + "/jwks.json"},
+				},
+			},
+		},
+		"tokenSources": []interface{}{
+			map[string]interface{}{"type": "header", "name": "Authorization"},
+			map[string]interface{}{"type": "cookie", "name": "access_token"},
+			map[string]interface{}{"type": "query", "name": "access_token"},
+		},
+		"stripToken": true,
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("GetPolicy failed: %v", err)
+	}
+
+	// Header source
+	ctx := createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", token)},
+	})
+	mods, ok := p.OnRequest(ctx, params).(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected header token to be accepted")
+	}
+	if len(mods.RemoveHeaders) != 1 || mods.RemoveHeaders[0] != "authorization" {
+		t.Errorf("Expected authorization header to be removed, got %v", mods.RemoveHeaders)
+	}
+
+	// Cookie source keeps the other cookies
+	ctx = createMockRequestContext(map[string][]string{
+		"cookie": {fmt.Sprintf("session=abc; access_token=%s; theme=dark", token)},
+	})
+	mods, ok = p.OnRequest(ctx, params).(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected cookie token to be accepted")
+	}
+	if mods.SetHeaders["cookie"] != "session=abc; theme=dark" {
+		t.Errorf("Expected token cookie to be stripped, got %q", mods.SetHeaders["cookie"])
+	}
+
+	// Query source
+	ctx = createMockRequestContext(map[string][]string{})
+	ctx.Path = "/api/test?access_token=" + token
+	mods, ok = p.OnRequest(ctx, params).(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected query token to be accepted")
+	}
+	if len(mods.RemoveQueryParameters) != 1 || mods.RemoveQueryParameters[0] != "access_token" {
+		t.Errorf("Expected access_token query parameter to be removed, got %v", mods.RemoveQueryParameters)
+	}
+
+	// Without stripToken the request is forwarded unchanged
+	params["stripToken"] = false
+	ctx = createMockRequestContext(map[string][]string{
+		"authorization": {fmt.Sprintf("Bearer %s", token)},
+	})
+	mods, ok = p.OnRequest(ctx, params).(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected header token to be accepted")
+	}
+	if len(mods.RemoveHeaders) != 0 {
+		t.Errorf("Expected no headers to be removed, got %v", mods.RemoveHeaders)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"testing"
 
 	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/mcp-rewrite/jsonrpc"
 )
 
 func TestParseAclConfig_ExceptionsStringList(t *testing.T) {
@@ -239,6 +240,179 @@ func TestOnResponse_FilterList_ResourcesUri(t *testing.T) {
 	}
 }
 
+func TestOnRequest_Batch_ForwardsAllowedElements(t *testing.T) {
+	params := map[string]any{
+		"tools": map[string]any{
+			"mode":       "allow",
+			"exceptions": []any{"toolA"},
+		},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	body := []byte(`[
+		{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"toolA"}},
+		{"jsonrpc":"2.0","id":"2","method":"tools/call","params":{"name":"toolB"}},
+		{"jsonrpc":"2.0","id":3,"method":"tools/list"},
+		{"jsonrpc":"2.0","method":"tools/call","params":{"name":"toolA"}}
+	]`)
+
+	ctx := createMockRequestContext(map[string][]string{})
+	ctx.Body = &policy.Body{Content: body, Present: true}
+
+	action := p.OnRequest(ctx, params)
+	mods, ok := action.(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamRequestModifications, got %T", action)
+	}
+
+	var forwarded []map[string]any
+	if err := json.Unmarshal(mods.Body, &forwarded); err != nil {
+		t.Fatalf("Failed to unmarshal forwarded batch: %v", err)
+	}
+	if len(forwarded) != 2 || forwarded[0]["id"] != "2" || forwarded[1]["id"] != float64(3) {
+		t.Fatalf("Expected requests 2 and 3 to be forwarded, got %v", forwarded)
+	}
+
+	errorEntries, _ := ctx.Metadata[metadataAclBatchErrors].([]any)
+	if len(errorEntries) != 1 {
+		t.Fatalf("Expected 1 error entry, got %d", len(errorEntries))
+	}
+	entry := errorEntries[0].(map[string]any)
+	if entry["id"] != float64(1) || entry["error"].(map[string]any)["code"] != -32000 {
+		t.Fatalf("Expected a denial error for id 1, got %v", entry)
+	}
+	if _, ok := ctx.Metadata[metadataMcpAction]; ok {
+		t.Fatalf("Expected no single-request metadata for a batch")
+	}
+}
+
+func TestOnRequest_Batch_AllDenied(t *testing.T) {
+	params := map[string]any{
+		"prompts": map[string]any{
+			"mode": "deny",
+		},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	body := []byte(`[
+		{"jsonrpc":"2.0","id":1,"method":"prompts/get","params":{"name":"promptA"}},
+		{"jsonrpc":"2.0","id":2,"method":"prompts/get","params":{}}
+	]`)
+
+	ctx := createMockRequestContext(map[string][]string{mcpSessionHeader: {"session-1"}})
+	ctx.Body = &policy.Body{Content: body, Present: true}
+
+	action := p.OnRequest(ctx, params)
+	response, ok := action.(policy.ImmediateResponse)
+	if !ok {
+		t.Fatalf("Expected ImmediateResponse, got %T", action)
+	}
+	if response.Headers[mcpSessionHeader] != "session-1" {
+		t.Fatalf("Expected session header, got %v", response.Headers)
+	}
+
+	var errorEntries []map[string]any
+	if err := json.Unmarshal(response.Body, &errorEntries); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(errorEntries) != 2 {
+		t.Fatalf("Expected 2 error entries, got %d", len(errorEntries))
+	}
+	if code := errorEntries[1]["error"].(map[string]any)["code"]; code != float64(-32602) {
+		t.Fatalf("Expected invalid params error for id 2, got %v", code)
+	}
+}
+
+func TestOnResponse_Batch_FiltersListByRequestID(t *testing.T) {
+	params := map[string]any{
+		"tools": map[string]any{
+			"mode":       "deny",
+			"exceptions": []any{"toolB"},
+		},
+		"prompts": map[string]any{
+			"mode": "allow",
+		},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	// Responses to a batch may come back in any order
+	body := []byte(`[
+		{"jsonrpc":"2.0","id":"b","result":{"prompts":[{"name":"promptA"}]}},
+		{"jsonrpc":"2.0","id":"a","result":{"tools":[{"name":"toolA"},{"name":"toolB"}]}}
+	]`)
+
+	ctx := createMockResponseContext(nil, nil)
+	ctx.RequestMethod = "POST"
+	ctx.RequestPath = "/mcp"
+	ctx.ResponseStatus = 200
+	ctx.ResponseBody = &policy.Body{Content: body, Present: true}
+	ctx.Metadata[metadataMcpBatchMethods] = map[string]string{`"a"`: "tools/list", `"b"`: "prompts/list"}
+	ctx.Metadata[metadataAclBatchErrors] = []any{jsonrpc.ErrorEntry(float64(3), -32000, "MCP capability not allowed", nil)}
+
+	action := p.OnResponse(ctx, params)
+	mods, ok := action.(policy.UpstreamResponseModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamResponseModifications, got %T", action)
+	}
+
+	var updated []map[string]any
+	if err := json.Unmarshal(mods.Body, &updated); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(updated) != 3 {
+		t.Fatalf("Expected 3 responses, got %d", len(updated))
+	}
+	if prompts := updated[0]["result"].(map[string]any)["prompts"].([]any); len(prompts) != 1 {
+		t.Fatalf("Expected prompts to be unchanged, got %v", prompts)
+	}
+	tools := updated[1]["result"].(map[string]any)["tools"].([]any)
+	if len(tools) != 1 || tools[0].(map[string]any)["name"] != "toolB" {
+		t.Fatalf("Expected only toolB, got %v", tools)
+	}
+	if updated[2]["id"] != float64(3) {
+		t.Fatalf("Expected the denied request error to be appended, got %v", updated[2])
+	}
+}
+
+func TestOnResponse_Batch_NotificationsOnlyForwarded(t *testing.T) {
+	p, err := GetPolicy(policy.PolicyMetadata{}, map[string]any{})
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	ctx := createMockResponseContext(nil, nil)
+	ctx.RequestMethod = "POST"
+	ctx.RequestPath = "/mcp"
+	ctx.ResponseStatus = 202
+	ctx.Metadata[metadataMcpBatchMethods] = map[string]string{}
+	ctx.Metadata[metadataAclBatchErrors] = []any{jsonrpc.ErrorEntry(float64(1), -32000, "MCP capability not allowed", nil)}
+
+	action := p.OnResponse(ctx, nil)
+	mods, ok := action.(policy.UpstreamResponseModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamResponseModifications, got %T", action)
+	}
+	if mods.StatusCode == nil || *mods.StatusCode != 200 {
+		t.Fatalf("Expected status 200, got %v", mods.StatusCode)
+	}
+	var updated []map[string]any
+	if err := json.Unmarshal(mods.Body, &updated); err != nil || len(updated) != 1 {
+		t.Fatalf("Expected a batch with the error entry, got %s", mods.Body)
+	}
+}
+
 func createMockRequestContext(headers map[string][]string) *policy.RequestContext {
 	return &policy.RequestContext{
 		SharedContext: &policy.SharedContext{
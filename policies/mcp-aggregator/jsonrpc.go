@@ -0,0 +1,48 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcpaggregator
+
+import (
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/mcp-rewrite/jsonrpc"
+)
+
+// buildJsonRpcResult builds a JSON-RPC result response entry.
+func buildJsonRpcResult(requestID any, result any) map[string]any {
+	return map[string]any{
+		"jsonrpc": "2.0",
+		"id":      requestID,
+		"result":  result,
+	}
+}
+
+// buildResponse builds the response to a request from a JSON-RPC response or batch, with the
+// session ID assigned to the client when not empty.
+func buildResponse(ctx *policy.RequestContext, statusCode int, payload any, sessionID string) policy.RequestAction {
+	var headers map[string]string
+	if sessionID != "" {
+		headers = map[string]string{mcpSessionHeader: sessionID}
+	}
+	return jsonrpc.Response(ctx.Headers, statusCode, payload, headers)
+}
+
+// buildErrorResponse builds a JSON-RPC error response for a request.
+func buildErrorResponse(ctx *policy.RequestContext, statusCode int, jsonRpcCode int, reason string, requestID any) policy.RequestAction {
+	return jsonrpc.ErrorResponse(ctx.Headers, statusCode, jsonRpcCode, reason, requestID, nil, nil)
+}
@@ -0,0 +1,759 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcpaggregator
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/mcp-rewrite/jsonrpc"
+)
+
+const (
+	mcpPathSegment           = "/mcp"
+	mcpSessionHeader         = "mcp-session-id"
+	mcpProtocolVersionHeader = "mcp-protocol-version"
+	defaultSeparator         = "__"
+	defaultTimeout           = 30 * time.Second
+	defaultSessionTimeout    = time.Hour
+	defaultServerName        = "mcp-aggregator"
+	defaultServerVersion     = "1.0.0"
+	// defaultMaxSessions bounds the number of gateway sessions kept per route
+	defaultMaxSessions = 1000
+	// maxUpstreamResponseSize bounds the upstream response body that is read
+	maxUpstreamResponseSize = 10 << 20
+	// maxListPages bounds the pages of a paginated list that are fetched from an upstream
+	maxListPages = 100
+)
+
+// capabilityTypes are the MCP capabilities that are aggregated
+var capabilityTypes = []string{"tools", "resources", "prompts"}
+
+// UpstreamConfig is an upstream MCP server
+type UpstreamConfig struct {
+	Name    string
+	URL     string
+	Prefix  string
+	Headers map[string]string
+}
+
+type McpAggregatorPolicy struct {
+	upstreams           []*UpstreamConfig
+	upstreamsByName     map[string]*UpstreamConfig
+	separator           string
+	allowPartialResults bool
+	serverName          string
+	serverVersion       string
+	client              *http.Client
+	sessions            *sessionStore
+	requestCounter      atomic.Int64
+}
+
+func GetPolicy(
+	metadata policy.PolicyMetadata,
+	params map[string]any,
+) (policy.Policy, error) {
+	slog.Debug("MCP Aggregator Policy: GetPolicy called")
+
+	upstreams, err := parseUpstreams(params)
+	if err != nil {
+		slog.Debug("MCP Aggregator Policy: Invalid upstreams configuration", "error", err)
+		return nil, fmt.Errorf("invalid upstreams configuration: %w", err)
+	}
+
+	separator := getStringParam(params, "separator", defaultSeparator)
+	if separator == "" {
+		return nil, fmt.Errorf("separator must not be empty")
+	}
+	timeout, err := parseDurationParam(params, "timeout", defaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	sessionTimeout, err := parseDurationParam(params, "sessionTimeout", defaultSessionTimeout)
+	if err != nil {
+		return nil, err
+	}
+	maxSessions, err := parsePositiveIntParam(params, "maxSessions", defaultMaxSessions)
+	if err != nil {
+		return nil, err
+	}
+
+	ins := &McpAggregatorPolicy{
+		upstreams:           upstreams,
+		upstreamsByName:     make(map[string]*UpstreamConfig, len(upstreams)),
+		separator:           separator,
+		allowPartialResults: getBoolParam(params, "allowPartialResults", true),
+		serverName:          defaultServerName,
+		serverVersion:       defaultServerVersion,
+		client:              &http.Client{Timeout: timeout},
+	}
+	ins.sessions = newSessionStore(sessionTimeout, maxSessions, ins.evictSession)
+	for _, upstream := range upstreams {
+		ins.upstreamsByName[upstream.Name] = upstream
+	}
+	if serverInfo, ok := params["serverInfo"].(map[string]any); ok {
+		ins.serverName = getStringParam(serverInfo, "name", defaultServerName)
+		ins.serverVersion = getStringParam(serverInfo, "version", defaultServerVersion)
+	}
+
+	slog.Debug("MCP Aggregator Policy: Parsed configuration",
+		"route", metadata.RouteName,
+		"upstreams", len(ins.upstreams),
+		"separator", ins.separator,
+		"timeout", timeout,
+		"sessionTimeout", sessionTimeout,
+		"maxSessions", maxSessions,
+		"allowPartialResults", ins.allowPartialResults,
+	)
+
+	return ins, nil
+}
+
+// parseUpstreams parses the upstream MCP servers. Names and prefixes must be unique.
+func parseUpstreams(params map[string]any) ([]*UpstreamConfig, error) {
+	raw, ok := params["upstreams"].([]any)
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("at least one upstream is required")
+	}
+
+	upstreams := make([]*UpstreamConfig, 0, len(raw))
+	names := make(map[string]struct{}, len(raw))
+	prefixes := make(map[string]struct{}, len(raw))
+	for i, item := range raw {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("upstreams[%d] must be an object", i)
+		}
+
+		name := strings.TrimSpace(getStringParam(entry, "name", ""))
+		if name == "" {
+			return nil, fmt.Errorf("upstreams[%d].name is required", i)
+		}
+		if _, exists := names[name]; exists {
+			return nil, fmt.Errorf("duplicate upstream name %q", name)
+		}
+		names[name] = struct{}{}
+
+		rawURL := getStringParam(entry, "url", "")
+		parsed, err := url.Parse(rawURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("upstreams[%d].url must be an absolute http or https URL", i)
+		}
+
+		prefix := getStringParam(entry, "prefix", name)
+		if prefix == "" {
+			return nil, fmt.Errorf("upstreams[%d].prefix must not be empty", i)
+		}
+		if _, exists := prefixes[prefix]; exists {
+			return nil, fmt.Errorf("duplicate upstream prefix %q", prefix)
+		}
+		prefixes[prefix] = struct{}{}
+
+		headers := make(map[string]string)
+		if rawHeaders, ok := entry["headers"].(map[string]any); ok {
+			for header, value := range rawHeaders {
+				str, ok := value.(string)
+				if !ok {
+					return nil, fmt.Errorf("upstreams[%d].headers.%s must be a string", i, header)
+				}
+				headers[header] = str
+			}
+		}
+
+		upstreams = append(upstreams, &UpstreamConfig{
+			Name:    name,
+			URL:     rawURL,
+			Prefix:  prefix,
+			Headers: headers,
+		})
+	}
+	return upstreams, nil
+}
+
+func (p *McpAggregatorPolicy) Mode() policy.ProcessingMode {
+	return policy.ProcessingMode{
+		RequestHeaderMode:  policy.HeaderModeSkip,
+		RequestBodyMode:    policy.BodyModeBuffer,
+		ResponseHeaderMode: policy.HeaderModeSkip,
+		ResponseBodyMode:   policy.BodyModeSkip,
+	}
+}
+
+// OnRequest answers MCP requests from the upstream servers. MCP requests are never forwarded
+// to the route upstream.
+func (p *McpAggregatorPolicy) OnRequest(ctx *policy.RequestContext, params map[string]any) policy.RequestAction {
+	if !strings.Contains(ctx.Path, mcpPathSegment) {
+		return nil
+	}
+
+	switch strings.ToUpper(ctx.Method) {
+	case http.MethodPost:
+		return p.handlePost(ctx)
+	case http.MethodDelete:
+		return p.handleDelete(ctx)
+	case http.MethodGet:
+		// Messages initiated by the upstreams are not relayed, so no event stream is offered
+		return policy.ImmediateResponse{
+			StatusCode: 405,
+			Headers:    map[string]string{"Allow": "POST, DELETE"},
+		}
+	default:
+		return nil
+	}
+}
+
+// OnResponse does nothing, since MCP requests are answered in the request phase.
+func (p *McpAggregatorPolicy) OnResponse(ctx *policy.ResponseContext, params map[string]any) policy.ResponseAction {
+	return nil
+}
+
+// handlePost answers a JSON-RPC request, notification or batch.
+func (p *McpAggregatorPolicy) handlePost(ctx *policy.RequestContext) policy.RequestAction {
+	slog.Debug("MCP Aggregator Policy: OnRequest started")
+
+	if ctx.Body == nil || len(ctx.Body.Content) == 0 {
+		return buildErrorResponse(ctx, 400, -32700, "Invalid JSON", nil)
+	}
+	payload, err := jsonrpc.ParsePayload(ctx.Body.Content, jsonrpc.IsEventStream(ctx.Headers))
+	if err != nil {
+		slog.Debug("MCP Aggregator Policy: Failed to parse MCP request", "error", err, "path", ctx.Path)
+		return buildErrorResponse(ctx, 400, -32700, "Invalid JSON", nil)
+	}
+
+	if !payload.Batch {
+		if message, ok := payload.Messages[0].(map[string]any); ok && message["method"] == "initialize" {
+			return p.handleInitialize(ctx, message)
+		}
+	}
+
+	sessionID := jsonrpc.SessionID(ctx.Headers)
+	if sessionID == "" {
+		return buildErrorResponse(ctx, 400, -32600, "Missing Mcp-Session-Id header", nil)
+	}
+	session, ok := p.sessions.get(sessionID)
+	if !ok {
+		slog.Debug("MCP Aggregator Policy: Unknown session", "sessionID", sessionID)
+		return buildErrorResponse(ctx, 404, -32001, "Session not found", nil)
+	}
+
+	responses := make([]any, 0, len(payload.Messages))
+	for _, message := range payload.Messages {
+		response, err := p.handleMessage(sessionID, session, message)
+		if errors.Is(err, errUpstreamSessionExpired) {
+			// The client starts a new session, which creates new upstream sessions
+			slog.Debug("MCP Aggregator Policy: Upstream session expired, ending session", "sessionID", sessionID)
+			p.endSession(sessionID)
+			return buildErrorResponse(ctx, 404, -32001, "Session not found", nil)
+		}
+		if response != nil {
+			responses = append(responses, response)
+		}
+	}
+
+	if len(responses) == 0 {
+		// Only notifications were sent, which are not answered
+		return policy.ImmediateResponse{
+			StatusCode: 202,
+			Headers:    map[string]string{mcpSessionHeader: sessionID},
+		}
+	}
+	if !payload.Batch {
+		return buildResponse(ctx, 200, responses[0], sessionID)
+	}
+	return buildResponse(ctx, 200, responses, sessionID)
+}
+
+// handleMessage answers a JSON-RPC message of a session. Notifications have no response.
+func (p *McpAggregatorPolicy) handleMessage(sessionID string, session *gatewaySession, raw any) (map[string]any, error) {
+	message, ok := raw.(map[string]any)
+	if !ok {
+		return jsonrpc.ErrorEntry(nil, -32600, "Invalid Request", nil), nil
+	}
+
+	id, isRequest := message["id"]
+	method, _ := message["method"].(string)
+	if method == "" {
+		_, hasResult := message["result"]
+		_, hasError := message["error"]
+		if isRequest && (hasResult || hasError) {
+			// Responses to requests of the upstreams are not relayed, since those requests are not either
+			return nil, nil
+		}
+		return jsonrpc.ErrorEntry(id, -32600, "Invalid Request", nil), nil
+	}
+	if !isRequest {
+		p.forwardNotification(session, message)
+		return nil, nil
+	}
+
+	switch method {
+	case "ping":
+		return buildJsonRpcResult(id, map[string]any{}), nil
+	case "initialize":
+		return jsonrpc.ErrorEntry(id, -32600, "Session is already initialized", nil), nil
+	case "tools/call", "resources/read", "prompts/get":
+		capabilityType, _, _ := jsonrpc.ParseMethod(method)
+		return p.handleRoutedRequest(session, capabilityType, message)
+	}
+	if capabilityType, action, ok := jsonrpc.ParseMethod(method); ok && action == "list" {
+		return p.handleList(sessionID, session, capabilityType, id)
+	}
+
+	slog.Debug("MCP Aggregator Policy: Unsupported method", "method", method)
+	return jsonrpc.ErrorEntry(id, -32601, "Method not found", nil), nil
+}
+
+// handleInitialize initializes a session with every upstream and answers with the merged
+// capabilities under a new gateway session ID.
+func (p *McpAggregatorPolicy) handleInitialize(ctx *policy.RequestContext, message map[string]any) policy.RequestAction {
+	id := message["id"]
+	params, hasParams := message["params"]
+
+	// Refuse before any upstream session is created; sessions that have not expired are never
+	// evicted, so that new clients can not end the sessions of others
+	if p.sessions.full() {
+		slog.Warn("MCP Aggregator Policy: Maximum number of sessions reached, rejecting initialize")
+		return buildErrorResponse(ctx, 503, -32603, "Too many sessions", id)
+	}
+
+	sessions := make([]*upstreamSession, len(p.upstreams))
+	instructions := make([]string, len(p.upstreams))
+	errs := make([]error, len(p.upstreams))
+	fanOut(p.upstreams, func(i int, upstream *UpstreamConfig) {
+		initialize := map[string]any{
+			"jsonrpc": "2.0",
+			"id":      p.nextRequestID(),
+			"method":  "initialize",
+		}
+		if hasParams {
+			initialize["params"] = params
+		}
+		sessions[i], instructions[i], errs[i] = p.initializeUpstream(upstream, initialize)
+	})
+
+	upstreams := make(map[string]*upstreamSession, len(p.upstreams))
+	var failed *UpstreamConfig
+	for i, upstream := range p.upstreams {
+		if errs[i] != nil {
+			slog.Debug("MCP Aggregator Policy: Failed to initialize upstream", "upstream", upstream.Name, "error", errs[i])
+			if failed == nil {
+				failed = upstream
+			}
+			continue
+		}
+		upstreams[upstream.Name] = sessions[i]
+	}
+	if failed != nil && (!p.allowPartialResults || len(upstreams) == 0) {
+		p.terminateAll(upstreams)
+		return buildErrorResponse(ctx, 502, -32603, fmt.Sprintf("Failed to initialize upstream server %s", failed.Name), id)
+	}
+
+	sessionID, err := p.sessions.create(upstreams)
+	if err != nil {
+		p.terminateAll(upstreams)
+		if errors.Is(err, errSessionStoreFull) {
+			slog.Warn("MCP Aggregator Policy: Maximum number of sessions reached, rejecting initialize")
+			return buildErrorResponse(ctx, 503, -32603, "Too many sessions", id)
+		}
+		slog.Debug("MCP Aggregator Policy: Failed to create session", "error", err)
+		return buildErrorResponse(ctx, 500, -32603, "Failed to create session", id)
+	}
+
+	slog.Debug("MCP Aggregator Policy: Session initialized", "sessionID", sessionID, "upstreams", len(upstreams))
+	return buildResponse(ctx, 200, buildJsonRpcResult(id, p.buildInitializeResult(upstreams, instructions)), sessionID)
+}
+
+// initializeUpstream sends initialize and the initialized notification to an upstream and
+// returns its session and instructions.
+func (p *McpAggregatorPolicy) initializeUpstream(upstream *UpstreamConfig, initialize map[string]any) (*upstreamSession, string, error) {
+	response, err := p.send(upstream, nil, initialize)
+	if err != nil {
+		return nil, "", err
+	}
+	result, err := responseResult(response.message)
+	if err != nil {
+		return nil, "", err
+	}
+
+	session := &upstreamSession{sessionID: response.sessionID}
+	session.protocolVersion, _ = result["protocolVersion"].(string)
+	session.capabilities, _ = result["capabilities"].(map[string]any)
+	instructions, _ := result["instructions"].(string)
+
+	initialized := map[string]any{"jsonrpc": "2.0", "method": "notifications/initialized"}
+	if _, err := p.send(upstream, session, initialized); err != nil {
+		p.terminate(upstream, session)
+		return nil, "", err
+	}
+	return session, instructions, nil
+}
+
+// buildInitializeResult merges the initialize results of the upstreams. The lowest protocol
+// version of the upstreams is used, and change notifications and subscriptions are not
+// declared, since messages initiated by the upstreams are not relayed.
+func (p *McpAggregatorPolicy) buildInitializeResult(upstreams map[string]*upstreamSession, instructions []string) map[string]any {
+	capabilities := make(map[string]any)
+	protocolVersion := ""
+	merged := make([]string, 0, len(instructions))
+	for i, upstream := range p.upstreams {
+		session, ok := upstreams[upstream.Name]
+		if !ok {
+			continue
+		}
+		for _, capabilityType := range capabilityTypes {
+			if session.hasCapability(capabilityType) {
+				capabilities[capabilityType] = map[string]any{}
+			}
+		}
+		if session.protocolVersion != "" && (protocolVersion == "" || session.protocolVersion < protocolVersion) {
+			protocolVersion = session.protocolVersion
+		}
+		if instructions[i] != "" {
+			merged = append(merged, fmt.Sprintf("%s: %s", upstream.Name, instructions[i]))
+		}
+	}
+
+	result := map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities":    capabilities,
+		"serverInfo": map[string]any{
+			"name":    p.serverName,
+			"version": p.serverVersion,
+		},
+	}
+	if len(merged) > 0 {
+		result["instructions"] = strings.Join(merged, "\n\n")
+	}
+	return result
+}
+
+// handleList lists a capability type on every upstream of the session that declared it and
+// merges the lists with the upstream prefixes applied.
+func (p *McpAggregatorPolicy) handleList(sessionID string, session *gatewaySession, capabilityType string, id any) (map[string]any, error) {
+	upstreams := p.sessionUpstreams(session, capabilityType)
+	lists := make([][]any, len(upstreams))
+	errs := make([]error, len(upstreams))
+	fanOut(upstreams, func(i int, upstream *UpstreamConfig) {
+		lists[i], errs[i] = p.listUpstream(upstream, session.upstreams[upstream.Name], capabilityType)
+	})
+
+	merged := make([]any, 0)
+	resourceOwners := make(map[string]string)
+	for i, upstream := range upstreams {
+		if errs[i] != nil {
+			if errors.Is(errs[i], errUpstreamSessionExpired) {
+				return nil, errs[i]
+			}
+			slog.Debug("MCP Aggregator Policy: Failed to list upstream capabilities", "upstream", upstream.Name, "capabilityType", capabilityType, "error", errs[i])
+			if !p.allowPartialResults {
+				return jsonrpc.ErrorEntry(id, -32603, fmt.Sprintf("Upstream server %s failed to list %s", upstream.Name, capabilityType), nil), nil
+			}
+			continue
+		}
+		if capabilityType == "resources" {
+			collectResourceOwners(lists[i], upstream.Name, resourceOwners)
+		}
+		merged = append(merged, jsonrpc.PrefixListItems(lists[i], upstream.Prefix, p.separator)...)
+	}
+	if capabilityType == "resources" {
+		p.sessions.setResourceOwners(sessionID, resourceOwners)
+	}
+
+	slog.Debug("MCP Aggregator Policy: Merged list", "capabilityType", capabilityType, "upstreams", len(upstreams), "items", len(merged))
+	return buildJsonRpcResult(id, map[string]any{capabilityType: merged}), nil
+}
+
+// listUpstream fetches every page of a list from an upstream.
+func (p *McpAggregatorPolicy) listUpstream(upstream *UpstreamConfig, session *upstreamSession, capabilityType string) ([]any, error) {
+	items := make([]any, 0)
+	cursor := ""
+	for page := 0; page < maxListPages; page++ {
+		message := map[string]any{
+			"jsonrpc": "2.0",
+			"id":      p.nextRequestID(),
+			"method":  capabilityType + "/list",
+		}
+		if cursor != "" {
+			message["params"] = map[string]any{"cursor": cursor}
+		}
+
+		response, err := p.send(upstream, session, message)
+		if err != nil {
+			return nil, err
+		}
+		result, err := responseResult(response.message)
+		if err != nil {
+			return nil, err
+		}
+		if list, ok := result[capabilityType].([]any); ok {
+			items = append(items, list...)
+		}
+		cursor, _ = result["nextCursor"].(string)
+		if cursor == "" {
+			return items, nil
+		}
+	}
+
+	slog.Debug("MCP Aggregator Policy: Upstream list has more pages than allowed", "upstream", upstream.Name, "capabilityType", capabilityType, "maxPages", maxListPages)
+	return items, nil
+}
+
+// collectResourceOwners records the upstream of each listed resource URI. A URI listed by
+// several upstreams belongs to the first one.
+func collectResourceOwners(items []any, upstreamName string, owners map[string]string) {
+	for _, item := range items {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		uri, ok := entry["uri"].(string)
+		if !ok || uri == "" {
+			continue
+		}
+		if _, exists := owners[uri]; !exists {
+			owners[uri] = upstreamName
+		}
+	}
+}
+
+// handleRoutedRequest forwards tools/call, resources/read and prompts/get to the upstream that
+// owns the capability, with the upstream prefix removed from the name.
+func (p *McpAggregatorPolicy) handleRoutedRequest(session *gatewaySession, capabilityType string, message map[string]any) (map[string]any, error) {
+	id := message["id"]
+	keyField := jsonrpc.ParamKey(capabilityType)
+	params, ok := message["params"].(map[string]any)
+	if !ok {
+		return jsonrpc.ErrorEntry(id, -32602, "Invalid params", nil), nil
+	}
+	key, ok := params[keyField].(string)
+	if !ok || key == "" {
+		return jsonrpc.ErrorEntry(id, -32602, fmt.Sprintf("Missing %s", keyField), nil), nil
+	}
+
+	upstream, target, found := p.route(session, capabilityType, key)
+	if !found {
+		slog.Debug("MCP Aggregator Policy: No upstream for capability", "capabilityType", capabilityType, "key", key)
+		if capabilityType == "resources" {
+			return jsonrpc.ErrorEntry(id, -32002, "Resource not found", map[string]any{"uri": key}), nil
+		}
+		return jsonrpc.ErrorEntry(id, -32602, fmt.Sprintf("Unknown %s: %s", strings.TrimSuffix(capabilityType, "s"), key), nil), nil
+	}
+
+	forwardedParams := make(map[string]any, len(params))
+	for name, value := range params {
+		forwardedParams[name] = value
+	}
+	forwardedParams[keyField] = target
+	forwarded := make(map[string]any, len(message))
+	for name, value := range message {
+		forwarded[name] = value
+	}
+	forwarded["params"] = forwardedParams
+
+	slog.Debug("MCP Aggregator Policy: Routing request", "method", message["method"], "key", key, "upstream", upstream.Name, "target", target)
+	response, err := p.send(upstream, session.upstreams[upstream.Name], forwarded)
+	if err != nil {
+		if errors.Is(err, errUpstreamSessionExpired) {
+			return nil, err
+		}
+		slog.Debug("MCP Aggregator Policy: Upstream request failed", "upstream", upstream.Name, "error", err)
+		return jsonrpc.ErrorEntry(id, -32603, fmt.Sprintf("Upstream server %s is unavailable", upstream.Name), nil), nil
+	}
+	return response.message, nil
+}
+
+// route returns the upstream that owns a capability and the capability name on the upstream.
+// Tools and prompts are routed by the longest matching upstream prefix, resources by the URIs
+// of the last resources/list.
+func (p *McpAggregatorPolicy) route(session *gatewaySession, capabilityType string, key string) (*UpstreamConfig, string, bool) {
+	candidates := p.sessionUpstreams(session, capabilityType)
+
+	if capabilityType == "resources" {
+		if owner, ok := session.resourceOwners[key]; ok {
+			upstream, ok := p.upstreamsByName[owner]
+			return upstream, key, ok
+		}
+		// A single upstream with resources serves every URI, even before resources/list
+		if len(candidates) == 1 {
+			return candidates[0], key, true
+		}
+		return nil, "", false
+	}
+
+	prefixes := make([]string, len(candidates))
+	for i, upstream := range candidates {
+		prefixes[i] = upstream.Prefix
+	}
+	prefix, target, ok := jsonrpc.UnprefixName(key, p.separator, prefixes)
+	if !ok {
+		return nil, "", false
+	}
+	for _, upstream := range candidates {
+		if upstream.Prefix == prefix {
+			return upstream, target, true
+		}
+	}
+	return nil, "", false
+}
+
+// forwardNotification sends a notification of the client to every upstream of the session.
+// The initialized notification was already sent to the upstreams during initialize.
+func (p *McpAggregatorPolicy) forwardNotification(session *gatewaySession, message map[string]any) {
+	if message["method"] == "notifications/initialized" {
+		return
+	}
+	fanOut(p.sessionUpstreams(session, ""), func(_ int, upstream *UpstreamConfig) {
+		if _, err := p.send(upstream, session.upstreams[upstream.Name], message); err != nil {
+			slog.Debug("MCP Aggregator Policy: Failed to forward notification", "upstream", upstream.Name, "method", message["method"], "error", err)
+		}
+	})
+}
+
+// handleDelete ends a session and its upstream sessions.
+func (p *McpAggregatorPolicy) handleDelete(ctx *policy.RequestContext) policy.RequestAction {
+	sessionID := jsonrpc.SessionID(ctx.Headers)
+	if sessionID == "" {
+		return buildErrorResponse(ctx, 400, -32600, "Missing Mcp-Session-Id header", nil)
+	}
+	if !p.endSession(sessionID) {
+		return buildErrorResponse(ctx, 404, -32001, "Session not found", nil)
+	}
+	slog.Debug("MCP Aggregator Policy: Session ended", "sessionID", sessionID)
+	return policy.ImmediateResponse{StatusCode: 200}
+}
+
+// endSession removes a gateway session and terminates its upstream sessions.
+func (p *McpAggregatorPolicy) endSession(sessionID string) bool {
+	session, ok := p.sessions.remove(sessionID)
+	if !ok {
+		return false
+	}
+	p.terminateAll(session.upstreams)
+	return true
+}
+
+// evictSession terminates the upstream sessions of a gateway session that expired. The
+// upstreams are called in the background, so that the request that found the session gone
+// is not delayed.
+func (p *McpAggregatorPolicy) evictSession(session *gatewaySession) {
+	slog.Debug("MCP Aggregator Policy: Session evicted, terminating upstream sessions", "upstreams", len(session.upstreams))
+	go p.terminateAll(session.upstreams)
+}
+
+// terminateAll terminates the given upstream sessions.
+func (p *McpAggregatorPolicy) terminateAll(upstreams map[string]*upstreamSession) {
+	fanOut(p.sessionUpstreams(&gatewaySession{upstreams: upstreams}, ""), func(_ int, upstream *UpstreamConfig) {
+		p.terminate(upstream, upstreams[upstream.Name])
+	})
+}
+
+// sessionUpstreams returns the upstreams of a session in configuration order. When a capability
+// type is given, only the upstreams that declared it are returned.
+func (p *McpAggregatorPolicy) sessionUpstreams(session *gatewaySession, capabilityType string) []*UpstreamConfig {
+	upstreams := make([]*UpstreamConfig, 0, len(session.upstreams))
+	for _, upstream := range p.upstreams {
+		upstreamSession, ok := session.upstreams[upstream.Name]
+		if !ok || (capabilityType != "" && !upstreamSession.hasCapability(capabilityType)) {
+			continue
+		}
+		upstreams = append(upstreams, upstream)
+	}
+	return upstreams
+}
+
+// nextRequestID returns a JSON-RPC id for a request the gateway sends to an upstream.
+func (p *McpAggregatorPolicy) nextRequestID() string {
+	return fmt.Sprintf("%s-%d", defaultServerName, p.requestCounter.Add(1))
+}
+
+// responseResult returns the result of a JSON-RPC response.
+func responseResult(message map[string]any) (map[string]any, error) {
+	if errorObject, hasError := message["error"]; hasError {
+		return nil, fmt.Errorf("upstream returned an error: %v", errorObject)
+	}
+	result, ok := message["result"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("upstream result is not an object")
+	}
+	return result, nil
+}
+
+func getStringParam(params map[string]any, key, defaultValue string) string {
+	if v, ok := params[key]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return defaultValue
+}
+
+func getBoolParam(params map[string]any, key string, defaultValue bool) bool {
+	if v, ok := params[key]; ok {
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+// parsePositiveIntParam parses a positive integer parameter.
+func parsePositiveIntParam(params map[string]any, key string, defaultValue int) (int, error) {
+	v, ok := params[key]
+	if !ok || v == nil {
+		return defaultValue, nil
+	}
+	var n int
+	switch value := v.(type) {
+	case int:
+		n = value
+	case int64:
+		n = int(value)
+	case float64:
+		if value != float64(int(value)) {
+			return 0, fmt.Errorf("%s must be a positive integer, got %v", key, v)
+		}
+		n = int(value)
+	default:
+		return 0, fmt.Errorf("%s must be a positive integer, got %v", key, v)
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %v", key, v)
+	}
+	return n, nil
+}
+
+// parseDurationParam parses a positive duration parameter.
+func parseDurationParam(params map[string]any, key string, defaultValue time.Duration) (time.Duration, error) {
+	s := getStringParam(params, key, "")
+	if s == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got %q", key, s)
+	}
+	return d, nil
+}
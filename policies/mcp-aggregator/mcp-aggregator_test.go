@@ -0,0 +1,489 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcpaggregator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/mcp-rewrite/jsonrpc"
+)
+
+// mockMcpServer is an upstream MCP server that records the requests it receives.
+type mockMcpServer struct {
+	*httptest.Server
+	name       string
+	sse        bool
+	capability map[string]any
+	mu         sync.Mutex
+	requests   []map[string]any
+	sessions   []string
+	terminated []string
+}
+
+func newMockMcpServer(t *testing.T, name string, sse bool) *mockMcpServer {
+	t.Helper()
+	server := &mockMcpServer{
+		name: name,
+		sse:  sse,
+		capability: map[string]any{
+			"tools":     map[string]any{"listChanged": true},
+			"resources": map[string]any{},
+		},
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.handle))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *mockMcpServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessionID := r.Header.Get(mcpSessionHeader)
+	if r.Method == http.MethodDelete {
+		s.terminated = append(s.terminated, sessionID)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	var message map[string]any
+	_ = json.Unmarshal(body, &message)
+	s.requests = append(s.requests, message)
+	s.sessions = append(s.sessions, sessionID)
+
+	method := message["method"].(string)
+	id, isRequest := message["id"]
+	if !isRequest {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var result map[string]any
+	params, _ := message["params"].(map[string]any)
+	switch method {
+	case "initialize":
+		w.Header().Set(mcpSessionHeader, s.name+"-session")
+		result = map[string]any{
+			"protocolVersion": "2025-06-18",
+			"capabilities":    s.capability,
+			"serverInfo":      map[string]any{"name": s.name},
+			"instructions":    "Use " + s.name + " tools.",
+		}
+	case "tools/list":
+		// The tools are split over two pages
+		if params["cursor"] == "page-2" {
+			result = map[string]any{"tools": []any{map[string]any{"name": "second", "inputSchema": map[string]any{}}}}
+		} else {
+			result = map[string]any{"tools": []any{map[string]any{"name": "first", "inputSchema": map[string]any{}}}, "nextCursor": "page-2"}
+		}
+	case "resources/list":
+		result = map[string]any{"resources": []any{map[string]any{"uri": "file:///" + s.name + ".txt", "name": "readme"}}}
+	case "tools/call", "resources/read":
+		result = map[string]any{"server": s.name, "params": params}
+	default:
+		result = nil
+	}
+
+	response := map[string]any{"jsonrpc": "2.0", "id": id, "result": result}
+	if result == nil {
+		response = jsonrpc.ErrorEntry(id, -32601, "Method not found", nil)
+	}
+	data, _ := json.Marshal(response)
+	if s.sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+		progress := `{"jsonrpc":"2.0","method":"notifications/progress","params":{"progress":1}}`
+		_, _ = w.Write(jsonrpc.BuildEventStream([]jsonrpc.Event{{Data: progress}, {Fields: []string{"event: message"}, Data: string(data)}}))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// received returns the requests received for a method and the session IDs they were sent with.
+func (s *mockMcpServer) received(method string) ([]map[string]any, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var requests []map[string]any
+	var sessions []string
+	for i, request := range s.requests {
+		if request["method"] == method {
+			requests = append(requests, request)
+			sessions = append(sessions, s.sessions[i])
+		}
+	}
+	return requests, sessions
+}
+
+func newAggregator(t *testing.T, extra map[string]any, servers ...*mockMcpServer) policy.Policy {
+	t.Helper()
+	upstreams := make([]any, 0, len(servers))
+	for _, server := range servers {
+		upstreams = append(upstreams, map[string]any{
+			"name":    server.name,
+			"url":     server.URL + "/mcp",
+			"headers": map[string]any{"x-upstream": server.name},
+		})
+	}
+	params := map[string]any{"upstreams": upstreams}
+	for key, value := range extra {
+		params[key] = value
+	}
+	p, err := GetPolicy(policy.PolicyMetadata{RouteName: "aggregator"}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	return p
+}
+
+func createMockRequestContext(method, body, sessionID string) *policy.RequestContext {
+	headers := map[string][]string{"content-type": {"application/json"}}
+	if sessionID != "" {
+		headers[mcpSessionHeader] = []string{sessionID}
+	}
+	return &policy.RequestContext{
+		SharedContext: &policy.SharedContext{
+			RequestID: "test-request-id",
+			Metadata:  map[string]any{},
+		},
+		Headers: policy.NewHeaders(headers),
+		Body:    &policy.Body{Content: []byte(body), Present: body != ""},
+		Path:    "/mcp",
+		Method:  method,
+		Scheme:  "http",
+	}
+}
+
+// call sends a request to the policy and returns the immediate response.
+func call(t *testing.T, p policy.Policy, method, body, sessionID string) policy.ImmediateResponse {
+	t.Helper()
+	response, ok := p.OnRequest(createMockRequestContext(method, body, sessionID), nil).(policy.ImmediateResponse)
+	if !ok {
+		t.Fatalf("Expected ImmediateResponse for %s %s", method, body)
+	}
+	return response
+}
+
+// result returns the result of a JSON-RPC response body, failing on errors.
+func result(t *testing.T, response policy.ImmediateResponse) map[string]any {
+	t.Helper()
+	var message map[string]any
+	if err := json.Unmarshal(response.Body, &message); err != nil {
+		t.Fatalf("Failed to unmarshal response %s: %v", response.Body, err)
+	}
+	result, ok := message["result"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected a result, got %s", response.Body)
+	}
+	return result
+}
+
+func initialize(t *testing.T, p policy.Policy) string {
+	t.Helper()
+	response := call(t, p, "POST", `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`, "")
+	if response.StatusCode != 200 || response.Headers[mcpSessionHeader] == "" {
+		t.Fatalf("Expected an initialized session, got %d %v %s", response.StatusCode, response.Headers, response.Body)
+	}
+	return response.Headers[mcpSessionHeader]
+}
+
+func TestInitializeAndList_MergesUpstreams(t *testing.T) {
+	weather := newMockMcpServer(t, "weather", false)
+	search := newMockMcpServer(t, "search", true)
+	search.capability = map[string]any{"tools": map[string]any{}}
+	p := newAggregator(t, nil, weather, search)
+
+	response := call(t, p, "POST", `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`, "")
+	sessionID := response.Headers[mcpSessionHeader]
+	if sessionID == "" || sessionID == "weather-session" {
+		t.Fatalf("Expected a gateway session ID, got %q", sessionID)
+	}
+	initialized := result(t, response)
+	capabilities := initialized["capabilities"].(map[string]any)
+	if len(capabilities) != 2 || capabilities["tools"] == nil || capabilities["resources"] == nil {
+		t.Fatalf("Expected tools and resources capabilities, got %v", capabilities)
+	}
+	if initialized["protocolVersion"] != "2025-06-18" || initialized["instructions"] != "weather: Use weather tools.\n\nsearch: Use search tools." {
+		t.Fatalf("Unexpected initialize result %v", initialized)
+	}
+	for _, server := range []*mockMcpServer{weather, search} {
+		if requests, _ := server.received("notifications/initialized"); len(requests) != 1 {
+			t.Fatalf("Expected %s to be notified of initialization", server.name)
+		}
+	}
+
+	tools := result(t, call(t, p, "POST", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, sessionID))["tools"].([]any)
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.(map[string]any)["name"].(string))
+	}
+	if fmt.Sprint(names) != "[weather__first weather__second search__first search__second]" {
+		t.Fatalf("Unexpected merged tools %v", names)
+	}
+	_, sessions := search.received("tools/list")
+	if len(sessions) != 2 || sessions[0] != "search-session" {
+		t.Fatalf("Expected both pages to be listed in the upstream session, got %v", sessions)
+	}
+
+	// Only upstreams that declared resources are asked for them
+	resources := result(t, call(t, p, "POST", `{"jsonrpc":"2.0","id":2,"method":"resources/list"}`, sessionID))["resources"].([]any)
+	if len(resources) != 1 || resources[0].(map[string]any)["name"] != "weather__readme" {
+		t.Fatalf("Unexpected merged resources %v", resources)
+	}
+	if requests, _ := search.received("resources/list"); len(requests) != 0 {
+		t.Fatalf("Expected search not to be asked for resources")
+	}
+}
+
+func TestToolsCall_RoutedToOwningUpstream(t *testing.T) {
+	weather := newMockMcpServer(t, "weather", false)
+	search := newMockMcpServer(t, "search", true)
+	p := newAggregator(t, nil, weather, search)
+	sessionID := initialize(t, p)
+
+	response := call(t, p, "POST", `{"jsonrpc":"2.0","id":"call-1","method":"tools/call","params":{"name":"search__web","arguments":{"q":"mcp"}}}`, sessionID)
+	if response.Headers[mcpSessionHeader] != sessionID {
+		t.Fatalf("Expected the gateway session ID, got %v", response.Headers)
+	}
+	called := result(t, response)
+	if called["server"] != "search" || called["params"].(map[string]any)["name"] != "web" {
+		t.Fatalf("Expected the call to reach search as web, got %v", called)
+	}
+	requests, sessions := search.received("tools/call")
+	if len(requests) != 1 || requests[0]["id"] != "call-1" || sessions[0] != "search-session" {
+		t.Fatalf("Unexpected upstream request %v in session %v", requests, sessions)
+	}
+	if requests, _ := weather.received("tools/call"); len(requests) != 0 {
+		t.Fatalf("Expected weather not to be called")
+	}
+
+	// Resources are routed by the URIs of the last resources/list
+	call(t, p, "POST", `{"jsonrpc":"2.0","id":2,"method":"resources/list"}`, sessionID)
+	read := result(t, call(t, p, "POST", `{"jsonrpc":"2.0","id":3,"method":"resources/read","params":{"uri":"file:///search.txt"}}`, sessionID))
+	if read["server"] != "search" {
+		t.Fatalf("Expected the read to reach search, got %v", read)
+	}
+
+	tests := map[string]float64{
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"unknown__web"}}`:                -32602,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"search__"}}`:                    -32602,
+		`{"jsonrpc":"2.0","id":4,"method":"resources/read","params":{"uri":"file:///other.txt"}}`:        -32002,
+		`{"jsonrpc":"2.0","id":4,"method":"completion/complete","params":{"ref":{"type":"ref/prompt"}}}`: -32601,
+	}
+	for body, code := range tests {
+		var message map[string]any
+		if err := json.Unmarshal(call(t, p, "POST", body, sessionID).Body, &message); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if errorObject, ok := message["error"].(map[string]any); !ok || errorObject["code"] != code {
+			t.Fatalf("Expected error %v for %s, got %v", code, body, message)
+		}
+	}
+}
+
+func TestBatch_AnswersEachRequest(t *testing.T) {
+	weather := newMockMcpServer(t, "weather", false)
+	search := newMockMcpServer(t, "search", false)
+	p := newAggregator(t, map[string]any{"separator": "."}, weather, search)
+	sessionID := initialize(t, p)
+
+	batch := `[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"weather.forecast"}},` +
+		`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":0}},` +
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}]`
+	var responses []map[string]any
+	if err := json.Unmarshal(call(t, p, "POST", batch, sessionID).Body, &responses); err != nil || len(responses) != 2 {
+		t.Fatalf("Expected 2 responses, got %v", responses)
+	}
+	if responses[0]["result"].(map[string]any)["server"] != "weather" || responses[1]["id"] != float64(2) {
+		t.Fatalf("Unexpected batch responses %v", responses)
+	}
+	for _, server := range []*mockMcpServer{weather, search} {
+		if requests, _ := server.received("notifications/cancelled"); len(requests) != 1 {
+			t.Fatalf("Expected the notification to reach %s", server.name)
+		}
+	}
+
+	notification := call(t, p, "POST", `{"jsonrpc":"2.0","method":"notifications/initialized"}`, sessionID)
+	if notification.StatusCode != 202 {
+		t.Fatalf("Expected status 202 for a notification, got %d", notification.StatusCode)
+	}
+}
+
+func TestSession_Lifecycle(t *testing.T) {
+	weather := newMockMcpServer(t, "weather", false)
+	search := newMockMcpServer(t, "search", false)
+	p := newAggregator(t, nil, weather, search)
+	list := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+
+	if response := call(t, p, "POST", list, ""); response.StatusCode != 400 {
+		t.Fatalf("Expected status 400 without a session, got %d", response.StatusCode)
+	}
+	if response := call(t, p, "POST", list, "unknown"); response.StatusCode != 404 {
+		t.Fatalf("Expected status 404 for an unknown session, got %d", response.StatusCode)
+	}
+	if response := call(t, p, "GET", "", ""); response.StatusCode != 405 {
+		t.Fatalf("Expected status 405 for GET, got %d", response.StatusCode)
+	}
+
+	sessionID := initialize(t, p)
+	if response := call(t, p, "DELETE", "", sessionID); response.StatusCode != 200 {
+		t.Fatalf("Expected status 200 for DELETE, got %d", response.StatusCode)
+	}
+	for _, server := range []*mockMcpServer{weather, search} {
+		if len(server.terminated) != 1 || server.terminated[0] != server.name+"-session" {
+			t.Fatalf("Expected the %s session to be terminated, got %v", server.name, server.terminated)
+		}
+	}
+	if response := call(t, p, "POST", list, sessionID); response.StatusCode != 404 {
+		t.Fatalf("Expected status 404 after DELETE, got %d", response.StatusCode)
+	}
+
+	// Requests outside the MCP endpoint are forwarded
+	ctx := createMockRequestContext("GET", "", "")
+	ctx.Path = "/.well-known/oauth-protected-resource"
+	if action := p.OnRequest(ctx, nil); action != nil {
+		t.Fatalf("Expected no action outside the MCP endpoint, got %T", action)
+	}
+}
+
+func TestSession_EvictionTerminatesUpstreams(t *testing.T) {
+	weather := newMockMcpServer(t, "weather", false)
+	p := newAggregator(t, map[string]any{"sessionTimeout": "10ms"}, weather)
+
+	sessionID := initialize(t, p)
+	time.Sleep(20 * time.Millisecond)
+	if response := call(t, p, "POST", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, sessionID); response.StatusCode != 404 {
+		t.Fatalf("Expected status 404 for an expired session, got %d", response.StatusCode)
+	}
+	// Upstream sessions of evicted sessions are terminated in the background
+	deadline := time.Now().Add(time.Second)
+	for {
+		weather.mu.Lock()
+		terminated := append([]string(nil), weather.terminated...)
+		weather.mu.Unlock()
+		if len(terminated) == 1 && terminated[0] == "weather-session" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the expired upstream session to be terminated, got %v", terminated)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+}
+
+func TestSession_MaxSessions(t *testing.T) {
+	var evicted []*gatewaySession
+	store := newSessionStore(time.Hour, 2, func(session *gatewaySession) {
+		evicted = append(evicted, session)
+	})
+	first, err := store.create(map[string]*upstreamSession{"weather": {sessionID: "first"}})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if _, err := store.create(map[string]*upstreamSession{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	// Live sessions are not evicted to make room
+	if !store.full() {
+		t.Fatal("Expected the store to be full")
+	}
+	if _, err := store.create(map[string]*upstreamSession{}); !errors.Is(err, errSessionStoreFull) {
+		t.Fatalf("Expected errSessionStoreFull, got %v", err)
+	}
+	if _, ok := store.get(first); !ok || len(evicted) != 0 {
+		t.Fatalf("Expected the first session to be kept, evicted %d sessions", len(evicted))
+	}
+
+	// Expired sessions make room
+	store.mu.Lock()
+	store.sessions[first].lastUsed = time.Now().Add(-2 * time.Hour)
+	store.mu.Unlock()
+	if _, err := store.create(map[string]*upstreamSession{}); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if len(evicted) != 1 || evicted[0].upstreams["weather"].sessionID != "first" {
+		t.Fatalf("Expected the expired session to be evicted, got %d sessions", len(evicted))
+	}
+
+	// initialize is rejected before any upstream session is created
+	weather := newMockMcpServer(t, "weather", false)
+	p := newAggregator(t, map[string]any{"maxSessions": float64(1)}, weather)
+	initialize(t, p)
+	response := call(t, p, "POST", `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{}}`, "")
+	if response.StatusCode != 503 || response.Headers[mcpSessionHeader] != "" {
+		t.Fatalf("Expected status 503 without a session, got %d %v", response.StatusCode, response.Headers)
+	}
+	if requests, _ := weather.received("initialize"); len(requests) != 1 {
+		t.Fatalf("Expected a single upstream initialize, got %d", len(requests))
+	}
+}
+
+func TestInitialize_UnavailableUpstream(t *testing.T) {
+	weather := newMockMcpServer(t, "weather", false)
+	down := newMockMcpServer(t, "down", false)
+	down.Close()
+
+	p := newAggregator(t, nil, weather, down)
+	sessionID := initialize(t, p)
+	tools := result(t, call(t, p, "POST", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, sessionID))["tools"].([]any)
+	if len(tools) != 2 {
+		t.Fatalf("Expected the tools of the available upstream, got %v", tools)
+	}
+
+	strict := newAggregator(t, map[string]any{"allowPartialResults": false}, weather, down)
+	response := call(t, strict, "POST", `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{}}`, "")
+	if response.StatusCode != 502 || response.Headers[mcpSessionHeader] != "" {
+		t.Fatalf("Expected status 502 without a session, got %d %v", response.StatusCode, response.Headers)
+	}
+	if len(weather.terminated) != 1 {
+		t.Fatalf("Expected the weather session to be terminated, got %v", weather.terminated)
+	}
+}
+
+func TestGetPolicy_InvalidConfiguration(t *testing.T) {
+	upstream := map[string]any{"name": "a", "url": "http://localhost:8080/mcp"}
+	tests := []map[string]any{
+		{},
+		{"upstreams": []any{}},
+		{"upstreams": []any{map[string]any{"url": "http://localhost:8080/mcp"}}},
+		{"upstreams": []any{map[string]any{"name": "a", "url": "localhost:8080"}}},
+		{"upstreams": []any{upstream, upstream}},
+		{"upstreams": []any{upstream, map[string]any{"name": "b", "prefix": "a", "url": "http://localhost:8081/mcp"}}},
+		{"upstreams": []any{upstream}, "timeout": "soon"},
+		{"upstreams": []any{upstream}, "separator": ""},
+		{"upstreams": []any{upstream}, "maxSessions": float64(0)},
+		{"upstreams": []any{upstream}, "maxSessions": "many"},
+	}
+	for i, params := range tests {
+		if _, err := GetPolicy(policy.PolicyMetadata{RouteName: "invalid"}, params); err == nil {
+			t.Fatalf("Expected error for case %d", i)
+		}
+	}
+}
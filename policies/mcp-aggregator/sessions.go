@@ -0,0 +1,193 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcpaggregator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// errSessionStoreFull is returned when the store holds the maximum number of sessions and none
+// of them has expired
+var errSessionStoreFull = errors.New("session store is full")
+
+// upstreamSession is the state of an MCP session with an upstream server.
+type upstreamSession struct {
+	// sessionID is the Mcp-Session-Id assigned by the upstream, empty for stateless upstreams
+	sessionID       string
+	protocolVersion string
+	capabilities    map[string]any
+}
+
+// hasCapability reports whether the upstream declared a server capability in initialize.
+func (s *upstreamSession) hasCapability(capabilityType string) bool {
+	_, ok := s.capabilities[capabilityType]
+	return ok
+}
+
+// gatewaySession maps a gateway Mcp-Session-Id to the sessions with the upstream servers.
+// The upstreams and resource owners of a stored session are never modified; updates replace
+// the session. lastUsed is only accessed by the store with its lock held.
+type gatewaySession struct {
+	upstreams map[string]*upstreamSession
+	// resourceOwners maps resource URIs from resources/list to the upstream serving them
+	resourceOwners map[string]string
+	lastUsed       time.Time
+}
+
+// sessionStore keeps the gateway sessions of a policy instance.
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*gatewaySession
+	timeout  time.Duration
+	// capacity bounds the number of sessions; live sessions are never evicted to make room
+	capacity int
+	// onEvict is called without the lock held for each session that is removed because it expired
+	onEvict func(*gatewaySession)
+}
+
+func newSessionStore(timeout time.Duration, capacity int, onEvict func(*gatewaySession)) *sessionStore {
+	return &sessionStore{
+		sessions: make(map[string]*gatewaySession),
+		timeout:  timeout,
+		capacity: capacity,
+		onEvict:  onEvict,
+	}
+}
+
+// create stores a session with the given upstream sessions and returns its gateway session ID.
+// errSessionStoreFull is returned when the store is full of sessions that have not expired.
+func (s *sessionStore) create(upstreams map[string]*upstreamSession) (string, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+	sessionID := hex.EncodeToString(idBytes)
+
+	s.mu.Lock()
+	now := time.Now()
+	var expired []*gatewaySession
+	if len(s.sessions) >= s.capacity {
+		expired = s.removeExpired(now)
+	}
+	if len(s.sessions) >= s.capacity {
+		s.mu.Unlock()
+		s.evict(expired...)
+		return "", errSessionStoreFull
+	}
+	s.sessions[sessionID] = &gatewaySession{
+		upstreams:      upstreams,
+		resourceOwners: map[string]string{},
+		lastUsed:       now,
+	}
+	s.mu.Unlock()
+
+	s.evict(expired...)
+	return sessionID, nil
+}
+
+// full reports whether a session can not be created, after removing expired sessions. It is
+// checked before upstream sessions are created for a new session.
+func (s *sessionStore) full() bool {
+	s.mu.Lock()
+	var expired []*gatewaySession
+	if len(s.sessions) >= s.capacity {
+		expired = s.removeExpired(time.Now())
+	}
+	full := len(s.sessions) >= s.capacity
+	s.mu.Unlock()
+
+	s.evict(expired...)
+	return full
+}
+
+// get returns a session that has not been idle for longer than the session timeout.
+func (s *sessionStore) get(sessionID string) (*gatewaySession, bool) {
+	s.mu.Lock()
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		s.mu.Unlock()
+		return nil, false
+	}
+	now := time.Now()
+	if now.Sub(session.lastUsed) > s.timeout {
+		delete(s.sessions, sessionID)
+		s.mu.Unlock()
+		s.evict(session)
+		return nil, false
+	}
+	session.lastUsed = now
+	s.mu.Unlock()
+	return session, true
+}
+
+// setResourceOwners records the upstream of each resource URI listed by the upstreams.
+func (s *sessionStore) setResourceOwners(sessionID string, owners map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return
+	}
+	// Copy on write, so that sessions returned by get are never modified
+	s.sessions[sessionID] = &gatewaySession{
+		upstreams:      session.upstreams,
+		resourceOwners: owners,
+		lastUsed:       time.Now(),
+	}
+}
+
+// remove deletes a session and returns it.
+func (s *sessionStore) remove(sessionID string) (*gatewaySession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if ok {
+		delete(s.sessions, sessionID)
+	}
+	return session, ok
+}
+
+// removeExpired deletes the sessions idle for longer than the session timeout and returns
+// them. The caller must hold the lock.
+func (s *sessionStore) removeExpired(now time.Time) []*gatewaySession {
+	var expired []*gatewaySession
+	for sessionID, session := range s.sessions {
+		if now.Sub(session.lastUsed) > s.timeout {
+			delete(s.sessions, sessionID)
+			expired = append(expired, session)
+		}
+	}
+	return expired
+}
+
+// evict passes removed sessions to onEvict. The caller must not hold the lock.
+func (s *sessionStore) evict(sessions ...*gatewaySession) {
+	if s.onEvict == nil {
+		return
+	}
+	for _, session := range sessions {
+		s.onEvict(session)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcpaggregator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/wso2/gateway-controllers/policies/mcp-rewrite/jsonrpc"
+)
+
+// errUpstreamSessionExpired is returned when an upstream no longer knows its session
+var errUpstreamSessionExpired = errors.New("upstream session expired")
+
+// upstreamResponse is the response of an upstream to a JSON-RPC message.
+type upstreamResponse struct {
+	// message is the JSON-RPC response, nil for notifications
+	message   map[string]any
+	sessionID string
+}
+
+// send posts a JSON-RPC message to an upstream within its session. The session is nil before
+// initialize. For requests, the response with the id of the message is returned, taken from a
+// JSON body or from the events of an SSE body.
+func (p *McpAggregatorPolicy) send(upstream *UpstreamConfig, session *upstreamSession, message map[string]any) (*upstreamResponse, error) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, upstream.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	p.setUpstreamHeaders(req, upstream, session)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && session != nil && session.sessionID != "" {
+		return nil, errUpstreamSessionExpired
+	}
+	responseBody, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	result := &upstreamResponse{sessionID: resp.Header.Get(mcpSessionHeader)}
+
+	id, isRequest := message["id"]
+	if !isRequest {
+		if resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("upstream returned status %d", resp.StatusCode)
+		}
+		return result, nil
+	}
+
+	// JSON-RPC errors may come with an error status, so the body is read before the status
+	result.message = findResponse(responseBody, strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/event-stream"), jsonrpc.IDKey(id))
+	if result.message == nil {
+		return nil, fmt.Errorf("upstream returned status %d without a response to the request", resp.StatusCode)
+	}
+	return result, nil
+}
+
+// terminate ends the session with an upstream. Errors are logged, since the gateway session
+// ends regardless.
+func (p *McpAggregatorPolicy) terminate(upstream *UpstreamConfig, session *upstreamSession) {
+	if session.sessionID == "" {
+		return
+	}
+	req, err := http.NewRequest(http.MethodDelete, upstream.URL, nil)
+	if err != nil {
+		slog.Debug("MCP Aggregator Policy: Failed to create session termination request", "upstream", upstream.Name, "error", err)
+		return
+	}
+	p.setUpstreamHeaders(req, upstream, session)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		slog.Debug("MCP Aggregator Policy: Failed to terminate upstream session", "upstream", upstream.Name, "error", err)
+		return
+	}
+	resp.Body.Close()
+	slog.Debug("MCP Aggregator Policy: Terminated upstream session", "upstream", upstream.Name, "status", resp.StatusCode)
+}
+
+// setUpstreamHeaders sets the configured headers and the session headers of an upstream request.
+func (p *McpAggregatorPolicy) setUpstreamHeaders(req *http.Request, upstream *UpstreamConfig, session *upstreamSession) {
+	for name, value := range upstream.Headers {
+		req.Header.Set(name, value)
+	}
+	if session == nil {
+		return
+	}
+	if session.sessionID != "" {
+		req.Header.Set(mcpSessionHeader, session.sessionID)
+	}
+	if session.protocolVersion != "" {
+		req.Header.Set(mcpProtocolVersionHeader, session.protocolVersion)
+	}
+}
+
+// findResponse returns the JSON-RPC response with the given id key from a JSON or SSE body.
+// SSE bodies may carry notifications and requests of the upstream before the response.
+func findResponse(body []byte, isSse bool, idKey string) map[string]any {
+	payloads := [][]byte{body}
+	if isSse {
+		payloads = payloads[:0]
+		for _, event := range jsonrpc.ParseEventStream(body) {
+			if strings.TrimSpace(event.Data) != "" {
+				payloads = append(payloads, []byte(event.Data))
+			}
+		}
+	}
+
+	for _, payload := range payloads {
+		messages, _, err := jsonrpc.ParseMessages(payload)
+		if err != nil {
+			continue
+		}
+		for _, message := range messages {
+			response, ok := message.(map[string]any)
+			if !ok {
+				continue
+			}
+			id, hasID := response["id"]
+			_, hasResult := response["result"]
+			_, hasError := response["error"]
+			if hasID && (hasResult || hasError) && jsonrpc.IDKey(id) == idKey {
+				return response
+			}
+		}
+	}
+	return nil
+}
+
+// fanOut calls fn for each upstream concurrently and waits for all calls to finish.
+func fanOut(upstreams []*UpstreamConfig, fn func(i int, upstream *UpstreamConfig)) {
+	var wg sync.WaitGroup
+	for i, upstream := range upstreams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(i, upstream)
+		}()
+	}
+	wg.Wait()
+}
@@ -31,6 +31,7 @@ import (
 
 const (
 	WWWAuthenticateHeader  = "WWW-Authenticate"
+	JwtChallengeHeader     = "www-authenticate" // Challenge header set by the JWT Auth Policy
 	AuthMethodBearer       = "Bearer resource_metadata="
 	WellKnownPath          = ".well-known/oauth-protected-resource"
 	McpSessionHeader       = "mcp-session-id"
@@ -161,27 +162,32 @@ func (p *McpAuthPolicy) handleAuth(ctx *policy.RequestContext, params map[string
 	slog.Debug("MCP Auth Policy: Delegating authentication to JWT Auth Policy")
 	jwtPolicy, _ := jwtauth.GetPolicy(policy.PolicyMetadata{}, params)
 	reqAction := jwtPolicy.OnRequest(ctx, params)
-	if _, ok := reqAction.(policy.ImmediateResponse); ok {
+	if ir, ok := reqAction.(policy.ImmediateResponse); ok {
 		slog.Debug("MCP Auth Policy: Authentication failed in JWT Auth Policy, handling failure")
-		headers := reqAction.(policy.ImmediateResponse).Headers
-		ir := reqAction.(policy.ImmediateResponse)
-		escapedDesc := ""
-		contentType := ir.Headers["content-type"]
-		if contentType == "application/json" {
-			var errResp map[string]any
-			if err := json.Unmarshal(ir.Body, &errResp); err == nil {
-				if errDesc, ok := errResp["message"].(string); ok {
-					escapedDesc = strings.ReplaceAll(errDesc, "\"", "'")
+		headers := ir.Headers
+		var wwwAuthHeader string
+		if challenge, ok := headers[JwtChallengeHeader]; ok {
+			// JWT Auth Policy already classified the failure; advertise the resource metadata alongside it
+			delete(headers, JwtChallengeHeader)
+			wwwAuthHeader = mergeWwwAuthenticateHeader(ctx, params, scopes, challenge)
+		} else {
+			escapedDesc := ""
+			if headers["content-type"] == "application/json" {
+				var errResp map[string]any
+				if err := json.Unmarshal(ir.Body, &errResp); err == nil {
+					if errDesc, ok := errResp["message"].(string); ok {
+						escapedDesc = strings.ReplaceAll(errDesc, "\"", "'")
+					}
 				}
 			}
+			wwwAuthHeader = generateWwwAuthenticateHeader(ctx, params, scopes, escapedDesc)
 		}
-		wwwAuthHeader := generateWwwAuthenticateHeader(ctx, params, scopes, escapedDesc)
 		headers[WWWAuthenticateHeader] = wwwAuthHeader
 		headers[McpSessionHeader] = sessionId
 		return policy.ImmediateResponse{
-			StatusCode: reqAction.(policy.ImmediateResponse).StatusCode,
+			StatusCode: ir.StatusCode,
 			Headers:    headers,
-			Body:       reqAction.(policy.ImmediateResponse).Body,
+			Body:       ir.Body,
 		}
 	}
 	return reqAction
@@ -274,6 +280,97 @@ func generateWwwAuthenticateHeader(ctx *policy.RequestContext, params map[string
 	return headerValue
 }
 
+// mergeWwwAuthenticateHeader adds the resource metadata URL to the Bearer challenge returned by the
+// JWT Auth Policy. The MCP scopes are added when the Bearer challenge does not already carry a scope.
+// Challenges of other schemes, such as DPoP, are kept as separate challenges after the Bearer one.
+func mergeWwwAuthenticateHeader(ctx *policy.RequestContext, params map[string]any, scopes []string, challenge string) string {
+	slog.Debug("MCP Auth Policy: Merging WWW-Authenticate header from JWT Auth Policy")
+	var bearerParams []string
+	var others []string
+	for _, c := range parseChallenges(challenge) {
+		if strings.EqualFold(c.scheme, "Bearer") {
+			bearerParams = append(bearerParams, c.params...)
+			continue
+		}
+		other := c.scheme
+		if len(c.params) > 0 {
+			other += " " + strings.Join(c.params, ", ")
+		}
+		others = append(others, other)
+	}
+
+	headerValue := AuthMethodBearer + "\"" + generateResourcePath(ctx, params, WellKnownPath) + "\""
+	hasScope := false
+	for _, param := range bearerParams {
+		if name, _, _ := strings.Cut(param, "="); strings.EqualFold(strings.TrimSpace(name), "scope") {
+			hasScope = true
+		}
+	}
+	if len(scopes) > 0 && !hasScope {
+		headerValue += ", scope=\"" + strings.Join(scopes, " ") + "\""
+	}
+	for _, param := range bearerParams {
+		headerValue += ", " + param
+	}
+	for _, other := range others {
+		headerValue += ", " + other
+	}
+	return headerValue
+}
+
+// wwwAuthenticateChallenge is a challenge of a WWW-Authenticate header value
+type wwwAuthenticateChallenge struct {
+	scheme string
+	params []string // auth-params, or a token68, as they appear in the header
+}
+
+// parseChallenges splits a WWW-Authenticate header value into its challenges (RFC 9110 section 11.6.1).
+// Items separated by commas outside quoted strings start a new challenge when their first token is
+// not followed by "=".
+func parseChallenges(header string) []wwwAuthenticateChallenge {
+	var challenges []wwwAuthenticateChallenge
+	for _, item := range splitOutsideQuotes(header, ',') {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		token, rest, _ := strings.Cut(item, " ")
+		if !strings.Contains(token, "=") {
+			challenges = append(challenges, wwwAuthenticateChallenge{scheme: token})
+			item = strings.TrimSpace(rest)
+			if item == "" {
+				continue
+			}
+		}
+		if len(challenges) == 0 {
+			continue
+		}
+		last := &challenges[len(challenges)-1]
+		last.params = append(last.params, item)
+	}
+	return challenges
+}
+
+// splitOutsideQuotes splits s at each sep that is not inside a quoted string
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	inQuotes, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case inQuotes && s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
 // parseAuthority extracts host and port from an authority string (e.g., "example.com:8080")
 func parseAuthority(authority string) (host string, port int) {
 	if authority == "" {
@@ -19,9 +19,18 @@
 package mcpauthn
 
 import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
 )
@@ -315,6 +324,141 @@ func TestOnRequest_Delegation_Failure(t *testing.T) {
 	}
 }
 
+func TestOnRequest_Delegation_DPoPFailure(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]any{{
+				"kty": "RSA",
+				"kid": "test-kid",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+			}},
+		})
+	}))
+	defer jwksServer.Close()
+
+	params := map[string]any{
+		"gatewayHost":       "gateway.com",
+		"allowedAlgorithms": []any{"RS256"},
+		"validateIssuer":    false,
+		"keyManagers": []any{
+			map[string]any{
+				"name": "test-issuer",
+				"jwks": map[string]any{
+					"remote": map[string]any{"uri": jwksServer.URL + "/jwks.json"},
+				},
+			},
+		},
+		"dpop": "required",
+	}
+
+	// A valid token that is not DPoP-bound is rejected with a DPoP challenge
+	token := signTestToken(t, privateKey, "test-kid", map[string]any{
+		"sub": "user123",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	ctx := createMockRequestContext(map[string][]string{"authorization": {"DPoP " + token}})
+	ctx.Path = "/api/resource"
+
+	resp, ok := (&McpAuthPolicy{}).OnRequest(ctx, params).(policy.ImmediateResponse)
+	if !ok {
+		t.Fatalf("Expected ImmediateResponse")
+	}
+	if resp.StatusCode != 401 {
+		t.Fatalf("Expected status 401, got %d", resp.StatusCode)
+	}
+
+	challenges := parseChallenges(resp.Headers[WWWAuthenticateHeader])
+	if len(challenges) != 2 || challenges[0].scheme != "Bearer" || challenges[1].scheme != "DPoP" {
+		t.Fatalf("Expected a Bearer and a DPoP challenge, got %q", resp.Headers[WWWAuthenticateHeader])
+	}
+	if challenges[0].params[0] != `resource_metadata="http://gateway.com:8080/.well-known/oauth-protected-resource"` {
+		t.Errorf("Expected resource metadata in the Bearer challenge, got %v", challenges[0].params)
+	}
+	dpop := strings.Join(challenges[1].params, ", ")
+	if !strings.Contains(dpop, `error="invalid_token"`) || !strings.Contains(dpop, "algs=") {
+		t.Errorf("Expected the DPoP challenge of the JWT Auth Policy, got %q", dpop)
+	}
+}
+
+// signTestToken returns an RS256 JWT with the given claims
+func signTestToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]any{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestMergeWwwAuthenticateHeader(t *testing.T) {
+	ctx := createMockRequestContext(map[string][]string{})
+	params := map[string]any{
+		"gatewayHost": "gateway.com",
+	}
+	resourceMetadata := `Bearer resource_metadata="http://gateway.com:8080/.well-known/oauth-protected-resource"`
+
+	tests := []struct {
+		name      string
+		scopes    []string
+		challenge string
+		want      string
+	}{
+		{
+			name:      "no token",
+			scopes:    []string{"mcp:tools"},
+			challenge: "Bearer",
+			want:      resourceMetadata + `, scope="mcp:tools"`,
+		},
+		{
+			name:      "invalid token keeps error",
+			challenge: `Bearer error="invalid_token", error_description="The access token expired"`,
+			want:      resourceMetadata + `, error="invalid_token", error_description="The access token expired"`,
+		},
+		{
+			name:      "scope from JWT challenge is kept",
+			scopes:    []string{"mcp:tools"},
+			challenge: `Bearer realm="mcp", scope="mcp:read", error="insufficient_scope"`,
+			want:      resourceMetadata + `, realm="mcp", scope="mcp:read", error="insufficient_scope"`,
+		},
+		{
+			name:      "quoted commas are kept",
+			challenge: `Bearer error="invalid_token", error_description="Expired, renew it"`,
+			want:      resourceMetadata + `, error="invalid_token", error_description="Expired, renew it"`,
+		},
+		{
+			name:      "DPoP challenge is kept separate",
+			scopes:    []string{"mcp:tools"},
+			challenge: `DPoP scope="mcp:read", error="invalid_dpop_proof", algs="ES256 RS256"`,
+			want:      resourceMetadata + `, scope="mcp:tools", DPoP scope="mcp:read", error="invalid_dpop_proof", algs="ES256 RS256"`,
+		},
+		{
+			name:      "DPoP challenge without parameters",
+			challenge: `DPoP`,
+			want:      resourceMetadata + `, DPoP`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeWwwAuthenticateHeader(ctx, params, tt.scopes, tt.challenge); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
 func createMockRequestContext(headers map[string][]string) *policy.RequestContext {
 	return &policy.RequestContext{
 		SharedContext: &policy.SharedContext{
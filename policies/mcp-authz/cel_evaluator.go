@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcpauthz
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/cel-go/cel"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/mcp-rewrite/jsonrpc"
+)
+
+// conditionEnv is the CEL environment shared by all rule conditions
+var (
+	conditionEnv     *cel.Env
+	conditionEnvOnce sync.Once
+	conditionEnvErr  error
+)
+
+// getConditionEnv returns the CEL environment for rule conditions.
+// Claims and params are exposed as dynamic maps so nested values can be accessed with field
+// selection, e.g. params.arguments.to or claims.realm_access.roles.
+func getConditionEnv() (*cel.Env, error) {
+	conditionEnvOnce.Do(func() {
+		conditionEnv, conditionEnvErr = cel.NewEnv(
+			cel.Variable("claims", cel.MapType(cel.StringType, cel.DynType)),
+			cel.Variable("params", cel.MapType(cel.StringType, cel.DynType)),
+			cel.Variable("method", cel.StringType),
+			// Request context variables
+			cel.Variable("request.Headers", cel.MapType(cel.StringType, cel.ListType(cel.StringType))),
+			cel.Variable("request.SessionID", cel.StringType),
+		)
+	})
+	if conditionEnvErr != nil {
+		return nil, fmt.Errorf("failed to create condition CEL environment: %w", conditionEnvErr)
+	}
+	return conditionEnv, nil
+}
+
+// compileCondition compiles a rule condition, which must return a bool
+func compileCondition(expression string) (cel.Program, error) {
+	env, err := getConditionEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("CEL compilation failed: %w", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("CEL expression must return bool, got %s", ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("CEL program creation failed: %w", err)
+	}
+	return program, nil
+}
+
+// evaluateCondition evaluates a compiled condition. A condition that fails to evaluate, for
+// example because an argument it reads is missing, or does not return a bool, is not satisfied.
+func evaluateCondition(program cel.Program, evalCtx map[string]any) (bool, error) {
+	result, _, err := program.Eval(evalCtx)
+	if err != nil {
+		return false, fmt.Errorf("CEL evaluation failed: %w", err)
+	}
+
+	satisfied, ok := result.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("CEL expression must return bool, got %T", result.Value())
+	}
+	return satisfied, nil
+}
+
+// buildConditionEvalContext builds the CEL evaluation context for the conditions of an MCP request
+func buildConditionEvalContext(ctx *policy.RequestContext, method string, params MCPRequestParams, claims jwt.MapClaims) map[string]any {
+	// Convert headers to map[string][]string for CEL
+	headers := make(map[string][]string)
+	if ctx.Headers != nil {
+		ctx.Headers.Iterate(func(key string, values []string) {
+			headers[key] = values
+		})
+	}
+
+	arguments := params.Arguments
+	if arguments == nil {
+		arguments = map[string]any{}
+	}
+
+	return map[string]any{
+		"claims": map[string]any(claims),
+		"params": map[string]any{
+			"name":      params.Name,
+			"uri":       params.URI,
+			"arguments": arguments,
+		},
+		"method":            method,
+		"request.Headers":   headers,
+		"request.SessionID": jsonrpc.SessionID(ctx.Headers),
+	}
+}
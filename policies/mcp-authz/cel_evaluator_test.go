@@ -0,0 +1,213 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcpauthz
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+func createConditionParams() map[string]any {
+	return map[string]any{
+		"rules": []any{
+			map[string]any{
+				"name":      "email-domain",
+				"attribute": map[string]any{"type": "tool", "name": "send_email"},
+				"condition": `params.arguments.to.endsWith("@" + claims.email_domain)`,
+			},
+			map[string]any{
+				"name":      "allowed-tables",
+				"attribute": map[string]any{"type": "tool", "name": "query_db"},
+				"condition": `params.arguments.table in claims.tables`,
+			},
+			map[string]any{
+				"name":      "session-header",
+				"attribute": map[string]any{"type": "tool", "name": "audit"},
+				"condition": `request.SessionID != "" && request.Headers["x-tenant"][0] == claims.tenant`,
+			},
+		},
+	}
+}
+
+func createMockRequestContext(body string, headers map[string][]string) *policy.RequestContext {
+	return &policy.RequestContext{
+		SharedContext: &policy.SharedContext{
+			RequestID: "test-request-id",
+			Metadata: map[string]any{
+				MetadataValidatedClaims: jwt.MapClaims{
+					"sub":          "alice",
+					"email_domain": "example.com",
+					"tables":       []any{"orders", "customers"},
+					"tenant":       "acme",
+				},
+			},
+		},
+		Headers: policy.NewHeaders(headers),
+		Body:    &policy.Body{Content: []byte(body), Present: true},
+		Path:    "/mcp",
+		Method:  "POST",
+		Scheme:  "http",
+	}
+}
+
+func TestOnRequest_Conditions(t *testing.T) {
+	p, err := GetPolicy(policy.PolicyMetadata{}, createConditionParams())
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		headers    map[string][]string
+		failedRule string
+	}{
+		{
+			name: "email to caller domain",
+			body: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"send_email","arguments":{"to":"bob@example.com"}}}`,
+		},
+		{
+			name:       "email to other domain",
+			body:       `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"send_email","arguments":{"to":"bob@example.com.evil.io"}}}`,
+			failedRule: "email-domain",
+		},
+		{
+			name:       "missing argument",
+			body:       `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"send_email"}}`,
+			failedRule: "email-domain",
+		},
+		{
+			name: "table listed in claim",
+			body: `{"jsonrpc":"2.0","id":"q","method":"tools/call","params":{"name":"query_db","arguments":{"table":"orders"}}}`,
+		},
+		{
+			name:       "table not listed in claim",
+			body:       `{"jsonrpc":"2.0","id":"q","method":"tools/call","params":{"name":"query_db","arguments":{"table":"payments"}}}`,
+			failedRule: "allowed-tables",
+		},
+		{
+			name:    "session and header",
+			body:    `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"audit"}}`,
+			headers: map[string][]string{MCPSessionHeader: {"session-1"}, "x-tenant": {"acme"}},
+		},
+		{
+			name:       "no session",
+			body:       `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"audit"}}`,
+			headers:    map[string][]string{"x-tenant": {"acme"}},
+			failedRule: "session-header",
+		},
+		{
+			name: "tool without rule",
+			body: `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"get_weather"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action := p.OnRequest(createMockRequestContext(tt.body, tt.headers), nil)
+			if tt.failedRule == "" {
+				if action != nil {
+					t.Fatalf("Expected no action, got %T", action)
+				}
+				return
+			}
+
+			response, ok := action.(policy.ImmediateResponse)
+			if !ok {
+				t.Fatalf("Expected ImmediateResponse, got %T", action)
+			}
+			if response.StatusCode != 403 {
+				t.Fatalf("Expected status 403, got %d", response.StatusCode)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(response.Body, &body); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			errorObject := body["error"].(map[string]any)
+			if rule := errorObject["data"].(map[string]any)["rule"]; rule != tt.failedRule {
+				t.Fatalf("Expected failed rule %q, got %v", tt.failedRule, rule)
+			}
+		})
+	}
+}
+
+func TestOnRequest_ConditionsInBatch(t *testing.T) {
+	p, err := GetPolicy(policy.PolicyMetadata{}, createConditionParams())
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	ctx := createMockRequestContext(`[
+		{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"query_db","arguments":{"table":"payments"}}},
+		{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"query_db","arguments":{"table":"orders"}}}
+	]`, nil)
+
+	action := p.OnRequest(ctx, nil)
+	mods, ok := action.(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamRequestModifications, got %T", action)
+	}
+	var forwarded []map[string]any
+	if err := json.Unmarshal(mods.Body, &forwarded); err != nil || len(forwarded) != 1 || forwarded[0]["id"] != float64(2) {
+		t.Fatalf("Expected only request 2 to be forwarded, got %s", mods.Body)
+	}
+
+	response := p.OnResponse(&policy.ResponseContext{
+		SharedContext:   ctx.SharedContext,
+		ResponseHeaders: policy.NewHeaders(nil),
+		ResponseStatus:  200,
+		ResponseBody:    &policy.Body{Content: []byte(`[{"jsonrpc":"2.0","id":2,"result":{}}]`), Present: true},
+	}, nil).(policy.UpstreamResponseModifications)
+
+	var responses []map[string]any
+	if err := json.Unmarshal(response.Body, &responses); err != nil || len(responses) != 2 {
+		t.Fatalf("Expected 2 responses, got %s", response.Body)
+	}
+	denied := responses[1]
+	if denied["id"] != float64(1) {
+		t.Fatalf("Expected the error for request 1, got %v", denied)
+	}
+	if rule := denied["error"].(map[string]any)["data"].(map[string]any)["rule"]; rule != "allowed-tables" {
+		t.Fatalf("Expected failed rule allowed-tables, got %v", rule)
+	}
+}
+
+func TestGetPolicy_InvalidCondition(t *testing.T) {
+	tests := []string{
+		`params.arguments.to.endsWith(`,
+		`"not a bool"`,
+		`unknown.field == "x"`,
+	}
+	for _, condition := range tests {
+		params := map[string]any{
+			"rules": []any{
+				map[string]any{
+					"attribute": map[string]any{"type": "tool"},
+					"condition": condition,
+				},
+			},
+		}
+		if _, err := GetPolicy(policy.PolicyMetadata{}, params); err == nil {
+			t.Fatalf("Expected error for condition %q", condition)
+		}
+	}
+}
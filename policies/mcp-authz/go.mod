@@ -4,5 +4,21 @@ go 1.25.1
 
 require (
 	github.com/golang-jwt/jwt/v5 v5.2.2
-	github.com/wso2/api-platform/sdk v0.3.1
+	github.com/google/cel-go v0.26.1
+	github.com/wso2/api-platform/sdk v0.3.7
+	github.com/wso2/gateway-controllers/policies/mcp-rewrite v0.4.0
 )
+
+require (
+	cel.dev/expr v0.24.0 // indirect
+	github.com/antlr4-go/antlr/v4 v4.13.0 // indirect
+	github.com/stoewer/go-strcase v1.2.0 // indirect
+	golang.org/x/crypto v0.46.0 // indirect
+	golang.org/x/exp v0.0.0-20250408133849-7e4ce0ab07d0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20251213004720-97cd9d5aeac2 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20251213004720-97cd9d5aeac2 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
+)
+
+replace github.com/wso2/gateway-controllers/policies/mcp-rewrite => ../mcp-rewrite
@@ -19,6 +19,7 @@
 package mcpauthz
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -27,7 +28,9 @@ import (
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/cel-go/cel"
 	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/mcp-rewrite/jsonrpc"
 )
 
 const (
@@ -38,10 +41,16 @@ const (
 	MetadataMcpMethod         = "mcp.method"
 	MetadataMcpCapabilityType = "mcp.type"
 	MetadataMcpCapabilityName = "mcp.name"
+	MetadataMcpBatchErrors    = "mcp.authz.batchErrors"
+	MCPSessionHeader          = jsonrpc.SessionHeader
+
+	// forbiddenMessage is the error message of requests denied by a rule
+	forbiddenMessage = "Forbidden: insufficient permissions to access this MCP resource"
 )
 
 // MCPRequest represents the JSON-RPC MCP request structure
 type MCPRequest struct {
+	ID     any              `json:"id,omitempty"` // Nil for notifications
 	Method string           `json:"method"`
 	Params MCPRequestParams `json:"params"`
 }
@@ -59,9 +68,14 @@ type MCPRequestParams struct {
 
 // Rule represents a single authorization rule
 type Rule struct {
+	Name           string
 	Attribute      Attribute
 	RequiredClaims map[string]string
 	RequiredScopes []string
+	Condition      string
+
+	// program is the compiled Condition
+	program cel.Program
 }
 
 // Attribute represents the MCP resource attribute being authorized
@@ -72,6 +86,9 @@ type Attribute struct {
 
 type McpAuthzPolicy struct {
 	Rules []Rule
+
+	// hasConditions is true if any rule has a CEL condition
+	hasConditions bool
 }
 
 func GetPolicy(
@@ -88,6 +105,11 @@ func GetPolicy(
 		return nil, fmt.Errorf("failed to parse rules: %w", err)
 	}
 	p.Rules = rules
+	for _, rule := range rules {
+		if rule.program != nil {
+			p.hasConditions = true
+		}
+	}
 
 	slog.Debug("MCP Authorization Policy: Parsed policy configuration",
 		"rulesCount", len(p.Rules))
@@ -126,7 +148,16 @@ func parseRules(params map[string]any) ([]Rule, error) {
 
 // parseRule parses a single rule from a map
 func parseRule(ruleMap map[string]any, index int) (Rule, error) {
-	rule := Rule{}
+	rule := Rule{Name: fmt.Sprintf("rules[%d]", index)}
+
+	// Parse name (optional, identifies the rule in errors)
+	if nameRaw, ok := ruleMap["name"]; ok {
+		name, ok := nameRaw.(string)
+		if !ok || strings.TrimSpace(name) == "" {
+			return rule, fmt.Errorf("rules[%d].name must be a non-empty string", index)
+		}
+		rule.Name = name
+	}
 
 	// Parse attribute (required)
 	attrRaw, ok := ruleMap["attribute"]
@@ -194,6 +225,20 @@ func parseRule(ruleMap map[string]any, index int) (Rule, error) {
 		}
 	}
 
+	// Parse condition (optional CEL expression)
+	if conditionRaw, ok := ruleMap["condition"]; ok {
+		condition, ok := conditionRaw.(string)
+		if !ok || strings.TrimSpace(condition) == "" {
+			return rule, fmt.Errorf("rules[%d].condition must be a non-empty string", index)
+		}
+		program, err := compileCondition(condition)
+		if err != nil {
+			return rule, fmt.Errorf("rules[%d].condition is invalid: %w", index, err)
+		}
+		rule.Condition = condition
+		rule.program = program
+	}
+
 	return rule, nil
 }
 
@@ -203,11 +248,17 @@ func isValidAttributeType(attrType string) bool {
 }
 
 func (p *McpAuthzPolicy) Mode() policy.ProcessingMode {
+	// The response body is only needed to add the errors of denied batch elements,
+	// which requires at least one rule
+	responseBodyMode := policy.BodyModeSkip
+	if len(p.Rules) > 0 {
+		responseBodyMode = policy.BodyModeBuffer
+	}
 	return policy.ProcessingMode{
 		RequestHeaderMode:  policy.HeaderModeSkip,
 		RequestBodyMode:    policy.BodyModeBuffer,
 		ResponseHeaderMode: policy.HeaderModeSkip,
-		ResponseBodyMode:   policy.BodyModeSkip,
+		ResponseBodyMode:   responseBodyMode,
 	}
 }
 
@@ -230,9 +281,18 @@ func (p *McpAuthzPolicy) OnRequest(ctx *policy.RequestContext, params map[string
 		return p.handleAuthFailure(ctx, "Unauthorized: scope/claim validation failed", nil)
 	}
 
+	payload, err := jsonrpc.ParsePayload(ctx.Body.Content, jsonrpc.IsEventStream(ctx.Headers))
+	if err != nil {
+		slog.Debug("MCP Authorization Policy: Failed to parse MCP request", "error", err)
+		return p.handleAuthFailure(ctx, "Invalid MCP request format", nil)
+	}
+	if payload.Batch {
+		return p.handleBatchRequest(ctx, claims, payload)
+	}
+
 	// Parse MCP request to extract method and name
-	var mcpReq MCPRequest
-	if err := json.Unmarshal(ctx.Body.Content, &mcpReq); err != nil {
+	mcpReq, err := parseMCPRequest(payload.Messages[0])
+	if err != nil {
 		slog.Debug("MCP Authorization Policy: Failed to parse MCP request", "error", err)
 		return p.handleAuthFailure(ctx, "Invalid MCP request format", nil)
 	}
@@ -258,20 +318,215 @@ func (p *McpAuthzPolicy) OnRequest(ctx *policy.RequestContext, params map[string
 	ctx.Metadata[MetadataMcpCapabilityName] = attributeName
 
 	// Check authorization rules
-	authorized, missingScopes := p.checkAuthorization(attributeType, attributeName, mcpReq.Method, claims)
+	authorized, missingScopes, failedRule := p.checkAuthorization(attributeType, attributeName, mcpReq.Method, claims, p.conditionEvalContext(ctx, mcpReq, claims))
 	if !authorized {
 		slog.Debug("MCP Authorization Policy: Authorization check failed",
 			"attributeName", mcpReq.Params.Name,
-			"method", mcpReq.Method)
-		return p.handleAuthFailure(ctx, "Forbidden: insufficient permissions to access this MCP resource", missingScopes)
+			"method", mcpReq.Method,
+			"failedRule", failedRule)
+		return p.handleRuleFailure(ctx, mcpReq.ID, failedRule, missingScopes)
 	}
 
 	slog.Debug("MCP Authorization Policy: Authorization check passed")
 	return nil
 }
 
+// handleBatchRequest authorizes each element of a JSON-RPC batch. Denied elements are removed
+// from the forwarded batch and answered with JSON-RPC errors for their ids, which are added to
+// the upstream response. Denied notifications are dropped without an error.
+func (p *McpAuthzPolicy) handleBatchRequest(ctx *policy.RequestContext, claims jwt.MapClaims, payload jsonrpc.Payload) policy.RequestAction {
+	forwarded := make([]any, 0, len(payload.Messages))
+	errorEntries := make([]any, 0)
+	missingScopes := make(map[string]struct{})
+	denied := false
+
+	for _, message := range payload.Messages {
+		requestPayload, ok := message.(map[string]any)
+		if !ok {
+			// Not a request; the upstream answers it with an Invalid Request error
+			forwarded = append(forwarded, message)
+			continue
+		}
+		requestID, hasID := requestPayload["id"]
+
+		mcpReq, err := parseMCPRequest(requestPayload)
+		if err != nil {
+			slog.Debug("MCP Authorization Policy: Failed to parse MCP batch element", "error", err)
+			if hasID {
+				errorEntries = append(errorEntries, jsonrpc.ErrorEntry(requestID, -32600, "Invalid MCP request format", nil))
+			}
+			continue
+		}
+
+		attributeType, ok := p.getAttributeTypeFromMethod(mcpReq.Method)
+		if !ok {
+			forwarded = append(forwarded, message)
+			continue
+		}
+		attributeName := p.getAttributeNameFromParams(mcpReq.Method, mcpReq.Params)
+
+		authorized, scopes, failedRule := p.checkAuthorization(attributeType, attributeName, mcpReq.Method, claims, p.conditionEvalContext(ctx, mcpReq, claims))
+		if authorized {
+			forwarded = append(forwarded, message)
+			continue
+		}
+
+		slog.Debug("MCP Authorization Policy: Authorization check failed for batch element",
+			"attributeName", attributeName,
+			"method", mcpReq.Method,
+			"failedRule", failedRule)
+		denied = true
+		for s := range scopes {
+			missingScopes[s] = struct{}{}
+		}
+		if hasID {
+			errorEntries = append(errorEntries, jsonrpc.ErrorEntry(requestID, -32000, forbiddenMessage, map[string]any{"rule": failedRule}))
+		}
+	}
+
+	if len(forwarded) == len(payload.Messages) {
+		slog.Debug("MCP Authorization Policy: Authorization check passed for batch")
+		return nil
+	}
+
+	if len(forwarded) == 0 {
+		if len(errorEntries) == 0 {
+			// Only notifications were sent, which are not answered
+			return jsonrpc.Response(ctx.Headers, 202, nil, nil)
+		}
+		if !denied {
+			return jsonrpc.Response(ctx.Headers, 400, errorEntries, nil)
+		}
+		var scopes []string
+		for s := range missingScopes {
+			scopes = append(scopes, s)
+		}
+		headers := map[string]string{
+			WWWAuthenticateHeader: generateWwwAuthenticateHeader(ctx, scopes, forbiddenMessage),
+		}
+		return jsonrpc.Response(ctx.Headers, 403, errorEntries, headers)
+	}
+
+	body, err := payload.Encode(forwarded)
+	if err != nil {
+		slog.Debug("MCP Authorization Policy: Failed to marshal forwarded batch", "error", err)
+		return p.handleAuthFailure(ctx, "Invalid MCP request format", nil)
+	}
+
+	slog.Debug("MCP Authorization Policy: Forwarding authorized batch elements",
+		"batchSize", len(payload.Messages),
+		"forwarded", len(forwarded),
+		"errors", len(errorEntries))
+	ctx.Metadata[MetadataMcpBatchErrors] = errorEntries
+	return policy.UpstreamRequestModifications{
+		Body: body,
+	}
+}
+
+// OnResponse adds the errors of batch elements denied on the request path to the batch response
 func (p *McpAuthzPolicy) OnResponse(ctx *policy.ResponseContext, params map[string]any) policy.ResponseAction {
-	return nil
+	errorEntries, ok := ctx.Metadata[MetadataMcpBatchErrors].([]any)
+	if !ok || len(errorEntries) == 0 {
+		return nil
+	}
+	if ctx.ResponseStatus >= 300 {
+		slog.Debug("MCP Authorization Policy: Batch response skipped, upstream returned an error", "status", ctx.ResponseStatus)
+		return nil
+	}
+
+	errorsJSON, err := json.Marshal(errorEntries)
+	if err != nil {
+		slog.Debug("MCP Authorization Policy: Failed to marshal batch errors", "error", err)
+		return nil
+	}
+
+	var content []byte
+	if ctx.ResponseBody != nil && ctx.ResponseBody.Present {
+		content = ctx.ResponseBody.Content
+	}
+
+	if len(bytes.TrimSpace(content)) == 0 {
+		// Only notifications were forwarded, so the upstream accepted the batch without a body
+		statusCode := 200
+		return policy.UpstreamResponseModifications{
+			SetHeaders: map[string]string{"content-type": "application/json"},
+			Body:       errorsJSON,
+			StatusCode: &statusCode,
+		}
+	}
+
+	if jsonrpc.IsEventStream(ctx.ResponseHeaders) {
+		// Send the errors as an additional event of the stream
+		events := append(jsonrpc.ParseEventStream(content), jsonrpc.Event{Data: string(errorsJSON)})
+		return policy.UpstreamResponseModifications{
+			Body: jsonrpc.BuildEventStream(events),
+		}
+	}
+
+	var responses []json.RawMessage
+	if err := json.Unmarshal(content, &responses); err != nil {
+		if !json.Valid(content) {
+			slog.Debug("MCP Authorization Policy: Failed to parse MCP batch response", "error", err)
+			return nil
+		}
+		// A single response, e.g. an error for the whole batch
+		responses = []json.RawMessage{json.RawMessage(content)}
+	}
+	for _, entry := range errorEntries {
+		entryJSON, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		responses = append(responses, entryJSON)
+	}
+
+	body, err := json.Marshal(responses)
+	if err != nil {
+		slog.Debug("MCP Authorization Policy: Failed to marshal batch response", "error", err)
+		return nil
+	}
+	return policy.UpstreamResponseModifications{
+		Body: body,
+	}
+}
+
+// parseMCPRequest reads the id, method and params of a JSON-RPC request
+func parseMCPRequest(message any) (MCPRequest, error) {
+	var mcpReq MCPRequest
+	requestPayload, ok := message.(map[string]any)
+	if !ok {
+		return mcpReq, fmt.Errorf("JSON-RPC request must be an object")
+	}
+	mcpReq.ID = requestPayload["id"]
+	if mcpReq.Method, ok = requestPayload["method"].(string); !ok && requestPayload["method"] != nil {
+		return mcpReq, fmt.Errorf("method must be a string")
+	}
+
+	params, ok := requestPayload["params"].(map[string]any)
+	if !ok {
+		if requestPayload["params"] != nil {
+			return mcpReq, fmt.Errorf("params must be an object")
+		}
+		return mcpReq, nil
+	}
+	if mcpReq.Params.Name, ok = params["name"].(string); !ok && params["name"] != nil {
+		return mcpReq, fmt.Errorf("params.name must be a string")
+	}
+	if mcpReq.Params.URI, ok = params["uri"].(string); !ok && params["uri"] != nil {
+		return mcpReq, fmt.Errorf("params.uri must be a string")
+	}
+	if mcpReq.Params.Arguments, ok = params["arguments"].(map[string]any); !ok && params["arguments"] != nil {
+		return mcpReq, fmt.Errorf("params.arguments must be an object")
+	}
+	return mcpReq, nil
+}
+
+// conditionEvalContext builds the evaluation context of rule conditions, if any rule has one
+func (p *McpAuthzPolicy) conditionEvalContext(ctx *policy.RequestContext, mcpReq MCPRequest, claims jwt.MapClaims) map[string]any {
+	if !p.hasConditions {
+		return nil
+	}
+	return buildConditionEvalContext(ctx, mcpReq.Method, mcpReq.Params, claims)
 }
 
 // getAttributeTypeFromMethod extracts the attribute type from the MCP method
@@ -314,30 +569,36 @@ func (p *McpAuthzPolicy) getAttributeNameFromParams(method string, params MCPReq
 	}
 }
 
-// checkAuthorization validates whether the request should be authorized
-func (p *McpAuthzPolicy) checkAuthorization(attributeType, attributeName, method string, claims jwt.MapClaims) (bool, map[string]struct{}) {
+// checkAuthorization validates whether the request should be authorized.
+// It returns the missing scopes and the name of the first matching rule that did not grant access.
+func (p *McpAuthzPolicy) checkAuthorization(attributeType, attributeName, method string, claims jwt.MapClaims, evalCtx map[string]any) (bool, map[string]struct{}, string) {
 	if len(p.Rules) == 0 {
 		slog.Debug("MCP Authorization Policy: No rules configured")
-		return true, nil
+		return true, nil, ""
 	}
 
 	// Find matching rules (most specific first)
 	matchingRules := p.findMatchingRules(attributeType, attributeName, method)
 	if len(matchingRules) == 0 {
 		slog.Debug("MCP Authorization Policy: No matching rules found")
-		return true, nil
+		return true, nil, ""
 	}
 
 	var missingScopes = make(map[string]struct{})
 	// Check if any matching rule grants access
 	isAuthorized := true
+	failedRule := ""
 	for _, rule := range matchingRules {
-		if ok, scopes := p.ruleGrantsAccess(rule, claims); !ok {
+		if ok, scopes := p.ruleGrantsAccess(rule, claims, evalCtx); !ok {
 			slog.Debug("MCP Authorization Policy: Rule did not grant access",
+				"rule", rule.Name,
 				"attributeType", attributeType,
 				"attributeName", attributeName,
 				"missingScopes", scopes)
 			isAuthorized = false
+			if failedRule == "" {
+				failedRule = rule.Name
+			}
 			for _, s := range scopes {
 				if _, exists := missingScopes[s]; !exists {
 					missingScopes[s] = struct{}{}
@@ -347,7 +608,7 @@ func (p *McpAuthzPolicy) checkAuthorization(attributeType, attributeName, method
 		}
 	}
 
-	return isAuthorized, missingScopes
+	return isAuthorized, missingScopes, failedRule
 }
 
 // findMatchingRules returns rules that match the attribute, sorted by specificity
@@ -394,8 +655,8 @@ func (p *McpAuthzPolicy) findMatchingRules(attributeType, attributeName, method
 	return append(specificRules, wildcardRules...)
 }
 
-// ruleGrantsAccess checks if a rule's claims and scopes are satisfied
-func (p *McpAuthzPolicy) ruleGrantsAccess(rule Rule, claims jwt.MapClaims) (bool, []string) {
+// ruleGrantsAccess checks if a rule's claims, scopes and condition are satisfied
+func (p *McpAuthzPolicy) ruleGrantsAccess(rule Rule, claims jwt.MapClaims, evalCtx map[string]any) (bool, []string) {
 	// Check required claims
 	if len(rule.RequiredClaims) > 0 {
 		if !p.checkClaims(rule.RequiredClaims, claims) {
@@ -411,6 +672,20 @@ func (p *McpAuthzPolicy) ruleGrantsAccess(rule Rule, claims jwt.MapClaims) (bool
 		}
 	}
 
+	// Check condition
+	if rule.program != nil {
+		satisfied, err := evaluateCondition(rule.program, evalCtx)
+		if err != nil {
+			slog.Debug("MCP Authorization Policy: Condition evaluation failed",
+				"rule", rule.Name,
+				"condition", rule.Condition,
+				"error", err)
+		}
+		if !satisfied {
+			return false, nil
+		}
+	}
+
 	return true, nil
 }
 
@@ -619,3 +894,17 @@ func (p *McpAuthzPolicy) handleAuthFailure(ctx *policy.RequestContext, errorMess
 		Body:       bodyBytes,
 	}
 }
+
+// handleRuleFailure builds the response to a request denied by a rule: a JSON-RPC error with the
+// name of the failed rule in its data, and a WWW-Authenticate header with the missing scopes
+func (p *McpAuthzPolicy) handleRuleFailure(ctx *policy.RequestContext, requestID any, failedRule string, scopeMap map[string]struct{}) policy.RequestAction {
+	var missingScopes []string
+	for s := range scopeMap {
+		missingScopes = append(missingScopes, s)
+	}
+
+	headers := map[string]string{
+		WWWAuthenticateHeader: generateWwwAuthenticateHeader(ctx, missingScopes, forbiddenMessage),
+	}
+	return jsonrpc.ErrorResponse(ctx.Headers, 403, -32000, forbiddenMessage, requestID, map[string]any{"rule": failedRule}, headers)
+}
@@ -0,0 +1,376 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcpauthz
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+func createScopeParams() map[string]any {
+	return map[string]any{
+		"rules": []any{
+			map[string]any{
+				"name":           "admin-only",
+				"attribute":      map[string]any{"type": "tool", "name": "drop_table"},
+				"requiredScopes": []any{"db:admin"},
+			},
+		},
+	}
+}
+
+func createBatchResponseContext(ctx *policy.RequestContext, status int, contentType string, body string) *policy.ResponseContext {
+	headers := map[string][]string{}
+	if contentType != "" {
+		headers["content-type"] = []string{contentType}
+	}
+	return &policy.ResponseContext{
+		SharedContext:   ctx.SharedContext,
+		ResponseHeaders: policy.NewHeaders(headers),
+		ResponseStatus:  status,
+		ResponseBody:    &policy.Body{Content: []byte(body), Present: body != ""},
+	}
+}
+
+func newScopePolicy(t *testing.T) policy.Policy {
+	t.Helper()
+	p, err := GetPolicy(policy.PolicyMetadata{}, createScopeParams())
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	return p
+}
+
+func TestMode_ResponseBody(t *testing.T) {
+	withRules := newScopePolicy(t)
+	if mode := withRules.Mode().ResponseBodyMode; mode != policy.BodyModeBuffer {
+		t.Errorf("Expected buffered response body with rules, got %v", mode)
+	}
+
+	withoutRules, err := GetPolicy(policy.PolicyMetadata{}, map[string]any{"rules": []any{}})
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	if mode := withoutRules.Mode().ResponseBodyMode; mode != policy.BodyModeSkip {
+		t.Errorf("Expected skipped response body without rules, got %v", mode)
+	}
+}
+
+func TestOnRequest_Batch(t *testing.T) {
+	p := newScopePolicy(t)
+
+	tests := []struct {
+		name       string
+		body       string
+		statusCode int
+		forwarded  []float64
+		errorIDs   []any
+	}{
+		{
+			name: "all allowed",
+			body: `[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_weather"}},
+				{"jsonrpc":"2.0","id":2,"method":"tools/list"}]`,
+		},
+		{
+			name: "denied request",
+			body: `[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"drop_table"}},
+				{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_weather"}}]`,
+			forwarded: []float64{2},
+			errorIDs:  []any{float64(1)},
+		},
+		{
+			name: "denied notification",
+			body: `[{"jsonrpc":"2.0","method":"tools/call","params":{"name":"drop_table"}},
+				{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_weather"}}]`,
+			forwarded: []float64{2},
+			errorIDs:  []any{},
+		},
+		{
+			name: "non-request element",
+			body: `[1, {"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_weather"}}]`,
+		},
+		{
+			name: "invalid params",
+			body: `[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":5}},
+				{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_weather"}}]`,
+			forwarded: []float64{2},
+			errorIDs:  []any{float64(1)},
+		},
+		{
+			name: "all denied",
+			body: `[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"drop_table"}},
+				{"jsonrpc":"2.0","id":"two","method":"tools/call","params":{"name":"drop_table"}}]`,
+			statusCode: 403,
+			errorIDs:   []any{float64(1), "two"},
+		},
+		{
+			name:       "only denied notifications",
+			body:       `[{"jsonrpc":"2.0","method":"tools/call","params":{"name":"drop_table"}}]`,
+			statusCode: 202,
+		},
+		{
+			name:       "only invalid elements",
+			body:       `[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":5}}]`,
+			statusCode: 400,
+			errorIDs:   []any{float64(1)},
+		},
+		{
+			name:       "empty batch",
+			body:       `[]`,
+			statusCode: 403,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createMockRequestContext(tt.body, nil)
+			action := p.OnRequest(ctx, nil)
+
+			if tt.statusCode != 0 {
+				response, ok := action.(policy.ImmediateResponse)
+				if !ok {
+					t.Fatalf("Expected ImmediateResponse, got %T", action)
+				}
+				if response.StatusCode != tt.statusCode {
+					t.Fatalf("Expected status %d, got %d", tt.statusCode, response.StatusCode)
+				}
+				if tt.errorIDs == nil {
+					return
+				}
+				var errors []map[string]any
+				if err := json.Unmarshal(response.Body, &errors); err != nil {
+					t.Fatalf("Expected a batch of errors, got %s", response.Body)
+				}
+				assertErrorIDs(t, errors, tt.errorIDs)
+				if tt.statusCode == 403 && response.Headers[WWWAuthenticateHeader] == "" {
+					t.Errorf("Expected %s header", WWWAuthenticateHeader)
+				}
+				return
+			}
+
+			if tt.forwarded == nil {
+				if action != nil {
+					t.Fatalf("Expected no action, got %T", action)
+				}
+				if _, ok := ctx.Metadata[MetadataMcpBatchErrors]; ok {
+					t.Fatalf("Expected no batch errors in metadata")
+				}
+				return
+			}
+
+			mods, ok := action.(policy.UpstreamRequestModifications)
+			if !ok {
+				t.Fatalf("Expected UpstreamRequestModifications, got %T", action)
+			}
+			var forwarded []map[string]any
+			if err := json.Unmarshal(mods.Body, &forwarded); err != nil {
+				t.Fatalf("Failed to unmarshal forwarded batch: %v", err)
+			}
+			if len(forwarded) != len(tt.forwarded) {
+				t.Fatalf("Expected %d forwarded requests, got %s", len(tt.forwarded), mods.Body)
+			}
+			for i, id := range tt.forwarded {
+				if forwarded[i]["id"] != id {
+					t.Errorf("Expected forwarded request %v, got %v", id, forwarded[i]["id"])
+				}
+			}
+
+			entries, ok := ctx.Metadata[MetadataMcpBatchErrors].([]any)
+			if !ok {
+				t.Fatalf("Expected batch errors in metadata")
+			}
+			entriesJSON, _ := json.Marshal(entries)
+			var errors []map[string]any
+			if err := json.Unmarshal(entriesJSON, &errors); err != nil {
+				t.Fatalf("Failed to unmarshal batch errors: %v", err)
+			}
+			assertErrorIDs(t, errors, tt.errorIDs)
+		})
+	}
+}
+
+func TestOnResponse_BatchErrors(t *testing.T) {
+	p := newScopePolicy(t)
+	requestBody := `[{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"drop_table"}},
+		{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_weather"}}]`
+
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		// expectedIDs is nil if the response is not modified
+		expectedIDs []any
+		sse         bool
+	}{
+		{
+			name:        "batch response",
+			status:      200,
+			contentType: "application/json",
+			body:        `[{"jsonrpc":"2.0","id":2,"result":{}}]`,
+			expectedIDs: []any{float64(2), float64(1)},
+		},
+		{
+			name:        "single response",
+			status:      200,
+			contentType: "application/json",
+			body:        `{"jsonrpc":"2.0","id":null,"error":{"code":-32603,"message":"Internal error"}}`,
+			expectedIDs: []any{nil, float64(1)},
+		},
+		{
+			name:        "empty response",
+			status:      202,
+			expectedIDs: []any{float64(1)},
+		},
+		{
+			name:        "event stream",
+			status:      200,
+			contentType: "text/event-stream",
+			body:        "event: message\ndata: [{\"jsonrpc\":\"2.0\",\"id\":2,\"result\":{}}]\n\n",
+			expectedIDs: []any{float64(1)},
+			sse:         true,
+		},
+		{
+			name:        "upstream error",
+			status:      500,
+			contentType: "application/json",
+			body:        `{"error":"unavailable"}`,
+		},
+		{
+			name:        "invalid response",
+			status:      200,
+			contentType: "application/json",
+			body:        `not json`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createMockRequestContext(requestBody, nil)
+			if _, ok := p.OnRequest(ctx, nil).(policy.UpstreamRequestModifications); !ok {
+				t.Fatalf("Expected the batch to be forwarded")
+			}
+
+			action := p.OnResponse(createBatchResponseContext(ctx, tt.status, tt.contentType, tt.body), nil)
+			if tt.expectedIDs == nil {
+				if action != nil {
+					t.Fatalf("Expected no action, got %T", action)
+				}
+				return
+			}
+
+			mods, ok := action.(policy.UpstreamResponseModifications)
+			if !ok {
+				t.Fatalf("Expected UpstreamResponseModifications, got %T", action)
+			}
+
+			responseBody := mods.Body
+			if tt.sse {
+				if !strings.HasPrefix(string(mods.Body), strings.TrimRight(tt.body, "\n")) {
+					t.Fatalf("Expected the upstream events to be kept, got %q", mods.Body)
+				}
+				events := strings.Split(strings.TrimSpace(string(mods.Body)), "\n\n")
+				last := events[len(events)-1]
+				if !strings.HasPrefix(last, "data: ") {
+					t.Fatalf("Expected an event with the batch errors, got %q", last)
+				}
+				responseBody = []byte(strings.TrimPrefix(last, "data: "))
+			}
+
+			var responses []map[string]any
+			if err := json.Unmarshal(responseBody, &responses); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v (%s)", err, responseBody)
+			}
+			if len(responses) != len(tt.expectedIDs) {
+				t.Fatalf("Expected %d responses, got %s", len(tt.expectedIDs), responseBody)
+			}
+			for i, id := range tt.expectedIDs {
+				if responses[i]["id"] != id {
+					t.Errorf("Expected response %d to have id %v, got %v", i, id, responses[i]["id"])
+				}
+			}
+			denied := responses[len(responses)-1]["error"].(map[string]any)
+			if rule := denied["data"].(map[string]any)["rule"]; rule != "admin-only" {
+				t.Errorf("Expected failed rule admin-only, got %v", rule)
+			}
+			if tt.body == "" && (mods.StatusCode == nil || *mods.StatusCode != 200) {
+				t.Errorf("Expected status 200 for an empty upstream response")
+			}
+		})
+	}
+}
+
+func TestOnRequest_EventStreamBatch(t *testing.T) {
+	p := newScopePolicy(t)
+	body := "event: message\ndata: [{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"tools/call\",\"params\":{\"name\":\"drop_table\"}}," +
+		"{\"jsonrpc\":\"2.0\",\"id\":2,\"method\":\"tools/call\",\"params\":{\"name\":\"get_weather\"}}]\n\n"
+	ctx := createMockRequestContext(body, map[string][]string{
+		"content-type":   {"text/event-stream"},
+		"mcp-session-id": {"session-1"},
+	})
+
+	mods, ok := p.OnRequest(ctx, nil).(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamRequestModifications for an SSE batch")
+	}
+	if !strings.HasPrefix(string(mods.Body), "event: message\ndata: ") || strings.Contains(string(mods.Body), "drop_table") {
+		t.Fatalf("Expected the denied element to be removed from the event, got %q", mods.Body)
+	}
+
+	denied := createMockRequestContext("data: {\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"tools/call\",\"params\":{\"name\":\"drop_table\"}}\n\n",
+		map[string][]string{"content-type": {"text/event-stream"}, "mcp-session-id": {"session-1"}})
+	response, ok := p.OnRequest(denied, nil).(policy.ImmediateResponse)
+	if !ok || response.StatusCode != 403 {
+		t.Fatalf("Expected a 403 response for a denied SSE request, got %v", response)
+	}
+	if response.Headers["Content-Type"] != "text/event-stream" || response.Headers[MCPSessionHeader] != "session-1" {
+		t.Errorf("Expected an event stream response with the session ID, got %v", response.Headers)
+	}
+}
+
+func TestOnResponse_NoBatchErrors(t *testing.T) {
+	p := newScopePolicy(t)
+	ctx := createMockRequestContext(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"get_weather"}}`, nil)
+	if action := p.OnRequest(ctx, nil); action != nil {
+		t.Fatalf("Expected no action, got %T", action)
+	}
+
+	action := p.OnResponse(createBatchResponseContext(ctx, 200, "application/json", `{"jsonrpc":"2.0","id":1,"result":{}}`), nil)
+	if action != nil {
+		t.Fatalf("Expected no action, got %T", action)
+	}
+}
+
+func assertErrorIDs(t *testing.T, errors []map[string]any, expected []any) {
+	t.Helper()
+	if len(errors) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), errors)
+	}
+	for i, id := range expected {
+		if errors[i]["id"] != id {
+			t.Errorf("Expected error %d to have id %v, got %v", i, id, errors[i]["id"])
+		}
+		if _, ok := errors[i]["error"].(map[string]any); !ok {
+			t.Errorf("Expected error %d to be a JSON-RPC error, got %v", i, errors[i])
+		}
+	}
+}
@@ -0,0 +1,22 @@
+module github.com/wso2/gateway-controllers/policies/mcp-ratelimit
+
+go 1.25.1
+
+require (
+	github.com/golang-jwt/jwt/v5 v5.2.2
+	github.com/redis/go-redis/v9 v9.17.2
+	github.com/wso2/api-platform/sdk v0.3.10
+	github.com/wso2/gateway-controllers/policies/advanced-ratelimit v0.5.0
+	github.com/wso2/gateway-controllers/policies/mcp-rewrite v0.4.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	golang.org/x/crypto v0.46.0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
+)
+
+replace github.com/wso2/gateway-controllers/policies/advanced-ratelimit => ../advanced-ratelimit
+
+replace github.com/wso2/gateway-controllers/policies/mcp-rewrite => ../mcp-rewrite
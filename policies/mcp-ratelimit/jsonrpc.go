@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcpratelimit
+
+import (
+	"strings"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/mcp-rewrite/jsonrpc"
+)
+
+// isMcpPostRequest reports whether the request targets the MCP endpoint.
+func isMcpPostRequest(method, path string) bool {
+	return strings.EqualFold(method, "POST") && strings.Contains(path, mcpPathSegment)
+}
+
+// buildRequestErrorResponse builds an error response for a request with additional headers.
+func (p *McpRateLimitPolicy) buildRequestErrorResponse(ctx *policy.RequestContext, statusCode int, jsonRpcCode int, reason string, requestID any, data map[string]any, headers map[string]string) policy.RequestAction {
+	return jsonrpc.ErrorResponse(ctx.Headers, statusCode, jsonRpcCode, reason, requestID, data, headers)
+}
+
+// buildBatchErrorResponse builds the response to a batch in which every element is rejected.
+func (p *McpRateLimitPolicy) buildBatchErrorResponse(ctx *policy.RequestContext, statusCode int, errorEntries []any, headers map[string]string) policy.RequestAction {
+	if len(errorEntries) == 0 {
+		// Only notifications were sent, which are not answered
+		return jsonrpc.Response(ctx.Headers, 202, nil, headers)
+	}
+	return jsonrpc.Response(ctx.Headers, statusCode, errorEntries, headers)
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcpratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/wso2/gateway-controllers/policies/advanced-ratelimit/limiter"
+)
+
+// limiterSpec describes a memory-backed limiter and the key it is cached under
+type limiterSpec struct {
+	cacheKey string
+	config   limiter.Config
+}
+
+// limiterCache keeps the memory-backed limiters of each route, so that a new policy instance
+// for the same route continues the counts of unchanged limits. Redis-backed limiters keep
+// their state in Redis and are not cached.
+type limiterCache struct {
+	mu          sync.Mutex
+	byKey       map[string]limiter.Limiter
+	keysByRoute map[string]map[string]struct{}
+}
+
+// globalLimiterCache is the singleton cache for memory-backed limiters
+var globalLimiterCache = &limiterCache{
+	byKey:       make(map[string]limiter.Limiter),
+	keysByRoute: make(map[string]map[string]struct{}),
+}
+
+// acquire returns a limiter per spec, reusing cached limiters, and closes the limiters of the
+// route that are no longer configured.
+func (c *limiterCache) acquire(routeName string, specs []limiterSpec) ([]limiter.Limiter, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	limiters := make([]limiter.Limiter, len(specs))
+	desired := make(map[string]struct{}, len(specs))
+	var created []string
+	for i, spec := range specs {
+		desired[spec.cacheKey] = struct{}{}
+		if cached, ok := c.byKey[spec.cacheKey]; ok {
+			limiters[i] = cached
+			continue
+		}
+		rlLimiter, err := limiter.CreateLimiter(spec.config)
+		if err != nil {
+			for _, key := range created {
+				c.closeLimiter(key)
+			}
+			return nil, err
+		}
+		c.byKey[spec.cacheKey] = rlLimiter
+		created = append(created, spec.cacheKey)
+		limiters[i] = rlLimiter
+	}
+
+	for key := range c.keysByRoute[routeName] {
+		if _, stillUsed := desired[key]; !stillUsed {
+			c.closeLimiter(key)
+		}
+	}
+	c.keysByRoute[routeName] = desired
+
+	slog.Debug("MCP Rate Limit Policy: Memory limiters ready", "route", routeName, "limiters", len(limiters), "created", len(created))
+	return limiters, nil
+}
+
+// closeLimiter closes and removes a cached limiter. The caller must hold the lock.
+func (c *limiterCache) closeLimiter(key string) {
+	rlLimiter, ok := c.byKey[key]
+	if !ok {
+		return
+	}
+	if err := rlLimiter.Close(); err != nil {
+		slog.Warn("MCP Rate Limit Policy: Failed to close stale limiter", "cacheKey", key[:16], "error", err)
+	}
+	delete(c.byKey, key)
+}
+
+// getLimiterCacheKey computes the cache key of a memory-backed limiter from everything that
+// affects its counts.
+func getLimiterCacheKey(routeName, algorithm string, cleanupInterval time.Duration, capabilityType string, entry *CapabilityLimit) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "route:%s|algo:%s|cleanup:%s|%s:%s|limits:", routeName, algorithm, cleanupInterval, capabilityType, entry.Name)
+	for i, lim := range entry.Limits {
+		fmt.Fprintf(h, "[%d:l=%d,d=%s,b=%d]", i, lim.Limit, lim.Duration, lim.Burst)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// toLimiterLimits converts parsed limits to limiter limits
+func toLimiterLimits(limits []LimitConfig) []limiter.LimitConfig {
+	limiterLimits := make([]limiter.LimitConfig, len(limits))
+	for i, lim := range limits {
+		limiterLimits[i] = limiter.LimitConfig{
+			Limit:    lim.Limit,
+			Duration: lim.Duration,
+			Burst:    lim.Burst,
+		}
+	}
+	return limiterLimits
+}
+
+// newRedisClient creates the Redis client from the redis system parameters and reports whether
+// requests are allowed when Redis is unavailable.
+func newRedisClient(params map[string]any) (*redis.Client, bool, error) {
+	failOpen := getStringParam(params, "redis.failureMode", "open") == "open"
+	connTimeout := getDurationParam(params, "redis.connectionTimeout", 5*time.Second)
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:         fmt.Sprintf("%s:%d", getStringParam(params, "redis.host", "localhost"), getIntParam(params, "redis.port", 6379)),
+		Username:     getStringParam(params, "redis.username", ""),
+		Password:     getStringParam(params, "redis.password", ""),
+		DB:           getIntParam(params, "redis.db", 0),
+		DialTimeout:  connTimeout,
+		ReadTimeout:  getDurationParam(params, "redis.readTimeout", 3*time.Second),
+		WriteTimeout: getDurationParam(params, "redis.writeTimeout", 3*time.Second),
+	})
+
+	// Test connection (fail-fast if configured to fail closed)
+	ctx, cancel := context.WithTimeout(context.Background(), connTimeout)
+	defer cancel()
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		if !failOpen {
+			return nil, false, fmt.Errorf("redis connection failed and failureMode=closed: %w", err)
+		}
+		slog.Warn("MCP Rate Limit Policy: Redis connection failed but failureMode=open", "error", err)
+	}
+	return redisClient, failOpen, nil
+}
+
+// lookupParam returns a parameter by a dotted path such as "redis.host"
+func lookupParam(params map[string]any, key string) (any, bool) {
+	keys := strings.Split(key, ".")
+	current := params
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := current[k].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	value, ok := current[keys[len(keys)-1]]
+	return value, ok
+}
+
+func getStringParam(params map[string]any, key string, defaultVal string) string {
+	if value, ok := lookupParam(params, key); ok {
+		if str, ok := value.(string); ok {
+			return str
+		}
+	}
+	return defaultVal
+}
+
+func getIntParam(params map[string]any, key string, defaultVal int) int {
+	if value, ok := lookupParam(params, key); ok {
+		if number, ok := toInt64(value); ok {
+			return int(number)
+		}
+	}
+	return defaultVal
+}
+
+func getDurationParam(params map[string]any, key string, defaultVal time.Duration) time.Duration {
+	if value, ok := lookupParam(params, key); ok {
+		if str, ok := value.(string); ok {
+			if duration, err := time.ParseDuration(str); err == nil {
+				return duration
+			}
+		}
+	}
+	return defaultVal
+}
@@ -0,0 +1,678 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcpratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/advanced-ratelimit/limiter"
+	"github.com/wso2/gateway-controllers/policies/mcp-rewrite/jsonrpc"
+
+	_ "github.com/wso2/gateway-controllers/policies/advanced-ratelimit/algorithms/fixedwindow" // Register Fixed Window algorithm
+	_ "github.com/wso2/gateway-controllers/policies/advanced-ratelimit/algorithms/gcra"        // Register GCRA algorithm
+)
+
+const (
+	mcpPathSegment               = "/mcp"
+	mcpSessionHeader             = "mcp-session-id"
+	metadataValidatedClaims      = "auth.validatedClaims"
+	metadataRateLimitBatchErrors = "mcp.ratelimit.batchErrors"
+	capabilityTypeTools          = "tools"
+	capabilityTypeResources      = "resources"
+	capabilityTypePrompts        = "prompts"
+	defaultIdentityClaim         = "sub"
+	identityAnonymous            = ""
+	wildcard                     = "*"
+	rateLimitExceededStatusCode  = 429
+	rateLimitExceededJsonRpcCode = -32000
+)
+
+// Caller identity types for the identity parameter
+const (
+	IdentityClaim   = "claim"   // A claim of the validated token, "sub" by default
+	IdentitySession = "session" // The MCP session ID
+	IdentityHeader  = "header"  // A request header
+	IdentityNone    = "none"    // All callers share the limit
+)
+
+// LimitConfig holds a parsed rate limit
+type LimitConfig struct {
+	Limit    int64
+	Duration time.Duration
+	Burst    int64
+}
+
+// CapabilityLimit holds the limits of a capability name or wildcard pattern and their limiter
+type CapabilityLimit struct {
+	Name    string
+	Limits  []LimitConfig
+	limiter limiter.Limiter
+}
+
+// capabilityLimits holds the limits of a capability type. Exact names take precedence over
+// patterns, and longer patterns over shorter ones.
+type capabilityLimits struct {
+	exact    map[string]*CapabilityLimit
+	patterns []*CapabilityLimit
+}
+
+// IdentityConfig selects the caller identity that limits are counted per
+type IdentityConfig struct {
+	Type string
+	Key  string
+}
+
+// McpRateLimitPolicy limits MCP tool calls, resource reads and prompt gets per capability and caller
+type McpRateLimitPolicy struct {
+	routeName     string
+	limits        map[string]*capabilityLimits
+	identity      IdentityConfig
+	backend       string
+	redisFailOpen bool
+}
+
+// rateLimitExceeded describes a JSON-RPC request that is over its limit. The result is nil when
+// the limit could not be checked and the policy fails closed.
+type rateLimitExceeded struct {
+	capabilityType string
+	name           string
+	result         *limiter.Result
+}
+
+// GetPolicy creates the MCP rate limit policy for a route. Memory-backed limiters are shared with
+// the previous instance of the route when their limits are unchanged, so counts survive updates.
+func GetPolicy(
+	metadata policy.PolicyMetadata,
+	params map[string]any,
+) (policy.Policy, error) {
+	slog.Debug("MCP Rate Limit Policy: GetPolicy called", "route", metadata.RouteName)
+
+	routeName := metadata.RouteName
+	if routeName == "" {
+		routeName = "unknown-route"
+	}
+
+	identity, err := parseIdentityConfig(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity configuration: %w", err)
+	}
+
+	limits := make(map[string]*capabilityLimits)
+	var entries []*CapabilityLimit
+	var specs []limiterSpec
+	algorithm := getStringParam(params, "algorithm", "fixed-window")
+	backend := getStringParam(params, "backend", "memory")
+	cleanupInterval := getDurationParam(params, "memory.cleanupInterval", 5*time.Minute)
+	for _, capabilityType := range []string{capabilityTypeTools, capabilityTypeResources, capabilityTypePrompts} {
+		parsed, err := parseCapabilityLimits(params, capabilityType)
+		if err != nil {
+			slog.Debug("MCP Rate Limit Policy: Invalid capability limits", "capabilityType", capabilityType, "error", err)
+			return nil, fmt.Errorf("invalid %s configuration: %w", capabilityType, err)
+		}
+		limits[capabilityType] = newCapabilityLimits(parsed)
+		for _, entry := range parsed {
+			entries = append(entries, entry)
+			specs = append(specs, limiterSpec{
+				cacheKey: getLimiterCacheKey(routeName, algorithm, cleanupInterval, capabilityType, entry),
+				config: limiter.Config{
+					Algorithm:       algorithm,
+					Limits:          toLimiterLimits(entry.Limits),
+					Backend:         backend,
+					CleanupInterval: cleanupInterval,
+				},
+			})
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("at least one tools, resources or prompts limit is required")
+	}
+
+	redisFailOpen := true
+	var limiters []limiter.Limiter
+	if backend == "redis" {
+		var redisClient *redis.Client
+		redisClient, redisFailOpen, err = newRedisClient(params)
+		if err != nil {
+			return nil, err
+		}
+		keyPrefix := getStringParam(params, "redis.keyPrefix", "ratelimit:v1:")
+		for _, spec := range specs {
+			spec.config.RedisClient = redisClient
+			spec.config.KeyPrefix = keyPrefix
+			spec.config.CleanupInterval = 0
+			rlLimiter, err := limiter.CreateLimiter(spec.config)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create Redis limiter: %w", err)
+			}
+			limiters = append(limiters, rlLimiter)
+		}
+	} else {
+		limiters, err = globalLimiterCache.acquire(routeName, specs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create memory limiter: %w", err)
+		}
+	}
+	for i, entry := range entries {
+		entry.limiter = limiters[i]
+	}
+
+	slog.Debug("MCP Rate Limit Policy: Parsed configuration",
+		"route", routeName,
+		"backend", backend,
+		"algorithm", algorithm,
+		"identity", identity.Type,
+		"limitCount", len(entries),
+	)
+
+	return &McpRateLimitPolicy{
+		routeName:     routeName,
+		limits:        limits,
+		identity:      identity,
+		backend:       backend,
+		redisFailOpen: redisFailOpen,
+	}, nil
+}
+
+// parseIdentityConfig parses the identity parameter. Callers are identified by the "sub" claim by default.
+func parseIdentityConfig(params map[string]any) (IdentityConfig, error) {
+	identity := IdentityConfig{Type: IdentityClaim, Key: defaultIdentityClaim}
+
+	raw, ok := params["identity"]
+	if !ok || raw == nil {
+		return identity, nil
+	}
+	identityMap, ok := raw.(map[string]any)
+	if !ok {
+		return identity, fmt.Errorf("identity must be an object")
+	}
+
+	if typeRaw, ok := identityMap["type"]; ok {
+		identityType, ok := typeRaw.(string)
+		if !ok {
+			return identity, fmt.Errorf("identity.type must be a string")
+		}
+		identity.Type = identityType
+	}
+	key, _ := identityMap["key"].(string)
+
+	switch identity.Type {
+	case IdentityClaim:
+		if strings.TrimSpace(key) != "" {
+			identity.Key = key
+		}
+	case IdentityHeader:
+		if strings.TrimSpace(key) == "" {
+			return identity, fmt.Errorf("identity.key is required for type %q", IdentityHeader)
+		}
+		identity.Key = strings.ToLower(key)
+	case IdentitySession, IdentityNone:
+		identity.Key = ""
+	default:
+		return identity, fmt.Errorf("unsupported identity.type %q", identity.Type)
+	}
+	return identity, nil
+}
+
+// parseCapabilityLimits parses the limits of a capability type. Resources are named by URI.
+func parseCapabilityLimits(params map[string]any, capabilityType string) ([]*CapabilityLimit, error) {
+	raw, ok := params[capabilityType]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array", capabilityType)
+	}
+
+	entries := make([]*CapabilityLimit, 0, len(list))
+	seen := make(map[string]struct{}, len(list))
+	for i, item := range list {
+		entryMap, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s[%d] must be an object", capabilityType, i)
+		}
+		name, _ := entryMap["name"].(string)
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%s[%d].name must be a non-empty string", capabilityType, i)
+		}
+		if _, exists := seen[name]; exists {
+			return nil, fmt.Errorf("%s[%d].name %q is duplicated", capabilityType, i, name)
+		}
+		seen[name] = struct{}{}
+
+		limits, err := parseLimits(entryMap["limits"])
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", capabilityType, i, err)
+		}
+		entries = append(entries, &CapabilityLimit{Name: name, Limits: limits})
+	}
+	return entries, nil
+}
+
+// parseLimits parses a non-empty limits array
+func parseLimits(raw any) ([]LimitConfig, error) {
+	list, ok := raw.([]any)
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("limits must be a non-empty array")
+	}
+
+	limits := make([]LimitConfig, 0, len(list))
+	for i, item := range list {
+		limitMap, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("limits[%d] must be an object", i)
+		}
+		limit, ok := toInt64(limitMap["limit"])
+		if !ok || limit < 1 {
+			return nil, fmt.Errorf("limits[%d].limit must be a positive integer", i)
+		}
+		durationStr, ok := limitMap["duration"].(string)
+		if !ok {
+			return nil, fmt.Errorf("limits[%d].duration must be a string", i)
+		}
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("limits[%d].duration must be a positive duration", i)
+		}
+		burst := limit
+		if burstRaw, ok := limitMap["burst"]; ok && burstRaw != nil {
+			burst, ok = toInt64(burstRaw)
+			if !ok || burst < 1 {
+				return nil, fmt.Errorf("limits[%d].burst must be a positive integer", i)
+			}
+		}
+		limits = append(limits, LimitConfig{Limit: limit, Duration: duration, Burst: burst})
+	}
+	return limits, nil
+}
+
+// newCapabilityLimits indexes the limits of a capability type by name and pattern
+func newCapabilityLimits(entries []*CapabilityLimit) *capabilityLimits {
+	limits := &capabilityLimits{exact: make(map[string]*CapabilityLimit)}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name, wildcard) {
+			limits.patterns = append(limits.patterns, entry)
+			continue
+		}
+		limits.exact[entry.Name] = entry
+	}
+	return limits
+}
+
+// find returns the limit of a capability: the entry with its exact name, or else the most
+// specific matching pattern, so that "*" is the default for all other capabilities.
+func (l *capabilityLimits) find(name string) *CapabilityLimit {
+	if l == nil {
+		return nil
+	}
+	if entry, ok := l.exact[name]; ok {
+		return entry
+	}
+	var best *CapabilityLimit
+	for _, entry := range l.patterns {
+		if !matchWildcard(entry.Name, name) {
+			continue
+		}
+		if best == nil || len(strings.ReplaceAll(entry.Name, wildcard, "")) > len(strings.ReplaceAll(best.Name, wildcard, "")) {
+			best = entry
+		}
+	}
+	return best
+}
+
+// matchWildcard reports whether a name matches a pattern in which "*" matches any sequence of characters
+func matchWildcard(pattern, name string) bool {
+	parts := strings.Split(pattern, wildcard)
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	last := len(parts) - 1
+	for _, part := range parts[1:last] {
+		index := strings.Index(name, part)
+		if index < 0 {
+			return false
+		}
+		name = name[index+len(part):]
+	}
+	return strings.HasSuffix(name, parts[last])
+}
+
+func (p *McpRateLimitPolicy) Mode() policy.ProcessingMode {
+	return policy.ProcessingMode{
+		RequestHeaderMode:  policy.HeaderModeSkip,
+		RequestBodyMode:    policy.BodyModeBuffer,
+		ResponseHeaderMode: policy.HeaderModeSkip,
+		ResponseBodyMode:   policy.BodyModeBuffer,
+	}
+}
+
+func (p *McpRateLimitPolicy) OnRequest(ctx *policy.RequestContext, params map[string]any) policy.RequestAction {
+	if !isMcpPostRequest(ctx.Method, ctx.Path) {
+		return nil
+	}
+	slog.Debug("MCP Rate Limit Policy: OnRequest started")
+
+	if ctx.Body == nil || len(ctx.Body.Content) == 0 {
+		return nil
+	}
+
+	payload, err := jsonrpc.ParsePayload(ctx.Body.Content, jsonrpc.IsEventStream(ctx.Headers))
+	if err != nil {
+		// Not a JSON-RPC request, so there is no capability to limit
+		slog.Debug("MCP Rate Limit Policy: Failed to parse MCP request", "error", err, "path", ctx.Path)
+		return nil
+	}
+
+	if ctx.Metadata == nil {
+		ctx.Metadata = make(map[string]any)
+	}
+	identity := p.callerIdentity(ctx)
+
+	if payload.Batch {
+		return p.handleBatchRequest(ctx, payload, identity)
+	}
+
+	requestPayload, ok := payload.Messages[0].(map[string]any)
+	if !ok {
+		return nil
+	}
+	exceeded := p.checkRequest(requestPayload, identity)
+	if exceeded == nil {
+		return nil
+	}
+	return p.buildRequestErrorResponse(ctx, rateLimitExceededStatusCode, rateLimitExceededJsonRpcCode,
+		exceeded.message(), requestPayload["id"], exceeded.data(), exceeded.headers())
+}
+
+// checkRequest counts a tools/call, resources/read or prompts/get request against the limit
+// of its capability and returns why it is rejected, or nil if it is allowed.
+func (p *McpRateLimitPolicy) checkRequest(requestPayload map[string]any, identity string) *rateLimitExceeded {
+	method, _ := requestPayload["method"].(string)
+	capabilityType, action, ok := jsonrpc.ParseMethod(method)
+	if !ok || !isRateLimited(capabilityType, action) {
+		return nil
+	}
+
+	paramsRaw, _ := requestPayload["params"].(map[string]any)
+	name, _ := paramsRaw[jsonrpc.ParamKey(capabilityType)].(string)
+	if strings.TrimSpace(name) == "" {
+		return nil
+	}
+
+	entry := p.limits[capabilityType].find(name)
+	if entry == nil {
+		return nil
+	}
+
+	key := p.limitKey(capabilityType, name, identity)
+	result, err := entry.limiter.Allow(context.Background(), key)
+	if err != nil {
+		if p.backend == "redis" && p.redisFailOpen {
+			slog.Warn("MCP Rate Limit Policy: Rate limit check failed (fail-open)", "capabilityType", capabilityType, "name", name, "error", err)
+			return nil
+		}
+		slog.Error("MCP Rate Limit Policy: Rate limit check failed (fail-closed)", "capabilityType", capabilityType, "name", name, "error", err)
+		return &rateLimitExceeded{capabilityType: capabilityType, name: name}
+	}
+	if !result.Allowed {
+		slog.Debug("MCP Rate Limit Policy: Rate limit exceeded", "capabilityType", capabilityType, "name", name, "limit", entry.Name, "key", key)
+		return &rateLimitExceeded{capabilityType: capabilityType, name: name, result: result}
+	}
+
+	slog.Debug("MCP Rate Limit Policy: Rate limit check passed", "capabilityType", capabilityType, "name", name, "remaining", result.Remaining)
+	return nil
+}
+
+// handleBatchRequest checks each element of a JSON-RPC batch. Elements over their limit are
+// removed from the forwarded batch and answered with JSON-RPC errors for their ids, which are
+// added to the upstream response. Notifications over their limit are dropped without an error.
+func (p *McpRateLimitPolicy) handleBatchRequest(ctx *policy.RequestContext, payload jsonrpc.Payload, identity string) policy.RequestAction {
+	forwarded := make([]any, 0, len(payload.Messages))
+	errorEntries := make([]any, 0)
+	var headers map[string]string
+
+	for _, message := range payload.Messages {
+		requestPayload, ok := message.(map[string]any)
+		if !ok {
+			forwarded = append(forwarded, message)
+			continue
+		}
+		exceeded := p.checkRequest(requestPayload, identity)
+		if exceeded == nil {
+			forwarded = append(forwarded, message)
+			continue
+		}
+		if requestID, hasID := requestPayload["id"]; hasID {
+			errorEntries = append(errorEntries, jsonrpc.ErrorEntry(requestID, rateLimitExceededJsonRpcCode, exceeded.message(), exceeded.data()))
+		}
+		if headers == nil {
+			headers = exceeded.headers()
+		}
+	}
+
+	if len(forwarded) == len(payload.Messages) {
+		return nil
+	}
+
+	if len(forwarded) == 0 {
+		return p.buildBatchErrorResponse(ctx, rateLimitExceededStatusCode, errorEntries, headers)
+	}
+
+	updatedPayload, err := payload.Encode(forwarded)
+	if err != nil {
+		slog.Debug("MCP Rate Limit Policy: Failed to marshal updated batch", "error", err)
+		return p.buildRequestErrorResponse(ctx, 500, -32603, "Failed to update MCP request", nil, nil, nil)
+	}
+
+	slog.Debug("MCP Rate Limit Policy: Batch limited", "batchSize", len(payload.Messages), "forwarded", len(forwarded), "errors", len(errorEntries))
+	if len(errorEntries) > 0 {
+		ctx.Metadata[metadataRateLimitBatchErrors] = errorEntries
+	}
+	return policy.UpstreamRequestModifications{
+		Body: updatedPayload,
+	}
+}
+
+// OnResponse adds the errors of batch elements rejected on the request path to the upstream response.
+func (p *McpRateLimitPolicy) OnResponse(ctx *policy.ResponseContext, params map[string]any) policy.ResponseAction {
+	if !isMcpPostRequest(ctx.RequestMethod, ctx.RequestPath) || ctx.Metadata == nil {
+		return nil
+	}
+	errorEntries, _ := ctx.Metadata[metadataRateLimitBatchErrors].([]any)
+	if len(errorEntries) == 0 {
+		return nil
+	}
+	if ctx.ResponseStatus >= 300 {
+		slog.Debug("MCP Rate Limit Policy: Batch response skipped, upstream returned an error", "status", ctx.ResponseStatus)
+		return nil
+	}
+
+	errorsJSON, err := json.Marshal(errorEntries)
+	if err != nil {
+		slog.Debug("MCP Rate Limit Policy: Failed to marshal batch errors", "error", err)
+		return nil
+	}
+
+	var content []byte
+	if ctx.ResponseBody != nil && ctx.ResponseBody.Present {
+		content = ctx.ResponseBody.Content
+	}
+
+	if jsonrpc.IsEventStream(ctx.ResponseHeaders) {
+		events := append(jsonrpc.ParseEventStream(content), jsonrpc.Event{Data: string(errorsJSON)})
+		return policy.UpstreamResponseModifications{
+			Body: jsonrpc.BuildEventStream(events),
+		}
+	}
+
+	if len(strings.TrimSpace(string(content))) == 0 {
+		// Only notifications were forwarded, so the upstream accepted the batch without a body
+		statusCode := 200
+		return policy.UpstreamResponseModifications{
+			SetHeaders: map[string]string{"Content-Type": "application/json"},
+			Body:       errorsJSON,
+			StatusCode: &statusCode,
+		}
+	}
+
+	var responsePayload any
+	if err := json.Unmarshal(content, &responsePayload); err != nil {
+		slog.Debug("MCP Rate Limit Policy: Failed to parse MCP batch response", "error", err)
+		return nil
+	}
+	if responses, ok := responsePayload.([]any); ok {
+		responsePayload = append(responses, errorEntries...)
+	} else {
+		responsePayload = append([]any{responsePayload}, errorEntries...)
+	}
+
+	updatedPayload, err := json.Marshal(responsePayload)
+	if err != nil {
+		slog.Debug("MCP Rate Limit Policy: Failed to marshal updated batch response", "error", err)
+		return nil
+	}
+	return policy.UpstreamResponseModifications{
+		Body: updatedPayload,
+	}
+}
+
+// callerIdentity returns the identity the limits of a request are counted for. Callers without
+// a value for the configured identity share one count.
+func (p *McpRateLimitPolicy) callerIdentity(ctx *policy.RequestContext) string {
+	switch p.identity.Type {
+	case IdentityNone:
+		return identityAnonymous
+	case IdentitySession:
+		return jsonrpc.SessionID(ctx.Headers)
+	case IdentityHeader:
+		if ctx.Headers == nil {
+			return identityAnonymous
+		}
+		if values := ctx.Headers.Get(p.identity.Key); len(values) > 0 {
+			return values[0]
+		}
+		return identityAnonymous
+	default:
+		var claims map[string]any
+		switch value := ctx.Metadata[metadataValidatedClaims].(type) {
+		case jwt.MapClaims:
+			claims = value
+		case map[string]any:
+			claims = value
+		}
+		claim, ok := claims[p.identity.Key]
+		if !ok || claim == nil {
+			return identityAnonymous
+		}
+		if value, ok := claim.(string); ok {
+			return value
+		}
+		return fmt.Sprint(claim)
+	}
+}
+
+// limitKey builds the limiter key of a capability and caller on this route
+func (p *McpRateLimitPolicy) limitKey(capabilityType, name, identity string) string {
+	return fmt.Sprintf("mcp:%s:%s:%s:%s", p.routeName, capabilityType, name, identity)
+}
+
+// message returns the JSON-RPC error message of a rejected request
+func (e *rateLimitExceeded) message() string {
+	return fmt.Sprintf("Rate limit exceeded for %s %s", strings.TrimSuffix(e.capabilityType, "s"), e.name)
+}
+
+// data returns the JSON-RPC error data of a rejected request
+func (e *rateLimitExceeded) data() map[string]any {
+	data := map[string]any{
+		"capabilityType": e.capabilityType,
+		"name":           e.name,
+	}
+	if seconds := e.retryAfterSeconds(); seconds > 0 {
+		data["retryAfter"] = seconds
+	}
+	return data
+}
+
+// headers returns the rate limit headers of the 429 response to a rejected request
+func (e *rateLimitExceeded) headers() map[string]string {
+	headers := make(map[string]string)
+	if e.result == nil {
+		return headers
+	}
+	headers["x-ratelimit-limit"] = strconv.FormatInt(e.result.Limit, 10)
+	headers["x-ratelimit-remaining"] = strconv.FormatInt(e.result.Remaining, 10)
+	headers["x-ratelimit-reset"] = strconv.FormatInt(e.result.Reset.Unix(), 10)
+	if seconds := e.retryAfterSeconds(); seconds > 0 {
+		headers["retry-after"] = strconv.FormatInt(seconds, 10)
+	}
+	return headers
+}
+
+// retryAfterSeconds returns the whole seconds to wait before retrying, or 0 if unknown
+func (e *rateLimitExceeded) retryAfterSeconds() int64 {
+	if e.result == nil || e.result.RetryAfter <= 0 {
+		return 0
+	}
+	seconds := int64(e.result.RetryAfter.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// isRateLimited reports whether requests of a method are counted against capability limits
+func isRateLimited(capabilityType, action string) bool {
+	switch capabilityType {
+	case capabilityTypeTools:
+		return action == "call"
+	case capabilityTypeResources:
+		return action == "read"
+	case capabilityTypePrompts:
+		return action == "get"
+	default:
+		return false
+	}
+}
+
+// toInt64 converts a whole JSON number to int64
+func toInt64(value any) (int64, bool) {
+	switch number := value.(type) {
+	case float64:
+		if number != float64(int64(number)) {
+			return 0, false
+		}
+		return int64(number), true
+	case int:
+		return int64(number), true
+	case int64:
+		return number, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcpratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/mcp-rewrite/jsonrpc"
+)
+
+func createToolLimitParams() map[string]any {
+	return map[string]any{
+		"tools": []any{
+			map[string]any{
+				"name":   "web_search",
+				"limits": []any{map[string]any{"limit": float64(2), "duration": "1m"}},
+			},
+			map[string]any{
+				"name":   "*",
+				"limits": []any{map[string]any{"limit": float64(3), "duration": "1m"}},
+			},
+		},
+	}
+}
+
+func newPolicy(t *testing.T, routeName string, params map[string]any) policy.Policy {
+	t.Helper()
+	p, err := GetPolicy(policy.PolicyMetadata{RouteName: routeName}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	return p
+}
+
+func createMockRequestContext(body string, headers map[string][]string, subject string) *policy.RequestContext {
+	metadata := make(map[string]any)
+	if subject != "" {
+		metadata[metadataValidatedClaims] = jwt.MapClaims{"sub": subject}
+	}
+	return &policy.RequestContext{
+		SharedContext: &policy.SharedContext{
+			RequestID: "test-request-id",
+			Metadata:  metadata,
+		},
+		Headers: policy.NewHeaders(headers),
+		Body:    &policy.Body{Content: []byte(body), Present: true},
+		Path:    "/mcp",
+		Method:  "POST",
+		Scheme:  "http",
+	}
+}
+
+func toolCall(id int, name string) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":%q}}`, id, name)
+}
+
+func TestOnRequest_LimitsPerToolWithWildcardDefault(t *testing.T) {
+	p := newPolicy(t, "per-tool", createToolLimitParams())
+
+	tests := []struct {
+		tool    string
+		subject string
+		allowed int
+	}{
+		{tool: "web_search", subject: "alice", allowed: 2},
+		{tool: "get_weather", subject: "alice", allowed: 3},
+		// Every tool without its own limit has its own count under the default
+		{tool: "get_time", subject: "alice", allowed: 3},
+		// Callers are counted separately
+		{tool: "web_search", subject: "bob", allowed: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool+"/"+tt.subject, func(t *testing.T) {
+			for i := 0; i < tt.allowed; i++ {
+				if action := p.OnRequest(createMockRequestContext(toolCall(i, tt.tool), nil, tt.subject), nil); action != nil {
+					t.Fatalf("Expected call %d to be allowed, got %T", i+1, action)
+				}
+			}
+
+			action := p.OnRequest(createMockRequestContext(toolCall(42, tt.tool), nil, tt.subject), nil)
+			response, ok := action.(policy.ImmediateResponse)
+			if !ok {
+				t.Fatalf("Expected ImmediateResponse, got %T", action)
+			}
+			if response.StatusCode != 429 {
+				t.Fatalf("Expected status 429, got %d", response.StatusCode)
+			}
+			if response.Headers["retry-after"] == "" || response.Headers["x-ratelimit-limit"] == "" {
+				t.Fatalf("Expected rate limit headers, got %v", response.Headers)
+			}
+
+			var body map[string]any
+			if err := json.Unmarshal(response.Body, &body); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if body["id"] != float64(42) {
+				t.Fatalf("Expected id 42, got %v", body["id"])
+			}
+			errorObject := body["error"].(map[string]any)
+			if errorObject["code"] != float64(rateLimitExceededJsonRpcCode) {
+				t.Fatalf("Expected code %d, got %v", rateLimitExceededJsonRpcCode, errorObject["code"])
+			}
+			if name := errorObject["data"].(map[string]any)["name"]; name != tt.tool {
+				t.Fatalf("Expected name %q, got %v", tt.tool, name)
+			}
+		})
+	}
+
+	// Methods other than calls are not limited
+	list := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+	if action := p.OnRequest(createMockRequestContext(list, nil, "alice"), nil); action != nil {
+		t.Fatalf("Expected tools/list to be allowed, got %T", action)
+	}
+}
+
+func TestOnRequest_EventStreamAndSessionIdentity(t *testing.T) {
+	params := map[string]any{
+		"prompts": []any{
+			map[string]any{
+				"name":   "summarize_*",
+				"limits": []any{map[string]any{"limit": float64(1), "duration": "1h"}},
+			},
+		},
+		"identity": map[string]any{"type": "session"},
+	}
+	p := newPolicy(t, "sse", params)
+
+	headers := map[string][]string{
+		"content-type":   {"text/event-stream"},
+		mcpSessionHeader: {"session-1"},
+	}
+	body := string(jsonrpc.BuildEventStream([]jsonrpc.Event{{Data: `{"jsonrpc":"2.0","id":"a","method":"prompts/get","params":{"name":"summarize_text"}}`}}))
+
+	if action := p.OnRequest(createMockRequestContext(body, headers, ""), nil); action != nil {
+		t.Fatalf("Expected the first request to be allowed, got %T", action)
+	}
+
+	response, ok := p.OnRequest(createMockRequestContext(body, headers, ""), nil).(policy.ImmediateResponse)
+	if !ok || response.StatusCode != 429 {
+		t.Fatalf("Expected a 429 response, got %v", response)
+	}
+	if response.Headers["Content-Type"] != "text/event-stream" || response.Headers[mcpSessionHeader] != "session-1" {
+		t.Fatalf("Expected an event stream for the session, got %v", response.Headers)
+	}
+	events := jsonrpc.ParseEventStream(response.Body)
+	var message map[string]any
+	if len(events) != 1 || json.Unmarshal([]byte(events[0].Data), &message) != nil || message["id"] != "a" {
+		t.Fatalf("Expected a JSON-RPC error event for id a, got %s", response.Body)
+	}
+
+	otherSession := map[string][]string{"content-type": {"text/event-stream"}, mcpSessionHeader: {"session-2"}}
+	if action := p.OnRequest(createMockRequestContext(body, otherSession, ""), nil); action != nil {
+		t.Fatalf("Expected another session to be allowed, got %T", action)
+	}
+}
+
+func TestOnRequest_BatchRejectsElementsOverLimit(t *testing.T) {
+	p := newPolicy(t, "batch", createToolLimitParams())
+
+	batch := fmt.Sprintf(`[%s,%s,%s,{"jsonrpc":"2.0","method":"tools/call","params":{"name":"web_search"}}]`,
+		toolCall(1, "web_search"), toolCall(2, "web_search"), toolCall(3, "web_search"))
+	ctx := createMockRequestContext(batch, nil, "alice")
+
+	mods, ok := p.OnRequest(ctx, nil).(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamRequestModifications")
+	}
+	var forwarded []map[string]any
+	if err := json.Unmarshal(mods.Body, &forwarded); err != nil || len(forwarded) != 2 {
+		t.Fatalf("Expected 2 forwarded requests, got %s", mods.Body)
+	}
+
+	response := p.OnResponse(&policy.ResponseContext{
+		SharedContext:   ctx.SharedContext,
+		ResponseHeaders: policy.NewHeaders(nil),
+		ResponseStatus:  200,
+		RequestMethod:   "POST",
+		RequestPath:     "/mcp",
+		ResponseBody:    &policy.Body{Content: []byte(`[{"jsonrpc":"2.0","id":1,"result":{}},{"jsonrpc":"2.0","id":2,"result":{}}]`), Present: true},
+	}, nil).(policy.UpstreamResponseModifications)
+
+	var responses []map[string]any
+	if err := json.Unmarshal(response.Body, &responses); err != nil || len(responses) != 3 {
+		t.Fatalf("Expected 3 responses, got %s", response.Body)
+	}
+	if responses[2]["id"] != float64(3) || responses[2]["error"] == nil {
+		t.Fatalf("Expected a rate limit error for request 3, got %v", responses[2])
+	}
+
+	// A batch with only limited elements is answered without forwarding
+	rejected, ok := p.OnRequest(createMockRequestContext(fmt.Sprintf(`[%s]`, toolCall(4, "web_search")), nil, "alice"), nil).(policy.ImmediateResponse)
+	if !ok || rejected.StatusCode != 429 {
+		t.Fatalf("Expected a 429 response, got %v", rejected)
+	}
+}
+
+func TestGetPolicy_InvalidConfiguration(t *testing.T) {
+	limits := []any{map[string]any{"limit": float64(1), "duration": "1m"}}
+	tests := []map[string]any{
+		{},
+		{"tools": []any{map[string]any{"name": "a"}}},
+		{"tools": []any{map[string]any{"name": "a", "limits": []any{map[string]any{"limit": float64(0), "duration": "1m"}}}}},
+		{"tools": []any{map[string]any{"name": "a", "limits": []any{map[string]any{"limit": float64(1), "duration": "soon"}}}}},
+		{"tools": []any{map[string]any{"name": "a", "limits": limits}, map[string]any{"name": "a", "limits": limits}}},
+		{"tools": []any{map[string]any{"name": "a", "limits": limits}}, "identity": map[string]any{"type": "header"}},
+		{"tools": []any{map[string]any{"name": "a", "limits": limits}}, "identity": map[string]any{"type": "ip"}},
+	}
+	for i, params := range tests {
+		if _, err := GetPolicy(policy.PolicyMetadata{RouteName: "invalid"}, params); err == nil {
+			t.Fatalf("Expected error for case %d", i)
+		}
+	}
+}
+
+func TestMatchWildcard(t *testing.T) {
+	limits := newCapabilityLimits([]*CapabilityLimit{
+		{Name: "*"},
+		{Name: "file:///logs/*"},
+		{Name: "file:///logs/*.gz"},
+		{Name: "file:///logs/app.log"},
+	})
+
+	tests := map[string]string{
+		"file:///logs/app.log":        "file:///logs/app.log",
+		"file:///logs/2026/01/a.gz":   "file:///logs/*.gz",
+		"file:///logs/2026/01/a.txt":  "file:///logs/*",
+		"file:///data/customers.json": "*",
+	}
+	for name, expected := range tests {
+		if entry := limits.find(name); entry == nil || entry.Name != expected {
+			t.Fatalf("Expected %q to match %q, got %v", name, expected, entry)
+		}
+	}
+}
@@ -0,0 +1,508 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcprewrite
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const (
+	// maxLearnedSessions bounds the number of MCP sessions with learned schemas
+	maxLearnedSessions = 1000
+)
+
+// ArgumentValidationConfig configures validation of tools/call and prompts/get arguments.
+// Requests for capabilities without a configured or learned definition are forwarded without
+// validation unless RejectUnknown is set.
+type ArgumentValidationConfig struct {
+	Tools             bool
+	Prompts           bool
+	LearnFromUpstream bool
+	RejectUnknown     bool
+}
+
+// PromptArgument is a declared prompt argument.
+type PromptArgument struct {
+	Name     string
+	Required bool
+}
+
+// argumentSchemas holds the argument definitions of tools and prompts by name.
+type argumentSchemas struct {
+	tools   map[string]*gojsonschema.Schema
+	prompts map[string][]PromptArgument
+}
+
+// learnedSchemas holds the argument definitions learned from the upstream for one MCP session.
+type learnedSchemas struct {
+	argumentSchemas
+	lastUsed time.Time
+}
+
+// schemaCache caches argument definitions learned from upstream list responses per MCP session.
+// Requests without a session ID share the entry of the empty session ID.
+type schemaCache struct {
+	mu       sync.Mutex
+	sessions map[string]*learnedSchemas
+}
+
+func newSchemaCache() *schemaCache {
+	return &schemaCache{sessions: make(map[string]*learnedSchemas)}
+}
+
+// parseArgumentValidationConfig parses the argumentValidation parameter.
+func parseArgumentValidationConfig(params map[string]any) (ArgumentValidationConfig, error) {
+	config := ArgumentValidationConfig{}
+
+	raw, ok := params["argumentValidation"]
+	if !ok || raw == nil {
+		return config, nil
+	}
+	entry, ok := raw.(map[string]any)
+	if !ok {
+		return config, fmt.Errorf("argumentValidation must be an object")
+	}
+
+	fields := map[string]*bool{
+		"tools":             &config.Tools,
+		"prompts":           &config.Prompts,
+		"learnFromUpstream": &config.LearnFromUpstream,
+		"rejectUnknown":     &config.RejectUnknown,
+	}
+	for field, target := range fields {
+		value, ok := entry[field]
+		if !ok {
+			continue
+		}
+		enabled, ok := value.(bool)
+		if !ok {
+			return config, fmt.Errorf("argumentValidation.%s must be a boolean", field)
+		}
+		*target = enabled
+	}
+
+	return config, nil
+}
+
+// compileConfiguredSchemas compiles the argument definitions of the configured tools and prompts,
+// keyed by user-facing name.
+func compileConfiguredSchemas(tools, prompts CapabilityConfig, validation ArgumentValidationConfig) (argumentSchemas, error) {
+	schemas := argumentSchemas{
+		tools:   make(map[string]*gojsonschema.Schema),
+		prompts: make(map[string][]PromptArgument),
+	}
+
+	if validation.Tools {
+		for _, entry := range tools.Entries {
+			schema, err := compileSchema(entry.Response["inputSchema"])
+			if err != nil {
+				return schemas, fmt.Errorf("tools %q has an invalid inputSchema: %w", entry.Key, err)
+			}
+			schemas.tools[entry.Key] = schema
+		}
+	}
+
+	if validation.Prompts {
+		for _, entry := range prompts.Entries {
+			raw, ok := entry.Response["arguments"]
+			if !ok {
+				continue
+			}
+			arguments, err := parsePromptArguments(raw)
+			if err != nil {
+				return schemas, fmt.Errorf("prompts %q has invalid arguments: %w", entry.Key, err)
+			}
+			schemas.prompts[entry.Key] = arguments
+		}
+	}
+
+	return schemas, nil
+}
+
+// compileSchema compiles a JSON Schema given as a parsed JSON object. Only references within the
+// schema are allowed, as gojsonschema would otherwise fetch http:// and file:// references while
+// compiling schemas that may come from the upstream.
+func compileSchema(raw any) (*gojsonschema.Schema, error) {
+	schema, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("schema must be a JSON object")
+	}
+	if err := checkSchemaReferences(schema); err != nil {
+		return nil, err
+	}
+	return gojsonschema.NewSchema(gojsonschema.NewGoLoader(schema))
+}
+
+// schemaDataKeywords hold instance values rather than schemas, so they are not checked for references.
+var schemaDataKeywords = map[string]bool{"enum": true, "const": true, "default": true, "examples": true}
+
+// checkSchemaReferences rejects $ref values that are not fragments of the schema itself and $id
+// values that would change the base URI the fragments are resolved against.
+func checkSchemaReferences(node any) error {
+	switch value := node.(type) {
+	case map[string]any:
+		for key, child := range value {
+			if schemaDataKeywords[key] {
+				continue
+			}
+			if ref, ok := child.(string); ok {
+				switch key {
+				case "$ref":
+					if !strings.HasPrefix(ref, "#") {
+						return fmt.Errorf("$ref %q is not allowed, only references within the schema are supported", ref)
+					}
+				case "$id", "id":
+					if ref != "" && !strings.HasPrefix(ref, "#") {
+						return fmt.Errorf("%s %q is not allowed", key, ref)
+					}
+				}
+				continue
+			}
+			if err := checkSchemaReferences(child); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, child := range value {
+			if err := checkSchemaReferences(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parsePromptArguments parses the arguments of a prompt definition.
+func parsePromptArguments(raw any) ([]PromptArgument, error) {
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("arguments must be an array")
+	}
+	arguments := make([]PromptArgument, 0, len(list))
+	for i, item := range list {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("arguments[%d] must be an object", i)
+		}
+		name, _ := entry["name"].(string)
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("arguments[%d].name must be a non-empty string", i)
+		}
+		required, _ := entry["required"].(bool)
+		arguments = append(arguments, PromptArgument{Name: name, Required: required})
+	}
+	return arguments, nil
+}
+
+// validatesArguments reports whether the arguments of the capability type are validated.
+func (p *McpRewritePolicy) validatesArguments(capabilityType string) bool {
+	switch capabilityType {
+	case "tools":
+		return p.validation.Tools
+	case "prompts":
+		return p.validation.Prompts
+	default:
+		return false
+	}
+}
+
+// learnsSchemas reports whether argument definitions of the capability type are learned from
+// upstream list responses.
+func (p *McpRewritePolicy) learnsSchemas(capabilityType string) bool {
+	return p.validation.LearnFromUpstream && p.validatesArguments(capabilityType)
+}
+
+// validateArguments validates the arguments of a tools/call or prompts/get request against the
+// configured definition of the capability, or the definition learned from the upstream. Requests
+// for capabilities without a known definition are not validated, unless RejectUnknown is set.
+func (p *McpRewritePolicy) validateArguments(capabilityType string, paramsRaw map[string]any, sessionID string) *requestRejection {
+	name, _ := paramsRaw["name"].(string)
+	if strings.TrimSpace(name) == "" {
+		return nil
+	}
+	arguments, hasArguments := paramsRaw["arguments"]
+
+	switch capabilityType {
+	case "tools":
+		schema := p.toolSchema(name, sessionID)
+		if schema == nil {
+			return p.unknownCapability("tool", name)
+		}
+		if !hasArguments || arguments == nil {
+			arguments = map[string]any{}
+		}
+		errors := validateToolArguments(schema, arguments)
+		if len(errors) == 0 {
+			return nil
+		}
+		slog.Debug("MCP Rewrite Policy: Tool arguments do not match the input schema", "tool", name, "errors", errors)
+		return &requestRejection{
+			code:   -32602,
+			reason: fmt.Sprintf("Invalid arguments for tool %s", name),
+			data:   map[string]any{"errors": errors},
+		}
+	case "prompts":
+		definition, ok := p.promptArguments(name, sessionID)
+		if !ok {
+			return p.unknownCapability("prompt", name)
+		}
+		errors := validatePromptArguments(definition, arguments)
+		if len(errors) == 0 {
+			return nil
+		}
+		slog.Debug("MCP Rewrite Policy: Prompt arguments do not match the prompt definition", "prompt", name, "errors", errors)
+		return &requestRejection{
+			code:   -32602,
+			reason: fmt.Sprintf("Invalid arguments for prompt %s", name),
+			data:   map[string]any{"errors": errors},
+		}
+	default:
+		return nil
+	}
+}
+
+// unknownCapability rejects a request for a capability without a known definition if RejectUnknown
+// is set. Otherwise the request is forwarded without validation.
+func (p *McpRewritePolicy) unknownCapability(kind, name string) *requestRejection {
+	if !p.validation.RejectUnknown {
+		return nil
+	}
+	slog.Debug("MCP Rewrite Policy: Rejecting request for a capability without a known definition", "kind", kind, "name", name)
+	return &requestRejection{
+		code:   -32602,
+		reason: fmt.Sprintf("Unknown %s %s", kind, name),
+	}
+}
+
+// toolSchema returns the input schema of a tool by user-facing name. Configured schemas take
+// precedence over learned ones, which are keyed by the upstream tool name.
+func (p *McpRewritePolicy) toolSchema(name, sessionID string) *gojsonschema.Schema {
+	if schema, ok := p.configuredSchemas.tools[name]; ok {
+		return schema
+	}
+	if !p.validation.LearnFromUpstream {
+		return nil
+	}
+	learned := p.learned.get(sessionID)
+	if learned == nil {
+		return nil
+	}
+	return learned.tools[p.upstreamName(p.tools, name)]
+}
+
+// promptArguments returns the argument definition of a prompt by user-facing name.
+func (p *McpRewritePolicy) promptArguments(name, sessionID string) ([]PromptArgument, bool) {
+	if arguments, ok := p.configuredSchemas.prompts[name]; ok {
+		return arguments, true
+	}
+	if !p.validation.LearnFromUpstream {
+		return nil, false
+	}
+	learned := p.learned.get(sessionID)
+	if learned == nil {
+		return nil, false
+	}
+	arguments, ok := learned.prompts[p.upstreamName(p.prompts, name)]
+	return arguments, ok
+}
+
+// upstreamName returns the backend name a user-facing capability name is forwarded as.
+func (p *McpRewritePolicy) upstreamName(config CapabilityConfig, name string) string {
+	if entry, ok := config.Lookup[name]; ok && entry.Target != "" {
+		return entry.Target
+	}
+	return name
+}
+
+// validateToolArguments validates tool arguments against a JSON Schema and returns the errors.
+func validateToolArguments(schema *gojsonschema.Schema, arguments any) []string {
+	result, err := schema.Validate(gojsonschema.NewGoLoader(arguments))
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if result.Valid() {
+		return nil
+	}
+	errors := make([]string, 0, len(result.Errors()))
+	for _, resultError := range result.Errors() {
+		errors = append(errors, fmt.Sprintf("%s: %s", resultError.Field(), resultError.Description()))
+	}
+	return errors
+}
+
+// validatePromptArguments checks that required prompt arguments are present, that no undeclared
+// arguments are sent, and that all values are strings, and returns the errors.
+func validatePromptArguments(definition []PromptArgument, arguments any) []string {
+	values := map[string]any{}
+	if arguments != nil {
+		argumentMap, ok := arguments.(map[string]any)
+		if !ok {
+			return []string{"arguments: must be an object"}
+		}
+		values = argumentMap
+	}
+
+	declared := make(map[string]struct{}, len(definition))
+	var errors []string
+	for _, argument := range definition {
+		declared[argument.Name] = struct{}{}
+		if _, ok := values[argument.Name]; !ok && argument.Required {
+			errors = append(errors, fmt.Sprintf("%s: is required", argument.Name))
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := declared[name]; !ok {
+			errors = append(errors, fmt.Sprintf("%s: is not a declared argument", name))
+			continue
+		}
+		if _, ok := values[name].(string); !ok {
+			errors = append(errors, fmt.Sprintf("%s: must be a string", name))
+		}
+	}
+	return errors
+}
+
+// learnSchemas records the argument definitions in an upstream tools/list or prompts/list
+// response for the session. Items with invalid definitions are skipped.
+func (p *McpRewritePolicy) learnSchemas(capabilityType string, responsePayload map[string]any, sessionID string) {
+	resultRaw, ok := responsePayload["result"].(map[string]any)
+	if !ok {
+		return
+	}
+	items, ok := resultRaw[capabilityType].([]any)
+	if !ok {
+		return
+	}
+
+	tools := make(map[string]*gojsonschema.Schema)
+	prompts := make(map[string][]PromptArgument)
+	for _, item := range items {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, _ := entry["name"].(string)
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		if capabilityType == "tools" {
+			schema, err := compileSchema(entry["inputSchema"])
+			if err != nil {
+				slog.Debug("MCP Rewrite Policy: Skipping invalid upstream input schema", "tool", name, "error", err)
+				continue
+			}
+			tools[name] = schema
+			continue
+		}
+		arguments := []PromptArgument{}
+		if raw, ok := entry["arguments"]; ok && raw != nil {
+			parsed, err := parsePromptArguments(raw)
+			if err != nil {
+				slog.Debug("MCP Rewrite Policy: Skipping invalid upstream prompt arguments", "prompt", name, "error", err)
+				continue
+			}
+			arguments = parsed
+		}
+		prompts[name] = arguments
+	}
+
+	slog.Debug("MCP Rewrite Policy: Learned argument definitions from upstream", "capabilityType", capabilityType, "tools", len(tools), "prompts", len(prompts))
+	p.learned.add(sessionID, tools, prompts)
+}
+
+// get returns a copy of the learned definitions of a session, or nil if there are none. The maps
+// of the copy are never modified, since add replaces them instead.
+func (c *schemaCache) get(sessionID string) *argumentSchemas {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	learned, ok := c.sessions[sessionID]
+	if !ok {
+		return nil
+	}
+	learned.lastUsed = time.Now()
+	schemas := learned.argumentSchemas
+	return &schemas
+}
+
+// add merges learned definitions into the session's entry, so that paginated list responses
+// add up. The least recently used session is evicted when the cache is full.
+func (c *schemaCache) add(sessionID string, tools map[string]*gojsonschema.Schema, prompts map[string][]PromptArgument) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	learned, ok := c.sessions[sessionID]
+	if !ok {
+		if len(c.sessions) >= maxLearnedSessions {
+			c.evictOldest()
+		}
+		learned = &learnedSchemas{argumentSchemas: argumentSchemas{
+			tools:   make(map[string]*gojsonschema.Schema),
+			prompts: make(map[string][]PromptArgument),
+		}}
+		c.sessions[sessionID] = learned
+	}
+
+	// Copy on write, so that definitions returned by get are never modified
+	mergedTools := make(map[string]*gojsonschema.Schema, len(learned.tools)+len(tools))
+	for name, schema := range learned.tools {
+		mergedTools[name] = schema
+	}
+	for name, schema := range tools {
+		mergedTools[name] = schema
+	}
+	mergedPrompts := make(map[string][]PromptArgument, len(learned.prompts)+len(prompts))
+	for name, arguments := range learned.prompts {
+		mergedPrompts[name] = arguments
+	}
+	for name, arguments := range prompts {
+		mergedPrompts[name] = arguments
+	}
+	learned.tools = mergedTools
+	learned.prompts = mergedPrompts
+	learned.lastUsed = time.Now()
+}
+
+// evictOldest removes the least recently used session. The caller must hold the lock.
+func (c *schemaCache) evictOldest() {
+	oldestID := ""
+	var oldest time.Time
+	first := true
+	for sessionID, learned := range c.sessions {
+		if first || learned.lastUsed.Before(oldest) {
+			oldestID = sessionID
+			oldest = learned.lastUsed
+			first = false
+		}
+	}
+	delete(c.sessions, oldestID)
+}
@@ -0,0 +1,316 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package mcprewrite
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+func TestOnRequest_ValidatesToolArguments(t *testing.T) {
+	params := map[string]any{
+		"tools": []any{
+			map[string]any{
+				"name":        "query",
+				"description": "desc",
+				"inputSchema": `{"type":"object","properties":{"table":{"type":"string","enum":["orders","customers"]},"limit":{"type":"integer","maximum":100}},"required":["table"],"additionalProperties":false}`,
+				"target":      "run_query",
+			},
+		},
+		"argumentValidation": map[string]any{"tools": true},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		errorCount int
+	}{
+		{
+			name: "valid arguments",
+			body: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"query","arguments":{"table":"orders","limit":10}}}`,
+		},
+		{
+			name:       "missing arguments",
+			body:       `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"query"}}`,
+			errorCount: 1,
+		},
+		{
+			name:       "invalid values and unknown property",
+			body:       `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"query","arguments":{"table":"payments","limit":1000,"drop":true}}}`,
+			errorCount: 3,
+		},
+		{
+			name: "tool without schema",
+			body: `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"other","arguments":{"x":1}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := createMockRequestContext(nil)
+			ctx.Body = &policy.Body{Content: []byte(tt.body), Present: true}
+
+			action := p.OnRequest(ctx, params)
+			if tt.errorCount == 0 {
+				if _, ok := action.(policy.ImmediateResponse); ok {
+					t.Fatalf("Expected the request to be forwarded, got %T", action)
+				}
+				return
+			}
+
+			response, ok := action.(policy.ImmediateResponse)
+			if !ok {
+				t.Fatalf("Expected ImmediateResponse, got %T", action)
+			}
+			if response.StatusCode != 400 {
+				t.Fatalf("Expected status 400, got %d", response.StatusCode)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(response.Body, &body); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			errorObject := body["error"].(map[string]any)
+			if errorObject["code"] != float64(-32602) {
+				t.Fatalf("Expected code -32602, got %v", errorObject["code"])
+			}
+			errors := errorObject["data"].(map[string]any)["errors"].([]any)
+			if len(errors) != tt.errorCount {
+				t.Fatalf("Expected %d errors, got %v", tt.errorCount, errors)
+			}
+		})
+	}
+}
+
+func TestOnRequest_ValidatesToolArgumentsWithLearnedSchema(t *testing.T) {
+	params := map[string]any{
+		"argumentValidation": map[string]any{"tools": true, "learnFromUpstream": true},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	sessionHeaders := map[string][]string{mcpSessionHeader: {"session-1"}}
+	callBody := []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_weather","arguments":{"city":42}}}`)
+
+	// Nothing is learned yet, so the call is not validated
+	ctx := createMockRequestContext(sessionHeaders)
+	ctx.Body = &policy.Body{Content: callBody, Present: true}
+	if action := p.OnRequest(ctx, params); action != nil {
+		t.Fatalf("Expected no action before learning, got %T", action)
+	}
+
+	responseCtx := createMockResponseContext(sessionHeaders, nil)
+	responseCtx.RequestMethod = "POST"
+	responseCtx.RequestPath = "/mcp"
+	responseCtx.ResponseStatus = 200
+	responseCtx.Metadata[metadataMcpCapabilityType] = "tools"
+	responseCtx.Metadata[metadataMcpAction] = "list"
+	responseCtx.ResponseBody = &policy.Body{
+		Content: []byte(`{"jsonrpc":"2.0","id":1,"result":{"tools":[{"name":"get_weather","inputSchema":{"type":"object","properties":{"city":{"type":"string"}}}}]}}`),
+		Present: true,
+	}
+	if action := p.OnResponse(responseCtx, params); action != nil {
+		t.Fatalf("Expected the list response to be unchanged, got %T", action)
+	}
+
+	ctx = createMockRequestContext(sessionHeaders)
+	ctx.Body = &policy.Body{Content: callBody, Present: true}
+	response, ok := p.OnRequest(ctx, params).(policy.ImmediateResponse)
+	if !ok || response.StatusCode != 400 {
+		t.Fatalf("Expected a 400 response after learning, got %v", response)
+	}
+
+	// Schemas are learned per session
+	ctx = createMockRequestContext(map[string][]string{mcpSessionHeader: {"session-2"}})
+	ctx.Body = &policy.Body{Content: callBody, Present: true}
+	if action := p.OnRequest(ctx, params); action != nil {
+		t.Fatalf("Expected no action for another session, got %T", action)
+	}
+}
+
+func TestOnRequest_ValidatesPromptArgumentsInBatch(t *testing.T) {
+	params := map[string]any{
+		"prompts": []any{
+			map[string]any{
+				"name":      "summarize",
+				"arguments": `[{"name":"text","required":true},{"name":"style"}]`,
+			},
+		},
+		"argumentValidation": map[string]any{"prompts": true},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	body := []byte(`[
+		{"jsonrpc":"2.0","id":1,"method":"prompts/get","params":{"name":"summarize","arguments":{"text":"hello"}}},
+		{"jsonrpc":"2.0","id":2,"method":"prompts/get","params":{"name":"summarize","arguments":{"style":1,"tone":"dry"}}}
+	]`)
+
+	ctx := createMockRequestContext(nil)
+	ctx.Body = &policy.Body{Content: body, Present: true}
+
+	action := p.OnRequest(ctx, params)
+	mods, ok := action.(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamRequestModifications, got %T", action)
+	}
+	var forwarded []map[string]any
+	if err := json.Unmarshal(mods.Body, &forwarded); err != nil || len(forwarded) != 1 || forwarded[0]["id"] != float64(1) {
+		t.Fatalf("Expected only request 1 to be forwarded, got %s", mods.Body)
+	}
+
+	errorEntries, _ := ctx.Metadata[metadataRewriteBatchErrors].([]any)
+	if len(errorEntries) != 1 {
+		t.Fatalf("Expected 1 error entry, got %v", errorEntries)
+	}
+	errorObject := errorEntries[0].(map[string]any)["error"].(map[string]any)
+	errors := errorObject["data"].(map[string]any)["errors"].([]string)
+	expected := []string{"text: is required", "style: must be a string", "tone: is not a declared argument"}
+	if len(errors) != len(expected) {
+		t.Fatalf("Expected errors %v, got %v", expected, errors)
+	}
+	for i := range expected {
+		if errors[i] != expected[i] {
+			t.Fatalf("Expected errors %v, got %v", expected, errors)
+		}
+	}
+}
+
+func TestGetPolicy_InvalidArgumentValidation(t *testing.T) {
+	tests := []map[string]any{
+		{"argumentValidation": map[string]any{"tools": "yes"}},
+		{
+			"tools": []any{
+				map[string]any{"name": "toolA", "description": "desc", "inputSchema": `{"type":"unknown"}`},
+			},
+			"argumentValidation": map[string]any{"tools": true},
+		},
+		{
+			"prompts":            []any{map[string]any{"name": "promptA", "arguments": `[{"required":true}]`}},
+			"argumentValidation": map[string]any{"prompts": true},
+		},
+		{"argumentValidation": map[string]any{"tools": true, "rejectUnknown": "yes"}},
+		{
+			"tools": []any{
+				map[string]any{"name": "toolA", "description": "desc", "inputSchema": `{"$ref":"http://example.com/schema.json"}`},
+			},
+			"argumentValidation": map[string]any{"tools": true},
+		},
+	}
+	for i, params := range tests {
+		if _, err := GetPolicy(policy.PolicyMetadata{}, params); err == nil {
+			t.Fatalf("Expected error for case %d", i)
+		}
+	}
+}
+
+func TestOnRequest_RejectsUnknownCapabilities(t *testing.T) {
+	params := map[string]any{
+		"argumentValidation": map[string]any{"tools": true, "learnFromUpstream": true, "rejectUnknown": true},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	ctx := createMockRequestContext(map[string][]string{mcpSessionHeader: {"session-1"}})
+	ctx.Body = &policy.Body{Content: []byte(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"get_weather","arguments":{}}}`), Present: true}
+	response, ok := p.OnRequest(ctx, params).(policy.ImmediateResponse)
+	if !ok || response.StatusCode != 400 {
+		t.Fatalf("Expected a 400 response for a tool without a known schema, got %v", response)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(response.Body, &payload); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if message := payload["error"].(map[string]any)["message"]; message != "Unknown tool get_weather" {
+		t.Errorf("Unexpected error message %v", message)
+	}
+}
+
+func TestCompileSchema_References(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		valid  bool
+	}{
+		{"local reference", `{"definitions":{"city":{"type":"string"}},"properties":{"city":{"$ref":"#/definitions/city"}}}`, true},
+		{"property named $ref", `{"properties":{"$ref":{"type":"string"},"id":{"type":"string"}}}`, true},
+		{"reference in enum data", `{"enum":[{"$ref":"http://example.com"}]}`, true},
+		{"remote reference", `{"properties":{"city":{"$ref":"http://example.com/city.json"}}}`, false},
+		{"file reference", `{"items":[{"$ref":"file:///etc/passwd"}]}`, false},
+		{"relative reference", `{"$ref":"city.json#/definitions/city"}`, false},
+		{"remote base", `{"$id":"http://example.com/root.json","properties":{"city":{"$ref":"#/definitions/city"}}}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var schema any
+			if err := json.Unmarshal([]byte(tt.schema), &schema); err != nil {
+				t.Fatalf("Invalid test schema: %v", err)
+			}
+			if _, err := compileSchema(schema); tt.valid != (err == nil) {
+				t.Errorf("Expected valid=%v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestSchemaCache_ConcurrentGetAndAdd(t *testing.T) {
+	cache := newSchemaCache()
+	prompts := map[string][]PromptArgument{"summarize": {}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cache.add("session-1", nil, prompts)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if learned := cache.get("session-1"); learned != nil {
+					_ = learned.prompts["summarize"]
+					_ = len(learned.tools)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if learned := cache.get("session-1"); learned == nil || len(learned.prompts) != 1 {
+		t.Fatalf("expected the learned prompt, got %v", learned)
+	}
+}
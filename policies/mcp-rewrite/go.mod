@@ -2,9 +2,14 @@ module github.com/wso2/gateway-controllers/policies/mcp-rewrite
 
 go 1.25.1
 
-require github.com/wso2/api-platform/sdk v0.3.7
+require (
+	github.com/wso2/api-platform/sdk v0.3.7
+	github.com/xeipuuv/gojsonschema v1.2.0
+)
 
 require (
+	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
+	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
 	golang.org/x/crypto v0.46.0 // indirect
 	golang.org/x/sys v0.39.0 // indirect
 )
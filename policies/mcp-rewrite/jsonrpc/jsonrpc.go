@@ -0,0 +1,330 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package jsonrpc parses and builds MCP JSON-RPC bodies, sent either as JSON or as a
+// text/event-stream, so that every MCP policy reads requests the same way.
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+// SessionHeader is the header carrying the MCP session ID
+const SessionHeader = "mcp-session-id"
+
+// Event is a server-sent event. Fields holds the non-data lines (event, id, retry and
+// comments) as they were received.
+type Event struct {
+	Fields []string
+	Data   string
+}
+
+// Payload is a parsed MCP request body. A single JSON-RPC request has one message,
+// a JSON-RPC batch has one message per array element.
+type Payload struct {
+	Messages []any
+	Batch    bool
+
+	events     []Event
+	eventIndex int
+}
+
+// IsEventStream reports whether headers indicate an SSE payload.
+func IsEventStream(headers *policy.Headers) bool {
+	if headers == nil {
+		return false
+	}
+	values := headers.Get("content-type")
+	if len(values) == 0 {
+		values = headers.Get("Content-Type")
+	}
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), "text/event-stream") {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseEventStream splits an SSE payload into events.
+func ParseEventStream(body []byte) []Event {
+	lines := strings.Split(string(body), "\n")
+	events := make([]Event, 0)
+	var fields []string
+	var dataLines []string
+
+	flush := func() {
+		if len(fields) == 0 && len(dataLines) == 0 {
+			return
+		}
+		event := Event{
+			Fields: append([]string(nil), fields...),
+			Data:   strings.Join(dataLines, "\n"),
+		}
+		events = append(events, event)
+		fields = nil
+		dataLines = nil
+	}
+
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			flush()
+			continue
+		}
+		if strings.HasPrefix(line, "data:") {
+			data := strings.TrimPrefix(line, "data:")
+			data = strings.TrimPrefix(data, " ")
+			dataLines = append(dataLines, data)
+			continue
+		}
+		fields = append(fields, line)
+	}
+	flush()
+
+	return events
+}
+
+// BuildEventStream builds a raw SSE payload from events.
+func BuildEventStream(events []Event) []byte {
+	var builder strings.Builder
+	for _, event := range events {
+		for _, field := range event.Fields {
+			builder.WriteString(field)
+			builder.WriteString("\n")
+		}
+		if event.Data != "" {
+			for _, line := range strings.Split(event.Data, "\n") {
+				builder.WriteString("data: ")
+				builder.WriteString(line)
+				builder.WriteString("\n")
+			}
+		}
+		builder.WriteString("\n")
+	}
+	return []byte(builder.String())
+}
+
+// ParsePayload extracts the JSON-RPC request or batch, handling SSE bodies. For an event
+// stream the first event holding JSON-RPC is used.
+func ParsePayload(body []byte, isSse bool) (Payload, error) {
+	if !isSse {
+		messages, batch, err := ParseMessages(body)
+		if err != nil {
+			return Payload{eventIndex: -1}, err
+		}
+		return Payload{Messages: messages, Batch: batch, eventIndex: -1}, nil
+	}
+
+	events := ParseEventStream(body)
+	for i, event := range events {
+		if strings.TrimSpace(event.Data) == "" {
+			continue
+		}
+		messages, batch, err := ParseMessages([]byte(event.Data))
+		if err != nil {
+			continue
+		}
+		return Payload{Messages: messages, Batch: batch, events: events, eventIndex: i}, nil
+	}
+	return Payload{events: events, eventIndex: -1}, fmt.Errorf("no JSON payload found in event stream")
+}
+
+// ParseMessages parses a JSON-RPC object or a non-empty JSON-RPC batch.
+func ParseMessages(data []byte) ([]any, bool, error) {
+	var payload any
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, false, err
+	}
+	switch value := payload.(type) {
+	case map[string]any:
+		return []any{value}, false, nil
+	case []any:
+		if len(value) == 0 {
+			return nil, true, fmt.Errorf("empty JSON-RPC batch")
+		}
+		return value, true, nil
+	default:
+		return nil, false, fmt.Errorf("JSON-RPC payload must be an object or an array")
+	}
+}
+
+// Encode builds the request body with the given messages in place of the parsed ones. The
+// other events of an event stream are kept as they were received.
+func (p Payload) Encode(messages []any) ([]byte, error) {
+	var payload any = messages
+	if !p.Batch {
+		payload = messages[0]
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.events) > 0 && p.eventIndex >= 0 {
+		events := append([]Event(nil), p.events...)
+		events[p.eventIndex].Data = string(body)
+		body = BuildEventStream(events)
+	}
+	return body, nil
+}
+
+// IDKey returns the key of a JSON-RPC id, keeping string and number ids distinct.
+func IDKey(id any) string {
+	key, err := json.Marshal(id)
+	if err != nil {
+		return fmt.Sprint(id)
+	}
+	return string(key)
+}
+
+// ErrorEntry builds a JSON-RPC error response entry. The error data is omitted when nil.
+func ErrorEntry(requestID any, jsonRpcCode int, reason string, data map[string]any) map[string]any {
+	errorObject := map[string]any{
+		"code":    jsonRpcCode,
+		"message": reason,
+	}
+	if data != nil {
+		errorObject["data"] = data
+	}
+	return map[string]any{
+		"jsonrpc": "2.0",
+		"id":      requestID,
+		"error":   errorObject,
+	}
+}
+
+// Response builds the response to a request from a JSON-RPC response or batch. The payload is
+// sent as an SSE event when the request was sent as an event stream, and the body is omitted
+// when payload is nil. The MCP session ID of the request is kept and headers are added last, so
+// they can replace it. A payload that cannot be marshaled is answered with an internal error.
+func Response(requestHeaders *policy.Headers, statusCode int, payload any, headers map[string]string) policy.ImmediateResponse {
+	return buildResponse(requestHeaders, statusCode, payload, nil, headers)
+}
+
+// ErrorResponse builds the response to a request from a JSON-RPC error, as Response does. The
+// error data is omitted when nil.
+func ErrorResponse(requestHeaders *policy.Headers, statusCode int, jsonRpcCode int, reason string, requestID any, data map[string]any, headers map[string]string) policy.ImmediateResponse {
+	return buildResponse(requestHeaders, statusCode, ErrorEntry(requestID, jsonRpcCode, reason, data), requestID, headers)
+}
+
+// buildResponse builds the response of Response and ErrorResponse. requestID is the id of the
+// internal error sent when the payload cannot be marshaled.
+func buildResponse(requestHeaders *policy.Headers, statusCode int, payload any, requestID any, headers map[string]string) policy.ImmediateResponse {
+	responseHeaders := make(map[string]string, len(headers)+2)
+	if sessionID := SessionID(requestHeaders); sessionID != "" {
+		responseHeaders[SessionHeader] = sessionID
+	}
+
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			statusCode = 500
+			body, err = json.Marshal(ErrorEntry(requestID, -32603, "Unexpected error", nil))
+			if err != nil {
+				body, _ = json.Marshal(ErrorEntry(nil, -32603, "Unexpected error", nil))
+			}
+		}
+		responseHeaders["Content-Type"] = "application/json"
+		if IsEventStream(requestHeaders) {
+			responseHeaders["Content-Type"] = "text/event-stream"
+			body = BuildEventStream([]Event{{Data: string(body)}})
+		}
+	}
+	for name, value := range headers {
+		responseHeaders[name] = value
+	}
+
+	return policy.ImmediateResponse{
+		StatusCode: statusCode,
+		Headers:    responseHeaders,
+		Body:       body,
+	}
+}
+
+// SessionID extracts the MCP session ID from headers.
+func SessionID(headers *policy.Headers) string {
+	if headers == nil {
+		return ""
+	}
+	values := headers.Get(SessionHeader)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// ParseMethod splits an MCP method into capability type and action.
+func ParseMethod(method string) (capabilityType string, action string, ok bool) {
+	parts := strings.Split(method, "/")
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	switch parts[0] {
+	case "tools", "resources", "prompts":
+		return parts[0], parts[1], true
+	default:
+		return "", "", false
+	}
+}
+
+// ParamKey returns the parameter name used for the capability identifier.
+func ParamKey(capabilityType string) string {
+	if capabilityType == "resources" {
+		return "uri"
+	}
+	return "name"
+}
+
+// PrefixListItems prefixes the names of capability list items in place with prefix and
+// separator. Items without a name are left as they are; resources keep their URI.
+func PrefixListItems(items []any, prefix, separator string) []any {
+	for _, item := range items {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		name, ok := entry["name"].(string)
+		if !ok || strings.TrimSpace(name) == "" {
+			continue
+		}
+		entry["name"] = prefix + separator + name
+	}
+	return items
+}
+
+// UnprefixName finds the longest of prefixes that a prefixed name starts with, followed by
+// separator, and returns it with the name without the prefix.
+func UnprefixName(name, separator string, prefixes []string) (prefix string, unprefixed string, ok bool) {
+	for _, candidate := range prefixes {
+		full := candidate + separator
+		if len(name) > len(full) && strings.HasPrefix(name, full) && (!ok || len(candidate) > len(prefix)) {
+			prefix, ok = candidate, true
+		}
+	}
+	if !ok {
+		return "", "", false
+	}
+	return prefix, name[len(prefix)+len(separator):], true
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2026, WSO2 LLC. (https://www.wso2.com).
+ *
+ * WSO2 LLC. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package jsonrpc
+
+import (
+	"testing"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+func TestEventStreamRoundTrip(t *testing.T) {
+	body := "event: message\r\nid: 7\ndata: {\"a\":1}\n\n: keep-alive\n\ndata: line1\ndata:line2\n\n"
+	events := ParseEventStream([]byte(body))
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %#v", events)
+	}
+	if events[0].Data != `{"a":1}` || len(events[0].Fields) != 2 || events[0].Fields[0] != "event: message" {
+		t.Fatalf("unexpected first event %#v", events[0])
+	}
+	if events[2].Data != "line1\nline2" {
+		t.Fatalf("expected multi-line data to be joined, got %q", events[2].Data)
+	}
+
+	rebuilt := ParseEventStream(BuildEventStream(events))
+	if len(rebuilt) != len(events) || rebuilt[2].Data != events[2].Data || rebuilt[1].Fields[0] != ": keep-alive" {
+		t.Fatalf("expected events to survive a round trip, got %#v", rebuilt)
+	}
+}
+
+func TestParsePayload(t *testing.T) {
+	payload, err := ParsePayload([]byte(`[{"jsonrpc":"2.0","id":1,"method":"tools/list"}]`), false)
+	if err != nil || !payload.Batch || len(payload.Messages) != 1 {
+		t.Fatalf("expected a batch with one message, got %#v %v", payload, err)
+	}
+
+	for _, body := range []string{`[]`, `"text"`, `{`} {
+		if _, err := ParsePayload([]byte(body), false); err == nil {
+			t.Errorf("expected %s to be rejected", body)
+		}
+	}
+
+	stream := "event: open\n\nid: 1\ndata: {\"jsonrpc\":\"2.0\",\"id\":\"a\",\"method\":\"tools/call\"}\n\n"
+	payload, err = ParsePayload([]byte(stream), true)
+	if err != nil || payload.Batch || len(payload.Messages) != 1 {
+		t.Fatalf("expected a single request from the event stream, got %#v %v", payload, err)
+	}
+
+	body, err := payload.Encode([]any{map[string]any{"id": "b"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "event: open\n\nid: 1\ndata: {\"id\":\"b\"}\n\n"; string(body) != want {
+		t.Fatalf("expected the other events to be kept, got %q", body)
+	}
+
+	if _, err := ParsePayload([]byte(": comment\n\n"), true); err == nil {
+		t.Fatal("expected an event stream without JSON to be rejected")
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	if IDKey("1") == IDKey(float64(1)) {
+		t.Fatal("expected string and number ids to have different keys")
+	}
+
+	entry := ErrorEntry(float64(3), -32602, "Invalid params", nil)
+	if _, ok := entry["error"].(map[string]any)["data"]; ok || entry["id"] != float64(3) {
+		t.Fatalf("unexpected error entry %#v", entry)
+	}
+
+	headers := policy.NewHeaders(map[string][]string{"content-type": {"Text/Event-Stream; charset=utf-8"}, SessionHeader: {"s-1"}})
+	if !IsEventStream(headers) || SessionID(headers) != "s-1" || IsEventStream(nil) || SessionID(nil) != "" {
+		t.Fatal("unexpected header handling")
+	}
+
+	if capabilityType, action, ok := ParseMethod("resources/read"); !ok || capabilityType != "resources" || action != "read" || ParamKey(capabilityType) != "uri" {
+		t.Fatalf("unexpected method parsing %q %q %v", capabilityType, action, ok)
+	}
+	if _, _, ok := ParseMethod("initialize"); ok {
+		t.Fatal("expected non-capability methods to be ignored")
+	}
+}
+
+func TestPrefixedNames(t *testing.T) {
+	items := PrefixListItems([]any{
+		map[string]any{"name": "search"},
+		map[string]any{"uri": "file:///a"},
+		"not an item",
+	}, "web", "__")
+	if items[0].(map[string]any)["name"] != "web__search" {
+		t.Fatalf("expected a prefixed name, got %v", items[0])
+	}
+	if _, ok := items[1].(map[string]any)["name"]; ok {
+		t.Fatalf("expected an item without a name to be left as it is, got %v", items[1])
+	}
+
+	prefixes := []string{"web", "web__internal"}
+	tests := []struct {
+		name       string
+		prefix     string
+		unprefixed string
+		ok         bool
+	}{
+		{name: "web__search", prefix: "web", unprefixed: "search", ok: true},
+		{name: "web__internal__search", prefix: "web__internal", unprefixed: "search", ok: true},
+		{name: "web__", ok: false},
+		{name: "search", ok: false},
+	}
+	for _, tt := range tests {
+		prefix, unprefixed, ok := UnprefixName(tt.name, "__", prefixes)
+		if prefix != tt.prefix || unprefixed != tt.unprefixed || ok != tt.ok {
+			t.Errorf("UnprefixName(%q) = %q, %q, %v", tt.name, prefix, unprefixed, ok)
+		}
+	}
+}
+
+func TestResponses(t *testing.T) {
+	sse := policy.NewHeaders(map[string][]string{"content-type": {"text/event-stream"}, SessionHeader: {"s-1"}})
+
+	response := ErrorResponse(sse, 429, -32000, "Rate limited", float64(1), nil, map[string]string{"Retry-After": "5"})
+	if response.StatusCode != 429 || response.Headers["Content-Type"] != "text/event-stream" ||
+		response.Headers[SessionHeader] != "s-1" || response.Headers["Retry-After"] != "5" {
+		t.Fatalf("unexpected headers %v", response.Headers)
+	}
+	if string(response.Body) != "data: {\"error\":{\"code\":-32000,\"message\":\"Rate limited\"},\"id\":1,\"jsonrpc\":\"2.0\"}\n\n" {
+		t.Fatalf("unexpected body %q", response.Body)
+	}
+
+	response = Response(nil, 200, []any{map[string]any{"bad": func() {}}}, map[string]string{SessionHeader: "s-2"})
+	if response.StatusCode != 500 || response.Headers["Content-Type"] != "application/json" || response.Headers[SessionHeader] != "s-2" {
+		t.Fatalf("expected an internal error, got %v", response)
+	}
+
+	response = Response(sse, 202, nil, nil)
+	if response.Body != nil || response.Headers["Content-Type"] != "" || response.Headers[SessionHeader] != "s-1" {
+		t.Fatalf("expected an empty response, got %v", response)
+	}
+}
@@ -25,13 +25,16 @@ import (
 	"strings"
 
 	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/mcp-rewrite/jsonrpc"
 )
 
 const (
-	mcpPathSegment            = "/mcp"
-	metadataMcpCapabilityType = "mcp.capabilityType"
-	metadataMcpAction         = "mcp.action"
-	mcpSessionHeader          = "mcp-session-id"
+	mcpPathSegment             = "/mcp"
+	metadataMcpCapabilityType  = "mcp.capabilityType"
+	metadataMcpAction          = "mcp.action"
+	metadataMcpBatchMethods    = "mcp.batch.methods"
+	metadataRewriteBatchErrors = "mcp.rewrite.batchErrors"
+	mcpSessionHeader           = "mcp-session-id"
 )
 
 type CapabilityEntry struct {
@@ -48,14 +51,19 @@ type CapabilityConfig struct {
 }
 
 type McpRewritePolicy struct {
-	tools     CapabilityConfig
-	resources CapabilityConfig
-	prompts   CapabilityConfig
+	tools             CapabilityConfig
+	resources         CapabilityConfig
+	prompts           CapabilityConfig
+	validation        ArgumentValidationConfig
+	configuredSchemas argumentSchemas
+	learned           *schemaCache
 }
 
-type sseEvent struct {
-	fields []string
-	data   string
+// requestRejection describes why a JSON-RPC request is not forwarded.
+type requestRejection struct {
+	code   int
+	reason string
+	data   map[string]any
 }
 
 func GetPolicy(
@@ -84,9 +92,24 @@ func GetPolicy(
 		return nil, fmt.Errorf("invalid prompts configuration: %w", err)
 	}
 
+	validationConfig, err := parseArgumentValidationConfig(params)
+	if err != nil {
+		slog.Debug("MCP Rewrite Policy: Invalid argument validation configuration", "error", err)
+		return nil, fmt.Errorf("invalid argument validation configuration: %w", err)
+	}
+
+	configuredSchemas, err := compileConfiguredSchemas(toolsConfig, promptsConfig, validationConfig)
+	if err != nil {
+		slog.Debug("MCP Rewrite Policy: Invalid argument schema", "error", err)
+		return nil, fmt.Errorf("invalid argument validation configuration: %w", err)
+	}
+
 	ins.tools = toolsConfig
 	ins.resources = resourcesConfig
 	ins.prompts = promptsConfig
+	ins.validation = validationConfig
+	ins.configuredSchemas = configuredSchemas
+	ins.learned = newSchemaCache()
 
 	slog.Debug("MCP Rewrite Policy: Parsed configuration",
 		"toolsEnabled", ins.tools.Enabled,
@@ -95,6 +118,9 @@ func GetPolicy(
 		"resourcesCount", len(ins.resources.Entries),
 		"promptsEnabled", ins.prompts.Enabled,
 		"promptsCount", len(ins.prompts.Entries),
+		"validateToolArguments", ins.validation.Tools,
+		"validatePromptArguments", ins.validation.Prompts,
+		"learnFromUpstream", ins.validation.LearnFromUpstream,
 	)
 
 	return ins, nil
@@ -255,78 +281,162 @@ func (p *McpRewritePolicy) OnRequest(ctx *policy.RequestContext, params map[stri
 		return nil
 	}
 
-	requestPayload, requestEvents, requestEventIndex, err := parseRequestPayload(ctx.Body.Content, isEventStream(ctx.Headers))
+	payload, err := jsonrpc.ParsePayload(ctx.Body.Content, jsonrpc.IsEventStream(ctx.Headers))
 	if err != nil {
 		slog.Debug("MCP Rewrite Policy: Failed to parse MCP request", "error", err, "path", ctx.Path)
-		return p.buildRequestErrorResponse(ctx, 400, -32700, "Invalid JSON", nil)
+		return p.buildRequestErrorResponse(ctx, 400, -32700, "Invalid JSON", nil, nil)
 	}
 
-	requestID := requestPayload["id"]
+	if ctx.Metadata == nil {
+		ctx.Metadata = make(map[string]any)
+	}
 
-	method, _ := requestPayload["method"].(string)
-	capabilityType, action, ok := parseMcpMethod(method)
-	if !ok {
-		return nil
+	if payload.Batch {
+		return p.handleBatchRequest(ctx, payload)
 	}
 
-	if ctx.Metadata == nil {
-		ctx.Metadata = make(map[string]any)
+	requestPayload := payload.Messages[0].(map[string]any)
+	requestID := requestPayload["id"]
+
+	capabilityType, action, rewritten, rejection := p.rewriteRequest(requestPayload, jsonrpc.SessionID(ctx.Headers))
+	if capabilityType == "" {
+		return nil
 	}
 	ctx.Metadata[metadataMcpCapabilityType] = capabilityType
 	ctx.Metadata[metadataMcpAction] = action
 
-	if !rewriteApplicable(capabilityType, action) {
+	if rejection != nil {
+		return p.buildRequestErrorResponse(ctx, 400, rejection.code, rejection.reason, requestID, rejection.data)
+	}
+	if !rewritten {
 		return nil
 	}
 
-	config := p.getCapabilityConfig(capabilityType)
-	if !config.Enabled {
-		return nil
+	updatedPayload, err := payload.Encode(payload.Messages)
+	if err != nil {
+		slog.Debug("MCP Rewrite Policy: Failed to marshal updated request", "capabilityType", capabilityType, "requestID", requestID, "error", err)
+		return p.buildRequestErrorResponse(ctx, 500, -32603, "Failed to update MCP request", requestID, nil)
+	}
+	return policy.UpstreamRequestModifications{
+		Body: updatedPayload,
 	}
+}
 
-	if len(config.Entries) == 0 {
-		return nil
+// rewriteRequest validates the arguments of a single JSON-RPC request and rewrites its
+// capability name in place to its configured target. The capability type is empty when the
+// method is not a tools, resources or prompts method.
+func (p *McpRewritePolicy) rewriteRequest(requestPayload map[string]any, sessionID string) (string, string, bool, *requestRejection) {
+	requestID := requestPayload["id"]
+
+	method, _ := requestPayload["method"].(string)
+	capabilityType, action, ok := jsonrpc.ParseMethod(method)
+	if !ok {
+		return "", "", false, nil
+	}
+
+	if !rewriteApplicable(capabilityType, action) {
+		return capabilityType, action, false, nil
+	}
+
+	config := p.getCapabilityConfig(capabilityType)
+	validate := p.validatesArguments(capabilityType)
+	if (!config.Enabled || len(config.Entries) == 0) && !validate {
+		return capabilityType, action, false, nil
 	}
 
 	paramsRaw, ok := requestPayload["params"].(map[string]any)
 	if !ok {
 		slog.Debug("MCP Rewrite Policy: Invalid request params", "capabilityType", capabilityType, "requestID", requestID, "error", "params not a map")
-		return p.buildRequestErrorResponse(ctx, 400, -32602, "Invalid MCP request params", requestID)
+		return capabilityType, action, false, &requestRejection{code: -32602, reason: "Invalid MCP request params"}
 	}
 
-	paramKey := getParamKey(capabilityType)
+	paramKey := jsonrpc.ParamKey(capabilityType)
 	capabilityName, _ := paramsRaw[paramKey].(string)
 	if strings.TrimSpace(capabilityName) == "" {
 		slog.Debug("MCP Rewrite Policy: Missing capability name", "capabilityType", capabilityType, "requestID", requestID, "paramKey", paramKey)
-		return p.buildRequestErrorResponse(ctx, 400, -32602, fmt.Sprintf("Missing MCP %s name", capabilityType), requestID)
+		return capabilityType, action, false, &requestRejection{code: -32602, reason: fmt.Sprintf("Missing MCP %s name", capabilityType)}
+	}
+
+	if validate {
+		if rejection := p.validateArguments(capabilityType, paramsRaw, sessionID); rejection != nil {
+			return capabilityType, action, false, rejection
+		}
 	}
 
 	entry, exists := config.Lookup[capabilityName]
 	if !exists {
-		return nil
+		return capabilityType, action, false, nil
 	}
 
-	if entry.Target != "" && entry.Target != capabilityName {
-		paramsRaw[paramKey] = entry.Target
-		requestPayload["params"] = paramsRaw
+	if entry.Target == "" || entry.Target == capabilityName {
+		return capabilityType, action, false, nil
+	}
 
-		updatedPayload, err := json.Marshal(requestPayload)
-		if err != nil {
-			slog.Debug("MCP Rewrite Policy: Failed to marshal updated request", "capabilityType", capabilityType, "capabilityName", capabilityName, "requestID", requestID, "error", err)
-			return p.buildRequestErrorResponse(ctx, 500, -32603, "Failed to update MCP request", requestID)
+	paramsRaw[paramKey] = entry.Target
+	requestPayload["params"] = paramsRaw
+	slog.Debug("MCP Rewrite Policy: Request rewritten", "capabilityType", capabilityType, "requestName", capabilityName, "targetName", entry.Target, "requestID", requestID)
+	return capabilityType, action, true, nil
+}
+
+// handleBatchRequest rewrites each element of a JSON-RPC batch. Invalid elements are removed
+// from the forwarded batch and answered with JSON-RPC errors for their ids, which are added to
+// the upstream response. Invalid notifications are dropped without an error.
+func (p *McpRewritePolicy) handleBatchRequest(ctx *policy.RequestContext, payload jsonrpc.Payload) policy.RequestAction {
+	methods := make(map[string]string)
+	forwarded := make([]any, 0, len(payload.Messages))
+	errorEntries := make([]any, 0)
+	updated := false
+
+	for _, message := range payload.Messages {
+		requestPayload, ok := message.(map[string]any)
+		if !ok {
+			// Not a request; the upstream answers it with an Invalid Request error
+			forwarded = append(forwarded, message)
+			continue
 		}
+		requestID, hasID := requestPayload["id"]
 
-		if len(requestEvents) > 0 && requestEventIndex >= 0 {
-			requestEvents[requestEventIndex].data = string(updatedPayload)
-			updatedPayload = buildEventStream(requestEvents)
+		_, _, rewritten, rejection := p.rewriteRequest(requestPayload, jsonrpc.SessionID(ctx.Headers))
+		if rejection != nil {
+			if hasID {
+				errorEntries = append(errorEntries, jsonrpc.ErrorEntry(requestID, rejection.code, rejection.reason, rejection.data))
+			}
+			updated = true
+			continue
+		}
+		if rewritten {
+			updated = true
 		}
-		slog.Debug("MCP Rewrite Policy: Request rewritten", "capabilityType", capabilityType, "requestName", capabilityName, "targetName", entry.Target, "requestID", requestID)
-		return policy.UpstreamRequestModifications{
-			Body: updatedPayload,
+
+		if method, ok := requestPayload["method"].(string); ok && hasID {
+			methods[jsonrpc.IDKey(requestID)] = method
 		}
+		forwarded = append(forwarded, message)
+	}
+
+	ctx.Metadata[metadataMcpBatchMethods] = methods
+
+	if !updated {
+		return nil
+	}
+
+	if len(forwarded) == 0 {
+		return p.buildBatchErrorResponse(ctx, 400, errorEntries)
+	}
+
+	updatedPayload, err := payload.Encode(forwarded)
+	if err != nil {
+		slog.Debug("MCP Rewrite Policy: Failed to marshal updated batch", "error", err)
+		return p.buildRequestErrorResponse(ctx, 500, -32603, "Failed to update MCP request", nil, nil)
 	}
 
-	return nil
+	slog.Debug("MCP Rewrite Policy: Batch rewritten", "batchSize", len(payload.Messages), "forwarded", len(forwarded), "errors", len(errorEntries))
+	if len(errorEntries) > 0 {
+		ctx.Metadata[metadataRewriteBatchErrors] = errorEntries
+	}
+	return policy.UpstreamRequestModifications{
+		Body: updatedPayload,
+	}
 }
 
 func (p *McpRewritePolicy) OnResponse(ctx *policy.ResponseContext, params map[string]any) policy.ResponseAction {
@@ -339,6 +449,10 @@ func (p *McpRewritePolicy) OnResponse(ctx *policy.ResponseContext, params map[st
 		return nil
 	}
 
+	if methods, ok := ctx.Metadata[metadataMcpBatchMethods].(map[string]string); ok {
+		return p.handleBatchResponse(ctx, methods)
+	}
+
 	capabilityType, _ := ctx.Metadata[metadataMcpCapabilityType].(string)
 	action, _ := ctx.Metadata[metadataMcpAction].(string)
 	if action != "list" {
@@ -347,55 +461,40 @@ func (p *McpRewritePolicy) OnResponse(ctx *policy.ResponseContext, params map[st
 	}
 
 	config := p.getCapabilityConfig(capabilityType)
-	if !config.Enabled {
+	learn := p.learnsSchemas(capabilityType)
+	if !config.Enabled && !learn {
 		return nil
 	}
 
 	if ctx.ResponseBody == nil || !ctx.ResponseBody.Present {
 		return nil
 	}
+	sessionID := getResponseSessionID(ctx)
 
-	if isEventStream(ctx.ResponseHeaders) {
-		events := parseEventStream(ctx.ResponseBody.Content)
+	if jsonrpc.IsEventStream(ctx.ResponseHeaders) {
+		events := jsonrpc.ParseEventStream(ctx.ResponseBody.Content)
 		updated := false
 		for i, event := range events {
-			if strings.TrimSpace(event.data) == "" {
+			if strings.TrimSpace(event.Data) == "" {
 				continue
 			}
 			var responsePayload map[string]any
-			if err := json.Unmarshal([]byte(event.data), &responsePayload); err != nil {
-				continue
-			}
-			if _, hasError := responsePayload["error"]; hasError {
-				slog.Debug("MCP Rewrite Policy: Upstream response contains error", "capabilityType", capabilityType)
-				continue
-			}
-			resultRaw, ok := responsePayload["result"].(map[string]any)
-			if !ok {
-				slog.Debug("MCP Rewrite Policy: Invalid MCP response result", "capabilityType", capabilityType, "error", "result not an object")
+			if err := json.Unmarshal([]byte(event.Data), &responsePayload); err != nil {
 				continue
 			}
-
-			listKey := capabilityType
-			existing, ok := resultRaw[listKey].([]any)
-			if !ok {
-				continue
+			if learn {
+				p.learnSchemas(capabilityType, responsePayload, sessionID)
 			}
-
-			filtered, changed := rewriteListItems(existing, capabilityType, config)
-			if !changed {
+			if !config.Enabled || !p.rewriteListResult(responsePayload, capabilityType) {
 				continue
 			}
 
-			resultRaw[listKey] = filtered
-			responsePayload["result"] = resultRaw
-
 			updatedPayload, err := json.Marshal(responsePayload)
 			if err != nil {
 				slog.Debug("MCP Rewrite Policy: Failed to marshal updated response", "capabilityType", capabilityType, "error", err)
 				continue
 			}
-			events[i].data = string(updatedPayload)
+			events[i].Data = string(updatedPayload)
 			updated = true
 		}
 
@@ -404,7 +503,7 @@ func (p *McpRewritePolicy) OnResponse(ctx *policy.ResponseContext, params map[st
 			return nil
 		}
 		return policy.UpstreamResponseModifications{
-			Body: buildEventStream(events),
+			Body: jsonrpc.BuildEventStream(events),
 		}
 	}
 
@@ -414,34 +513,119 @@ func (p *McpRewritePolicy) OnResponse(ctx *policy.ResponseContext, params map[st
 		return nil
 	}
 
-	if _, hasError := responsePayload["error"]; hasError {
-		slog.Debug("MCP Rewrite Policy: Upstream response contains error", "capabilityType", capabilityType)
+	if learn {
+		p.learnSchemas(capabilityType, responsePayload, sessionID)
+	}
+	if !config.Enabled || !p.rewriteListResult(responsePayload, capabilityType) {
 		return nil
 	}
 
-	resultRaw, ok := responsePayload["result"].(map[string]any)
-	if !ok {
-		slog.Debug("MCP Rewrite Policy: Invalid MCP response result", "capabilityType", capabilityType, "error", "result not an object")
+	updatedPayload, err := json.Marshal(responsePayload)
+	if err != nil {
+		slog.Debug("MCP Rewrite Policy: Failed to marshal updated response", "capabilityType", capabilityType, "error", err)
 		return nil
 	}
 
-	listKey := capabilityType
-	existing, ok := resultRaw[listKey].([]any)
-	if !ok {
+	return policy.UpstreamResponseModifications{
+		Body: updatedPayload,
+	}
+}
+
+// handleBatchResponse rewrites the list results of a batch response, matching each response to
+// its request by id, and adds the errors of the elements rejected on the request path.
+func (p *McpRewritePolicy) handleBatchResponse(ctx *policy.ResponseContext, methods map[string]string) policy.ResponseAction {
+	errorEntries, _ := ctx.Metadata[metadataRewriteBatchErrors].([]any)
+	if ctx.ResponseStatus >= 300 {
+		slog.Debug("MCP Rewrite Policy: Batch response skipped, upstream returned an error", "status", ctx.ResponseStatus)
 		return nil
 	}
 
-	filtered, changed := rewriteListItems(existing, capabilityType, config)
-	if !changed {
+	var content []byte
+	if ctx.ResponseBody != nil && ctx.ResponseBody.Present {
+		content = ctx.ResponseBody.Content
+	}
+	sessionID := getResponseSessionID(ctx)
+
+	if jsonrpc.IsEventStream(ctx.ResponseHeaders) {
+		events := jsonrpc.ParseEventStream(content)
+		updated := false
+		for i, event := range events {
+			if strings.TrimSpace(event.Data) == "" {
+				continue
+			}
+			var responsePayload any
+			if err := json.Unmarshal([]byte(event.Data), &responsePayload); err != nil {
+				continue
+			}
+			if !p.rewriteBatchResponse(responsePayload, methods, sessionID) {
+				continue
+			}
+
+			updatedPayload, err := json.Marshal(responsePayload)
+			if err != nil {
+				slog.Debug("MCP Rewrite Policy: Failed to marshal updated batch response", "error", err)
+				continue
+			}
+			events[i].Data = string(updatedPayload)
+			updated = true
+		}
+
+		if len(errorEntries) > 0 {
+			data, err := json.Marshal(errorEntries)
+			if err == nil {
+				events = append(events, jsonrpc.Event{Data: string(data)})
+				updated = true
+			}
+		}
+
+		if !updated {
+			return nil
+		}
+		return policy.UpstreamResponseModifications{
+			Body: jsonrpc.BuildEventStream(events),
+		}
+	}
+
+	if len(strings.TrimSpace(string(content))) == 0 {
+		if len(errorEntries) == 0 {
+			return nil
+		}
+		// Only notifications were forwarded, so the upstream accepted the batch without a body
+		body, err := json.Marshal(errorEntries)
+		if err != nil {
+			slog.Debug("MCP Rewrite Policy: Failed to marshal batch errors", "error", err)
+			return nil
+		}
+		statusCode := 200
+		return policy.UpstreamResponseModifications{
+			SetHeaders: map[string]string{"Content-Type": "application/json"},
+			Body:       body,
+			StatusCode: &statusCode,
+		}
+	}
+
+	var responsePayload any
+	if err := json.Unmarshal(content, &responsePayload); err != nil {
+		slog.Debug("MCP Rewrite Policy: Failed to parse MCP batch response", "error", err)
 		return nil
 	}
 
-	resultRaw[listKey] = filtered
-	responsePayload["result"] = resultRaw
+	updated := p.rewriteBatchResponse(responsePayload, methods, sessionID)
+	if len(errorEntries) > 0 {
+		if responses, ok := responsePayload.([]any); ok {
+			responsePayload = append(responses, errorEntries...)
+		} else {
+			responsePayload = append([]any{responsePayload}, errorEntries...)
+		}
+		updated = true
+	}
+	if !updated {
+		return nil
+	}
 
 	updatedPayload, err := json.Marshal(responsePayload)
 	if err != nil {
-		slog.Debug("MCP Rewrite Policy: Failed to marshal updated response", "capabilityType", capabilityType, "error", err)
+		slog.Debug("MCP Rewrite Policy: Failed to marshal updated batch response", "error", err)
 		return nil
 	}
 
@@ -450,9 +634,77 @@ func (p *McpRewritePolicy) OnResponse(ctx *policy.ResponseContext, params map[st
 	}
 }
 
+// rewriteBatchResponse learns from and rewrites the list results of the responses in a payload,
+// which is a single JSON-RPC response or a batch. The request method of each response is found by id.
+func (p *McpRewritePolicy) rewriteBatchResponse(responsePayload any, methods map[string]string, sessionID string) bool {
+	var responses []any
+	switch payload := responsePayload.(type) {
+	case []any:
+		responses = payload
+	case map[string]any:
+		responses = []any{payload}
+	default:
+		return false
+	}
+
+	updated := false
+	for _, response := range responses {
+		responseMap, ok := response.(map[string]any)
+		if !ok {
+			continue
+		}
+		method, ok := methods[jsonrpc.IDKey(responseMap["id"])]
+		if !ok {
+			continue
+		}
+		capabilityType, action, ok := jsonrpc.ParseMethod(method)
+		if !ok || action != "list" {
+			continue
+		}
+		if p.learnsSchemas(capabilityType) {
+			p.learnSchemas(capabilityType, responseMap, sessionID)
+		}
+		if !p.getCapabilityConfig(capabilityType).Enabled {
+			continue
+		}
+		if p.rewriteListResult(responseMap, capabilityType) {
+			updated = true
+		}
+	}
+	return updated
+}
+
+// rewriteListResult rewrites the list in the result of a list response and reports whether it changed.
+func (p *McpRewritePolicy) rewriteListResult(responsePayload map[string]any, capabilityType string) bool {
+	if _, hasError := responsePayload["error"]; hasError {
+		slog.Debug("MCP Rewrite Policy: Upstream response contains error", "capabilityType", capabilityType)
+		return false
+	}
+	resultRaw, ok := responsePayload["result"].(map[string]any)
+	if !ok {
+		slog.Debug("MCP Rewrite Policy: Invalid MCP response result", "capabilityType", capabilityType, "error", "result not an object")
+		return false
+	}
+
+	listKey := capabilityType
+	existing, ok := resultRaw[listKey].([]any)
+	if !ok {
+		return false
+	}
+
+	filtered, changed := rewriteListItems(existing, capabilityType, p.getCapabilityConfig(capabilityType))
+	if !changed {
+		return false
+	}
+
+	resultRaw[listKey] = filtered
+	responsePayload["result"] = resultRaw
+	return true
+}
+
 // rewriteListItems filters and rewrites list items based on configured entries.
 func rewriteListItems(items []any, capabilityType string, config CapabilityConfig) ([]any, bool) {
-	keyField := getParamKey(capabilityType)
+	keyField := jsonrpc.ParamKey(capabilityType)
 	filtered := make([]any, 0, len(items))
 	changed := false
 
@@ -513,23 +765,6 @@ func (p *McpRewritePolicy) getCapabilityConfig(capabilityType string) Capability
 	}
 }
 
-// parseMcpMethod splits an MCP method into capability type and action.
-func parseMcpMethod(method string) (string, string, bool) {
-	parts := strings.Split(method, "/")
-	if len(parts) != 2 {
-		return "", "", false
-	}
-
-	capabilityType := parts[0]
-	action := parts[1]
-	switch capabilityType {
-	case "tools", "resources", "prompts":
-		return capabilityType, action, true
-	default:
-		return "", "", false
-	}
-}
-
 // rewriteApplicable reports whether request rewriting applies for a method.
 func rewriteApplicable(capabilityType, action string) bool {
 	switch capabilityType {
@@ -544,158 +779,18 @@ func rewriteApplicable(capabilityType, action string) bool {
 	}
 }
 
-// getParamKey returns the parameter name used for the capability identifier.
-func getParamKey(capabilityType string) string {
-	if capabilityType == "resources" {
-		return "uri"
-	}
-	return "name"
-}
-
-// isEventStream reports whether headers indicate an SSE payload.
-func isEventStream(headers *policy.Headers) bool {
-	if headers == nil {
-		return false
-	}
-	values := headers.Get("content-type")
-	if len(values) == 0 {
-		values = headers.Get("Content-Type")
-	}
-	for _, value := range values {
-		if strings.Contains(strings.ToLower(value), "text/event-stream") {
-			return true
-		}
-	}
-	return false
-}
-
-// parseEventStream splits an SSE payload into events.
-func parseEventStream(body []byte) []sseEvent {
-	lines := strings.Split(string(body), "\n")
-	events := make([]sseEvent, 0)
-	var fields []string
-	var dataLines []string
-
-	flush := func() {
-		if len(fields) == 0 && len(dataLines) == 0 {
-			return
-		}
-		event := sseEvent{
-			fields: append([]string(nil), fields...),
-			data:   strings.Join(dataLines, "\n"),
-		}
-		events = append(events, event)
-		fields = nil
-		dataLines = nil
-	}
-
-	for _, line := range lines {
-		line = strings.TrimSuffix(line, "\r")
-		if line == "" {
-			flush()
-			continue
-		}
-		if strings.HasPrefix(line, "data:") {
-			data := strings.TrimPrefix(line, "data:")
-			data = strings.TrimPrefix(data, " ")
-			dataLines = append(dataLines, data)
-			continue
-		}
-		fields = append(fields, line)
-	}
-	flush()
-
-	return events
-}
-
-// buildEventStream builds a raw SSE payload from events.
-func buildEventStream(events []sseEvent) []byte {
-	var builder strings.Builder
-	for _, event := range events {
-		for _, field := range event.fields {
-			builder.WriteString(field)
-			builder.WriteString("\n")
-		}
-		if event.data != "" {
-			for _, line := range strings.Split(event.data, "\n") {
-				builder.WriteString("data: ")
-				builder.WriteString(line)
-				builder.WriteString("\n")
-			}
-		}
-		builder.WriteString("\n")
-	}
-	return []byte(builder.String())
-}
-
-// parseRequestPayload extracts the JSON-RPC payload, handling SSE bodies.
-func parseRequestPayload(body []byte, isSse bool) (map[string]any, []sseEvent, int, error) {
-	if !isSse {
-		var payload map[string]any
-		if err := json.Unmarshal(body, &payload); err != nil {
-			return nil, nil, -1, err
-		}
-		return payload, nil, -1, nil
-	}
-
-	events := parseEventStream(body)
-	for i, event := range events {
-		if strings.TrimSpace(event.data) == "" {
-			continue
-		}
-		var payload map[string]any
-		if err := json.Unmarshal([]byte(event.data), &payload); err != nil {
-			continue
-		}
-		return payload, events, i, nil
-	}
-	return nil, events, -1, fmt.Errorf("no JSON payload found in event stream")
-}
-
 // buildRequestErrorResponse builds an error response for a request.
-func (p *McpRewritePolicy) buildRequestErrorResponse(ctx *policy.RequestContext, statusCode int, jsonRpcCode int, reason string, requestID any) policy.RequestAction {
-	sessionID := getSessionID(ctx.Headers)
-	if isEventStream(ctx.Headers) {
-		return p.buildEventStreamErrorResponse(statusCode, jsonRpcCode, reason, requestID, sessionID)
-	}
-	return p.buildErrorResponse(statusCode, jsonRpcCode, reason, requestID, sessionID)
+func (p *McpRewritePolicy) buildRequestErrorResponse(ctx *policy.RequestContext, statusCode int, jsonRpcCode int, reason string, requestID any, data map[string]any) policy.RequestAction {
+	return jsonrpc.ErrorResponse(ctx.Headers, statusCode, jsonRpcCode, reason, requestID, data, nil)
 }
 
-// buildEventStreamErrorResponse builds an SSE error response.
-func (p *McpRewritePolicy) buildEventStreamErrorResponse(statusCode int, jsonRpcCode int, reason string, requestID any, sessionID string) policy.RequestAction {
-	responseBody := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      requestID,
-		"error": map[string]any{
-			"code":    jsonRpcCode,
-			"message": reason,
-		},
-	}
-	body, err := json.Marshal(responseBody)
-	if err != nil {
-		slog.Debug("MCP Rewrite Policy: Failed to marshal event-stream error response", "error", err)
-		idBytes, idErr := json.Marshal(requestID)
-		if idErr != nil {
-			idBytes = []byte("null")
-		}
-		body = fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%s,"error":{"code":-32603,"message":"Unexpected error"}}`, string(idBytes))
-	}
-
-	event := sseEvent{data: string(body)}
-	streamBody := buildEventStream([]sseEvent{event})
-
-	headers := map[string]string{
-		"Content-Type": "text/event-stream",
-	}
-	if sessionID != "" {
-		headers[mcpSessionHeader] = sessionID
-	}
-
-	return policy.ImmediateResponse{
-		StatusCode: statusCode,
-		Headers:    headers,
-		Body:       streamBody,
+// buildBatchErrorResponse builds the response to a batch in which every element is rejected.
+func (p *McpRewritePolicy) buildBatchErrorResponse(ctx *policy.RequestContext, statusCode int, errorEntries []any) policy.RequestAction {
+	if len(errorEntries) == 0 {
+		// Only notifications were sent, which are not answered
+		return jsonrpc.Response(ctx.Headers, 202, nil, nil)
 	}
+	return jsonrpc.Response(ctx.Headers, statusCode, errorEntries, nil)
 }
 
 // isMcpPostRequest reports whether the request targets the MCP endpoint.
@@ -703,48 +798,11 @@ func isMcpPostRequest(method, path string) bool {
 	return strings.EqualFold(method, "POST") && strings.Contains(path, mcpPathSegment)
 }
 
-// buildErrorResponse builds a JSON error response.
-func (p *McpRewritePolicy) buildErrorResponse(statusCode int, jsonRpcCode int, reason string, requestID any, sessionID string) policy.RequestAction {
-	responseBody := map[string]any{
-		"jsonrpc": "2.0",
-		"id":      requestID,
-		"error": map[string]any{
-			"code":    jsonRpcCode,
-			"message": reason,
-		},
-	}
-	body, err := json.Marshal(responseBody)
-	if err != nil {
-		slog.Debug("MCP Rewrite Policy: Failed to marshal error response", "error", err)
-		idBytes, idErr := json.Marshal(requestID)
-		if idErr != nil {
-			idBytes = []byte("null")
-		}
-		body = fmt.Appendf(nil, `{"jsonrpc":"2.0","id":%s,"error":{"code":-32603,"message":"Unexpected error"}}`, string(idBytes))
-	}
-
-	headers := map[string]string{
-		"Content-Type": "application/json",
-	}
-	if sessionID != "" {
-		headers[mcpSessionHeader] = sessionID
-	}
-
-	return policy.ImmediateResponse{
-		StatusCode: statusCode,
-		Headers:    headers,
-		Body:       body,
-	}
-}
-
-// getSessionID extracts the MCP session ID from headers.
-func getSessionID(headers *policy.Headers) string {
-	if headers == nil {
-		return ""
-	}
-	values := headers.Get(mcpSessionHeader)
-	if len(values) == 0 {
-		return ""
+// getResponseSessionID returns the MCP session ID of a response. The session ID assigned by
+// the upstream in the response is used when the request has none.
+func getResponseSessionID(ctx *policy.ResponseContext) string {
+	if sessionID := jsonrpc.SessionID(ctx.RequestHeaders); sessionID != "" {
+		return sessionID
 	}
-	return values[0]
+	return jsonrpc.SessionID(ctx.ResponseHeaders)
 }
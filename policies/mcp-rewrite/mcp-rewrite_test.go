@@ -23,6 +23,7 @@ import (
 	"testing"
 
 	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+	"github.com/wso2/gateway-controllers/policies/mcp-rewrite/jsonrpc"
 )
 
 func TestOnRequest_RewritesToolCallTarget(t *testing.T) {
@@ -286,6 +287,116 @@ func TestOnResponse_RewritesListItems_WithAllCapabilities(t *testing.T) {
 	}
 }
 
+func TestOnRequest_Batch_RewritesEachElement(t *testing.T) {
+	params := map[string]any{
+		"tools": []any{
+			map[string]any{
+				"name":        "toolA",
+				"description": "desc",
+				"inputSchema": `{"type":"object"}`,
+				"target":      "backendTool",
+			},
+		},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	body := []byte(`[
+		{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"toolA"}},
+		{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"other"}},
+		{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{}},
+		{"jsonrpc":"2.0","id":4,"method":"tools/list"}
+	]`)
+
+	ctx := createMockRequestContext(nil)
+	ctx.Body = &policy.Body{Content: body, Present: true}
+
+	action := p.OnRequest(ctx, params)
+	mods, ok := action.(policy.UpstreamRequestModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamRequestModifications, got %T", action)
+	}
+
+	var forwarded []map[string]any
+	if err := json.Unmarshal(mods.Body, &forwarded); err != nil {
+		t.Fatalf("Failed to unmarshal forwarded batch: %v", err)
+	}
+	if len(forwarded) != 3 {
+		t.Fatalf("Expected 3 forwarded requests, got %d", len(forwarded))
+	}
+	if name := forwarded[0]["params"].(map[string]any)["name"]; name != "backendTool" {
+		t.Fatalf("Expected rewritten name 'backendTool', got %v", name)
+	}
+	if name := forwarded[1]["params"].(map[string]any)["name"]; name != "other" {
+		t.Fatalf("Expected name 'other' to be unchanged, got %v", name)
+	}
+
+	errorEntries, _ := ctx.Metadata[metadataRewriteBatchErrors].([]any)
+	if len(errorEntries) != 1 || errorEntries[0].(map[string]any)["id"] != float64(3) {
+		t.Fatalf("Expected an error entry for id 3, got %v", errorEntries)
+	}
+	methods, _ := ctx.Metadata[metadataMcpBatchMethods].(map[string]string)
+	if methods["4"] != "tools/list" {
+		t.Fatalf("Expected batch methods by id, got %v", methods)
+	}
+}
+
+func TestOnResponse_Batch_RewritesListByRequestID(t *testing.T) {
+	params := map[string]any{
+		"tools": []any{
+			map[string]any{
+				"name":        "toolA",
+				"description": "desc",
+				"inputSchema": `{"type":"object"}`,
+				"target":      "backendTool",
+			},
+		},
+	}
+
+	p, err := GetPolicy(policy.PolicyMetadata{}, params)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	body := []byte(`[
+		{"jsonrpc":"2.0","id":1,"result":{"content":[{"type":"text","text":"done"}],"tools":[{"name":"backendTool"}]}},
+		{"jsonrpc":"2.0","id":2,"result":{"tools":[{"name":"backendTool"},{"name":"other"}]}}
+	]`)
+
+	ctx := createMockResponseContext(nil, map[string][]string{"content-type": {"text/event-stream"}})
+	ctx.RequestMethod = "POST"
+	ctx.RequestPath = "/mcp"
+	ctx.ResponseStatus = 200
+	ctx.ResponseBody = &policy.Body{Content: jsonrpc.BuildEventStream([]jsonrpc.Event{{Data: string(body)}}), Present: true}
+	ctx.Metadata[metadataMcpBatchMethods] = map[string]string{"1": "tools/call", "2": "tools/list"}
+
+	action := p.OnResponse(ctx, params)
+	mods, ok := action.(policy.UpstreamResponseModifications)
+	if !ok {
+		t.Fatalf("Expected UpstreamResponseModifications, got %T", action)
+	}
+
+	events := jsonrpc.ParseEventStream(mods.Body)
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	var updated []map[string]any
+	if err := json.Unmarshal([]byte(events[0].Data), &updated); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	callTools := updated[0]["result"].(map[string]any)["tools"].([]any)
+	if callTools[0].(map[string]any)["name"] != "backendTool" {
+		t.Fatalf("Expected the tools/call result to be unchanged, got %v", callTools)
+	}
+	listTools := updated[1]["result"].(map[string]any)["tools"].([]any)
+	if len(listTools) != 2 || listTools[0].(map[string]any)["name"] != "toolA" {
+		t.Fatalf("Expected the tools/list result to be rewritten, got %v", listTools)
+	}
+}
+
 func createMockRequestContext(headers map[string][]string) *policy.RequestContext {
 	return &policy.RequestContext{
 		SharedContext: &policy.SharedContext{
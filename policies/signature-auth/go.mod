@@ -0,0 +1,10 @@
+module github.com/wso2/gateway-controllers/policies/signature-auth
+
+go 1.25.1
+
+require github.com/wso2/api-platform/sdk v0.3.10
+
+require (
+	golang.org/x/crypto v0.46.0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
+)
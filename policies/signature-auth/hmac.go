@@ -0,0 +1,297 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package signatureauth
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+const (
+	// Signature encodings for the encoding parameter
+	SignatureEncodingHex    = "hex"
+	SignatureEncodingBase64 = "base64"
+
+	// Components of the signed message
+	ComponentTimestamp = "timestamp"
+	ComponentNonce     = "nonce"
+	ComponentMethod    = "method"
+	ComponentPath      = "path" // Path including the query string
+	ComponentBody      = "body"
+	ComponentHeader    = "header:"  // header:<name>
+	ComponentLiteral   = "literal:" // literal:<value>
+)
+
+// hmacConfig describes where an HMAC signature is found and what it covers
+type hmacConfig struct {
+	profile         string
+	signatureHeader string
+	signaturePrefix string
+	encoding        string
+	keyIDHeader     string
+	keyID           string
+	timestampHeader string
+	nonceHeader     string
+	components      []string
+	separator       string
+}
+
+// profileDefaults are the settings of each HMAC profile; user parameters override them
+var profileDefaults = map[string]hmacConfig{
+	ProfileHMAC: {
+		signatureHeader: "X-Signature",
+		encoding:        SignatureEncodingHex,
+		components:      []string{ComponentBody},
+		separator:       "\n",
+	},
+	ProfileGitHub: {
+		signatureHeader: "X-Hub-Signature-256",
+		signaturePrefix: "sha256=",
+		encoding:        SignatureEncodingHex,
+		components:      []string{ComponentBody},
+	},
+	ProfileStripe: {
+		signatureHeader: "Stripe-Signature",
+		encoding:        SignatureEncodingHex,
+		components:      []string{ComponentTimestamp, ComponentBody},
+		separator:       ".",
+	},
+	ProfileSlack: {
+		signatureHeader: "X-Slack-Signature",
+		signaturePrefix: "v0=",
+		encoding:        SignatureEncodingHex,
+		timestampHeader: "X-Slack-Request-Timestamp",
+		components:      []string{ComponentLiteral + "v0", ComponentTimestamp, ComponentBody},
+		separator:       ":",
+	},
+}
+
+// getHMACConfig applies the user parameters to the defaults of the profile
+func getHMACConfig(profile string, params map[string]interface{}) (*hmacConfig, error) {
+	config := profileDefaults[profile]
+	config.profile = profile
+	config.signatureHeader = getStringParam(params, "signatureHeader", config.signatureHeader)
+	config.signaturePrefix = getStringParam(params, "signaturePrefix", config.signaturePrefix)
+	config.encoding = getStringParam(params, "encoding", config.encoding)
+	config.keyIDHeader = getStringParam(params, "keyIdHeader", config.keyIDHeader)
+	config.keyID = getStringParam(params, "keyId", config.keyID)
+	config.timestampHeader = getStringParam(params, "timestampHeader", config.timestampHeader)
+	config.nonceHeader = getStringParam(params, "nonceHeader", config.nonceHeader)
+	config.components = getStringArrayParam(params, "components", config.components)
+	if separator, ok := params["separator"].(string); ok {
+		config.separator = separator
+	}
+
+	if config.encoding != SignatureEncodingHex && config.encoding != SignatureEncodingBase64 {
+		return nil, fmt.Errorf("unsupported signature encoding %q", config.encoding)
+	}
+	if len(config.components) == 0 {
+		return nil, fmt.Errorf("at least one signed component must be configured")
+	}
+	for _, component := range config.components {
+		switch {
+		case component == ComponentTimestamp && config.timestampHeader == "" && profile != ProfileStripe:
+			return nil, fmt.Errorf("component %q requires timestampHeader", component)
+		case component == ComponentNonce && config.nonceHeader == "":
+			return nil, fmt.Errorf("component %q requires nonceHeader", component)
+		case component == ComponentTimestamp, component == ComponentNonce, component == ComponentMethod,
+			component == ComponentPath, component == ComponentBody:
+		case strings.HasPrefix(component, ComponentHeader) && len(component) > len(ComponentHeader):
+		case strings.HasPrefix(component, ComponentLiteral):
+		default:
+			return nil, fmt.Errorf("unsupported component %q", component)
+		}
+	}
+	// A timestamp or nonce that is not signed can be changed by anyone replaying a request
+	if config.timestampHeader != "" && !signsHeader(config.components, ComponentTimestamp, config.timestampHeader) {
+		return nil, fmt.Errorf("timestampHeader %q must be signed: add %q to components", config.timestampHeader, ComponentTimestamp)
+	}
+	if config.nonceHeader != "" && !signsHeader(config.components, ComponentNonce, config.nonceHeader) {
+		return nil, fmt.Errorf("nonceHeader %q must be signed: add %q to components", config.nonceHeader, ComponentNonce)
+	}
+	return &config, nil
+}
+
+// signsHeader reports whether the components sign a header, either through its own component
+// or as a header component
+func signsHeader(components []string, component, header string) bool {
+	for _, c := range components {
+		if c == component {
+			return true
+		}
+		if strings.HasPrefix(c, ComponentHeader) && strings.EqualFold(c[len(ComponentHeader):], header) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyHMACSignature checks a webhook-style HMAC signature carried in a single header
+func verifyHMACSignature(ctx *policy.RequestContext, config *hmacConfig, algorithm string, keys []*SharedSecret,
+	params map[string]interface{}) verification {
+	header := firstHeader(ctx, config.signatureHeader)
+	if header == "" {
+		return verification{reason: "missing signature"}
+	}
+
+	// Stripe carries the timestamp and one or more signatures in the same header
+	var encodedSignatures []string
+	var timestamp string
+	if config.profile == ProfileStripe {
+		for _, element := range strings.Split(header, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(element), "=")
+			switch name {
+			case "t":
+				timestamp = value
+			case "v1":
+				encodedSignatures = append(encodedSignatures, value)
+			}
+		}
+	} else {
+		if len(header) < len(config.signaturePrefix) ||
+			!strings.EqualFold(header[:len(config.signaturePrefix)], config.signaturePrefix) {
+			return verification{reason: "signature does not have the configured prefix"}
+		}
+		encodedSignatures = append(encodedSignatures, strings.TrimSpace(header[len(config.signaturePrefix):]))
+		if config.timestampHeader != "" {
+			timestamp = firstHeader(ctx, config.timestampHeader)
+		}
+	}
+
+	var signatures [][]byte
+	for _, encoded := range encodedSignatures {
+		signature, err := decodeSignature(encoded, config.encoding)
+		if err != nil {
+			slog.Debug("Signature Auth Policy: Failed to decode signature", "error", err)
+			continue
+		}
+		signatures = append(signatures, signature)
+	}
+	if len(signatures) == 0 {
+		return verification{reason: "malformed signature"}
+	}
+
+	result := verification{}
+	if config.timestampHeader != "" || config.profile == ProfileStripe {
+		if timestamp == "" {
+			return verification{reason: "missing timestamp"}
+		}
+		signedAt, err := parseUnixTimestamp(timestamp)
+		if err != nil {
+			return verification{reason: err.Error()}
+		}
+		if !checkTimestamp(signedAt, getDurationParam(params, "timestampTolerance", DefaultTimestampTolerance)) {
+			slog.Debug("Signature Auth Policy: Timestamp outside the tolerance window",
+				"timestamp", timestamp,
+			)
+			return verification{reason: "timestamp outside tolerance"}
+		}
+		result.timestamp = signedAt
+	}
+	if config.nonceHeader != "" {
+		result.nonce = firstHeader(ctx, config.nonceHeader)
+		if result.nonce == "" {
+			return verification{reason: "missing nonce"}
+		}
+	}
+
+	keyID := config.keyID
+	if config.keyIDHeader != "" {
+		if keyID = firstHeader(ctx, config.keyIDHeader); keyID == "" {
+			return verification{reason: "missing key ID"}
+		}
+	}
+	candidates := selectSecrets(keys, keyID)
+	if len(candidates) == 0 {
+		slog.Debug("Signature Auth Policy: Unknown key ID", "keyId", keyID)
+		return verification{reason: "unknown key ID"}
+	}
+
+	message, err := buildSignedMessage(ctx, config, timestamp, result.nonce)
+	if err != nil {
+		return verification{reason: err.Error()}
+	}
+	match := matchSecret(algorithm, candidates, message, signatures)
+	if match == nil {
+		return verification{reason: "signature mismatch"}
+	}
+	result.keyID = match.ID
+	return result
+}
+
+// buildSignedMessage joins the configured components with the separator
+func buildSignedMessage(ctx *policy.RequestContext, config *hmacConfig, timestamp, nonce string) ([]byte, error) {
+	parts := make([][]byte, 0, len(config.components))
+	for _, component := range config.components {
+		switch {
+		case component == ComponentTimestamp:
+			parts = append(parts, []byte(timestamp))
+		case component == ComponentNonce:
+			parts = append(parts, []byte(nonce))
+		case component == ComponentMethod:
+			parts = append(parts, []byte(ctx.Method))
+		case component == ComponentPath:
+			parts = append(parts, []byte(ctx.Path))
+		case component == ComponentBody:
+			parts = append(parts, requestBody(ctx))
+		case strings.HasPrefix(component, ComponentHeader):
+			name := component[len(ComponentHeader):]
+			values := ctx.Headers.Get(name)
+			if len(values) == 0 {
+				return nil, fmt.Errorf("missing signed header %q", name)
+			}
+			parts = append(parts, []byte(strings.Join(values, ",")))
+		case strings.HasPrefix(component, ComponentLiteral):
+			parts = append(parts, []byte(component[len(ComponentLiteral):]))
+		}
+	}
+	return bytes.Join(parts, []byte(config.separator)), nil
+}
+
+func decodeSignature(value, encoding string) ([]byte, error) {
+	if encoding == SignatureEncodingBase64 {
+		if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+			return decoded, nil
+		}
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	}
+	return hex.DecodeString(strings.ToLower(value))
+}
+
+// firstHeader returns the first value of a request header
+func firstHeader(ctx *policy.RequestContext, name string) string {
+	values := ctx.Headers.Get(name)
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(values[0])
+}
+
+// requestBody returns the buffered request body
+func requestBody(ctx *policy.RequestContext) []byte {
+	if ctx.Body == nil || !ctx.Body.Present {
+		return nil
+	}
+	return ctx.Body.Content
+}
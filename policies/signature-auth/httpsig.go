@@ -0,0 +1,267 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package signatureauth
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+const (
+	// Headers of RFC 9421 HTTP Message Signatures and RFC 9530 digests
+	SignatureInputHeader = "signature-input"
+	SignatureHeader      = "signature"
+	ContentDigestHeader  = "content-digest"
+)
+
+// defaultRequiredComponents must be covered by every HTTP message signature
+var defaultRequiredComponents = []string{"@method", "@authority", "@path"}
+
+// verifyMessageSignature checks an RFC 9421 HTTP message signature made with an HMAC key
+func verifyMessageSignature(ctx *policy.RequestContext, algorithm string, keys []*SharedSecret,
+	params map[string]interface{}) verification {
+	inputs, err := parseDictionary(strings.Join(ctx.Headers.Get(SignatureInputHeader), ", "))
+	if err != nil || len(inputs) == 0 {
+		slog.Debug("Signature Auth Policy: Missing or invalid Signature-Input header", "error", err)
+		return verification{reason: "missing or invalid Signature-Input"}
+	}
+	signatures, err := parseDictionary(strings.Join(ctx.Headers.Get(SignatureHeader), ", "))
+	if err != nil {
+		slog.Debug("Signature Auth Policy: Invalid Signature header", "error", err)
+		return verification{reason: "invalid Signature"}
+	}
+
+	// Use the configured label, or the first signature of the request
+	input := &inputs[0]
+	if label := getStringParam(params, "signatureLabel", ""); label != "" {
+		if input = findMember(inputs, label); input == nil {
+			return verification{reason: "signature label not found"}
+		}
+	}
+	if !input.isInnerList {
+		return verification{reason: "invalid Signature-Input"}
+	}
+	signatureMember := findMember(signatures, input.key)
+	if signatureMember == nil || signatureMember.isInnerList {
+		return verification{reason: "missing signature"}
+	}
+	signature, ok := signatureMember.item.value.([]byte)
+	if !ok {
+		return verification{reason: "malformed signature"}
+	}
+
+	result := verification{}
+	if alg, ok := input.params.get("alg").(string); ok && alg != algorithm {
+		slog.Debug("Signature Auth Policy: Signature algorithm does not match the configuration",
+			"alg", alg,
+			"algorithm", algorithm,
+		)
+		return verification{reason: "algorithm mismatch"}
+	}
+
+	tolerance := getDurationParam(params, "timestampTolerance", DefaultTimestampTolerance)
+	if created, ok := input.params.get("created").(int64); ok {
+		result.timestamp = time.Unix(created, 0)
+		if !checkTimestamp(result.timestamp, tolerance) {
+			slog.Debug("Signature Auth Policy: Created time outside the tolerance window", "created", created)
+			return verification{reason: "timestamp outside tolerance"}
+		}
+	} else if tolerance > 0 {
+		return verification{reason: "missing created parameter"}
+	}
+	if expires, ok := input.params.get("expires").(int64); ok && time.Now().After(time.Unix(expires, 0)) {
+		return verification{reason: "signature expired"}
+	}
+	result.nonce, _ = input.params.get("nonce").(string)
+	if result.nonce == "" && getBoolParam(params, "requireNonce", false) {
+		return verification{reason: "missing nonce"}
+	}
+
+	covered := make(map[string]bool, len(input.inner))
+	for _, component := range input.inner {
+		if name, ok := component.value.(string); ok {
+			covered[strings.ToLower(name)] = true
+		}
+	}
+	for _, component := range getStringArrayParam(params, "requiredComponents", defaultRequiredComponents) {
+		if !covered[strings.ToLower(component)] {
+			slog.Debug("Signature Auth Policy: Required component not covered by the signature",
+				"component", component,
+			)
+			return verification{reason: "required component not signed"}
+		}
+	}
+
+	body := requestBody(ctx)
+	if len(body) > 0 && getBoolParam(params, "requireContentDigest", true) && !covered[ContentDigestHeader] {
+		return verification{reason: "content-digest not signed"}
+	}
+	if covered[ContentDigestHeader] {
+		if err := verifyContentDigest(strings.Join(ctx.Headers.Get(ContentDigestHeader), ", "), body); err != nil {
+			slog.Debug("Signature Auth Policy: Content digest verification failed", "error", err)
+			return verification{reason: "content digest mismatch"}
+		}
+	}
+
+	base, err := signatureBase(ctx, input)
+	if err != nil {
+		slog.Debug("Signature Auth Policy: Failed to build signature base", "error", err)
+		return verification{reason: "invalid covered components"}
+	}
+
+	keyID, _ := input.params.get("keyid").(string)
+	if keyID == "" {
+		keyID = getStringParam(params, "keyId", "")
+	}
+	candidates := selectSecrets(keys, keyID)
+	if len(candidates) == 0 {
+		slog.Debug("Signature Auth Policy: Unknown key ID", "keyId", keyID)
+		return verification{reason: "unknown key ID"}
+	}
+	match := matchSecret(algorithm, candidates, base, [][]byte{signature})
+	if match == nil {
+		return verification{reason: "signature mismatch"}
+	}
+	result.keyID = match.ID
+	return result
+}
+
+// signatureBase builds the signature base of RFC 9421 section 2.5
+func signatureBase(ctx *policy.RequestContext, input *sfMember) ([]byte, error) {
+	var base strings.Builder
+	seen := make(map[string]bool, len(input.inner))
+	for _, component := range input.inner {
+		name, ok := component.value.(string)
+		if !ok {
+			return nil, fmt.Errorf("component identifier must be a string")
+		}
+		if seen[component.raw] {
+			return nil, fmt.Errorf("component %q is covered more than once", name)
+		}
+		seen[component.raw] = true
+
+		value, err := componentValue(ctx, name, component.params)
+		if err != nil {
+			return nil, err
+		}
+		base.WriteString(component.raw)
+		base.WriteString(": ")
+		base.WriteString(value)
+		base.WriteString("\n")
+	}
+	base.WriteString(`"@signature-params": `)
+	base.WriteString(input.raw)
+	return []byte(base.String()), nil
+}
+
+// componentValue returns the value of a derived component or header field
+func componentValue(ctx *policy.RequestContext, name string, params sfParams) (string, error) {
+	path, query, _ := strings.Cut(ctx.Path, "?")
+	if path == "" {
+		path = "/"
+	}
+
+	switch name {
+	case "@method":
+		return ctx.Method, nil
+	case "@target-uri":
+		return strings.ToLower(ctx.Scheme) + "://" + strings.ToLower(ctx.Authority) + ctx.Path, nil
+	case "@authority":
+		return strings.ToLower(ctx.Authority), nil
+	case "@scheme":
+		return strings.ToLower(ctx.Scheme), nil
+	case "@request-target":
+		return ctx.Path, nil
+	case "@path":
+		return path, nil
+	case "@query":
+		return "?" + query, nil
+	case "@query-param":
+		paramName, _ := params.get("name").(string)
+		values, err := url.ParseQuery(query)
+		if paramName == "" || err != nil || !values.Has(paramName) {
+			return "", fmt.Errorf("query parameter %q is not present", paramName)
+		}
+		return strings.ReplaceAll(url.QueryEscape(values.Get(paramName)), "+", "%20"), nil
+	}
+	if strings.HasPrefix(name, "@") {
+		return "", fmt.Errorf("unsupported derived component %q", name)
+	}
+	if len(params) > 0 {
+		return "", fmt.Errorf("unsupported parameters on component %q", name)
+	}
+
+	values := ctx.Headers.Get(name)
+	if len(values) == 0 {
+		return "", fmt.Errorf("covered header %q is not present", name)
+	}
+	trimmed := make([]string, len(values))
+	for i, value := range values {
+		trimmed[i] = strings.TrimSpace(value)
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+// verifyContentDigest checks the sha-256 and sha-512 digests of a Content-Digest header (RFC 9530)
+// against the body. At least one supported digest must be present.
+func verifyContentDigest(header string, body []byte) error {
+	digests, err := parseDictionary(header)
+	if err != nil {
+		return fmt.Errorf("invalid Content-Digest header: %w", err)
+	}
+	verified := false
+	for _, digest := range digests {
+		var expected []byte
+		switch digest.key {
+		case "sha-256":
+			sum := sha256.Sum256(body)
+			expected = sum[:]
+		case "sha-512":
+			sum := sha512.Sum512(body)
+			expected = sum[:]
+		default:
+			continue
+		}
+		value, ok := digest.item.value.([]byte)
+		if digest.isInnerList || !ok || subtle.ConstantTimeCompare(value, expected) != 1 {
+			return fmt.Errorf("%s digest does not match the body", digest.key)
+		}
+		verified = true
+	}
+	if !verified {
+		return fmt.Errorf("no supported digest algorithm")
+	}
+	return nil
+}
+
+func findMember(members []sfMember, key string) *sfMember {
+	for i := range members {
+		if members[i].key == key {
+			return &members[i]
+		}
+	}
+	return nil
+}
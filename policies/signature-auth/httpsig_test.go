@@ -0,0 +1,146 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package signatureauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// The shared key and signature of RFC 9421 Appendix B.2.5
+const rfcSharedSecret = "uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ=="
+
+func TestVerifyMessageSignature_RFC9421Example(t *testing.T) {
+	ctx := newRequestContext("POST", "/foo?param=Value&Pet=dog", map[string][]string{
+		"date":            {"Tue, 20 Apr 2021 02:07:55 GMT"},
+		"content-type":    {"application/json"},
+		"signature-input": {`sig-b25=("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`},
+		"signature":       {"sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:"},
+	}, `{"hello": "world"}`)
+	params := map[string]interface{}{
+		"profile":              "http-message-signatures",
+		"timestampTolerance":   "0s",
+		"requiredComponents":   []interface{}{"@authority"},
+		"requireContentDigest": false,
+		"keys": []interface{}{
+			map[string]interface{}{"id": "test-shared-secret", "secret": rfcSharedSecret, "encoding": "base64"},
+		},
+	}
+	expectSuccess(t, ctx, ins.OnRequest(ctx, params), "test-shared-secret")
+}
+
+func TestVerifyMessageSignature(t *testing.T) {
+	body := `{"amount":100}`
+	sum := sha256.Sum256([]byte(body))
+	digest := "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+	created := strconv.FormatInt(time.Now().Unix(), 10)
+
+	sign := func(components, signatureParams, digestHeader string) map[string][]string {
+		base := components + `"@signature-params": ` + signatureParams
+		mac := hmac.New(sha256.New, []byte("partner-secret"))
+		mac.Write([]byte(base))
+		return map[string][]string{
+			"content-digest":  {digestHeader},
+			"signature-input": {"sig1=" + signatureParams},
+			"signature":       {"sig1=:" + base64.StdEncoding.EncodeToString(mac.Sum(nil)) + ":"},
+		}
+	}
+	params := func() map[string]interface{} {
+		return map[string]interface{}{
+			"profile": "http-message-signatures",
+			"keys": []interface{}{
+				map[string]interface{}{"id": "partner", "secret": "partner-secret"},
+			},
+		}
+	}
+
+	fullComponents := "\"@method\": POST\n\"@authority\": example.com\n\"@path\": /payments\n\"content-digest\": " + digest + "\n"
+	fullParams := `("@method" "@authority" "@path" "content-digest");created=` + created + `;keyid="partner";nonce="n-1";alg="hmac-sha256"`
+
+	t.Run("valid signature", func(t *testing.T) {
+		ctx := newRequestContext("POST", "/payments", sign(fullComponents, fullParams, digest), body)
+		expectSuccess(t, ctx, ins.OnRequest(ctx, params()), "partner")
+	})
+
+	t.Run("replayed nonce", func(t *testing.T) {
+		ctx := newRequestContext("POST", "/payments", sign(fullComponents, fullParams, digest), body)
+		expectFailure(t, ctx, ins.OnRequest(ctx, params()))
+	})
+
+	t.Run("body does not match content digest", func(t *testing.T) {
+		signatureParams := `("@method" "@authority" "@path" "content-digest");created=` + created + `;keyid="partner";nonce="n-2"`
+		ctx := newRequestContext("POST", "/payments", sign(fullComponents, signatureParams, digest), `{"amount":1000}`)
+		expectFailure(t, ctx, ins.OnRequest(ctx, params()))
+	})
+
+	t.Run("body not covered", func(t *testing.T) {
+		components := "\"@method\": POST\n\"@authority\": example.com\n\"@path\": /payments\n"
+		signatureParams := `("@method" "@authority" "@path");created=` + created + `;keyid="partner"`
+		ctx := newRequestContext("POST", "/payments", sign(components, signatureParams, digest), body)
+		expectFailure(t, ctx, ins.OnRequest(ctx, params()))
+	})
+
+	t.Run("expired created time", func(t *testing.T) {
+		old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+		signatureParams := `("@method" "@authority" "@path" "content-digest");created=` + old + `;keyid="partner"`
+		ctx := newRequestContext("POST", "/payments", sign(fullComponents, signatureParams, digest), body)
+		expectFailure(t, ctx, ins.OnRequest(ctx, params()))
+	})
+
+	t.Run("algorithm mismatch", func(t *testing.T) {
+		signatureParams := `("@method" "@authority" "@path" "content-digest");created=` + created + `;keyid="partner";alg="hmac-sha512"`
+		ctx := newRequestContext("POST", "/payments", sign(fullComponents, signatureParams, digest), body)
+		expectFailure(t, ctx, ins.OnRequest(ctx, params()))
+	})
+}
+
+func TestParseDictionary(t *testing.T) {
+	members, err := parseDictionary(`sig1=("@method" "@query-param";name="id");created=1;keyid="k", sig2=:AQID:`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(members) != 2 {
+		t.Fatalf("expected 2 members, got %d", len(members))
+	}
+	sig1 := members[0]
+	if !sig1.isInnerList || len(sig1.inner) != 2 {
+		t.Fatalf("expected inner list of 2 items, got %#v", sig1)
+	}
+	if sig1.inner[1].raw != `"@query-param";name="id"` || sig1.inner[1].params.get("name") != "id" {
+		t.Fatalf("unexpected component %#v", sig1.inner[1])
+	}
+	if sig1.params.get("created") != int64(1) || sig1.params.get("keyid") != "k" {
+		t.Fatalf("unexpected parameters %#v", sig1.params)
+	}
+	if sig1.raw != `("@method" "@query-param";name="id");created=1;keyid="k"` {
+		t.Fatalf("unexpected raw member value %q", sig1.raw)
+	}
+	if value, ok := members[1].item.value.([]byte); !ok || len(value) != 3 {
+		t.Fatalf("unexpected byte sequence %#v", members[1].item.value)
+	}
+
+	for _, invalid := range []string{`sig1=("@method"`, `sig1=:AQID`, `Sig1=?1`, `sig1=1,`} {
+		if _, err := parseDictionary(invalid); err == nil {
+			t.Fatalf("expected error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package signatureauth
+
+import (
+	"container/list"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// Secret encodings for the encoding field of a key
+	SecretEncodingPlain  = "plain"
+	SecretEncodingBase64 = "base64"
+	SecretEncodingHex    = "hex"
+
+	// secretFileCheckInterval is the minimum time between checks of a key file for changes
+	secretFileCheckInterval = 5 * time.Second
+)
+
+// SharedSecret is an HMAC key declared in the policy configuration or a key file
+type SharedSecret struct {
+	ID     string // Key ID sent by the client and exported to metadata
+	Secret []byte // Decoded secret
+}
+
+// secretStore caches the key files shared across requests
+type secretStore struct {
+	mutex sync.Mutex
+	files map[string]*secretFile
+}
+
+// secretFile holds the keys loaded from a JSON key file
+type secretFile struct {
+	modTime   time.Time
+	size      int64
+	lastCheck time.Time
+	keys      []*SharedSecret
+}
+
+// getSecrets returns the keys of the policy followed by those of the key file
+func (p *SignatureAuthPolicy) getSecrets(params map[string]interface{}) ([]*SharedSecret, error) {
+	keys := parseSecrets(params["keys"], "policy")
+	if path := getStringParam(params, "keyFile", ""); path != "" {
+		p.storeOnce.Do(p.initStores)
+		fileKeys, err := p.store.getFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no shared secrets configured")
+	}
+	return keys, nil
+}
+
+// selectSecrets returns the keys with the given ID, or every key when no ID was provided
+func selectSecrets(keys []*SharedSecret, keyID string) []*SharedSecret {
+	if keyID == "" {
+		return keys
+	}
+	var selected []*SharedSecret
+	for _, key := range keys {
+		if key.ID == keyID {
+			selected = append(selected, key)
+		}
+	}
+	return selected
+}
+
+// parseSecrets reads a list of {id, secret, encoding} entries. Invalid entries are skipped.
+func parseSecrets(raw interface{}, source string) []*SharedSecret {
+	entries, ok := raw.([]interface{})
+	if !ok {
+		return nil
+	}
+	keys := make([]*SharedSecret, 0, len(entries))
+	for i, item := range entries {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			slog.Warn("Signature Auth Policy: Skipping invalid key entry", "source", source, "index", i)
+			continue
+		}
+		id := getStringParam(entry, "id", "")
+		secret, err := decodeSecret(getStringParam(entry, "secret", ""), getStringParam(entry, "encoding", SecretEncodingPlain))
+		if id == "" || err != nil {
+			slog.Warn("Signature Auth Policy: Skipping invalid key entry",
+				"source", source,
+				"index", i,
+				"keyId", id,
+				"error", err,
+			)
+			continue
+		}
+		keys = append(keys, &SharedSecret{ID: id, Secret: secret})
+	}
+	return keys
+}
+
+func decodeSecret(value, encoding string) ([]byte, error) {
+	if value == "" {
+		return nil, fmt.Errorf("secret is empty")
+	}
+	switch encoding {
+	case SecretEncodingPlain:
+		return []byte(value), nil
+	case SecretEncodingBase64:
+		return base64.StdEncoding.DecodeString(value)
+	case SecretEncodingHex:
+		return hex.DecodeString(value)
+	default:
+		return nil, fmt.Errorf("unsupported secret encoding %q", encoding)
+	}
+}
+
+// getFile returns the keys of a JSON key file ({"keys": [...]}), reloading it when its modification
+// time or size changed. A file that fails to load keeps the previously loaded keys.
+func (s *secretStore) getFile(path string) ([]*SharedSecret, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file, ok := s.files[path]
+	if ok && time.Since(file.lastCheck) < secretFileCheckInterval {
+		return file.keys, nil
+	}
+	if !ok {
+		file = &secretFile{}
+		s.files[path] = file
+	}
+	file.lastCheck = time.Now()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if file.keys != nil {
+			slog.Warn("Signature Auth Policy: Failed to stat key file, keeping previous keys",
+				"path", path,
+				"error", err,
+			)
+			return file.keys, nil
+		}
+		return nil, fmt.Errorf("failed to stat key file: %w", err)
+	}
+	if file.keys != nil && info.ModTime().Equal(file.modTime) && info.Size() == file.size {
+		return file.keys, nil
+	}
+
+	keys, err := loadSecretFile(path)
+	if err != nil {
+		if file.keys != nil {
+			slog.Warn("Signature Auth Policy: Failed to reload key file, keeping previous keys",
+				"path", path,
+				"error", err,
+			)
+			return file.keys, nil
+		}
+		return nil, err
+	}
+	file.keys = keys
+	file.modTime = info.ModTime()
+	file.size = info.Size()
+
+	slog.Debug("Signature Auth Policy: Loaded key file",
+		"path", path,
+		"keys", len(keys),
+	)
+	return keys, nil
+}
+
+func loadSecretFile(path string) ([]*SharedSecret, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	var content map[string]interface{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("failed to parse key file: %w", err)
+	}
+	return parseSecrets(content["keys"], path), nil
+}
+
+// replayCache remembers recently seen nonces until their timestamps are no longer accepted. When it is
+// full of live nonces, new nonces are refused rather than evicting one that could then be replayed.
+type replayCache struct {
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type replayEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+func newReplayCache() *replayCache {
+	return &replayCache{
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// seen records key until expiresAt and reports whether it was already recorded. full is true, and key
+// is not recorded, when capacity live keys are already recorded.
+func (c *replayCache) seen(key string, expiresAt time.Time, capacity int) (seen bool, full bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+
+	// Drop expired entries from the back; entries signed far apart may expire out of order, and
+	// those are removed once they reach the back or when the cache is full
+	for c.ll.Len() > 0 {
+		oldest := c.ll.Back().Value.(*replayEntry)
+		if now.Before(oldest.expiresAt) {
+			break
+		}
+		c.ll.Remove(c.ll.Back())
+		delete(c.items, oldest.key)
+	}
+
+	if element, ok := c.items[key]; ok {
+		if now.Before(element.Value.(*replayEntry).expiresAt) {
+			return true, false
+		}
+		c.ll.Remove(element)
+		delete(c.items, key)
+	}
+	if capacity > 0 && c.ll.Len() >= capacity {
+		c.removeExpired(now)
+		if c.ll.Len() >= capacity {
+			return false, true
+		}
+	}
+	c.items[key] = c.ll.PushFront(&replayEntry{key: key, expiresAt: expiresAt})
+	return false, false
+}
+
+// removeExpired drops every expired entry, including those behind entries that expire later
+func (c *replayCache) removeExpired(now time.Time) {
+	for element := c.ll.Back(); element != nil; {
+		prev := element.Prev()
+		if entry := element.Value.(*replayEntry); !now.Before(entry.expiresAt) {
+			c.ll.Remove(element)
+			delete(c.items, entry.key)
+		}
+		element = prev
+	}
+}
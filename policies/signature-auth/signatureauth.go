@@ -0,0 +1,317 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package signatureauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+const (
+	// Metadata keys for context storage
+	MetadataKeyAuthSuccess = "auth.success"
+	MetadataKeyAuthMethod  = "auth.method"
+	MetadataKeyKeyID       = "auth.keyId"
+
+	// Signature profiles for the profile parameter
+	ProfileHMAC                  = "hmac"                    // Configurable HMAC over selected components
+	ProfileGitHub                = "github"                  // X-Hub-Signature-256
+	ProfileStripe                = "stripe"                  // Stripe-Signature: t=...,v1=...
+	ProfileSlack                 = "slack"                   // X-Slack-Signature with X-Slack-Request-Timestamp
+	ProfileHTTPMessageSignatures = "http-message-signatures" // RFC 9421 with HMAC keys
+
+	// HMAC algorithms for the algorithm parameter
+	AlgorithmHMACSHA256 = "hmac-sha256"
+	AlgorithmHMACSHA512 = "hmac-sha512"
+
+	// Defaults
+	DefaultTimestampTolerance = 5 * time.Minute
+	DefaultNonceCacheSize     = 10000
+)
+
+// SignatureAuthPolicy verifies HMAC request signatures made with shared secrets
+type SignatureAuthPolicy struct {
+	storeOnce sync.Once
+	store     *secretStore
+	replay    *replayCache
+}
+
+var ins = &SignatureAuthPolicy{}
+
+func GetPolicy(
+	metadata policy.PolicyMetadata,
+	params map[string]interface{},
+) (policy.Policy, error) {
+	return ins, nil
+}
+
+// Mode returns the processing mode for this policy
+func (p *SignatureAuthPolicy) Mode() policy.ProcessingMode {
+	return policy.ProcessingMode{
+		RequestHeaderMode:  policy.HeaderModeProcess, // Process request headers for auth
+		RequestBodyMode:    policy.BodyModeBuffer,    // Signatures cover the request body
+		ResponseHeaderMode: policy.HeaderModeSkip,    // Don't process response headers
+		ResponseBodyMode:   policy.BodyModeSkip,      // Don't need response body
+	}
+}
+
+// verification is the outcome of checking a request signature
+type verification struct {
+	keyID     string    // ID of the key that produced the signature
+	nonce     string    // Nonce of the request, empty when none was sent
+	timestamp time.Time // Signing time, zero when the profile has none
+	reason    string    // Non-empty when the signature was rejected
+}
+
+// OnRequest verifies the request signature according to the configured profile
+func (p *SignatureAuthPolicy) OnRequest(ctx *policy.RequestContext, params map[string]interface{}) policy.RequestAction {
+	slog.Debug("Signature Auth Policy: OnRequest started",
+		"path", ctx.Path,
+		"method", ctx.Method,
+	)
+
+	profile := getStringParam(params, "profile", ProfileHMAC)
+	algorithm := getStringParam(params, "algorithm", AlgorithmHMACSHA256)
+	if newHash(algorithm) == nil {
+		slog.Debug("Signature Auth Policy: Unsupported algorithm", "algorithm", algorithm)
+		return p.handleAuthFailure(ctx, "unsupported algorithm")
+	}
+
+	keys, err := p.getSecrets(params)
+	if err != nil {
+		slog.Debug("Signature Auth Policy: Failed to load shared secrets", "error", err)
+		return p.handleAuthFailure(ctx, "no shared secrets available")
+	}
+
+	var result verification
+	switch profile {
+	case ProfileHMAC, ProfileGitHub, ProfileStripe, ProfileSlack:
+		config, err := getHMACConfig(profile, params)
+		if err != nil {
+			slog.Debug("Signature Auth Policy: Invalid configuration", "error", err)
+			return p.handleAuthFailure(ctx, "invalid configuration")
+		}
+		result = verifyHMACSignature(ctx, config, algorithm, keys, params)
+	case ProfileHTTPMessageSignatures:
+		result = verifyMessageSignature(ctx, algorithm, keys, params)
+	default:
+		slog.Debug("Signature Auth Policy: Unsupported profile", "profile", profile)
+		return p.handleAuthFailure(ctx, "unsupported profile")
+	}
+	if result.reason != "" {
+		return p.handleAuthFailure(ctx, result.reason)
+	}
+
+	if result.nonce != "" {
+		// A nonce only needs to be remembered while its timestamp is still accepted
+		tolerance := getDurationParam(params, "timestampTolerance", DefaultTimestampTolerance)
+		if tolerance <= 0 {
+			tolerance = DefaultTimestampTolerance
+		}
+		signedAt := result.timestamp
+		if signedAt.IsZero() {
+			signedAt = time.Now()
+		}
+		capacity := getIntParam(params, "nonceCacheSize", DefaultNonceCacheSize)
+		seen, full := p.getReplayCache().seen(result.keyID+"\x00"+result.nonce, signedAt.Add(tolerance), capacity)
+		if seen {
+			slog.Debug("Signature Auth Policy: Nonce already used",
+				"keyId", result.keyID,
+			)
+			return p.handleAuthFailure(ctx, "replayed nonce")
+		}
+		if full {
+			// Evicting a live nonce would allow it to be replayed, so refuse new nonces until some expire
+			slog.Warn("Signature Auth Policy: Nonce cache full, rejecting request",
+				"keyId", result.keyID,
+				"nonceCacheSize", capacity,
+			)
+			return p.handleAuthFailure(ctx, "nonce cache full")
+		}
+	}
+
+	return p.handleAuthSuccess(ctx, result.keyID)
+}
+
+// OnResponse is not used by this policy (authentication is request-only)
+func (p *SignatureAuthPolicy) OnResponse(ctx *policy.ResponseContext, params map[string]interface{}) policy.ResponseAction {
+	return nil
+}
+
+// handleAuthSuccess records the key that signed the request
+func (p *SignatureAuthPolicy) handleAuthSuccess(ctx *policy.RequestContext, keyID string) policy.RequestAction {
+	ctx.Metadata[MetadataKeyAuthSuccess] = true
+	ctx.Metadata[MetadataKeyAuthMethod] = "signature"
+	ctx.Metadata[MetadataKeyKeyID] = keyID
+
+	slog.Debug("Signature Auth Policy: Authentication successful",
+		"keyId", keyID,
+	)
+	return policy.UpstreamRequestModifications{}
+}
+
+// handleAuthFailure returns a 401 response. The reason is only logged, so clients cannot tell which
+// part of the signature check failed.
+func (p *SignatureAuthPolicy) handleAuthFailure(ctx *policy.RequestContext, reason string) policy.RequestAction {
+	slog.Debug("Signature Auth Policy: Authentication failed",
+		"reason", reason,
+		"apiId", ctx.APIId,
+		"apiName", ctx.APIName,
+		"apiVersion", ctx.APIVersion,
+		"method", ctx.Method,
+		"path", ctx.Path,
+	)
+
+	ctx.Metadata[MetadataKeyAuthSuccess] = false
+	ctx.Metadata[MetadataKeyAuthMethod] = "signature"
+	delete(ctx.Metadata, MetadataKeyKeyID)
+
+	body, _ := json.Marshal(map[string]string{
+		"error":   "Unauthorized",
+		"message": "Invalid or missing request signature",
+	})
+	return policy.ImmediateResponse{
+		StatusCode: 401,
+		Headers: map[string]string{
+			"content-type": "application/json",
+		},
+		Body: body,
+	}
+}
+
+// getReplayCache returns the policy's nonce cache, creating it on first use
+func (p *SignatureAuthPolicy) getReplayCache() *replayCache {
+	p.storeOnce.Do(p.initStores)
+	return p.replay
+}
+
+func (p *SignatureAuthPolicy) initStores() {
+	p.store = &secretStore{files: make(map[string]*secretFile)}
+	p.replay = newReplayCache()
+}
+
+// newHash returns the hash constructor of an HMAC algorithm, or nil when it is not supported
+func newHash(algorithm string) func() hash.Hash {
+	switch algorithm {
+	case AlgorithmHMACSHA256:
+		return sha256.New
+	case AlgorithmHMACSHA512:
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
+// computeHMAC returns the HMAC of message with the given secret
+func computeHMAC(algorithm string, secret, message []byte) []byte {
+	mac := hmac.New(newHash(algorithm), secret)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// matchSecret returns the key whose HMAC of message equals one of the signatures. Every key and
+// signature is compared, so the time taken does not reveal which one matched.
+func matchSecret(algorithm string, keys []*SharedSecret, message []byte, signatures [][]byte) *SharedSecret {
+	var match *SharedSecret
+	for _, key := range keys {
+		expected := computeHMAC(algorithm, key.Secret, message)
+		for _, signature := range signatures {
+			if hmac.Equal(expected, signature) && match == nil {
+				match = key
+			}
+		}
+	}
+	return match
+}
+
+// checkTimestamp reports whether a signing time is within the tolerance window around now
+func checkTimestamp(signedAt time.Time, tolerance time.Duration) bool {
+	if tolerance <= 0 {
+		return true
+	}
+	skew := time.Since(signedAt)
+	if skew < 0 {
+		skew = -skew
+	}
+	return skew <= tolerance
+}
+
+// parseUnixTimestamp parses a timestamp in seconds since the epoch
+func parseUnixTimestamp(value string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// Helper functions for type assertions
+func getStringParam(params map[string]interface{}, key, defaultValue string) string {
+	if v, ok := params[key].(string); ok && v != "" {
+		return v
+	}
+	return defaultValue
+}
+
+func getBoolParam(params map[string]interface{}, key string, defaultValue bool) bool {
+	if v, ok := params[key].(bool); ok {
+		return v
+	}
+	return defaultValue
+}
+
+func getIntParam(params map[string]interface{}, key string, defaultValue int) int {
+	switch v := params[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return defaultValue
+}
+
+func getDurationParam(params map[string]interface{}, key string, defaultValue time.Duration) time.Duration {
+	if d, err := time.ParseDuration(getStringParam(params, key, "")); err == nil {
+		return d
+	}
+	return defaultValue
+}
+
+func getStringArrayParam(params map[string]interface{}, key string, defaultValue []string) []string {
+	raw, ok := params[key].([]interface{})
+	if !ok {
+		return defaultValue
+	}
+	result := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
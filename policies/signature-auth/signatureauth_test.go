@@ -0,0 +1,281 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package signatureauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	policy "github.com/wso2/api-platform/sdk/gateway/policy/v1alpha"
+)
+
+func newRequestContext(method, path string, headers map[string][]string, body string) *policy.RequestContext {
+	return &policy.RequestContext{
+		SharedContext: &policy.SharedContext{
+			RequestID:  "req-1",
+			Metadata:   map[string]interface{}{},
+			APIId:      "api-1",
+			APIName:    "WebhooksAPI",
+			APIVersion: "v1",
+		},
+		Headers:   policy.NewHeaders(headers),
+		Body:      &policy.Body{Content: []byte(body), EndOfStream: true, Present: body != ""},
+		Path:      path,
+		Method:    method,
+		Authority: "example.com",
+		Scheme:    "https",
+	}
+}
+
+func keyParams(params map[string]interface{}) map[string]interface{} {
+	params["keys"] = []interface{}{
+		map[string]interface{}{"id": "partner-a", "secret": "secret-a"},
+		map[string]interface{}{"id": "partner-b", "secret": hex.EncodeToString([]byte("secret-b")), "encoding": "hex"},
+	}
+	return params
+}
+
+func hmacSHA256Hex(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func expectSuccess(t *testing.T, ctx *policy.RequestContext, action policy.RequestAction, keyID string) {
+	t.Helper()
+	if _, ok := action.(policy.UpstreamRequestModifications); !ok {
+		t.Fatalf("expected UpstreamRequestModifications, got %#v", action)
+	}
+	if ctx.Metadata[MetadataKeyAuthSuccess] != true {
+		t.Fatalf("expected auth.success=true, got %v", ctx.Metadata[MetadataKeyAuthSuccess])
+	}
+	if ctx.Metadata[MetadataKeyKeyID] != keyID {
+		t.Fatalf("expected auth.keyId=%s, got %v", keyID, ctx.Metadata[MetadataKeyKeyID])
+	}
+}
+
+func expectFailure(t *testing.T, ctx *policy.RequestContext, action policy.RequestAction) {
+	t.Helper()
+	resp, ok := action.(policy.ImmediateResponse)
+	if !ok {
+		t.Fatalf("expected ImmediateResponse, got %#v", action)
+	}
+	if resp.StatusCode != 401 {
+		t.Fatalf("expected status 401, got %d", resp.StatusCode)
+	}
+	if ctx.Metadata[MetadataKeyAuthSuccess] != false {
+		t.Fatalf("expected auth.success=false, got %v", ctx.Metadata[MetadataKeyAuthSuccess])
+	}
+}
+
+func TestSignatureAuthPolicy_GitHubProfile(t *testing.T) {
+	body := `{"action":"opened"}`
+	params := keyParams(map[string]interface{}{"profile": "github", "keyId": "partner-a"})
+	headers := func(delivery string) map[string][]string {
+		return map[string][]string{
+			"x-hub-signature-256": {"sha256=" + hmacSHA256Hex("secret-a", body)},
+			"x-github-delivery":   {delivery},
+		}
+	}
+
+	ctx := newRequestContext("POST", "/hooks/github", headers("delivery-1"), body)
+	expectSuccess(t, ctx, ins.OnRequest(ctx, params), "partner-a")
+
+	// GitHub signs only the body, so the unsigned delivery ID is not used as a nonce
+	ctx = newRequestContext("POST", "/hooks/github", headers("delivery-1"), body)
+	expectSuccess(t, ctx, ins.OnRequest(ctx, params), "partner-a")
+
+	// A modified body does not match the signature
+	ctx = newRequestContext("POST", "/hooks/github", headers("delivery-2"), `{"action":"closed"}`)
+	expectFailure(t, ctx, ins.OnRequest(ctx, params))
+}
+
+func TestSignatureAuthPolicy_SlackProfile(t *testing.T) {
+	body := "token=abc&team_id=T1"
+	params := keyParams(map[string]interface{}{"profile": "slack", "timestampTolerance": "5m"})
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		wantOK    bool
+	}{
+		{name: "within tolerance", timestamp: time.Now().Add(-time.Minute), wantOK: true},
+		{name: "too old", timestamp: time.Now().Add(-10 * time.Minute), wantOK: false},
+		{name: "too far in the future", timestamp: time.Now().Add(10 * time.Minute), wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamp := strconv.FormatInt(tt.timestamp.Unix(), 10)
+			ctx := newRequestContext("POST", "/hooks/slack", map[string][]string{
+				"x-slack-signature":         {"v0=" + hmacSHA256Hex("secret-b", "v0:"+timestamp+":"+body)},
+				"x-slack-request-timestamp": {timestamp},
+			}, body)
+			action := ins.OnRequest(ctx, params)
+			if tt.wantOK {
+				// No key ID is sent, so every configured key is tried
+				expectSuccess(t, ctx, action, "partner-b")
+			} else {
+				expectFailure(t, ctx, action)
+			}
+		})
+	}
+}
+
+func TestSignatureAuthPolicy_StripeProfile(t *testing.T) {
+	body := `{"id":"evt_1"}`
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	params := keyParams(map[string]interface{}{"profile": "stripe", "keyId": "partner-a"})
+
+	// During secret rotation Stripe sends one v1 signature per secret
+	header := "t=" + timestamp + ",v1=" + hmacSHA256Hex("old-secret", timestamp+"."+body) +
+		",v1=" + hmacSHA256Hex("secret-a", timestamp+"."+body)
+	ctx := newRequestContext("POST", "/hooks/stripe", map[string][]string{"stripe-signature": {header}}, body)
+	expectSuccess(t, ctx, ins.OnRequest(ctx, params), "partner-a")
+
+	header = "t=" + timestamp + ",v1=" + hmacSHA256Hex("secret-a", timestamp+".tampered")
+	ctx = newRequestContext("POST", "/hooks/stripe", map[string][]string{"stripe-signature": {header}}, body)
+	expectFailure(t, ctx, ins.OnRequest(ctx, params))
+}
+
+func TestSignatureAuthPolicy_GenericProfile(t *testing.T) {
+	body := `{"order":42}`
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	params := keyParams(map[string]interface{}{
+		"algorithm":       "hmac-sha512",
+		"encoding":        "base64",
+		"keyIdHeader":     "X-Key-Id",
+		"timestampHeader": "X-Timestamp",
+		"nonceHeader":     "X-Nonce",
+		"components":      []interface{}{"method", "path", "timestamp", "nonce", "header:content-type", "body"},
+	})
+	sign := func(secret, nonce string) string {
+		mac := hmac.New(sha512.New, []byte(secret))
+		mac.Write([]byte("POST\n/orders?dry=true\n" + timestamp + "\n" + nonce + "\napplication/json\n" + body))
+		return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	headers := func(keyID, signature, nonce string) map[string][]string {
+		return map[string][]string{
+			"x-key-id":     {keyID},
+			"x-signature":  {signature},
+			"x-timestamp":  {timestamp},
+			"x-nonce":      {nonce},
+			"content-type": {"application/json"},
+		}
+	}
+
+	ctx := newRequestContext("POST", "/orders?dry=true", headers("partner-b", sign("secret-b", "n-1"), "n-1"), body)
+	expectSuccess(t, ctx, ins.OnRequest(ctx, params), "partner-b")
+
+	// A signature made with another partner's secret is rejected
+	ctx = newRequestContext("POST", "/orders?dry=true", headers("partner-b", sign("secret-a", "n-2"), "n-2"), body)
+	expectFailure(t, ctx, ins.OnRequest(ctx, params))
+
+	ctx = newRequestContext("POST", "/orders?dry=true", headers("unknown", sign("secret-b", "n-3"), "n-3"), body)
+	expectFailure(t, ctx, ins.OnRequest(ctx, params))
+
+	// Nonces are tracked per key, so the same nonce is accepted for a different key
+	ctx = newRequestContext("POST", "/orders?dry=true", headers("partner-a", sign("secret-a", "n-1"), "n-1"), body)
+	expectSuccess(t, ctx, ins.OnRequest(ctx, params), "partner-a")
+}
+
+func TestGetHMACConfig_UnsignedReplayHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		params  map[string]interface{}
+		valid   bool
+	}{
+		{name: "unsigned nonce", profile: ProfileGitHub, params: map[string]interface{}{"nonceHeader": "X-GitHub-Delivery"}},
+		{name: "unsigned timestamp", profile: ProfileHMAC, params: map[string]interface{}{"timestampHeader": "X-Timestamp"}},
+		{name: "signed nonce", profile: ProfileHMAC, params: map[string]interface{}{
+			"nonceHeader": "X-Nonce",
+			"components":  []interface{}{"nonce", "body"},
+		}, valid: true},
+		{name: "nonce signed as header", profile: ProfileHMAC, params: map[string]interface{}{
+			"nonceHeader": "X-Nonce",
+			"components":  []interface{}{"header:x-nonce", "body"},
+		}, valid: true},
+		{name: "slack timestamp", profile: ProfileSlack, params: map[string]interface{}{}, valid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := getHMACConfig(tt.profile, tt.params)
+			if tt.valid && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expected the configuration to be rejected")
+			}
+		})
+	}
+}
+
+func TestSignatureAuthPolicy_KeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	secret := base64.StdEncoding.EncodeToString([]byte("file-secret"))
+	content := `{"keys":[{"id":"file-key","secret":"` + secret + `","encoding":"base64"}]}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	body := "payload"
+	ctx := newRequestContext("POST", "/hooks", map[string][]string{
+		"x-signature": {hmacSHA256Hex("file-secret", body)},
+		"x-key-id":    {"file-key"},
+	}, body)
+	action := ins.OnRequest(ctx, map[string]interface{}{"keyFile": path, "keyIdHeader": "X-Key-Id"})
+	expectSuccess(t, ctx, action, "file-key")
+}
+
+func TestSignatureAuthPolicy_ReplayCacheCapacity(t *testing.T) {
+	cache := newReplayCache()
+	expiresAt := time.Now().Add(time.Minute)
+	for _, nonce := range []string{"a", "b"} {
+		if seen, full := cache.seen(nonce, expiresAt, 2); seen || full {
+			t.Fatalf("nonce %s reported as seen=%v full=%v", nonce, seen, full)
+		}
+	}
+
+	// A full cache refuses new nonces instead of evicting a live one
+	if seen, full := cache.seen("c", expiresAt, 2); seen || !full {
+		t.Fatalf("expected nonce c to be refused by the full cache, got seen=%v full=%v", seen, full)
+	}
+	if seen, _ := cache.seen("a", expiresAt, 2); !seen {
+		t.Fatal("expected nonce a to still be reported as seen")
+	}
+
+	// Expired nonces free their slots, even behind live ones
+	cache.items["b"].Value.(*replayEntry).expiresAt = time.Now().Add(-time.Second)
+	if seen, full := cache.seen("c", expiresAt, 2); seen || full {
+		t.Fatalf("expected nonce c to be recorded, got seen=%v full=%v", seen, full)
+	}
+	if seen, _ := cache.seen("c", expiresAt, 2); !seen {
+		t.Fatal("expected nonce c to be reported as seen")
+	}
+	if seen, _ := cache.seen("a", expiresAt, 2); !seen {
+		t.Fatal("expected nonce a to still be reported as seen")
+	}
+}
@@ -0,0 +1,311 @@
+/*
+ *  Copyright (c) 2026, WSO2 LLC. (http://www.wso2.org) All Rights Reserved.
+ *
+ *  Licensed under the Apache License, Version 2.0 (the "License");
+ *  you may not use this file except in compliance with the License.
+ *  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *  Unless required by applicable law or agreed to in writing, software
+ *  distributed under the License is distributed on an "AS IS" BASIS,
+ *  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  See the License for the specific language governing permissions and
+ *  limitations under the License.
+ *
+ */
+
+package signatureauth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The parser below covers the parts of RFC 8941 Structured Field Values used by Signature-Input,
+// Signature and Content-Digest: dictionaries of items and inner lists with parameters. Bare items
+// are strings, tokens, integers, byte sequences and booleans; decimals are not supported.
+
+// sfToken is a structured field token, kept apart from strings
+type sfToken string
+
+type sfParam struct {
+	key   string
+	value interface{}
+}
+
+type sfParams []sfParam
+
+// get returns the value of a parameter, or nil when it is not present
+func (params sfParams) get(key string) interface{} {
+	for _, param := range params {
+		if param.key == key {
+			return param.value
+		}
+	}
+	return nil
+}
+
+// sfItem is a bare item with its parameters. raw is the item as it appeared in the field.
+type sfItem struct {
+	value  interface{}
+	params sfParams
+	raw    string
+}
+
+// sfMember is a dictionary member holding an item or an inner list. raw is the member value
+// (after "=") as it appeared in the field, including its parameters.
+type sfMember struct {
+	key         string
+	item        sfItem
+	inner       []sfItem
+	isInnerList bool
+	params      sfParams
+	raw         string
+}
+
+type sfParser struct {
+	input string
+	pos   int
+}
+
+// parseDictionary parses a structured field dictionary. An empty field yields no members.
+func parseDictionary(field string) ([]sfMember, error) {
+	p := &sfParser{input: strings.TrimSpace(field)}
+	var members []sfMember
+	for p.pos < len(p.input) {
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		member := sfMember{key: key}
+		if p.peek() == '=' {
+			p.pos++
+			start := p.pos
+			if p.peek() == '(' {
+				member.isInnerList = true
+				if member.inner, err = p.parseInnerList(); err != nil {
+					return nil, err
+				}
+				if member.params, err = p.parseParams(); err != nil {
+					return nil, err
+				}
+			} else {
+				if member.item, err = p.parseItem(); err != nil {
+					return nil, err
+				}
+				member.params = member.item.params
+			}
+			member.raw = p.input[start:p.pos]
+		} else {
+			start := p.pos
+			if member.params, err = p.parseParams(); err != nil {
+				return nil, err
+			}
+			member.item = sfItem{value: true, params: member.params, raw: p.input[start:p.pos]}
+			member.raw = member.item.raw
+		}
+		// Later members with the same key replace earlier ones
+		if existing := findMember(members, key); existing != nil {
+			*existing = member
+		} else {
+			members = append(members, member)
+		}
+
+		p.skipWhitespace()
+		if p.pos >= len(p.input) {
+			break
+		}
+		if p.input[p.pos] != ',' {
+			return nil, fmt.Errorf("expected ',' at offset %d", p.pos)
+		}
+		p.pos++
+		p.skipWhitespace()
+		if p.pos >= len(p.input) {
+			return nil, fmt.Errorf("trailing comma")
+		}
+	}
+	return members, nil
+}
+
+func (p *sfParser) parseInnerList() ([]sfItem, error) {
+	p.pos++ // (
+	var items []sfItem
+	for {
+		for p.peek() == ' ' {
+			p.pos++
+		}
+		if p.pos >= len(p.input) {
+			return nil, fmt.Errorf("unterminated inner list")
+		}
+		if p.input[p.pos] == ')' {
+			p.pos++
+			return items, nil
+		}
+		item, err := p.parseItem()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if c := p.peek(); c != ' ' && c != ')' {
+			return nil, fmt.Errorf("expected ' ' or ')' at offset %d", p.pos)
+		}
+	}
+}
+
+func (p *sfParser) parseItem() (sfItem, error) {
+	start := p.pos
+	value, err := p.parseBareItem()
+	if err != nil {
+		return sfItem{}, err
+	}
+	params, err := p.parseParams()
+	if err != nil {
+		return sfItem{}, err
+	}
+	return sfItem{value: value, params: params, raw: p.input[start:p.pos]}, nil
+}
+
+func (p *sfParser) parseParams() (sfParams, error) {
+	var params sfParams
+	for p.peek() == ';' {
+		p.pos++
+		for p.peek() == ' ' {
+			p.pos++
+		}
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		var value interface{} = true
+		if p.peek() == '=' {
+			p.pos++
+			if value, err = p.parseBareItem(); err != nil {
+				return nil, err
+			}
+		}
+		params = append(params, sfParam{key: key, value: value})
+	}
+	return params, nil
+}
+
+func (p *sfParser) parseBareItem() (interface{}, error) {
+	c := p.peek()
+	switch {
+	case c == '"':
+		return p.parseString()
+	case c == ':':
+		return p.parseByteSequence()
+	case c == '?':
+		if p.pos+1 < len(p.input) && (p.input[p.pos+1] == '0' || p.input[p.pos+1] == '1') {
+			p.pos += 2
+			return p.input[p.pos-1] == '1', nil
+		}
+		return nil, fmt.Errorf("invalid boolean at offset %d", p.pos)
+	case c == '-' || isDigit(c):
+		return p.parseInteger()
+	case isAlpha(c) || c == '*':
+		start := p.pos
+		for p.pos < len(p.input) && isTokenChar(p.input[p.pos]) {
+			p.pos++
+		}
+		return sfToken(p.input[start:p.pos]), nil
+	default:
+		return nil, fmt.Errorf("unexpected character at offset %d", p.pos)
+	}
+}
+
+func (p *sfParser) parseString() (string, error) {
+	p.pos++ // "
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+		switch {
+		case c == '\\':
+			if p.pos >= len(p.input) || (p.input[p.pos] != '"' && p.input[p.pos] != '\\') {
+				return "", fmt.Errorf("invalid escape in string")
+			}
+			b.WriteByte(p.input[p.pos])
+			p.pos++
+		case c == '"':
+			return b.String(), nil
+		case c < 0x20 || c > 0x7e:
+			return "", fmt.Errorf("invalid character in string")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+func (p *sfParser) parseByteSequence() ([]byte, error) {
+	p.pos++ // :
+	end := strings.IndexByte(p.input[p.pos:], ':')
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated byte sequence")
+	}
+	encoded := p.input[p.pos : p.pos+end]
+	p.pos += end + 1
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+func (p *sfParser) parseInteger() (int64, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.input) && isDigit(p.input[p.pos]) {
+		p.pos++
+	}
+	if p.peek() == '.' {
+		return 0, fmt.Errorf("decimals are not supported")
+	}
+	if p.pos-start > 16 {
+		return 0, fmt.Errorf("integer too long")
+	}
+	return strconv.ParseInt(p.input[start:p.pos], 10, 64)
+}
+
+func (p *sfParser) parseKey() (string, error) {
+	start := p.pos
+	if c := p.peek(); !(c >= 'a' && c <= 'z') && c != '*' {
+		return "", fmt.Errorf("invalid key at offset %d", p.pos)
+	}
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		if !(c >= 'a' && c <= 'z') && !isDigit(c) && c != '_' && c != '-' && c != '.' && c != '*' {
+			break
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos], nil
+}
+
+func (p *sfParser) skipWhitespace() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// peek returns the current character, or 0 at the end of the input
+func (p *sfParser) peek() byte {
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isTokenChar(c byte) bool {
+	return isAlpha(c) || isDigit(c) || strings.IndexByte("!#$%&'*+-.^_`|~:/", c) >= 0
+}